@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/michaelmccabe/ramjam/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Work with benchmark results",
+	Long: `Work with the JSON results written by ramjam load --json.
+Examples:
+  ramjam bench compare baseline.json current.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var benchCompareCmd = &cobra.Command{
+	Use:   "compare <base.json> <current.json>",
+	Short: "Compare two load test results and fail on regressions",
+	Long: `Compare the per-step latency and error rate of two results written by
+ramjam load --json. A step regresses when the chosen latency statistic grows
+by more than --threshold percent, or when it starts failing. The command
+exits with code 1 if any step regressed.
+Examples:
+  ramjam load checkout.yaml --vus 20 --duration 1m --json > current.json
+  ramjam bench compare baseline.json current.json
+  ramjam bench compare baseline.json current.json --metric p99 --threshold 20`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		metric, _ := cmd.Flags().GetString("metric")
+		threshold, _ := cmd.Flags().GetFloat64("threshold")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		cmp, err := runner.CompareBench(args[0], args[1], runner.BenchOptions{Metric: metric, Threshold: threshold})
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if asJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(cmp); err != nil {
+				return err
+			}
+		} else {
+			printBenchComparison(out, args[0], args[1], cmp)
+		}
+		if cmp.Regressions > 0 {
+			return &exitCodeError{exitFailed, fmt.Errorf("%d of %d steps regressed", cmp.Regressions, len(cmp.Steps))}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	benchCmd.AddCommand(benchCompareCmd)
+	benchCompareCmd.Flags().String("metric", "p95", "Latency statistic to compare: "+strings.Join(runner.BenchMetrics, ", "))
+	benchCompareCmd.Flags().Float64("threshold", 10, "Percentage increase in latency that counts as a regression")
+	benchCompareCmd.Flags().Bool("json", false, "Print the comparison as JSON")
+}
+
+// printBenchComparison writes a table of each step's latency in both
+// results, marking regressions.
+func printBenchComparison(out io.Writer, base, current string, cmp *runner.BenchComparison) {
+	fmt.Fprintf(out, "Comparing %s latency: %s -> %s (threshold %g%%)\n\n", cmp.Metric, base, current, cmp.Threshold)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  Step\tBase\tCurrent\tChange\t")
+	for _, d := range cmp.Steps {
+		switch {
+		case d.Base == nil:
+			fmt.Fprintf(tw, "  %s\t-\t%.1fms\tnew step\t\n", d.Step, d.Current.Metric(cmp.Metric))
+		case d.Current == nil:
+			fmt.Fprintf(tw, "  %s\t%.1fms\t-\tremoved\t\n", d.Step, d.Base.Metric(cmp.Metric))
+		default:
+			note := ""
+			if d.NewlyFailing {
+				note = fmt.Sprintf("newly failing (%.2f%% errors)  ", d.CurrentErrorRate*100)
+			}
+			if d.Regressed {
+				note += "REGRESSION"
+			}
+			fmt.Fprintf(tw, "  %s\t%.1fms\t%.1fms\t%+.1f%%\t%s\n", d.Step, d.Base.Metric(cmp.Metric), d.Current.Metric(cmp.Metric), d.ChangePercent, note)
+		}
+	}
+	tw.Flush()
+	if cmp.Regressions == 0 {
+		fmt.Fprintln(out, "\nNo regressions")
+	}
+}
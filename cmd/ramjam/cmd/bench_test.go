@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBenchCompareCmd(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.json")
+	os.WriteFile(base, []byte(`{"steps": [{"name": "checkout", "requests": 10, "p95_ms": 100}]}`), 0644)
+	current := filepath.Join(dir, "current.json")
+	os.WriteFile(current, []byte(`{"steps": [{"name": "checkout", "requests": 10, "p95_ms": 125}]}`), 0644)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+	defer benchCompareCmd.Flags().Set("threshold", "10")
+
+	rootCmd.SetArgs([]string{"bench", "compare", base, current})
+	err := rootCmd.Execute()
+	if exitCode(err) != exitFailed || err.Error() != "1 of 1 steps regressed" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "checkout  100.0ms  125.0ms  +25.0%  REGRESSION") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	out.Reset()
+	rootCmd.SetArgs([]string{"bench", "compare", base, current, "--threshold", "30"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "No regressions") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/michaelmccabe/ramjam/pkg/convert"
+	"github.com/spf13/cobra"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert <file>",
+	Short: "Convert a recorded session into a workflow skeleton",
+	Long: `Convert a recorded browser or proxy session into a YAML workflow with one
+step per request, including headers, bodies and the recorded status.
+Examples:
+  ramjam convert --from har session.har -o workflow.yaml
+  ramjam convert --from har session.har --include-assets`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		output, _ := cmd.Flags().GetString("output")
+		includeAssets, _ := cmd.Flags().GetBool("include-assets")
+		if from != "har" {
+			return fmt.Errorf("unsupported input format %q (expected har)", from)
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("open %s: %w", args[0], err)
+		}
+		defer f.Close()
+
+		name := strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+		wf, err := convert.FromHAR(f, convert.HAROptions{Name: name, IncludeAssets: includeAssets})
+		if err != nil {
+			return err
+		}
+		data, err := convert.Marshal(wf)
+		if err != nil {
+			return err
+		}
+
+		if output == "" {
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return fmt.Errorf("write %s: %w", output, err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Wrote %d steps to %s\n", len(wf.Workflow), output)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.Flags().String("from", "har", "Input format (har)")
+	convertCmd.Flags().StringP("output", "o", "", "Write the workflow to this file instead of stdout")
+	convertCmd.Flags().Bool("include-assets", false, "Keep requests for scripts, styles, images and fonts")
+}
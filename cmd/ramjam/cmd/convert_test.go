@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertCmdRegistered(t *testing.T) {
+	found := false
+	for _, c := range rootCmd.Commands() {
+		if c == convertCmd {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatal("convert command should be registered with root")
+	}
+}
+
+func TestConvertCmdHAR(t *testing.T) {
+	dir := t.TempDir()
+	harPath := filepath.Join(dir, "session.har")
+	har := `{"log": {"entries": [{"request": {"method": "GET", "url": "http://x.test/users"}, "response": {"status": 200}}]}}`
+	if err := os.WriteFile(harPath, []byte(har), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outPath := filepath.Join(dir, "workflow.yaml")
+
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+	rootCmd.SetArgs([]string{"convert", "--from", "har", harPath, "-o", outPath})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("convert failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "name: session") || !strings.Contains(string(data), "step: get-users") {
+		t.Errorf("unexpected workflow:\n%s", data)
+	}
+	if !strings.Contains(stdout.String(), "Wrote 1 steps to") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+}
+
+func TestConvertCmdUnknownFormat(t *testing.T) {
+	defer rootCmd.SetArgs(nil)
+	defer convertCmd.Flags().Set("from", "har")
+	rootCmd.SetArgs([]string{"convert", "--from", "postman", "x.json"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), `unsupported input format "postman"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/michaelmccabe/ramjam/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// addCustomCommands registers the subcommands defined in commands.yaml. Each
+// one is a shortcut for run with default workflows, environment, profile and
+// variables, and accepts all of run's flags.
+func addCustomCommands(cmds *config.CommandsConfig) error {
+	if cmds == nil {
+		return nil
+	}
+	for _, custom := range cmds.Commands {
+		if custom.Name == "" {
+			return fmt.Errorf("custom command without a name in commands.yaml")
+		}
+		if existing, _, err := rootCmd.Find([]string{custom.Name}); err == nil && existing != rootCmd {
+			return fmt.Errorf("custom command %q conflicts with an existing command", custom.Name)
+		}
+		rootCmd.AddCommand(customCommand(custom))
+	}
+	return nil
+}
+
+func customCommand(custom config.CustomCommand) *cobra.Command {
+	c := &cobra.Command{
+		Use:   custom.Name + " [files-or-folders...]",
+		Short: custom.Short,
+		Long:  custom.Long,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = custom.Args
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("%s: no workflow files given and none configured", custom.Name)
+			}
+			for flag, value := range map[string]string{"env": custom.Env, "profile": custom.Profile} {
+				if value != "" && !cmd.Flags().Changed(flag) {
+					if err := cmd.Flags().Set(flag, value); err != nil {
+						return err
+					}
+				}
+			}
+			if len(custom.Vars) > 0 {
+				// The configured variables go first so --var can override
+				// them.
+				names := make([]string, 0, len(custom.Vars))
+				for name := range custom.Vars {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				pairs := make([]string, 0, len(names))
+				for _, name := range names {
+					pairs = append(pairs, name+"="+custom.Vars[name])
+				}
+				given, _ := cmd.Flags().GetStringArray("var")
+				if err := cmd.Flags().Lookup("var").Value.(pflag.SliceValue).Replace(append(pairs, given...)); err != nil {
+					return err
+				}
+			}
+			return runCmd.RunE(cmd, args)
+		},
+	}
+	addRunFlags(c.Flags())
+	return c
+}
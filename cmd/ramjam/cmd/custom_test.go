@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/michaelmccabe/ramjam/pkg/config"
+)
+
+func TestCustomCommand(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, "smoke.yaml")
+	os.WriteFile(workflow, []byte(`
+config:
+  base_url: "`+srv.URL+`"
+workflow:
+- step: "health"
+  request:
+    url: "/${region}/${tier}/health"
+`), 0644)
+	t.Chdir(dir)
+
+	cmds := &config.CommandsConfig{Commands: []config.CustomCommand{{
+		Name:  "smoke",
+		Short: "Run the smoke tests",
+		Args:  []string{workflow},
+		Vars:  map[string]string{"region": "eu", "tier": "free"},
+	}}}
+	if err := addCustomCommands(cmds); err != nil {
+		t.Fatalf("addCustomCommands() error = %v", err)
+	}
+	smoke, _, _ := rootCmd.Find([]string{"smoke"})
+	defer rootCmd.RemoveCommand(smoke)
+	if smoke.Short != "Run the smoke tests" || smoke.Flags().Lookup("report") == nil {
+		t.Fatalf("smoke command = %q, report flag %v", smoke.Short, smoke.Flags().Lookup("report"))
+	}
+
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stdout)
+	defer rootCmd.SetArgs(nil)
+	rootCmd.SetArgs([]string{"smoke", "--var", "tier=pro"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("smoke command failed: %v\n%s", err, stdout.String())
+	}
+	if strings.Join(seen, ",") != "/eu/pro/health" {
+		t.Errorf("requests = %q", seen)
+	}
+
+	if err := addCustomCommands(&config.CommandsConfig{Commands: []config.CustomCommand{{Name: "run"}}}); err == nil {
+		t.Error("custom command named run was accepted")
+	}
+}
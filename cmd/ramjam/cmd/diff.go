@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/michaelmccabe/ramjam/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <files-or-folders...> --env <a.yaml> --env-b <b.yaml>",
+	Short: "Compare the responses of two environments",
+	Long: `Run the same workflows against two environments and compare each step's
+status and JSON body. Each environment is a project environment name (see
+ramjam env) or a YAML file of variables, such as base_url, that override the
+values in the workflow. The command fails if any step differs.
+Examples:
+  ramjam diff users.yaml --env staging --env-b production
+  ramjam diff users.yaml --env staging.yaml --env-b production.yaml
+  ramjam diff ./tests/ --env before.yaml --env-b after.yaml --ignore '$.updated_at' --ignore '$.items[*].id'`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envA, _ := cmd.Flags().GetString("env")
+		envB, _ := cmd.Flags().GetString("env-b")
+		ignore, _ := cmd.Flags().GetStringArray("ignore")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if envB == "" {
+			return fmt.Errorf("--env-b is required")
+		}
+
+		varsA := map[string]string{}
+		labelA := "workflow config"
+		if envA != "" {
+			vars, err := loadEnv(envA)
+			if err != nil {
+				return err
+			}
+			varsA, labelA = vars, envLabel(envA)
+		}
+		varsB, err := loadEnv(envB)
+		if err != nil {
+			return err
+		}
+		labelB := envLabel(envB)
+
+		diffs, err := runner.New(30*time.Second, false).Diff(args, varsA, varsB, ignore)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if asJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(diffs); err != nil {
+				return err
+			}
+		}
+
+		changed := 0
+		for _, d := range diffs {
+			if d.Missing == "" && len(d.Differences) == 0 {
+				continue
+			}
+			changed++
+			if asJSON {
+				continue
+			}
+			fmt.Fprintf(out, "%s (%s)\n", d.Step, d.File)
+			switch d.Missing {
+			case "a":
+				fmt.Fprintf(out, "  no response from %s\n", labelA)
+			case "b":
+				fmt.Fprintf(out, "  no response from %s\n", labelB)
+			}
+			for _, diff := range d.Differences {
+				fmt.Fprintf(out, "  %s: %s -> %s\n", diff.Path, diffValue(diff.A, diff.Kind == "added"), diffValue(diff.B, diff.Kind == "removed"))
+			}
+		}
+		if changed > 0 {
+			return fmt.Errorf("%d of %d steps differ between %s and %s", changed, len(diffs), labelA, labelB)
+		}
+		if !asJSON {
+			fmt.Fprintf(out, "No differences in %d steps between %s and %s\n", len(diffs), labelA, labelB)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().String("env", "", "Environment name or variables file for the first run (defaults to the workflow's own config)")
+	diffCmd.Flags().String("env-b", "", "Environment name or variables file for the second run")
+	diffCmd.Flags().StringArray("ignore", nil, "JSONPath to leave out of the comparison, such as $.updated_at (repeatable)")
+	diffCmd.Flags().Bool("json", false, "Print every step's differences as JSON")
+}
+
+func envLabel(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// diffValue formats one side of a difference as JSON, or (missing) when
+// the value only exists on the other side.
+func diffValue(v interface{}, missing bool) string {
+	if missing {
+		return "(missing)"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
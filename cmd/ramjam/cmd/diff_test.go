@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffCmd(t *testing.T) {
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"` + name + `"}`))
+		}))
+	}
+	a, b := newServer("Ada"), newServer("Grace")
+	defer a.Close()
+	defer b.Close()
+
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, "users.yaml")
+	os.WriteFile(workflow, []byte("workflow:\n  - step: get-user\n    request:\n      url: /users/1\n"), 0644)
+	envA := filepath.Join(dir, "staging.yaml")
+	os.WriteFile(envA, []byte("base_url: "+a.URL+"\n"), 0644)
+	envB := filepath.Join(dir, "production.yaml")
+	os.WriteFile(envB, []byte("base_url: "+b.URL+"\n"), 0644)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+	defer diffCmd.Flags().Set("env", "")
+	defer diffCmd.Flags().Set("env-b", "")
+	rootCmd.SetArgs([]string{"diff", workflow, "--env", envA, "--env-b", envB})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "1 of 1 steps differ between staging and production") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `$.name: "Ada" -> "Grace"`) {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestDiffCmdRequiresEnvB(t *testing.T) {
+	defer rootCmd.SetArgs(nil)
+	rootCmd.SetArgs([]string{"diff", "users.yaml"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--env-b is required") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
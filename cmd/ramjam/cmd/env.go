@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Manage named environments",
+	Long: `Manage named environments stored in the project's .ramjam/envs directory.
+An environment is a set of variables, such as base_url, that override the
+values in each workflow. run and validate use the current environment when
+--env is not given.
+Examples:
+  ramjam env set staging base_url=https://staging.example.com tenant=acme
+  ramjam env use staging
+  ramjam env list
+  ramjam env show production`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var envListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List environments, marking the current one",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project := currentProject()
+		names, err := project.Envs()
+		if err != nil {
+			return err
+		}
+		current, err := project.CurrentEnv()
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		if len(names) == 0 {
+			fmt.Fprintln(out, "No environments (create one with ramjam env set <name> key=value)")
+			return nil
+		}
+		for _, name := range names {
+			marker := " "
+			if name == current {
+				marker = "*"
+			}
+			fmt.Fprintf(out, "%s %s\n", marker, name)
+		}
+		return nil
+	},
+}
+
+var envShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Print the variables of an environment (the current one by default)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project := currentProject()
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		} else {
+			current, err := project.CurrentEnv()
+			if err != nil {
+				return err
+			}
+			if current == "" {
+				return fmt.Errorf("no current environment (select one with ramjam env use <name>)")
+			}
+			name = current
+		}
+		vars, err := project.Env(name)
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(vars))
+		for k := range vars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s=%s\n", k, vars[k])
+		}
+		return nil
+	},
+}
+
+var envSetCmd = &cobra.Command{
+	Use:   "set <name> <key=value...>",
+	Short: "Set variables in an environment, creating it if needed",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vars := map[string]string{}
+		for _, pair := range args[1:] {
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(k) == "" {
+				return fmt.Errorf("invalid variable %q (expected key=value)", pair)
+			}
+			vars[strings.TrimSpace(k)] = v
+		}
+		if err := currentProject().SetEnv(args[0], vars); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Set %d variable(s) in %s\n", len(vars), args[0])
+		return nil
+	},
+}
+
+var envUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Select the environment used when --env is not given",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := currentProject().UseEnv(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Using environment %s\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+	envCmd.AddCommand(envListCmd, envShowCmd, envSetCmd, envUseCmd)
+}
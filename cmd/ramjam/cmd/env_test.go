@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvCmdRegistered(t *testing.T) {
+	found := false
+	for _, c := range rootCmd.Commands() {
+		if c == envCmd {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatal("env command should be registered with root")
+	}
+}
+
+func TestEnvCmdWorkflow(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "tenant=%s", r.URL.Query().Get("tenant"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	os.WriteFile(filepath.Join(dir, "tenant.yaml"), []byte(`
+workflow:
+- step: "whoami"
+  request:
+    url: "/whoami"
+    params:
+      tenant: "${tenant}"
+  expect:
+    body_contains: "tenant=acme"
+`), 0644)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetErr(nil)
+	defer rootCmd.SetArgs(nil)
+	execute := func(args ...string) {
+		t.Helper()
+		out.Reset()
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("%v failed: %v\n%s", args, err, out.String())
+		}
+	}
+
+	execute("env", "set", "staging", "base_url="+srv.URL, "tenant=acme")
+	execute("env", "set", "dev", "base_url=http://localhost:1")
+	execute("env", "use", "staging")
+	execute("env", "list")
+	if out.String() != "  dev\n* staging\n" {
+		t.Errorf("unexpected list output:\n%s", out.String())
+	}
+	execute("env", "show")
+	if out.String() != "base_url="+srv.URL+"\ntenant=acme\n" {
+		t.Errorf("unexpected show output:\n%s", out.String())
+	}
+
+	// run picks up the current environment when --env is not given.
+	execute("run", "tenant.yaml")
+}
+
+func TestEnvCmdUseMissing(t *testing.T) {
+	t.Chdir(t.TempDir())
+	defer rootCmd.SetArgs(nil)
+	rootCmd.SetArgs([]string{"env", "use", "prod"})
+	if err := rootCmd.Execute(); err == nil || err.Error() != `environment "prod" does not exist` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/michaelmccabe/ramjam/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt <files-or-folders...>",
+	Short: "Rewrite workflow files in canonical form",
+	Long: `Rewrite workflow files with canonical key order, two-space indentation and
+double-quoted strings. Files that are already formatted are left untouched.
+Use --check in CI to list unformatted files without changing them.
+Examples:
+  ramjam fmt ./tests/
+  ramjam fmt --check ./tests/`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		check, _ := cmd.Flags().GetBool("check")
+
+		files, err := runner.New(30*time.Second, false).Files(args)
+		if err != nil {
+			return err
+		}
+
+		var unformatted int
+		for _, path := range files {
+			// JSON workflows are usually generated; formatting would
+			// rewrite them as YAML.
+			if filepath.Ext(path) == ".json" {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", path, err)
+			}
+			formatted, err := runner.Format(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if bytes.Equal(data, formatted) {
+				continue
+			}
+			unformatted++
+			fmt.Fprintln(cmd.OutOrStdout(), path)
+			if check {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				return fmt.Errorf("stat %s: %w", path, err)
+			}
+			if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("write %s: %w", path, err)
+			}
+		}
+		if check && unformatted > 0 {
+			return fmt.Errorf("%d file(s) need formatting; run ramjam fmt", unformatted)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+	fmtCmd.Flags().Bool("check", false, "List files that are not formatted and exit non-zero instead of rewriting them")
+}
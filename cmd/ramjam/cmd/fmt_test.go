@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFmtCmd(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "workflow.yaml")
+	original := "workflow:\n- request: {url: /ping}\n  step: ping\n"
+	os.WriteFile(path, []byte(original), 0644)
+
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+	defer fmtCmd.Flags().Set("check", "false")
+
+	rootCmd.SetArgs([]string{"fmt", "--check", dir})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "1 file(s) need formatting") {
+		t.Fatalf("expected --check to fail, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Fatalf("--check must not modify files, got:\n%s", data)
+	}
+
+	fmtCmd.Flags().Set("check", "false")
+	rootCmd.SetArgs([]string{"fmt", dir})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("fmt failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := "workflow:\n  - step: \"ping\"\n    request:\n      url: \"/ping\"\n"; string(data) != want {
+		t.Errorf("unexpected formatted file:\n%s", data)
+	}
+
+	stdout.Reset()
+	rootCmd.SetArgs([]string{"fmt", "--check", dir})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("expected formatted file to pass --check: %v", err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected no output, got %q", stdout.String())
+	}
+}
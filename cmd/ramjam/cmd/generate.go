@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/michaelmccabe/ramjam/pkg/convert"
+	"github.com/michaelmccabe/ramjam/pkg/openapi"
+	"github.com/spf13/cobra"
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate --openapi <spec>",
+	Short: "Scaffold workflows from an OpenAPI spec",
+	Long: `Scaffold one workflow file per tag from an OpenAPI 3 spec, with a step per
+operation, example bodies and the documented success status.
+Examples:
+  ramjam generate --openapi api.yaml
+  ramjam generate --openapi api.yaml -o ./tests/smoke`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		specPath, _ := cmd.Flags().GetString("openapi")
+		outDir, _ := cmd.Flags().GetString("output")
+		force, _ := cmd.Flags().GetBool("force")
+		if specPath == "" {
+			return fmt.Errorf("--openapi is required")
+		}
+
+		spec, err := openapi.Load(specPath)
+		if err != nil {
+			return err
+		}
+		generated, err := convert.FromOpenAPI(spec)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			return fmt.Errorf("create %s: %w", outDir, err)
+		}
+		for _, g := range generated {
+			path := filepath.Join(outDir, g.File)
+			if _, err := os.Stat(path); err == nil && !force {
+				return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+			}
+		}
+		for _, g := range generated {
+			data, err := convert.Marshal(g.Workflow)
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(outDir, g.File)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				return fmt.Errorf("write %s: %w", path, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Wrote %d steps to %s\n", len(g.Workflow.Workflow), path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+	generateCmd.Flags().String("openapi", "", "OpenAPI 3 spec (YAML or JSON) to generate workflows from")
+	generateCmd.Flags().StringP("output", "o", ".", "Directory to write workflow files to")
+	generateCmd.Flags().Bool("force", false, "Overwrite existing workflow files")
+}
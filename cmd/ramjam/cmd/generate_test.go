@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateCmdRegistered(t *testing.T) {
+	found := false
+	for _, c := range rootCmd.Commands() {
+		if c == generateCmd {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatal("generate command should be registered with root")
+	}
+}
+
+func TestGenerateCmdOpenAPI(t *testing.T) {
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+
+	args := []string{"generate", "--openapi", "../../../pkg/openapi/testdata/petstore.yaml", "-o", dir}
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "pets.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "step: create-pet") {
+		t.Errorf("unexpected workflow:\n%s", data)
+	}
+	if !strings.Contains(stdout.String(), "Wrote 4 steps to") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected overwrite protection, got %v", err)
+	}
+}
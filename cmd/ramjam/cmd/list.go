@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/michaelmccabe/ramjam/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list <files-or-folders...>",
+	Short: "List workflows and their steps without running them",
+	Long: `Print the name, description, tags and steps of each workflow file.
+Nothing is executed.
+Examples:
+  ramjam list ./tests/
+  ramjam list --json ./tests/ | jq '.[].name'`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		infos, err := runner.New(30*time.Second, false).List(args)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if asJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(infos)
+		}
+
+		for i, info := range infos {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			if info.Name != "" {
+				fmt.Fprintf(out, "%s (%s)\n", info.Name, info.Path)
+			} else {
+				fmt.Fprintln(out, info.Path)
+			}
+			if info.Error != "" {
+				fmt.Fprintf(out, "  error: %s\n", info.Error)
+				continue
+			}
+			if info.Description != "" {
+				fmt.Fprintf(out, "  %s\n", info.Description)
+			}
+			if len(info.Tags) > 0 {
+				fmt.Fprintf(out, "  tags: %s\n", strings.Join(info.Tags, ", "))
+			}
+			tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			for _, step := range info.Steps {
+				fmt.Fprintf(tw, "  - %s\t%s %s\n", step.Name, step.Method, step.URL)
+			}
+			tw.Flush()
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().Bool("json", false, "Print the list as JSON")
+}
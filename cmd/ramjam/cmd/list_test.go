@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestListCmd(t *testing.T) {
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+	defer listCmd.Flags().Set("json", "false")
+
+	path := "../../../resources/testdata/success/paramsGetTest.yaml"
+	rootCmd.SetArgs([]string{"list", path})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	for _, want := range []string{"Query Parameters Demo (" + path + ")", "  - user-by-id   GET /users?id=3"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	rootCmd.SetArgs([]string{"list", "--json", path})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("list --json failed: %v", err)
+	}
+	var infos []struct {
+		Name  string `json:"name"`
+		Steps []struct {
+			Name string `json:"name"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &infos); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, stdout.String())
+	}
+	if len(infos) != 1 || infos[0].Name != "Query Parameters Demo" || len(infos[0].Steps) != 2 {
+		t.Errorf("unexpected JSON output: %+v", infos)
+	}
+}
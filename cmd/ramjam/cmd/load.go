@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/michaelmccabe/ramjam/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var loadCmd = &cobra.Command{
+	Use:   "load <file> --vus <n> --duration <time>",
+	Short: "Run a workflow repeatedly across concurrent virtual users",
+	Long: `Load test an API by running one workflow in a loop across concurrent
+virtual users. Each virtual user has its own variables, so captured values
+never leak between users. When the duration is up, in-progress iterations
+finish and throughput, error rate and per-step results are reported.
+Failed steps are counted rather than stopping the test. The command fails
+if the results exceed any of the workflow's config.thresholds.
+Examples:
+  ramjam load checkout.yaml --vus 50 --duration 2m
+  ramjam load checkout.yaml --vus 10 --duration 30s --env staging --json
+  ramjam load checkout.yaml --vus 50 --duration 5m --warmup 30s`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vus, _ := cmd.Flags().GetInt("vus")
+		duration, _ := cmd.Flags().GetDuration("duration")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		warmupFor, warmupCount, err := warmupFlag(cmd)
+		if err != nil {
+			return err
+		}
+		vars, err := cliVars(cmd)
+		if err != nil {
+			return err
+		}
+
+		opts, err := headerOptions(cmd)
+		if err != nil {
+			return err
+		}
+		profileOpts, err := profileOptions(cmd)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, profileOpts...)
+		if lenient, _ := cmd.Flags().GetBool("lenient"); lenient {
+			opts = append(opts, runner.WithLenient(true))
+		}
+		if len(vars) > 0 {
+			opts = append(opts, runner.WithVars(vars))
+		}
+		if rate, _ := cmd.Flags().GetString("rate"); rate != "" {
+			limit, err := runner.ParseRateLimit(rate)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, runner.WithRateLimit(limit))
+		}
+		result, err := runner.New(30*time.Second, false, opts...).Load(args[0], runner.LoadOptions{
+			VUs:              vus,
+			Duration:         duration,
+			Warmup:           warmupFor,
+			WarmupIterations: warmupCount,
+		})
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if asJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(result); err != nil {
+				return err
+			}
+		} else {
+			printLoadResult(out, args[0], result)
+		}
+		if n := len(result.ThresholdFailures); n > 0 {
+			return &exitCodeError{exitFailed, fmt.Errorf("%d threshold(s) failed", n)}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(loadCmd)
+	loadCmd.Flags().Int("vus", 1, "Number of concurrent virtual users")
+	loadCmd.Flags().Duration("duration", 30*time.Second, "How long to keep starting iterations, such as 30s or 2m")
+	loadCmd.Flags().String("warmup", "", "Run the workflow for this long, such as 10s, or this many iterations before measuring")
+	loadCmd.Flags().StringArrayP("header", "H", nil, "Add a header to every request, as 'Name: value' (repeatable)")
+	loadCmd.Flags().String("user-agent", "", "User-Agent for files that don't set config.user_agent (default ramjam-cli)")
+	loadCmd.Flags().String("profile", "", "Merge a profile from the project's .ramjam.yaml into the workflow's config")
+	loadCmd.Flags().Bool("lenient", false, "Ignore unknown fields in the workflow file instead of failing it")
+	loadCmd.Flags().String("rate", "", "Maximum request rate across all virtual users, such as 100/s")
+	loadCmd.Flags().String("env", "", "Environment name or variables file (defaults to the current environment)")
+	loadCmd.Flags().StringArray("var", nil, "Set a variable as key=value, overriding the workflow file (repeatable)")
+	loadCmd.Flags().StringArray("var-file", nil, "Load variables from a YAML file of key: value pairs (repeatable)")
+	loadCmd.Flags().Bool("json", false, "Print the results as JSON")
+}
+
+// latencyColumns formats statistics as tab-separated table cells.
+func latencyColumns(s runner.LatencyStats) string {
+	cells := make([]string, 0, 7)
+	for _, ms := range []float64{s.MinMS, s.MeanMS, s.P50MS, s.P95MS, s.P99MS, s.MaxMS, s.StdDevMS} {
+		cells = append(cells, fmt.Sprintf("%.1fms", ms))
+	}
+	return strings.Join(cells, "\t")
+}
+
+// printLoadResult writes the run totals, a table of steps and each distinct
+// error, most frequent first.
+func printLoadResult(out io.Writer, path string, res *runner.LoadResult) {
+	fmt.Fprintf(out, "Load test: %s, %d VUs for %s", path, res.VUs, res.Duration.Round(time.Millisecond))
+	if res.WarmupIterations > 0 {
+		fmt.Fprintf(out, " after %d warm-up iterations", res.WarmupIterations)
+	}
+	fmt.Fprint(out, "\n\n")
+	fmt.Fprintf(out, "  Iterations:  %d (%d failed)\n", res.Iterations, res.FailedIterations)
+	fmt.Fprintf(out, "  Requests:    %d (%.1f/s)\n", res.Requests, res.RequestsPerSecond)
+	fmt.Fprintf(out, "  Error rate:  %.2f%%\n\n", res.ErrorRate*100)
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  Step\tRequests\tFailed\tMin\tMean\tp50\tp95\tp99\tMax\tStdDev")
+	for _, step := range res.Steps {
+		fmt.Fprintf(tw, "  %s\t%d\t%d\t%s\n", step.Name, step.Requests, step.Failed, latencyColumns(step.LatencyStats))
+	}
+	fmt.Fprintf(tw, "  %s\t%d\t%d\t%s\n", "all steps", res.Requests, res.FailedRequests, latencyColumns(res.Latency))
+	tw.Flush()
+
+	printThresholdFailures(out, res.ThresholdFailures)
+	if len(res.Errors) == 0 {
+		return
+	}
+	messages := make([]string, 0, len(res.Errors))
+	for msg := range res.Errors {
+		messages = append(messages, msg)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if res.Errors[messages[i]] != res.Errors[messages[j]] {
+			return res.Errors[messages[i]] > res.Errors[messages[j]]
+		}
+		return messages[i] < messages[j]
+	})
+	fmt.Fprintln(out, "\n  Errors:")
+	for _, msg := range messages {
+		fmt.Fprintf(out, "    %6d  %s\n", res.Errors[msg], msg)
+	}
+}
+
+// warmupFlag parses --warmup as either a duration or an iteration count.
+func warmupFlag(cmd *cobra.Command) (time.Duration, int, error) {
+	value, _ := cmd.Flags().GetString("warmup")
+	if value == "" {
+		return 0, 0, nil
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+		return 0, n, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, 0, fmt.Errorf("invalid --warmup %q (expected a duration such as 10s or a number of iterations)", value)
+	}
+	return d, 0, nil
+}
+
+func printThresholdFailures(out io.Writer, failures []string) {
+	if len(failures) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "\n  Thresholds:")
+	for _, f := range failures {
+		fmt.Fprintf(out, "    ✗ %s\n", f)
+	}
+}
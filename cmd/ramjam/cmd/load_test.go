@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestLoadCmd(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer api.Close()
+
+	workflow := filepath.Join(t.TempDir(), "health.yaml")
+	os.WriteFile(workflow, []byte("workflow:\n  - step: health\n    request:\n      url: /health\n"), 0644)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+	defer loadCmd.Flags().Set("vus", "1")
+	defer loadCmd.Flags().Set("duration", "30s")
+	defer loadCmd.Flags().Lookup("var").Value.(pflag.SliceValue).Replace(nil)
+	rootCmd.SetArgs([]string{"load", workflow, "--vus", "2", "--duration", "50ms", "--var", "base_url=" + api.URL})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Load test: " + workflow + ", 2 VUs for", "Error rate:  0.00%", "p95", "all steps"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestWarmupFlag(t *testing.T) {
+	defer loadCmd.Flags().Set("warmup", "")
+	tests := []struct {
+		value string
+		d     time.Duration
+		n     int
+		err   bool
+	}{
+		{value: ""},
+		{value: "10s", d: 10 * time.Second},
+		{value: "25", n: 25},
+		{value: "soon", err: true},
+		{value: "-5s", err: true},
+	}
+	for _, tt := range tests {
+		loadCmd.Flags().Set("warmup", tt.value)
+		d, n, err := warmupFlag(loadCmd)
+		if d != tt.d || n != tt.n || (err != nil) != tt.err {
+			t.Errorf("--warmup %q = %s, %d, %v", tt.value, d, n, err)
+		}
+	}
+}
+
+func TestRunCmdWarmupNeedsRepeat(t *testing.T) {
+	defer rootCmd.SetArgs(nil)
+	defer runCmd.Flags().Set("warmup", "")
+	rootCmd.SetArgs([]string{"run", "health.yaml", "--warmup", "10s"})
+	if err := rootCmd.Execute(); err == nil || err.Error() != "--warmup needs --repeat-for or --repeat-count" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/michaelmccabe/ramjam/pkg/mock"
+	"github.com/michaelmccabe/ramjam/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var mockCmd = &cobra.Command{
+	Use:   "mock <files-or-folders...>",
+	Short: "Serve the responses that workflows expect as a mock API",
+	Long: `Start an HTTP server that answers each request with the response the
+matching workflow step expects: its status, headers, and a JSON body built
+from json_path_match and captures. Steps that share a method and path are
+served in order, and the last one repeats.
+Examples:
+  ramjam mock ./tests/integration/
+  ramjam mock users.yaml --listen localhost:9000 --cors`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listen, _ := cmd.Flags().GetString("listen")
+		cors, _ := cmd.Flags().GetBool("cors")
+
+		files, err := runner.New(30*time.Second, false).Files(args)
+		if err != nil {
+			return err
+		}
+		srv, err := mock.Load(files)
+		if err != nil {
+			return err
+		}
+		srv.CORS = cors
+		out := cmd.OutOrStdout()
+		srv.Logf = func(format string, args ...interface{}) {
+			fmt.Fprintf(out, format+"\n", args...)
+		}
+
+		ln, err := net.Listen("tcp", listen)
+		if err != nil {
+			return fmt.Errorf("listen on %s: %w", listen, err)
+		}
+		fmt.Fprintf(out, "Serving %d routes on http://%s (Ctrl+C to stop)\n", len(srv.Routes()), ln.Addr())
+		for _, rt := range srv.Routes() {
+			fmt.Fprintf(out, "  %-7s %s\n", rt.Method, rt.Path)
+		}
+
+		server := &http.Server{Handler: srv}
+		served := make(chan error, 1)
+		go func() { served <- server.Serve(ln) }()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		select {
+		case <-ctx.Done():
+		case err := <-served:
+			if !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("mock server: %w", err)
+			}
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mockCmd)
+	mockCmd.Flags().String("listen", "localhost:8080", "Address for the mock server to listen on")
+	mockCmd.Flags().Bool("cors", false, "Allow cross-origin requests from browsers")
+}
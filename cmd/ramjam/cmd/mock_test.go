@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMockCmdRegistered(t *testing.T) {
+	found := false
+	for _, c := range rootCmd.Commands() {
+		if c == mockCmd {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatal("mock command should be registered with root")
+	}
+}
+
+func TestMockCmdMissingPath(t *testing.T) {
+	defer rootCmd.SetArgs(nil)
+	rootCmd.SetArgs([]string{"mock", "does-not-exist.yaml"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "unable to access does-not-exist.yaml") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/michaelmccabe/ramjam/pkg/convert"
+	"github.com/michaelmccabe/ramjam/pkg/record"
+	"github.com/spf13/cobra"
+)
+
+var recordCmd = &cobra.Command{
+	Use:   "record --target <url>",
+	Short: "Record live traffic through a local proxy as a workflow",
+	Long: `Start a local reverse proxy in front of an API and record every request sent
+through it. Point a browser, app or script at the proxy, then press Ctrl+C to
+write the session as a workflow. Values that a response returns and a later
+request sends back, such as IDs and tokens, become captures.
+Examples:
+  ramjam record --target https://api.example.com -o session.yaml
+  ramjam record --target http://localhost:3000 --listen localhost:9000`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, _ := cmd.Flags().GetString("target")
+		listen, _ := cmd.Flags().GetString("listen")
+		output, _ := cmd.Flags().GetString("output")
+		includeAssets, _ := cmd.Flags().GetBool("include-assets")
+		if target == "" {
+			return fmt.Errorf("--target is required")
+		}
+
+		rec, err := record.New(target)
+		if err != nil {
+			return err
+		}
+		rec.OnExchange = func(ex convert.Exchange) {
+			u, _ := url.Parse(ex.URL)
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s %s -> %d\n", ex.Method, u.RequestURI(), ex.Status)
+		}
+
+		ln, err := net.Listen("tcp", listen)
+		if err != nil {
+			return fmt.Errorf("listen on %s: %w", listen, err)
+		}
+		srv := &http.Server{Handler: rec}
+		served := make(chan error, 1)
+		go func() { served <- srv.Serve(ln) }()
+		fmt.Fprintf(cmd.ErrOrStderr(), "Recording %s on http://%s (Ctrl+C to stop and write the workflow)\n", target, ln.Addr())
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		select {
+		case <-ctx.Done():
+		case err := <-served:
+			if !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("proxy: %w", err)
+			}
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+
+		u, _ := url.Parse(target)
+		wf, err := convert.FromExchanges(rec.Exchanges(), convert.HAROptions{
+			Name:          u.Hostname(),
+			Description:   "Recorded from live traffic to " + target,
+			IncludeAssets: includeAssets,
+		})
+		if err != nil {
+			return err
+		}
+		data, err := convert.Marshal(wf)
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return fmt.Errorf("write %s: %w", output, err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %d steps to %s\n", len(wf.Workflow), output)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(recordCmd)
+	recordCmd.Flags().String("target", "", "Base URL of the API to proxy to")
+	recordCmd.Flags().String("listen", "localhost:8080", "Address for the recording proxy to listen on")
+	recordCmd.Flags().StringP("output", "o", "", "Write the workflow to this file instead of stdout")
+	recordCmd.Flags().Bool("include-assets", false, "Keep requests for scripts, styles, images and fonts")
+}
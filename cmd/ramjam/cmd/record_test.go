@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecordCmdRegistered(t *testing.T) {
+	found := false
+	for _, c := range rootCmd.Commands() {
+		if c == recordCmd {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatal("record command should be registered with root")
+	}
+}
+
+func TestRecordCmdRequiresTarget(t *testing.T) {
+	defer rootCmd.SetArgs(nil)
+	rootCmd.SetArgs([]string{"record"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--target is required") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRecordCmdInvalidTarget(t *testing.T) {
+	defer rootCmd.SetArgs(nil)
+	defer recordCmd.Flags().Set("target", "")
+	rootCmd.SetArgs([]string{"record", "--target", "localhost:3000"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "absolute http or https URL") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
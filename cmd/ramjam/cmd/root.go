@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/michaelmccabe/ramjam/pkg/config"
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"github.com/michaelmccabe/ramjam/resources"
 	"github.com/spf13/cobra"
 )
 
@@ -31,13 +35,60 @@ All HTTP requests are made through declarative YAML workflow files, providing:
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := addCustomCommands(commands)
+	if err == nil {
+		err = rootCmd.Execute()
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		if hint := e.Hint(err); hint != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+// exitCode returns the process exit code for an error returned by a
+// command: the code it carries, or 1.
+func exitCode(err error) int {
+	var ec *exitCodeError
+	if errors.As(err, &ec) {
+		return ec.code
+	}
+	return 1
+}
+
+// commands is the command text embedded from resources/commands.yaml, or nil
+// if it can't be parsed.
+var commands, _ = config.LoadCommandsFromBytes(resources.Commands)
+
+// applyCommandText sets the commands' text from cmds. The text written on
+// each command is kept if cmds is nil or leaves a field empty.
+func applyCommandText(cmds *config.CommandsConfig) {
+	if cmds == nil {
+		return
+	}
+	for c, text := range map[*cobra.Command]config.CommandText{
+		rootCmd:    cmds.Root,
+		runCmd:     cmds.Run,
+		versionCmd: cmds.Version,
+	} {
+		if text.Use != "" {
+			c.Use = text.Use
+		}
+		if text.Short != "" {
+			c.Short = text.Short
+		}
+		if text.Long != "" {
+			c.Long = text.Long
+		}
 	}
 }
 
 func init() {
+	applyCommandText(commands)
+
 	// Global flags can be added here
-	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().CountP("verbose", "v", "Enable verbose output (-vv also dumps requests and responses)")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Only print failures and the summary")
 }
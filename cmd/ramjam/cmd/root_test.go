@@ -3,6 +3,9 @@ package cmd
 import (
 	"bytes"
 	"testing"
+
+	"github.com/michaelmccabe/ramjam/pkg/config"
+	"github.com/michaelmccabe/ramjam/resources"
 )
 
 func TestRootCmd(t *testing.T) {
@@ -40,8 +43,18 @@ func TestRootCmdVerboseFlag(t *testing.T) {
 		t.Errorf("verbose shorthand = %v, want %v", flag.Shorthand, "v")
 	}
 
-	if flag.DefValue != "false" {
-		t.Errorf("verbose default value = %v, want %v", flag.DefValue, "false")
+	if flag.DefValue != "0" {
+		t.Errorf("verbose default value = %v, want %v", flag.DefValue, "0")
+	}
+}
+
+func TestRootCmdQuietFlag(t *testing.T) {
+	flag := rootCmd.PersistentFlags().Lookup("quiet")
+	if flag == nil {
+		t.Fatal("quiet flag not found")
+	}
+	if flag.Shorthand != "q" {
+		t.Errorf("quiet shorthand = %v, want q", flag.Shorthand)
 	}
 }
 
@@ -66,3 +79,34 @@ func TestRootCmdVersion(t *testing.T) {
 		t.Error("Version should not be empty")
 	}
 }
+
+func TestApplyCommandText(t *testing.T) {
+	use, short := versionCmd.Use, versionCmd.Short
+	defer func() { versionCmd.Use, versionCmd.Short = use, short }()
+
+	applyCommandText(&config.CommandsConfig{Version: config.CommandText{Short: "Show the version"}})
+	if versionCmd.Short != "Show the version" {
+		t.Errorf("Short = %q", versionCmd.Short)
+	}
+	if versionCmd.Use != use {
+		t.Errorf("Use = %q, want %q", versionCmd.Use, use)
+	}
+
+	applyCommandText(nil)
+	if versionCmd.Short != "Show the version" {
+		t.Errorf("Short without command text = %q", versionCmd.Short)
+	}
+}
+
+func TestEmbeddedCommandText(t *testing.T) {
+	cmds, err := config.LoadCommandsFromBytes(resources.Commands)
+	if err != nil {
+		t.Fatalf("LoadCommandsFromBytes() error = %v", err)
+	}
+	if runCmd.Use != cmds.Run.Use || runCmd.Short != cmds.Run.Short || runCmd.Long != cmds.Run.Long {
+		t.Errorf("run command text = %q, %q, want %q, %q", runCmd.Use, runCmd.Short, cmds.Run.Use, cmds.Run.Short)
+	}
+	if rootCmd.Long != cmds.Root.Long {
+		t.Errorf("root Long = %q, want %q", rootCmd.Long, cmds.Root.Long)
+	}
+}
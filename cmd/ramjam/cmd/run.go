@@ -1,11 +1,25 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
 	"github.com/michaelmccabe/ramjam/pkg/runner"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var runCmd = &cobra.Command{
@@ -15,36 +29,436 @@ var runCmd = &cobra.Command{
 Examples:
   ramjam run test-get.yaml
   ramjam run ./tests/integration/
+  ramjam run -r ./tests/
   ramjam run login.yaml signup.yaml profile.yaml`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		verbose, _ := cmd.Flags().GetBool("verbose")
-		r := runner.New(30*time.Second, verbose)
-		err := r.RunPaths(args)
-		if err == nil {
-			fmt.Println("All steps were run successfully")
-			return nil
-		}
-
-		if errs, ok := err.(interface{ Unwrap() []error }); ok {
-			for _, e := range errs.Unwrap() {
-				if se, ok := e.(*runner.StepError); ok {
-					fmt.Printf("Failed step: %s\n", se.Step)
-					if verbose {
-						fmt.Printf("Description: %s\n", se.Description)
-						fmt.Printf("Error: %v\n", se.Err)
-					}
-				} else {
-					fmt.Printf("Error: %v\n", e)
+		verbose, _ := cmd.Flags().GetCount("verbose")
+		quiet, _ := cmd.Flags().GetBool("quiet")
+		if quiet && verbose > 0 {
+			return fmt.Errorf("--quiet and --verbose cannot be used together")
+		}
+		certFile, _ := cmd.Flags().GetString("cert")
+		keyFile, _ := cmd.Flags().GetString("key")
+		caFile, _ := cmd.Flags().GetString("cacert")
+		insecure, _ := cmd.Flags().GetBool("insecure")
+		proxy, _ := cmd.Flags().GetString("proxy")
+		report, _ := cmd.Flags().GetString("report")
+		noColor, _ := cmd.Flags().GetBool("no-color")
+		logFormat, _ := cmd.Flags().GetString("log-format")
+		if !slices.Contains(runner.ReportFormats, report) {
+			last := len(runner.ReportFormats) - 1
+			return fmt.Errorf("unknown report format %q (expected %s or %s)", report, strings.Join(runner.ReportFormats[:last], ", "), runner.ReportFormats[last])
+		}
+		if logFormat != "text" && logFormat != "json" {
+			return fmt.Errorf("unknown log format %q (expected text or json)", logFormat)
+		}
+		if logFormat == "json" && report != runner.ReportText {
+			return fmt.Errorf("--log-format json cannot be combined with --report %s", report)
+		}
+		opts := []runner.Option{
+			runner.WithTLS(runner.TLSConfig{
+				CertFile:           certFile,
+				KeyFile:            keyFile,
+				CAFile:             caFile,
+				InsecureSkipVerify: insecure,
+			}),
+			runner.WithProxy(proxy),
+			runner.WithReport(report),
+			runner.WithOutput(cmd.OutOrStdout()),
+			runner.WithWarnings(cmd.ErrOrStderr()),
+			runner.WithColor(!noColor && colorSupported(cmd.OutOrStdout())),
+			runner.WithVerbosity(verbosity(verbose, quiet)),
+			runner.WithJSONLogs(logFormat == "json"),
+		}
+		if printCurl, _ := cmd.Flags().GetBool("print-curl"); printCurl {
+			opts = append(opts, runner.WithPrintCurl(true))
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if dryRun {
+			opts = append(opts, runner.WithDryRun(true))
+		}
+		if notifyURL, _ := cmd.Flags().GetString("notify-url"); notifyURL != "" {
+			format, _ := cmd.Flags().GetString("notify-format")
+			on, _ := cmd.Flags().GetString("notify-on")
+			if !slices.Contains(runner.NotifyFormats, format) {
+				return fmt.Errorf("unknown notify format %q (expected %s)", format, strings.Join(runner.NotifyFormats, " or "))
+			}
+			if on != "always" && on != "failure" {
+				return fmt.Errorf("unknown --notify-on value %q (expected always or failure)", on)
+			}
+			opts = append(opts, runner.WithNotify(runner.Notification{URL: notifyURL, Format: format, OnFailure: on == "failure"}))
+		}
+		metricsPush, _ := cmd.Flags().GetString("metrics-push")
+		metricsFile, _ := cmd.Flags().GetString("metrics-file")
+		if metricsPush != "" || metricsFile != "" {
+			opts = append(opts, runner.WithMetrics(runner.Metrics{PushURL: metricsPush, File: metricsFile}))
+		}
+		if rate, _ := cmd.Flags().GetString("rate"); rate != "" {
+			limit, err := runner.ParseRateLimit(rate)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, runner.WithRateLimit(limit))
+		}
+		headerOpts, err := headerOptions(cmd)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, headerOpts...)
+		profileOpts, err := profileOptions(cmd)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, profileOpts...)
+		if lenient, _ := cmd.Flags().GetBool("lenient"); lenient {
+			opts = append(opts, runner.WithLenient(true))
+		}
+		if strict, _ := cmd.Flags().GetBool("strict-vars"); strict {
+			opts = append(opts, runner.WithStrictVars(true))
+		}
+		if recursive, _ := cmd.Flags().GetBool("recursive"); recursive {
+			opts = append(opts, runner.WithRecursive(true))
+		}
+		steps, _ := cmd.Flags().GetStringArray("step")
+		fromStep, _ := cmd.Flags().GetString("from-step")
+		if len(steps) > 0 && fromStep != "" {
+			return fmt.Errorf("--step and --from-step cannot be used together")
+		}
+		if len(steps) > 0 {
+			opts = append(opts, runner.WithSteps(steps))
+		}
+		if fromStep != "" {
+			opts = append(opts, runner.WithFromStep(fromStep))
+		}
+		if sequential, _ := cmd.Flags().GetBool("sequential"); sequential {
+			opts = append(opts, runner.WithSequential(true))
+		}
+		if retries, _ := cmd.Flags().GetInt("retries"); retries > 0 {
+			opts = append(opts, runner.WithRetry(runner.Retry{Attempts: retries + 1}))
+		}
+		if endpoint, _ := cmd.Flags().GetString("otlp-endpoint"); endpoint != "" {
+			opts = append(opts, runner.WithTracing(runner.Tracing{Endpoint: endpoint, ServiceName: os.Getenv("OTEL_SERVICE_NAME")}))
+		}
+		vars, err := cliVars(cmd)
+		if err != nil {
+			return err
+		}
+		if len(vars) > 0 {
+			opts = append(opts, runner.WithVars(vars))
+		}
+		if har, _ := cmd.Flags().GetString("har"); har != "" {
+			opts = append(opts, runner.WithHAR(har))
+		}
+		if stderr := cmd.ErrOrStderr(); isTerminal(stderr) {
+			opts = append(opts, runner.WithProgress(stderr))
+		}
+		threshold, _ := cmd.Flags().GetInt("fail-threshold")
+		if threshold < 0 {
+			return fmt.Errorf("--fail-threshold must not be negative")
+		}
+		checkpoint, _ := cmd.Flags().GetBool("checkpoint")
+		resume, _ := cmd.Flags().GetBool("resume")
+		if checkpoint || resume {
+			stateFile, _ := cmd.Flags().GetString("state-file")
+			opts = append(opts, runner.WithCheckpoint(stateFile, resume))
+		}
+		r := runner.New(30*time.Second, verbose > 0, opts...)
+		watch, _ := cmd.Flags().GetBool("watch")
+		repeatFor, _ := cmd.Flags().GetDuration("repeat-for")
+		repeatCount, _ := cmd.Flags().GetInt("repeat-count")
+		if (checkpoint || resume) && (watch || repeatFor > 0 || repeatCount > 0) {
+			return fmt.Errorf("--checkpoint and --resume cannot be combined with --watch, --repeat-for or --repeat-count")
+		}
+		if warmup, _ := cmd.Flags().GetString("warmup"); warmup != "" && repeatFor <= 0 && repeatCount <= 0 {
+			return fmt.Errorf("--warmup needs --repeat-for or --repeat-count")
+		}
+		if repeatFor > 0 || repeatCount > 0 {
+			if watch {
+				return fmt.Errorf("--repeat-for and --repeat-count cannot be combined with --watch")
+			}
+			warmupFor, warmupCount, err := warmupFlag(cmd)
+			if err != nil {
+				return err
+			}
+			ctx, stop := interruptContext(cmd.Context())
+			defer stop()
+			result, err := r.Repeat(ctx, args, runner.RepeatOptions{
+				For:              repeatFor,
+				Count:            repeatCount,
+				Warmup:           warmupFor,
+				WarmupIterations: warmupCount,
+			})
+			if err != nil {
+				return &exitCodeError{exitError, err}
+			}
+			return repeatResult(cmd.OutOrStdout(), result, threshold)
+		}
+		if watch {
+			ctx, stop := interruptContext(cmd.Context())
+			defer stop()
+			return r.Watch(ctx, args, func(changed string, err error) {
+				if changed != "" {
+					fmt.Fprintf(cmd.OutOrStdout(), "\n%s changed; running again\n", changed)
+				}
+				if err := runResult(cmd.OutOrStdout(), err, report, verbose, dryRun, threshold); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
 				}
+				fmt.Fprintln(cmd.OutOrStdout(), "Watching for changes (Ctrl+C to stop)")
+			})
+		}
+		ctx, stop := interruptContext(cmd.Context())
+		defer stop()
+		return runResult(cmd.OutOrStdout(), r.RunPathsContext(ctx, args), report, verbose, dryRun, threshold)
+	},
+}
+
+// Exit codes for ramjam run.
+const (
+	exitFailed      = 1   // one or more steps failed
+	exitError       = 2   // a file could not be run or a request could not be sent
+	exitInterrupted = 130 // the run was stopped by SIGINT or SIGTERM
+)
+
+// interruptContext returns a context that is cancelled by SIGINT or
+// SIGTERM, so a run can abort its requests and still report. Once it has
+// been cancelled, a second signal kills the process as usual.
+func interruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// exitCodeError sets the process exit code for err.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string { return e.err.Error() }
+
+func (e *exitCodeError) Unwrap() error { return e.err }
+
+// runResult prints the outcome of a run and returns the error the command
+// should exit with. Up to threshold step failures are tolerated.
+func runResult(out io.Writer, err error, report string, verbose int, dryRun bool, threshold int) error {
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	} else if err != nil {
+		return &exitCodeError{exitError, fmt.Errorf("run failed: %w", err)}
+	}
+
+	// Step failures count towards the threshold; a file that could not be
+	// run always fails.
+	code := 0
+	if len(errs) > threshold {
+		code = exitFailed
+	}
+	for _, err := range errs {
+		if _, ok := err.(*runner.StepError); !ok {
+			code = exitFailed
+		}
+	}
+	for _, err := range errs {
+		if code != 0 && isExecutionError(err) {
+			code = exitError
+		}
+	}
+	interrupted := errors.Is(err, context.Canceled)
+	if interrupted {
+		code = exitInterrupted
+	}
+
+	if report == runner.ReportTAP {
+		// The TAP stream already describes every failure.
+		if code != 0 {
+			return &exitCodeError{code, fmt.Errorf("workflow failed")}
+		}
+		return nil
+	}
+	if err == nil && dryRun {
+		fmt.Fprintln(out, "Dry run complete; no requests were sent")
+		return nil
+	}
+	if err == nil {
+		fmt.Fprintln(out, "All steps were run successfully")
+		return nil
+	}
+
+	if interrupted {
+		fmt.Fprintln(out, "Run interrupted; steps that had not started were skipped")
+	}
+	for _, e := range errs {
+		if se, ok := e.(*runner.StepError); ok {
+			fmt.Fprintf(out, "Failed step: %s\n", se.Step)
+			if verbose > 0 {
+				fmt.Fprintf(out, "Description: %s\n", se.Description)
+				fmt.Fprintf(out, "Error: %v\n", se.Err)
 			}
-			return fmt.Errorf("workflow failed with %d errors", len(errs.Unwrap()))
+		} else if !errors.Is(e, context.Canceled) {
+			fmt.Fprintf(out, "Error: %v\n", e)
 		}
+	}
+	if interrupted {
+		return &exitCodeError{code, fmt.Errorf("run interrupted")}
+	}
+	if code == 0 {
+		fmt.Fprintf(out, "%d step(s) failed, within --fail-threshold %d\n", len(errs), threshold)
+		return nil
+	}
+	return &exitCodeError{code, fmt.Errorf("workflow failed with %d errors", len(errs))}
+}
 
-		return fmt.Errorf("run failed: %w", err)
-	},
+// repeatResult prints the summary of a soak run and returns the error the
+// command should exit with.
+func repeatResult(out io.Writer, res *runner.RepeatResult, threshold int) error {
+	fmt.Fprintf(out, "\nSoak run: %d iterations in %s (%d failed)", res.Iterations, res.Duration.Round(time.Millisecond), res.FailedIterations)
+	if res.WarmupIterations > 0 {
+		fmt.Fprintf(out, " after %d warm-up iterations", res.WarmupIterations)
+	}
+	fmt.Fprintln(out)
+	if f := res.FirstFailure; f != nil {
+		name := f.File
+		if f.Step != "" {
+			name += " > " + f.Step
+		}
+		fmt.Fprintf(out, "First failure: iteration %d at %s (after %s)\n", f.Iteration, f.Time.Format("15:04:05"), f.Time.Sub(res.Start).Round(time.Second))
+		fmt.Fprintf(out, "  %s: %s\n", name, f.Error)
+	}
+	fmt.Fprintln(out)
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  Step\tRequests\tFailed\tMin\tMean\tp50\tp95\tp99\tMax\tStdDev\tDrift")
+	for _, step := range res.Steps {
+		fmt.Fprintf(tw, "  %s > %s\t%d\t%d\t%s\t%+.1f%%\n", filepath.Base(step.File), step.Name, step.Requests, step.Failed, latencyColumns(step.LatencyStats), step.DriftPercent)
+	}
+	tw.Flush()
+	printThresholdFailures(out, res.ThresholdFailures)
+
+	if res.Failed > threshold {
+		return &exitCodeError{exitFailed, fmt.Errorf("%d failures in %d iterations", res.Failed, res.Iterations)}
+	}
+	if n := len(res.ThresholdFailures); n > 0 {
+		return &exitCodeError{exitFailed, fmt.Errorf("%d threshold(s) failed", n)}
+	}
+	return nil
+}
+
+// isExecutionError reports whether err stopped a file from running or a
+// request from being sent, as opposed to a failed expectation.
+func isExecutionError(err error) bool {
+	se, ok := err.(*runner.StepError)
+	if !ok {
+		return true
+	}
+	if errors.Is(se, e.ConnectionError) || errors.Is(se, e.TimeoutError) {
+		return true
+	}
+	var urlErr *url.Error
+	var pathErr *fs.PathError
+	return errors.As(se.Err, &urlErr) || errors.As(se.Err, &pathErr)
+}
+
+// headerOptions returns runner options for the --header and --user-agent
+// flags.
+func headerOptions(cmd *cobra.Command) ([]runner.Option, error) {
+	var opts []runner.Option
+	if userAgent, _ := cmd.Flags().GetString("user-agent"); userAgent != "" {
+		opts = append(opts, runner.WithUserAgent(userAgent))
+	}
+	values, _ := cmd.Flags().GetStringArray("header")
+	if len(values) == 0 {
+		return opts, nil
+	}
+	headers := map[string]string{}
+	for _, value := range values {
+		name, v, ok := strings.Cut(value, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --header %q (expected 'Name: value')", value)
+		}
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(v)
+	}
+	return append(opts, runner.WithHeaders(headers)), nil
 }
 
 func init() {
 	rootCmd.AddCommand(runCmd)
+	addRunFlags(runCmd.Flags())
+}
+
+// addRunFlags defines run's flags, which custom commands accept too.
+func addRunFlags(flags *pflag.FlagSet) {
+	flags.String("cert", "", "Client certificate file (PEM) for mutual TLS")
+	flags.String("key", "", "Client private key file (PEM) for mutual TLS")
+	flags.String("cacert", "", "CA bundle (PEM) used to verify server certificates")
+	flags.BoolP("insecure", "k", false, "Skip TLS certificate verification (for self-signed dev environments)")
+	flags.String("proxy", "", "Proxy URL for all requests (http, https or socks5); overrides HTTP_PROXY")
+	flags.String("rate", "", "Maximum request rate across all files, such as 10/s or 600/m")
+	flags.StringArrayP("header", "H", nil, "Add a header to every request, as 'Name: value' (repeatable)")
+	flags.String("user-agent", "", "User-Agent for files that don't set config.user_agent (default ramjam-cli)")
+	flags.Int("retries", 0, "Retry requests that fail with a network error, 429 or 5xx up to this many times")
+	flags.String("profile", "", "Merge a profile from the project's .ramjam.yaml into each workflow's config")
+	flags.String("env", "", "Environment name or variables file (defaults to the current environment)")
+	flags.StringArray("var", nil, "Set a variable as key=value, overriding the workflow file (repeatable)")
+	flags.StringArray("var-file", nil, "Load variables from a YAML file of key: value pairs (repeatable)")
+	flags.Int("fail-threshold", 0, "Number of failed steps to tolerate before exiting non-zero")
+	flags.Bool("watch", false, "Run again whenever a workflow or a file it references changes")
+	flags.Duration("repeat-for", 0, "Soak test: run the workflows over and over for this long, such as 1h")
+	flags.Int("repeat-count", 0, "Soak test: run the workflows this many times")
+	flags.String("warmup", "", "Soak test: run the workflows for this long, such as 10s, or this many times before measuring")
+	flags.BoolP("recursive", "r", false, "Also run workflow files in subdirectories of the directories given")
+	flags.StringArray("step", nil, "Run only this step, and the steps it lists under needs (repeatable)")
+	flags.String("from-step", "", "Start each workflow at this step, skipping the steps before it")
+	flags.Bool("checkpoint", false, "Record progress in the state file so an interrupted run can be resumed")
+	flags.Bool("resume", false, "Continue from the state file, skipping files and steps that already passed")
+	flags.String("state-file", runner.DefaultStateFile, "State file for --checkpoint and --resume")
+	flags.Bool("sequential", false, "Run files one at a time, in path order or the order _defaults.yaml lists them, instead of in parallel")
+	flags.Bool("lenient", false, "Ignore unknown fields in workflow files instead of failing them")
+	flags.Bool("strict-vars", false, "Fail a step that refers to an unset variable instead of sending the ${name} text")
+	flags.Bool("dry-run", false, "Print each resolved request instead of sending it")
+	flags.Bool("print-curl", false, "Print each request as an equivalent curl command")
+	flags.String("notify-url", "", "POST a summary of the run to this webhook URL")
+	flags.String("notify-format", runner.NotifyJSON, "Notification payload: json or slack (incoming webhook message)")
+	flags.String("notify-on", "always", "When to notify: always or failure")
+	flags.String("metrics-push", "", "Push Prometheus metrics for the run to this Pushgateway URL")
+	flags.String("metrics-file", "", "Write Prometheus metrics for the run to this file (for the node exporter textfile collector)")
+	flags.String("otlp-endpoint", "", "Export a trace per workflow file to this OTLP/HTTP collector (e.g. http://localhost:4318)")
+	flags.String("har", "", "Record every request and response to this file in HAR format")
+	flags.String("log-format", "text", "Log format for the text report: text or json (one object per line)")
+	flags.Bool("no-color", false, "Disable colored output")
+	flags.String("report", runner.ReportText, "Output format: text, tap (TAP version 13) or github (Actions annotations)")
+}
+
+// verbosity maps the -v count and --quiet flag to a runner log level.
+func verbosity(count int, quiet bool) runner.Verbosity {
+	switch {
+	case quiet:
+		return runner.VerbosityQuiet
+	case count >= 2:
+		return runner.VerbosityDebug
+	case count == 1:
+		return runner.VerbosityVerbose
+	default:
+		return runner.VerbosityNormal
+	}
+}
+
+// colorSupported reports whether w is a terminal and the user has not opted
+// out of color with NO_COLOR.
+func colorSupported(w io.Writer) bool {
+	return os.Getenv("NO_COLOR") == "" && isTerminal(w)
+}
+
+// isTerminal reports whether w is a file open on a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
@@ -2,11 +2,20 @@ package cmd
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/michaelmccabe/ramjam/pkg/runner"
+	"github.com/spf13/pflag"
 )
 
 func TestRunCmdRegistered(t *testing.T) {
@@ -22,6 +31,31 @@ func TestRunCmdRegistered(t *testing.T) {
 	}
 }
 
+func TestRunCmdConnectionFlags(t *testing.T) {
+	for _, name := range []string{"cert", "key", "cacert", "proxy"} {
+		flag := runCmd.Flags().Lookup(name)
+		if flag == nil {
+			t.Fatalf("%s flag not found", name)
+		}
+		if flag.DefValue != "" {
+			t.Errorf("%s default value = %v, want empty", name, flag.DefValue)
+		}
+	}
+}
+
+func TestRunCmdInsecureFlag(t *testing.T) {
+	flag := runCmd.Flags().Lookup("insecure")
+	if flag == nil {
+		t.Fatal("insecure flag not found")
+	}
+	if flag.Shorthand != "k" {
+		t.Errorf("insecure shorthand = %v, want k", flag.Shorthand)
+	}
+	if flag.DefValue != "false" {
+		t.Errorf("insecure default value = %v, want false", flag.DefValue)
+	}
+}
+
 func TestRunCmdNoArgs(t *testing.T) {
 	var stdout bytes.Buffer
 	rootCmd.SetOut(&stdout)
@@ -104,3 +138,232 @@ workflow:
 		t.Fatalf("run command failed: %v", err)
 	}
 }
+
+func TestRunCmdReportFlag(t *testing.T) {
+	flag := runCmd.Flags().Lookup("report")
+	if flag == nil {
+		t.Fatal("report flag not found")
+	}
+	if flag.DefValue != "text" {
+		t.Errorf("report default value = %v, want text", flag.DefValue)
+	}
+
+	defer rootCmd.SetArgs(nil)
+	defer runCmd.Flags().Set("report", "text")
+	rootCmd.SetArgs([]string{"run", "--report", "junit", "missing.yaml"})
+	err := rootCmd.Execute()
+	if err == nil || err.Error() != `unknown report format "junit" (expected text, tap or github)` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRunCmdVars(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tenant":"` + r.Header.Get("X-Tenant") + `","user":"` + r.URL.Query().Get("user") + `"}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, "tenant.yaml")
+	os.WriteFile(workflow, []byte(`
+config:
+  base_url: "http://unused.invalid"
+workflow:
+- step: "whoami"
+  request:
+    url: "/whoami"
+    headers:
+      X-Tenant: "${tenant}"
+    params:
+      user: "${user}"
+  expect:
+    json_path_match:
+    - path: "tenant"
+      value: "globex"
+    - path: "user"
+      value: "ada"
+`), 0644)
+	varFile := filepath.Join(dir, "vars.yaml")
+	os.WriteFile(varFile, []byte("base_url: "+srv.URL+"\ntenant: acme\nuser: ada\n"), 0644)
+
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stdout)
+	defer rootCmd.SetArgs(nil)
+	defer runCmd.Flags().Lookup("var").Value.(pflag.SliceValue).Replace(nil)
+	defer runCmd.Flags().Lookup("var-file").Value.(pflag.SliceValue).Replace(nil)
+
+	// --var overrides the value from --var-file.
+	rootCmd.SetArgs([]string{"run", workflow, "--var-file", varFile, "--var", "tenant=globex"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("run command failed: %v", err)
+	}
+}
+
+func TestRunCmdInvalidVar(t *testing.T) {
+	defer rootCmd.SetArgs(nil)
+	defer runCmd.Flags().Lookup("var").Value.(pflag.SliceValue).Replace(nil)
+	rootCmd.SetArgs([]string{"run", "missing.yaml", "--var", "tenant"})
+	err := rootCmd.Execute()
+	if err == nil || err.Error() != `invalid --var "tenant" (expected key=value)` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRunCmdInvalidHeader(t *testing.T) {
+	defer rootCmd.SetArgs(nil)
+	defer runCmd.Flags().Lookup("header").Value.(pflag.SliceValue).Replace(nil)
+	rootCmd.SetArgs([]string{"run", "missing.yaml", "-H", "X-Tenant"})
+	err := rootCmd.Execute()
+	if err == nil || err.Error() != `invalid --header "X-Tenant" (expected 'Name: value')` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRunCmdProfile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tenant":"` + r.Header.Get("X-Tenant") + `"}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".ramjam.yaml"), []byte("profiles:\n  staging:\n    base_url: "+srv.URL+"\n    headers:\n      X-Tenant: acme\n"), 0644)
+	workflow := filepath.Join(dir, "tenant.yaml")
+	os.WriteFile(workflow, []byte(`
+config:
+  base_url: "http://unused.invalid"
+workflow:
+- step: "whoami"
+  request:
+    url: "/whoami"
+  expect:
+    json_path_match:
+    - path: "tenant"
+      value: "acme"
+`), 0644)
+	t.Chdir(dir)
+
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stdout)
+	defer rootCmd.SetArgs(nil)
+	defer runCmd.Flags().Set("profile", "")
+
+	rootCmd.SetArgs([]string{"run", workflow, "--profile", "staging"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("run command failed: %v", err)
+	}
+	rootCmd.SetArgs([]string{"run", workflow, "--profile", "prod"})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), `profile "prod" not found`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRunResultExitCodes(t *testing.T) {
+	assertion := &runner.StepError{Step: "get", Err: errors.New("expected status 200, got 500")}
+	unreachable := &runner.StepError{Step: "get", Err: fmt.Errorf("request: %w", &url.Error{Op: "Get", URL: "http://x", Err: errors.New("connection refused")})}
+	parse := errors.New("parse a.yaml: yaml: line 1")
+
+	tests := []struct {
+		name      string
+		err       error
+		threshold int
+		want      int
+	}{
+		{"success", nil, 0, 0},
+		{"assertion", errors.Join(assertion), 0, exitFailed},
+		{"within threshold", errors.Join(assertion, assertion), 2, 0},
+		{"over threshold", errors.Join(assertion, assertion), 1, exitFailed},
+		{"request not sent", errors.Join(assertion, unreachable), 0, exitError},
+		{"request not sent within threshold", errors.Join(unreachable), 1, 0},
+		{"parse error", errors.Join(parse), 5, exitError},
+		{"run error", errors.New("no files found"), 0, exitError},
+		{"interrupted", errors.Join(assertion, context.Canceled), 5, exitInterrupted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runResult(io.Discard, tt.err, runner.ReportText, 0, false, tt.threshold)
+			got := 0
+			if err != nil {
+				got = exitCode(err)
+			}
+			if got != tt.want {
+				t.Errorf("exit code = %d, want %d (err %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRunCmdPollUnmet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "pending"}`))
+	}))
+	defer srv.Close()
+	workflow := filepath.Join(t.TempDir(), "job.yaml")
+	os.WriteFile(workflow, []byte("workflow:\n  - step: job\n    request:\n      url: "+srv.URL+"\n    poll:\n      interval: 10ms\n      max_wait: 30ms\n    expect:\n      json_path_match:\n        - path: status\n          value: done\n"), 0644)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+
+	rootCmd.SetArgs([]string{"run", workflow})
+	err := rootCmd.Execute()
+	if exitCode(err) != exitFailed {
+		t.Fatalf("exit code = %d, want %d (err %v)", exitCode(err), exitFailed, err)
+	}
+	if !strings.Contains(out.String(), "condition not met after 30ms") || strings.Contains(out.String(), "in time") {
+		t.Errorf("expected an unmet poll without a timeout hint:\n%s", out.String())
+	}
+}
+
+func TestRunCmdRepeat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	workflow := filepath.Join(t.TempDir(), "health.yaml")
+	os.WriteFile(workflow, []byte("workflow:\n  - step: health\n    request:\n      url: "+srv.URL+"\n    expect:\n      status: 200\n"), 0644)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+	defer runCmd.Flags().Set("repeat-count", "0")
+	defer runCmd.Flags().Set("fail-threshold", "0")
+	defer runCmd.Flags().Set("quiet", "false")
+
+	rootCmd.SetArgs([]string{"run", workflow, "--repeat-count", "3", "--quiet", "--fail-threshold", "2"})
+	err := rootCmd.Execute()
+	if exitCode(err) != exitFailed || err.Error() != "3 failures in 3 iterations" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"Soak run: 3 iterations", "First failure: iteration 1", "health.yaml > health: expected status 200, got 503", "Drift"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunCmdOutput(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	workflow := filepath.Join(t.TempDir(), "health.yaml")
+	os.WriteFile(workflow, []byte("workflow:\n  - step: health\n    request:\n      url: "+srv.URL+"\n    expect:\n      status: 200\n"), 0644)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+
+	rootCmd.SetArgs([]string{"run", workflow})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"✓ health", "Summary", "All steps were run successfully"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
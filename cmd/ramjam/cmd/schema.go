@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/michaelmccabe/ramjam/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Work with the JSON Schema for workflow files",
+	Long: `Work with the JSON Schema that describes workflow files.
+Examples:
+  ramjam schema print > ramjam.schema.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var schemaPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the JSON Schema for workflow files",
+	Long: `Print the JSON Schema for workflow files. Save it next to your workflows
+and point your editor at it for completion and inline validation. With the
+YAML extension for VS Code, add this comment to the top of a workflow:
+
+  # yaml-language-server: $schema=./ramjam.schema.json
+
+ramjam validate checks workflows against the same schema.
+Examples:
+  ramjam schema print > ramjam.schema.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schema, err := runner.Schema()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(schema))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+	schemaCmd.AddCommand(schemaPrintCmd)
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestSchemaPrintCmd(t *testing.T) {
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+
+	rootCmd.SetArgs([]string{"schema", "print"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Schema     string                     `json:"$schema"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &schema); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	for _, name := range []string{"metadata", "config", "workflow"} {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("schema has no %q property", name)
+		}
+	}
+}
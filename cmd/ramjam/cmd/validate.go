@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/michaelmccabe/ramjam/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate <files-or-folders...>",
+	Short: "Check workflow files for mistakes without running them",
+	Long: `Check workflow files without sending any requests. Reports unknown fields,
+undefined variables, captures that are never used or can never run, invalid
+JSONPath and regex syntax, and missing files.
+Examples:
+  ramjam validate ./tests/
+  ramjam validate login.yaml signup.yaml
+  ramjam validate ./tests/ --var-file staging.yaml`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vars, err := cliVars(cmd)
+		if err != nil {
+			return err
+		}
+		r := runner.New(30*time.Second, false, runner.WithVars(vars))
+		problems, err := r.Lint(args)
+		if err != nil {
+			return err
+		}
+
+		errCount := 0
+		for _, p := range problems {
+			fmt.Fprintln(cmd.OutOrStdout(), p)
+			if !p.Warning {
+				errCount++
+			}
+		}
+		if errCount > 0 {
+			return fmt.Errorf("found %d problem(s)", errCount)
+		}
+		if len(problems) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No problems found")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().String("env", "", "Environment name or variables file (defaults to the current environment)")
+	validateCmd.Flags().StringArray("var", nil, "Treat a variable as defined, as with run --var key=value (repeatable)")
+	validateCmd.Flags().StringArray("var-file", nil, "Treat the variables in a YAML file as defined (repeatable)")
+}
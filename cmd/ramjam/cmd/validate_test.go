@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateCmd(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.yaml")
+	os.WriteFile(good, []byte("workflow:\n- step: ping\n  request:\n    url: \"https://example.com\"\n"), 0644)
+	bad := filepath.Join(dir, "bad.yaml")
+	os.WriteFile(bad, []byte("workflow:\n- step: ping\n  request:\n    url: \"https://example.com/${id}\"\n"), 0644)
+
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+
+	rootCmd.SetArgs([]string{"validate", good})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("expected valid file to pass: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "No problems found") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	rootCmd.SetArgs([]string{"validate", dir})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "found 1 problem") {
+		t.Fatalf("expected validation failure, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), bad+`:4: step "ping": undefined variable ${id}`) {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+}
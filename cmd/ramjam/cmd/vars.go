@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/michaelmccabe/ramjam/pkg/config"
+	"github.com/michaelmccabe/ramjam/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+// cliVars merges the environment, --var-file and --var flags. The
+// environment comes from --env, or the project's current environment when
+// the command has an --env flag that was not set. Later sources override
+// earlier ones, and --var overrides everything.
+func cliVars(cmd *cobra.Command) (map[string]string, error) {
+	vars := map[string]string{}
+	if cmd.Flags().Lookup("env") != nil {
+		name, _ := cmd.Flags().GetString("env")
+		if name == "" {
+			current, err := currentProject().CurrentEnv()
+			if err != nil {
+				return nil, err
+			}
+			name = current
+		}
+		if name != "" {
+			envVars, err := loadEnv(name)
+			if err != nil {
+				return nil, err
+			}
+			vars = envVars
+		}
+	}
+
+	files, _ := cmd.Flags().GetStringArray("var-file")
+	pairs, _ := cmd.Flags().GetStringArray("var")
+	for _, f := range files {
+		fileVars, err := config.LoadVars(f)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+	for _, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid --var %q (expected key=value)", pair)
+		}
+		vars[strings.TrimSpace(k)] = v
+	}
+	return vars, nil
+}
+
+// loadEnv loads a YAML variables file if name is a path to one, and the
+// project environment called name otherwise.
+func loadEnv(name string) (map[string]string, error) {
+	if isVarFile(name) {
+		return config.LoadVars(name)
+	}
+	return currentProject().Env(name)
+}
+
+func isVarFile(name string) bool {
+	ext := filepath.Ext(name)
+	if ext != ".yaml" && ext != ".yml" && !strings.ContainsAny(name, `/\`) {
+		return false
+	}
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// currentProject returns the project that contains the working directory.
+func currentProject() *config.Project {
+	wd, err := os.Getwd()
+	if err != nil {
+		wd = "."
+	}
+	return config.FindProject(wd)
+}
+
+// profileOptions returns the runner option for --profile, which names a
+// profile in the nearest .ramjam.yaml.
+func profileOptions(cmd *cobra.Command) ([]runner.Option, error) {
+	name, _ := cmd.Flags().GetString("profile")
+	if name == "" {
+		return nil, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		wd = "."
+	}
+	path := config.FindProjectFile(wd)
+	if path == "" {
+		return nil, fmt.Errorf("--profile %s: no %s found in %s or its parents", name, config.ProjectFileName, wd)
+	}
+	profile, err := runner.LoadProfile(path, name)
+	if err != nil {
+		return nil, err
+	}
+	return []runner.Option{runner.WithProfile(profile)}, nil
+}
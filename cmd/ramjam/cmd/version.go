@@ -11,7 +11,7 @@ var versionCmd = &cobra.Command{
 	Short: "Print the version number of ramjam",
 	Long:  `All software has versions. This is ramjam's`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("ramjam version %s\n", Version)
+		fmt.Fprintf(cmd.OutOrStdout(), "ramjam version %s\n", Version)
 	},
 }
 
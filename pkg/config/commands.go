@@ -9,9 +9,25 @@ type CommandText struct {
 
 // CommandsConfig holds all command text definitions
 type CommandsConfig struct {
-	Root    CommandText `yaml:"root"`
-	Run     CommandText `yaml:"run"`
-	Version CommandText `yaml:"version"`
+	Root     CommandText     `yaml:"root"`
+	Run      CommandText     `yaml:"run"`
+	Version  CommandText     `yaml:"version"`
+	Commands []CustomCommand `yaml:"commands,omitempty"`
+}
+
+// CustomCommand defines a subcommand that runs a fixed set of workflows,
+// such as ramjam smoke, with its own environment, profile and variables.
+type CustomCommand struct {
+	Name  string `yaml:"name"`
+	Short string `yaml:"short"`
+	Long  string `yaml:"long,omitempty"`
+	// Args are the workflow files or directories run when none are given.
+	Args []string `yaml:"args"`
+	// Env, Profile and Vars are used as --env, --profile and --var unless
+	// those flags are given.
+	Env     string            `yaml:"env,omitempty"`
+	Profile string            `yaml:"profile,omitempty"`
+	Vars    map[string]string `yaml:"vars,omitempty"`
 }
 
 // LoadCommands loads command text from a YAML file
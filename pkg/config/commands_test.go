@@ -175,3 +175,26 @@ version:
 		})
 	}
 }
+
+func TestCustomCommands(t *testing.T) {
+	config, err := LoadCommandsFromBytes([]byte(`
+commands:
+- name: "smoke"
+  short: "Run the smoke tests"
+  args: ["tests/smoke", "tests/auth.yaml"]
+  env: "staging"
+  profile: "ci"
+  vars:
+    tenant: "acme"
+`))
+	if err != nil {
+		t.Fatalf("LoadCommandsFromBytes() error = %v", err)
+	}
+	if len(config.Commands) != 1 {
+		t.Fatalf("got %d commands, want 1", len(config.Commands))
+	}
+	smoke := config.Commands[0]
+	if smoke.Name != "smoke" || len(smoke.Args) != 2 || smoke.Env != "staging" || smoke.Profile != "ci" || smoke.Vars["tenant"] != "acme" {
+		t.Errorf("command = %+v", smoke)
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectDirName is the directory that holds a project's environments.
+const ProjectDirName = ".ramjam"
+
+// Project manages the named environments of a project. Each environment is
+// a YAML map of variables in envs/<name>.yaml, and the current environment
+// is recorded in current_env.
+type Project struct {
+	Dir string
+}
+
+// FindProject returns the project whose .ramjam directory is in start or
+// the nearest parent. If there is none, the project is rooted at start so
+// that it can be created.
+func FindProject(start string) *Project {
+	dir := start
+	for {
+		candidate := filepath.Join(dir, ProjectDirName)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return &Project{Dir: candidate}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return &Project{Dir: filepath.Join(start, ProjectDirName)}
+		}
+		dir = parent
+	}
+}
+
+func (p *Project) envPath(name string) string {
+	return filepath.Join(p.Dir, "envs", name+".yaml")
+}
+
+func (p *Project) currentPath() string {
+	return filepath.Join(p.Dir, "current_env")
+}
+
+// Envs returns the names of the project's environments in sorted order.
+func (p *Project) Envs() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(p.Dir, "envs"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err := e.Wrap(err, "failed to read environments"); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".yaml"); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Env loads the variables of a named environment.
+func (p *Project) Env(name string) (map[string]string, error) {
+	if err := checkEnvName(name); err != nil {
+		return nil, err
+	}
+	path := p.envPath(name)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("environment %q does not exist", name)
+	}
+	return LoadVars(path)
+}
+
+// SetEnv sets variables in a named environment, creating it if needed.
+// Other variables in the environment are kept.
+func (p *Project) SetEnv(name string, vars map[string]string) error {
+	if err := checkEnvName(name); err != nil {
+		return err
+	}
+	path := p.envPath(name)
+	existing := map[string]string{}
+	if _, err := os.Stat(path); err == nil {
+		if existing, err = LoadVars(path); err != nil {
+			return err
+		}
+	}
+	for k, v := range vars {
+		existing[k] = v
+	}
+
+	data, err := yaml.Marshal(existing)
+	if err := e.Wrapf(err, "failed to encode environment %s", name); err != nil {
+		return err
+	}
+	if err := e.Wrap(os.MkdirAll(filepath.Dir(path), 0755), "failed to create environments directory"); err != nil {
+		return err
+	}
+	return e.Wrapf(os.WriteFile(path, data, 0644), "failed to write file %s", path)
+}
+
+// CurrentEnv returns the environment selected with UseEnv, or "" if none
+// is selected.
+func (p *Project) CurrentEnv() (string, error) {
+	data, err := os.ReadFile(p.currentPath())
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err := e.Wrap(err, "failed to read current environment"); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// UseEnv records name as the current environment. The environment must
+// exist.
+func (p *Project) UseEnv(name string) error {
+	if err := checkEnvName(name); err != nil {
+		return err
+	}
+	if _, err := os.Stat(p.envPath(name)); os.IsNotExist(err) {
+		return fmt.Errorf("environment %q does not exist", name)
+	}
+	return e.Wrap(os.WriteFile(p.currentPath(), []byte(name+"\n"), 0644), "failed to write current environment")
+}
+
+func checkEnvName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid environment name %q", name)
+	}
+	return nil
+}
+
+// LoadVars reads a YAML file of variable names and single values, such as
+//
+//	base_url: https://staging.example.com
+//	tenant: acme
+func LoadVars(path string) (map[string]string, error) {
+	var raw map[string]interface{}
+	if err := LoadFile(path, &raw); err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("%s: variable %q must be a single value", path, k)
+		case nil:
+			vars[k] = ""
+		default:
+			vars[k] = fmt.Sprint(v)
+		}
+	}
+	return vars, nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindProject(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "tests", "smoke")
+	os.MkdirAll(nested, 0755)
+
+	if got := FindProject(nested).Dir; got != filepath.Join(nested, ProjectDirName) {
+		t.Errorf("without a project, Dir = %s", got)
+	}
+	os.Mkdir(filepath.Join(root, ProjectDirName), 0755)
+	if got := FindProject(nested).Dir; got != filepath.Join(root, ProjectDirName) {
+		t.Errorf("expected the parent project, got %s", got)
+	}
+}
+
+func TestProjectEnvs(t *testing.T) {
+	p := &Project{Dir: filepath.Join(t.TempDir(), ProjectDirName)}
+
+	if names, err := p.Envs(); err != nil || len(names) != 0 {
+		t.Fatalf("expected no environments, got %v, %v", names, err)
+	}
+	if err := p.SetEnv("staging", map[string]string{"base_url": "https://staging.test", "tenant": "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetEnv("staging", map[string]string{"tenant": "globex"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetEnv("dev", map[string]string{"base_url": "http://localhost"}); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := p.Envs()
+	if err != nil || !reflect.DeepEqual(names, []string{"dev", "staging"}) {
+		t.Errorf("Envs() = %v, %v", names, err)
+	}
+	vars, err := p.Env("staging")
+	if err != nil || !reflect.DeepEqual(vars, map[string]string{"base_url": "https://staging.test", "tenant": "globex"}) {
+		t.Errorf("Env(staging) = %v, %v", vars, err)
+	}
+	if _, err := p.Env("prod"); err == nil || err.Error() != `environment "prod" does not exist` {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if current, _ := p.CurrentEnv(); current != "" {
+		t.Errorf("expected no current environment, got %q", current)
+	}
+	if err := p.UseEnv("prod"); err == nil {
+		t.Error("expected error selecting a missing environment")
+	}
+	if err := p.UseEnv("staging"); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := p.CurrentEnv(); current != "staging" {
+		t.Errorf("CurrentEnv() = %q", current)
+	}
+}
+
+func TestInvalidEnvName(t *testing.T) {
+	p := &Project{Dir: t.TempDir()}
+	for _, name := range []string{"", "../secrets", ".hidden"} {
+		if err := p.SetEnv(name, map[string]string{"a": "b"}); err == nil {
+			t.Errorf("expected error for %q", name)
+		}
+	}
+}
+
+func TestLoadVars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	os.WriteFile(path, []byte("tenant: acme\nretries: 3\nempty:\n"), 0644)
+	vars, err := LoadVars(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["tenant"] != "acme" || vars["retries"] != "3" || vars["empty"] != "" {
+		t.Errorf("unexpected vars: %v", vars)
+	}
+
+	os.WriteFile(path, []byte("nested:\n  a: 1\n"), 0644)
+	if _, err := LoadVars(path); err == nil || !strings.Contains(err.Error(), `variable "nested" must be a single value`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	e "github.com/michaelmccabe/ramjam/pkg/errors"
 	"gopkg.in/yaml.v3"
@@ -11,34 +13,136 @@ import (
 // Loader handles loading and parsing YAML configuration files
 type Loader struct {
 	basePath string
+	opts     []Option
+}
+
+// Option configures how YAML is parsed.
+type Option func(*options)
+
+type options struct {
+	lookupEnv func(string) (string, bool)
+	cacheDir  *string
+}
+
+// WithEnvExpansion replaces ${VAR} and ${VAR:-default} in YAML values with
+// environment variables before unmarshalling. The default is used when VAR
+// is unset or empty. A reference to an unset
+// variable without a default is left as it is, so workflow variables such
+// as ${base_url} still reach the runner.
+func WithEnvExpansion() Option {
+	return func(o *options) {
+		o.lookupEnv = os.LookupEnv
+	}
 }
 
 // NewLoader creates a new Loader with the specified base path for resources
-func NewLoader(basePath string) *Loader {
+func NewLoader(basePath string, opts ...Option) *Loader {
 	return &Loader{
 		basePath: basePath,
+		opts:     opts,
 	}
 }
 
-// Load reads a YAML file and unmarshals it into the provided target
+// Load reads a YAML file and unmarshals it into the provided target. The
+// filename, or the loader's base path, may be an http or https URL.
 func (l *Loader) Load(filename string, target interface{}) error {
 	path := filepath.Join(l.basePath, filename)
-	return LoadFile(path, target)
+	switch {
+	case IsURL(filename):
+		path = filename
+	case IsURL(l.basePath):
+		base := l.basePath
+		if !strings.HasSuffix(base, "/") {
+			base += "/"
+		}
+		var err error
+		if path, err = ResolveURL(base, filename); err != nil {
+			return err
+		}
+	}
+	return LoadFile(path, target, l.opts...)
 }
 
-// LoadFile reads a YAML file from the given path and unmarshals it into the target
-func LoadFile(path string, target interface{}) error {
-	data, err := os.ReadFile(path)
-	if err := e.Wrapf(err, "failed to read file %s", path); err != nil {
-		return err
+// LoadFile reads a YAML file from the given path and unmarshals it into the target.
+// Paths that are http or https URLs are fetched with ReadURL.
+func LoadFile(path string, target interface{}, opts ...Option) error {
+	var data []byte
+	var err error
+	if IsURL(path) {
+		data, err = ReadURL(path, opts...)
+		if err != nil {
+			return err
+		}
+	} else {
+		data, err = os.ReadFile(path)
+		if err := e.Wrapf(err, "failed to read file %s", path); err != nil {
+			return err
+		}
 	}
 
-	return Parse(data, target)
+	return Parse(data, target, opts...)
 }
 
 // Parse parses YAML data and unmarshals it into the target
-func Parse(data []byte, target interface{}) error {
-	return e.Wrap(yaml.Unmarshal(data, target), "failed to parse YAML")
+func Parse(data []byte, target interface{}, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.lookupEnv == nil {
+		return e.Wrap(yaml.Unmarshal(data, target), "failed to parse YAML")
+	}
+
+	var doc yaml.Node
+	if err := e.Wrap(yaml.Unmarshal(data, &doc), "failed to parse YAML"); err != nil {
+		return err
+	}
+	if doc.Kind == 0 {
+		return nil
+	}
+	expandNode(&doc, o.lookupEnv)
+	return e.Wrap(doc.Decode(target), "failed to parse YAML")
+}
+
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandNode expands environment variables in every scalar value under n.
+// Mapping keys are left alone.
+func expandNode(n *yaml.Node, lookup func(string) (string, bool)) {
+	if n.Kind == yaml.ScalarNode {
+		expanded := expandEnv(n.Value, lookup)
+		if expanded != n.Value {
+			n.Value = expanded
+			// Let plain scalars resolve again, so ${PORT} can fill an int.
+			if n.Style == 0 {
+				n.Tag = ""
+			}
+		}
+		return
+	}
+	for i, child := range n.Content {
+		if n.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		expandNode(child, lookup)
+	}
+}
+
+func expandEnv(s string, lookup func(string) (string, bool)) string {
+	return envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		m := envRef.FindStringSubmatch(ref)
+		v, ok := lookup(m[1])
+		switch {
+		case ok && v != "":
+			return v
+		case m[2] != "":
+			// As in the shell, the default also replaces an empty value.
+			return m[3]
+		case ok:
+			return v
+		}
+		return ref
+	})
 }
 
 // LoadBytes is a convenience function to load YAML from embedded bytes
@@ -145,3 +145,64 @@ func TestLoadBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestParseWithEnvExpansion(t *testing.T) {
+	t.Setenv("RAMJAM_TEST_HOST", "api.internal")
+	t.Setenv("RAMJAM_TEST_PORT", "8443")
+	t.Setenv("RAMJAM_TEST_EMPTY", "")
+
+	data := []byte(`
+url: "https://${RAMJAM_TEST_HOST}:${RAMJAM_TEST_PORT}/v1"
+port: ${RAMJAM_TEST_PORT}
+token: ${RAMJAM_TEST_UNSET:-local-token}
+empty: ${RAMJAM_TEST_EMPTY:-fallback}
+runtime: "${base_url}/users"
+${RAMJAM_TEST_HOST}: key
+`)
+	var target struct {
+		URL     string `yaml:"url"`
+		Port    int    `yaml:"port"`
+		Token   string `yaml:"token"`
+		Empty   string `yaml:"empty"`
+		Runtime string `yaml:"runtime"`
+		Key     string `yaml:"${RAMJAM_TEST_HOST}"`
+	}
+	if err := Parse(data, &target, WithEnvExpansion()); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := struct {
+		URL     string `yaml:"url"`
+		Port    int    `yaml:"port"`
+		Token   string `yaml:"token"`
+		Empty   string `yaml:"empty"`
+		Runtime string `yaml:"runtime"`
+		Key     string `yaml:"${RAMJAM_TEST_HOST}"`
+	}{"https://api.internal:8443/v1", 8443, "local-token", "fallback", "${base_url}/users", "key"}
+	if target != want {
+		t.Errorf("Parse() = %+v, want %+v", target, want)
+	}
+
+	// Without the option, references are left for the caller.
+	var raw map[string]string
+	if err := Parse(data, &raw); err != nil || raw["token"] != "${RAMJAM_TEST_UNSET:-local-token}" {
+		t.Errorf("Parse() without expansion: token = %q, err = %v", raw["token"], err)
+	}
+}
+
+func TestLoaderWithEnvExpansion(t *testing.T) {
+	t.Setenv("RAMJAM_TEST_MESSAGE", "hello")
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "test.yaml"), []byte("message: ${RAMJAM_TEST_MESSAGE}"), 0644); err != nil {
+		t.Fatalf("Failed to write test.yaml: %v", err)
+	}
+
+	var target struct {
+		Message string `yaml:"message"`
+	}
+	if err := NewLoader(tmpDir, WithEnvExpansion()).Load("test.yaml", &target); err != nil {
+		t.Fatalf("Loader.Load() error = %v", err)
+	}
+	if target.Message != "hello" {
+		t.Errorf("Loader.Load() message = %v, want hello", target.Message)
+	}
+}
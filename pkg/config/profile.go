@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName is the project file that defines named profiles.
+const ProjectFileName = ".ramjam.yaml"
+
+// FindProjectFile returns the path of the .ramjam.yaml in start or the
+// nearest parent, or "" if there is none.
+func FindProjectFile(start string) string {
+	dir := start
+	for {
+		candidate := filepath.Join(dir, ProjectFileName)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadProfile unmarshals the profile called name from the project file at
+// path into target. Environment variables in the file are expanded, so
+// profiles can refer to secrets without containing them.
+//
+//	profiles:
+//	  staging:
+//	    base_url: https://staging.example.com
+func LoadProfile(path, name string, target interface{}) error {
+	var file struct {
+		Profiles map[string]yaml.Node `yaml:"profiles"`
+	}
+	if err := LoadFile(path, &file, WithEnvExpansion()); err != nil {
+		return err
+	}
+	node, ok := file.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(file.Profiles))
+		for n := range file.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("profile %q not found: %s defines no profiles", name, path)
+		}
+		return fmt.Errorf("profile %q not found in %s (available: %s)", name, path, strings.Join(names, ", "))
+	}
+	return e.Wrapf(node.Decode(target), "failed to parse profile %s", name)
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindProjectFile(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "tests", "smoke")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectFile(nested); got != "" {
+		t.Errorf("FindProjectFile() = %q before the file exists", got)
+	}
+	path := filepath.Join(root, ProjectFileName)
+	os.WriteFile(path, []byte("profiles: {}\n"), 0644)
+	if got := FindProjectFile(nested); got != path {
+		t.Errorf("FindProjectFile() = %q, want %q", got, path)
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	t.Setenv("RAMJAM_TEST_TOKEN", "s3cret")
+	path := filepath.Join(t.TempDir(), ProjectFileName)
+	os.WriteFile(path, []byte(`
+profiles:
+  staging:
+    base_url: https://staging.example.com
+    token: ${RAMJAM_TEST_TOKEN}
+  prod:
+    base_url: https://api.example.com
+`), 0644)
+
+	var profile struct {
+		BaseURL string `yaml:"base_url"`
+		Token   string `yaml:"token"`
+	}
+	if err := LoadProfile(path, "staging", &profile); err != nil {
+		t.Fatalf("LoadProfile() error = %v", err)
+	}
+	if profile.BaseURL != "https://staging.example.com" || profile.Token != "s3cret" {
+		t.Errorf("LoadProfile() = %+v", profile)
+	}
+
+	err := LoadProfile(path, "qa", &profile)
+	if err == nil || err.Error() != `profile "qa" not found in `+path+` (available: prod, staging)` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
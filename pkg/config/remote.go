@@ -0,0 +1,173 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// remoteTimeout bounds how long fetching a remote file may take.
+const remoteTimeout = 30 * time.Second
+
+// IsURL reports whether path is an http or https URL rather than a local
+// file.
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://")
+}
+
+// WithCacheDir caches remote files in dir instead of the user's cache
+// directory. An empty dir disables caching.
+func WithCacheDir(dir string) Option {
+	return func(o *options) {
+		o.cacheDir = &dir
+	}
+}
+
+// ResolveURL resolves ref against the URL of the file that refers to it, as
+// a relative link in a web page would be.
+func ResolveURL(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err := e.Wrapf(err, "parse URL %s", base); err != nil {
+		return "", err
+	}
+	r, err := url.Parse(ref)
+	if err := e.Wrapf(err, "parse URL %s", ref); err != nil {
+		return "", err
+	}
+	return b.ResolveReference(r).String(), nil
+}
+
+// ReadURL fetches the file at rawURL. A fragment of the form
+// #sha256=<hex> pins the file's checksum: a download with a different
+// checksum is an error, and a cached copy that matches is used without
+// contacting the server. Other files are revalidated with the ETag of the
+// cached copy, so unchanged files aren't downloaded again.
+func ReadURL(rawURL string, opts ...Option) ([]byte, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	u, err := url.Parse(rawURL)
+	if err := e.Wrapf(err, "parse URL %s", rawURL); err != nil {
+		return nil, err
+	}
+	var pinned string
+	if sum, ok := strings.CutPrefix(u.Fragment, "sha256="); ok {
+		pinned = strings.ToLower(sum)
+	} else if u.Fragment != "" {
+		return nil, fmt.Errorf("fetch %s: unsupported fragment %q (expected sha256=<hex>)", rawURL, u.Fragment)
+	}
+	u.Fragment = ""
+	target := u.String()
+
+	cache := o.cache(target)
+	cached, _ := cache.read()
+	if pinned != "" && cached != nil && checksum(cached) == pinned {
+		return cached, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err := e.Wrapf(err, "fetch %s", target); err != nil {
+		return nil, err
+	}
+	if etag := cache.etag(); etag != "" && cached != nil {
+		req.Header.Set("If-None-Match", etag)
+	}
+	client := &http.Client{Timeout: remoteTimeout}
+	resp, err := client.Do(req)
+	if err := e.Wrapf(err, "fetch %s", target); err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var data []byte
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		if cached == nil {
+			return nil, fmt.Errorf("fetch %s: 304 Not Modified without a cached copy", target)
+		}
+		data = cached
+	case http.StatusOK:
+		data, err = io.ReadAll(resp.Body)
+		if err := e.Wrapf(err, "fetch %s", target); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("fetch %s: %s", target, resp.Status)
+	}
+
+	if pinned != "" {
+		if got := checksum(data); got != pinned {
+			return nil, fmt.Errorf("fetch %s: checksum mismatch: got sha256=%s, want sha256=%s", target, got, pinned)
+		}
+	}
+	if resp.StatusCode == http.StatusOK {
+		cache.write(data, resp.Header.Get("ETag"))
+	}
+	return data, nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// urlCache stores downloaded files, and their ETags, under a name derived
+// from the URL. The zero value caches nothing.
+type urlCache struct {
+	path string
+}
+
+func (o options) cache(target string) urlCache {
+	dir := ""
+	if o.cacheDir != nil {
+		dir = *o.cacheDir
+	} else if userDir, err := os.UserCacheDir(); err == nil {
+		dir = filepath.Join(userDir, "ramjam", "remote")
+	}
+	if dir == "" {
+		return urlCache{}
+	}
+	return urlCache{path: filepath.Join(dir, checksum([]byte(target)))}
+}
+
+func (c urlCache) read() ([]byte, error) {
+	if c.path == "" {
+		return nil, os.ErrNotExist
+	}
+	return os.ReadFile(c.path)
+}
+
+func (c urlCache) etag() string {
+	if c.path == "" {
+		return ""
+	}
+	data, _ := os.ReadFile(c.path + ".etag")
+	return string(bytes.TrimSpace(data))
+}
+
+// write saves a downloaded file. Caching is best effort, so failures are
+// ignored.
+func (c urlCache) write(data []byte, etag string) {
+	if c.path == "" || os.MkdirAll(filepath.Dir(c.path), 0755) != nil {
+		return
+	}
+	if os.WriteFile(c.path, data, 0644) != nil {
+		return
+	}
+	if etag == "" {
+		os.Remove(c.path + ".etag")
+		return
+	}
+	os.WriteFile(c.path+".etag", []byte(etag), 0644)
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsURL(t *testing.T) {
+	for path, want := range map[string]bool{
+		"https://example.com/smoke.yaml": true,
+		"http://localhost:8080/a.yaml":   true,
+		"smoke.yaml":                     false,
+		"/tmp/https/smoke.yaml":          false,
+		"ftp://example.com/a.yaml":       false,
+	} {
+		if got := IsURL(path); got != want {
+			t.Errorf("IsURL(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestReadURL(t *testing.T) {
+	const body = "name: smoke\n"
+	var requests, revalidated int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/smoke.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	cache := WithCacheDir(t.TempDir())
+
+	for i := 0; i < 2; i++ {
+		data, err := ReadURL(srv.URL+"/smoke.yaml", cache)
+		if err != nil {
+			t.Fatalf("ReadURL() error = %v", err)
+		}
+		if string(data) != body {
+			t.Errorf("ReadURL() = %q", data)
+		}
+	}
+	if requests != 2 || revalidated != 1 {
+		t.Errorf("requests = %d, revalidated = %d, want 2 and 1", requests, revalidated)
+	}
+
+	pinned := srv.URL + "/smoke.yaml#sha256=" + checksum([]byte(body))
+	requests = 0
+	if _, err := ReadURL(pinned, cache); err != nil {
+		t.Fatalf("ReadURL(pinned) error = %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("pinned cached file made %d requests", requests)
+	}
+
+	_, err := ReadURL(srv.URL+"/smoke.yaml#sha256="+strings.Repeat("0", 64), WithCacheDir(""))
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("ReadURL(wrong checksum) error = %v", err)
+	}
+
+	_, err = ReadURL(srv.URL+"/missing.yaml", cache)
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("ReadURL(missing) error = %v", err)
+	}
+}
+
+func TestLoaderURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("key: " + r.URL.Path + "\n"))
+	}))
+	defer srv.Close()
+
+	var got struct {
+		Key string `yaml:"key"`
+	}
+	if err := NewLoader(srv.URL+"/suites", WithCacheDir("")).Load("smoke.yaml", &got); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got.Key != "/suites/smoke.yaml" {
+		t.Errorf("key = %q, want /suites/smoke.yaml", got.Key)
+	}
+	if err := LoadFile(srv.URL+"/direct.yaml", &got, WithCacheDir("")); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if got.Key != "/direct.yaml" {
+		t.Errorf("key = %q, want /direct.yaml", got.Key)
+	}
+}
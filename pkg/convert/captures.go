@@ -0,0 +1,302 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// minCaptureLength keeps short values such as 1 or "ok", which turn up in
+// requests by coincidence, from becoming captures.
+const minCaptureLength = 3
+
+// noisyResponseHeaders never carry values worth capturing.
+var noisyResponseHeaders = map[string]bool{
+	"date":                      true,
+	"server":                    true,
+	"content-type":              true,
+	"content-length":            true,
+	"content-encoding":          true,
+	"connection":                true,
+	"vary":                      true,
+	"cache-control":             true,
+	"expires":                   true,
+	"last-modified":             true,
+	"age":                       true,
+	"keep-alive":                true,
+	"transfer-encoding":         true,
+	"alt-svc":                   true,
+	"strict-transport-security": true,
+}
+
+// genericKeys are prefixed with the resource name, so "id" from POST /orders
+// becomes order_id.
+var genericKeys = map[string]bool{"id": true, "uuid": true, "key": true, "code": true}
+
+var (
+	jsonKey     = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+	camelBounds = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+	nonIdent    = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// responseValue is a value a response returned and how to capture it.
+type responseValue struct {
+	value   string
+	capture Capture
+}
+
+// inferCaptures finds values that a response returns and a later request
+// sends back, such as IDs and tokens. It adds a capture to the step that
+// received the value and replaces the literal in later requests with the
+// variable. Values the client sent before receiving them are left alone.
+func inferCaptures(steps []Step, exchanges []Exchange) {
+	taken := map[string]bool{}
+	done := map[string]bool{}
+	for i := range steps {
+		for _, rv := range responseValues(exchanges[i]) {
+			if done[rv.value] || sentBy(exchanges[:i+1], rv.value) {
+				continue
+			}
+			name := uniqueName(rv.capture.As, taken)
+			used := false
+			for j := i + 1; j < len(steps); j++ {
+				if replaceInRequest(&steps[j].Request, rv.value, "${"+name+"}") {
+					used = true
+				}
+			}
+			if !used {
+				continue
+			}
+			done[rv.value] = true
+			taken[name] = true
+			rv.capture.As = name
+			steps[i].Capture = append(steps[i].Capture, rv.capture)
+		}
+	}
+}
+
+// responseValues lists capturable values from a JSON response body, then
+// from response headers.
+func responseValues(ex Exchange) []responseValue {
+	resource := resourceName(ex.URL)
+	var values []responseValue
+
+	dec := json.NewDecoder(bytes.NewReader(ex.ResponseBody))
+	dec.UseNumber()
+	var body interface{}
+	if dec.Decode(&body) == nil {
+		walkJSON(body, "", func(p, key, value string) {
+			values = append(values, responseValue{value: value, capture: Capture{JSONPath: p, As: variableName(resource, key)}})
+		})
+	}
+
+	names := make([]string, 0, len(ex.ResponseHeader))
+	for name := range ex.ResponseHeader {
+		if !noisyResponseHeaders[strings.ToLower(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := ex.ResponseHeader.Get(name)
+		values = append(values, responseValue{value: value, capture: Capture{Header: name, As: variableName("", name)}})
+	}
+
+	var kept []responseValue
+	for _, v := range values {
+		if len(v.value) >= minCaptureLength {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// walkJSON calls fn for every string and number leaf with a path the runner
+// can evaluate. Leaves under keys that need quoting are skipped.
+func walkJSON(v interface{}, p string, fn func(path, key, value string)) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !jsonKey.MatchString(k) {
+				continue
+			}
+			child := k
+			if p != "" {
+				child = p + "." + k
+			}
+			switch leaf := val[k].(type) {
+			case string:
+				fn(child, k, leaf)
+			case json.Number:
+				fn(child, k, leaf.String())
+			default:
+				walkJSON(leaf, child, fn)
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			var child string
+			switch {
+			case p == "":
+				child = fmt.Sprintf("$[%d]", i)
+			case strings.HasSuffix(p, "]"):
+				// Nested arrays such as a[0][1] are not supported by json_path.
+				return
+			default:
+				child = fmt.Sprintf("%s[%d]", p, i)
+			}
+			walkJSON(item, child, fn)
+		}
+	}
+}
+
+// resourceName returns the singular form of the last non-ID path segment,
+// such as "order" for /v1/orders or /v1/orders/42.
+func resourceName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		// Skip IDs and versions such as 42, 3f2a9c or v1.
+		seg := segments[i]
+		if seg == "" || strings.ContainsAny(seg, "0123456789") {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(seg, "ies"):
+			return strings.TrimSuffix(seg, "ies") + "y"
+		case strings.HasSuffix(seg, "sses"), strings.HasSuffix(seg, "uses"):
+			return strings.TrimSuffix(seg, "es")
+		case strings.HasSuffix(seg, "s") && !strings.HasSuffix(seg, "ss") && !strings.HasSuffix(seg, "us"):
+			return strings.TrimSuffix(seg, "s")
+		}
+		return seg
+	}
+	return ""
+}
+
+// variableName turns a JSON key or header name into a snake_case variable.
+func variableName(resource, key string) string {
+	name := strings.ToLower(camelBounds.ReplaceAllString(key, "${1}_${2}"))
+	name = strings.Trim(nonIdent.ReplaceAllString(name, "_"), "_")
+	if genericKeys[name] && resource != "" {
+		name = strings.Trim(nonIdent.ReplaceAllString(strings.ToLower(resource), "_"), "_") + "_" + name
+	}
+	if name == "" {
+		name = "value"
+	}
+	return name
+}
+
+func uniqueName(name string, taken map[string]bool) string {
+	if !taken[name] {
+		return name
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s_%d", name, n)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// sentBy reports whether any of the exchanges' requests already contained
+// value.
+func sentBy(exchanges []Exchange, value string) bool {
+	for _, ex := range exchanges {
+		if _, ok := replaceToken(ex.URL, value, ""); ok {
+			return true
+		}
+		if _, ok := replaceToken(string(ex.Body), value, ""); ok {
+			return true
+		}
+		for _, values := range ex.Header {
+			for _, v := range values {
+				if _, ok := replaceToken(v, value, ""); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// replaceInRequest substitutes value in every string of a request and
+// reports whether anything changed.
+func replaceInRequest(req *Request, value, variable string) bool {
+	changed := false
+	replace := func(s string) string {
+		out, ok := replaceToken(s, value, variable)
+		changed = changed || ok
+		return out
+	}
+	req.URL = replace(req.URL)
+	req.BodyRaw = replace(req.BodyRaw)
+	for _, m := range []map[string]string{req.Params, req.Headers, req.Form} {
+		for k, v := range m {
+			m[k] = replace(v)
+		}
+	}
+	req.Body = replaceInJSON(req.Body, replace).(map[string]interface{})
+	return changed
+}
+
+func replaceInJSON(v interface{}, replace func(string) string) interface{} {
+	switch val := v.(type) {
+	case string:
+		return replace(val)
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = replaceInJSON(item, replace)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = replaceInJSON(item, replace)
+		}
+	}
+	return v
+}
+
+// replaceToken replaces occurrences of value in s that are not part of a
+// longer word, so 42 matches /orders/42 but not /orders/420.
+func replaceToken(s, value, repl string) (string, bool) {
+	var b strings.Builder
+	found := false
+	for {
+		i := strings.Index(s, value)
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		end := i + len(value)
+		if isWordByte(s, i-1) || isWordByte(s, end) {
+			b.WriteString(s[:i+1])
+			s = s[i+1:]
+			continue
+		}
+		b.WriteString(s[:i])
+		b.WriteString(repl)
+		s = s[end:]
+		found = true
+	}
+	return b.String(), found
+}
+
+func isWordByte(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
@@ -0,0 +1,94 @@
+package convert
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestFromExchangesInfersCaptures(t *testing.T) {
+	exchanges := []Exchange{
+		{
+			Method: "POST", URL: "https://api.example.com/v1/login",
+			Header:       http.Header{"Content-Type": {"application/json"}},
+			Body:         []byte(`{"user": "ada@example.com"}`),
+			Status:       200,
+			ResponseBody: []byte(`{"accessToken": "tok-abc123", "user": "ada@example.com"}`),
+		},
+		{
+			Method: "POST", URL: "https://api.example.com/v1/orders",
+			Header:         http.Header{"Authorization": {"Bearer tok-abc123"}, "Content-Type": {"application/json"}},
+			Body:           []byte(`{"sku": "A-100", "qty": 2}`),
+			Status:         201,
+			ResponseHeader: http.Header{"Etag": {`"v7"`}, "Date": {"Mon, 01 Jan 2024 00:00:00 GMT"}},
+			ResponseBody:   []byte(`{"id": 4217, "items": [{"sku": "A-100", "lineId": 99001}], "total": 2}`),
+		},
+		{
+			Method: "GET", URL: "https://api.example.com/v1/orders/4217",
+			Header:       http.Header{"Authorization": {"Bearer tok-abc123"}},
+			Status:       200,
+			ResponseBody: []byte(`{"id": 4217}`),
+		},
+		{
+			Method: "PATCH", URL: "https://api.example.com/v1/orders/4217/lines/99001",
+			Header: http.Header{"If-Match": {`"v7"`}, "Content-Type": {"application/json"}},
+			Body:   []byte(`{"note": "order 42170 is unrelated"}`),
+			Status: 200,
+		},
+	}
+
+	wf, err := FromExchanges(exchanges, HAROptions{Name: "orders"})
+	if err != nil {
+		t.Fatalf("FromExchanges failed: %v", err)
+	}
+	steps := wf.Workflow
+
+	if want := []Capture{{JSONPath: "accessToken", As: "access_token"}}; !reflect.DeepEqual(steps[0].Capture, want) {
+		t.Errorf("login captures = %+v, want %+v", steps[0].Capture, want)
+	}
+	want := []Capture{
+		{JSONPath: "id", As: "order_id"},
+		{JSONPath: "items[0].lineId", As: "line_id"},
+		{Header: "Etag", As: "etag"},
+	}
+	if !reflect.DeepEqual(steps[1].Capture, want) {
+		t.Errorf("create captures = %+v, want %+v", steps[1].Capture, want)
+	}
+	if steps[2].Capture != nil {
+		t.Errorf("expected no captures on fetch, got %+v", steps[2].Capture)
+	}
+
+	if got := steps[1].Request.Headers["Authorization"]; got != "Bearer ${access_token}" {
+		t.Errorf("expected token to be replaced, got %q", got)
+	}
+	if got := steps[2].Request.URL; got != "${base_url}/v1/orders/${order_id}" {
+		t.Errorf("unexpected fetch URL %q", got)
+	}
+	patch := steps[3].Request
+	if patch.URL != "${base_url}/v1/orders/${order_id}/lines/${line_id}" || patch.Headers["If-Match"] != "${etag}" {
+		t.Errorf("unexpected patch request %+v", patch)
+	}
+	if patch.Body["note"] != "order 42170 is unrelated" {
+		t.Errorf("expected partial matches to be left alone, got %v", patch.Body["note"])
+	}
+	// The user name was sent before the server echoed it, so it stays literal.
+	if steps[0].Request.Body["user"] != "ada@example.com" {
+		t.Errorf("unexpected login body %v", steps[0].Request.Body)
+	}
+}
+
+func TestResourceName(t *testing.T) {
+	tests := map[string]string{
+		"https://x/v1/orders":    "order",
+		"https://x/v1/orders/42": "order",
+		"https://x/categories/7": "category",
+		"https://x/addresses":    "address",
+		"https://x/v2/3f2a9c":    "",
+		"https://x/status":       "status",
+	}
+	for in, want := range tests {
+		if got := resourceName(in); got != want {
+			t.Errorf("resourceName(%s) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// Exchange is one recorded request and its response, from a HAR file or a
+// live recording.
+type Exchange struct {
+	Method         string
+	URL            string
+	Header         http.Header
+	Body           []byte
+	Status         int
+	ResponseHeader http.Header
+	// ResponseBody is the decoded (uncompressed) response body.
+	ResponseBody []byte
+}
+
+// FromExchanges returns a workflow with one step per exchange. The most
+// common origin becomes config.base_url, and values that a response returns
+// and a later request sends back, such as IDs and tokens, become captures.
+func FromExchanges(exchanges []Exchange, opts HAROptions) (*Workflow, error) {
+	var kept []Exchange
+	for _, ex := range exchanges {
+		if !opts.IncludeAssets && isAsset(ex) {
+			continue
+		}
+		kept = append(kept, ex)
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no requests to convert")
+	}
+
+	wf := &Workflow{
+		Metadata: Metadata{Name: opts.Name, Description: opts.Description},
+		Config:   Config{BaseURL: commonOrigin(kept)},
+	}
+	names := map[string]int{}
+	for _, ex := range kept {
+		step, err := exchangeStep(ex, wf.Config.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		names[step.Step]++
+		if n := names[step.Step]; n > 1 {
+			step.Step = fmt.Sprintf("%s-%d", step.Step, n)
+		}
+		wf.Workflow = append(wf.Workflow, step)
+	}
+	inferCaptures(wf.Workflow, kept)
+	return wf, nil
+}
+
+func exchangeStep(ex Exchange, baseURL string) (Step, error) {
+	u, err := url.Parse(ex.URL)
+	if err := e.Wrapf(err, "parse url %s", ex.URL); err != nil {
+		return Step{}, err
+	}
+	method := strings.ToUpper(ex.Method)
+
+	stepURL := ex.URL
+	if baseURL != "" && origin(u) == baseURL {
+		stepURL = "${base_url}" + u.RequestURI()
+	}
+
+	step := Step{
+		Step:    stepName(method, u.Path),
+		Request: Request{Method: method, URL: stepURL},
+		Expect:  Expect{Status: ex.Status},
+	}
+
+	names := make([]string, 0, len(ex.Header))
+	for name := range ex.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lower := strings.ToLower(name)
+		if skippedHeaders[lower] || strings.HasPrefix(lower, ":") || strings.HasPrefix(lower, "sec-") {
+			continue
+		}
+		if step.Request.Headers == nil {
+			step.Request.Headers = map[string]string{}
+		}
+		step.Request.Headers[name] = ex.Header.Get(name)
+	}
+
+	if len(ex.Body) > 0 {
+		setBody(&step.Request, ex.Header.Get("Content-Type"), string(ex.Body))
+	}
+	return step, nil
+}
+
+// setBody picks the most readable body form for the recorded payload.
+func setBody(req *Request, contentType, text string) {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		values, _ := url.ParseQuery(text)
+		if len(values) > 0 {
+			req.Form = map[string]string{}
+			for k := range values {
+				req.Form[k] = values.Get(k)
+			}
+			return
+		}
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var obj map[string]interface{}
+		if json.Unmarshal([]byte(text), &obj) == nil && len(obj) > 0 {
+			req.Body = obj
+			if mediaType != "application/json" {
+				req.ContentType = contentType
+			}
+			return
+		}
+	}
+	req.BodyRaw = text
+	req.ContentType = contentType
+}
+
+func isAsset(ex Exchange) bool {
+	u, err := url.Parse(ex.URL)
+	if err == nil && assetPattern.MatchString(u.Path) {
+		return true
+	}
+	mime := strings.ToLower(ex.ResponseHeader.Get("Content-Type"))
+	for _, prefix := range []string{"image/", "font/", "text/css", "text/javascript", "application/javascript"} {
+		if strings.HasPrefix(mime, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func commonOrigin(exchanges []Exchange) string {
+	counts := map[string]int{}
+	for _, ex := range exchanges {
+		if u, err := url.Parse(ex.URL); err == nil && u.Host != "" {
+			counts[origin(u)]++
+		}
+	}
+	origins := make([]string, 0, len(counts))
+	for o := range counts {
+		origins = append(origins, o)
+	}
+	sort.Slice(origins, func(i, j int) bool {
+		if counts[origins[i]] != counts[origins[j]] {
+			return counts[origins[i]] > counts[origins[j]]
+		}
+		return origins[i] < origins[j]
+	})
+	if len(origins) == 0 {
+		return ""
+	}
+	return origins[0]
+}
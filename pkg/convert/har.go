@@ -0,0 +1,213 @@
+// Package convert turns recorded HTTP sessions and API descriptions into
+// ramjam workflow skeletons.
+package convert
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow is the skeleton written by the converters. It mirrors the parts
+// of runner.InstructionsFile that a recording can fill in, with empty fields
+// omitted so the output is ready to edit.
+type Workflow struct {
+	Metadata Metadata `yaml:"metadata"`
+	Config   Config   `yaml:"config"`
+	Workflow []Step   `yaml:"workflow"`
+}
+
+type Metadata struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+}
+
+type Config struct {
+	BaseURL string `yaml:"base_url,omitempty"`
+}
+
+type Step struct {
+	Step    string    `yaml:"step"`
+	Request Request   `yaml:"request"`
+	Expect  Expect    `yaml:"expect"`
+	Capture []Capture `yaml:"capture,omitempty"`
+}
+
+type Request struct {
+	Method      string                 `yaml:"method"`
+	URL         string                 `yaml:"url"`
+	Params      map[string]string      `yaml:"params,omitempty"`
+	Headers     map[string]string      `yaml:"headers,omitempty"`
+	Body        map[string]interface{} `yaml:"body,omitempty"`
+	BodyRaw     string                 `yaml:"body_raw,omitempty"`
+	ContentType string                 `yaml:"content_type,omitempty"`
+	Form        map[string]string      `yaml:"form,omitempty"`
+}
+
+type Expect struct {
+	Status int `yaml:"status,omitempty"`
+}
+
+type Capture struct {
+	JSONPath string `yaml:"json_path,omitempty"`
+	Header   string `yaml:"header,omitempty"`
+	As       string `yaml:"as"`
+}
+
+// HAROptions controls which recorded entries become steps. It is shared by
+// FromExchanges.
+type HAROptions struct {
+	// Name is used for metadata.name.
+	Name string
+	// Description is used for metadata.description.
+	Description string
+	// IncludeAssets keeps requests for scripts, styles, images and fonts,
+	// which are dropped by default.
+	IncludeAssets bool
+}
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method   string         `json:"method"`
+		URL      string         `json:"url"`
+		Headers  []harNameValue `json:"headers"`
+		PostData *struct {
+			MimeType string         `json:"mimeType"`
+			Text     string         `json:"text"`
+			Params   []harNameValue `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int            `json:"status"`
+		Headers []harNameValue `json:"headers"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// skippedHeaders are set by ramjam or the transport and would only add noise
+// to a converted step.
+var skippedHeaders = map[string]bool{
+	"host":                      true,
+	"content-length":            true,
+	"content-type":              true,
+	"connection":                true,
+	"accept-encoding":           true,
+	"user-agent":                true,
+	"cookie":                    true,
+	"origin":                    true,
+	"referer":                   true,
+	"pragma":                    true,
+	"cache-control":             true,
+	"upgrade-insecure-requests": true,
+}
+
+var assetPattern = regexp.MustCompile(`(?i)\.(js|mjs|css|png|jpe?g|gif|svg|ico|webp|woff2?|ttf|eot|map)$`)
+
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+// FromHAR reads an HTTP Archive and returns a workflow with one step per
+// recorded request. The most common origin becomes config.base_url.
+func FromHAR(r io.Reader, opts HAROptions) (*Workflow, error) {
+	var har harFile
+	if err := e.Wrap(json.NewDecoder(r).Decode(&har), "parse har"); err != nil {
+		return nil, err
+	}
+	exchanges := make([]Exchange, 0, len(har.Log.Entries))
+	for _, entry := range har.Log.Entries {
+		exchanges = append(exchanges, entry.exchange())
+	}
+	if opts.Description == "" {
+		opts.Description = "Converted from a recorded HAR session"
+	}
+	return FromExchanges(exchanges, opts)
+}
+
+func (entry harEntry) exchange() Exchange {
+	ex := Exchange{
+		Method:         entry.Request.Method,
+		URL:            entry.Request.URL,
+		Header:         http.Header{},
+		Status:         entry.Response.Status,
+		ResponseHeader: http.Header{},
+	}
+	for _, h := range entry.Request.Headers {
+		ex.Header.Add(h.Name, h.Value)
+	}
+	if pd := entry.Request.PostData; pd != nil {
+		if pd.MimeType != "" {
+			ex.Header.Set("Content-Type", pd.MimeType)
+		}
+		ex.Body = []byte(pd.Text)
+		if pd.Text == "" && len(pd.Params) > 0 {
+			form := url.Values{}
+			for _, p := range pd.Params {
+				form.Add(p.Name, p.Value)
+			}
+			ex.Body = []byte(form.Encode())
+		}
+	}
+	for _, h := range entry.Response.Headers {
+		ex.ResponseHeader.Add(h.Name, h.Value)
+	}
+	if mime := entry.Response.Content.MimeType; mime != "" {
+		ex.ResponseHeader.Set("Content-Type", mime)
+	}
+	ex.ResponseBody = []byte(entry.Response.Content.Text)
+	if entry.Response.Content.Encoding == "base64" {
+		ex.ResponseBody, _ = base64.StdEncoding.DecodeString(entry.Response.Content.Text)
+	}
+	return ex
+}
+
+// Marshal encodes a workflow as YAML with two-space indentation.
+func Marshal(wf *Workflow) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := e.Wrap(enc.Encode(wf), "encode workflow"); err != nil {
+		return nil, err
+	}
+	if err := e.Wrap(enc.Close(), "encode workflow"); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func origin(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// stepName builds a readable step name such as "post-login" from the
+// method and the last path segment.
+func stepName(method, p string) string {
+	last := path.Base(strings.TrimSuffix(p, "/"))
+	if last == "." || last == "/" || last == "" {
+		last = "root"
+	}
+	name := nonWord.ReplaceAllString(strings.ToLower(method+"-"+last), "-")
+	return strings.Trim(name, "-")
+}
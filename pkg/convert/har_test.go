@@ -0,0 +1,158 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const sampleHAR = `{
+  "log": {
+    "version": "1.2",
+    "entries": [
+      {
+        "request": {
+          "method": "POST",
+          "url": "https://api.example.com/v1/login",
+          "headers": [
+            {"name": ":authority", "value": "api.example.com"},
+            {"name": "Content-Type", "value": "application/json"},
+            {"name": "User-Agent", "value": "Mozilla/5.0"},
+            {"name": "X-Client", "value": "web"}
+          ],
+          "postData": {"mimeType": "application/json", "text": "{\"user\": \"ada\", \"remember\": true}"}
+        },
+        "response": {"status": 200, "content": {"mimeType": "application/json"}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://cdn.example.com/app.js", "headers": []},
+        "response": {"status": 200, "content": {"mimeType": "application/javascript"}}
+      },
+      {
+        "request": {
+          "method": "POST",
+          "url": "https://api.example.com/v1/search?q=widgets",
+          "headers": [{"name": "Content-Type", "value": "application/x-www-form-urlencoded"}],
+          "postData": {"mimeType": "application/x-www-form-urlencoded", "text": "page=2&sort=name"}
+        },
+        "response": {"status": 200, "content": {"mimeType": "application/json"}}
+      },
+      {
+        "request": {
+          "method": "PUT",
+          "url": "https://uploads.example.com/v1/search",
+          "headers": [],
+          "postData": {"mimeType": "text/csv", "text": "a,b\n1,2\n"}
+        },
+        "response": {"status": 204, "content": {"mimeType": ""}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://api.example.com/v1/search", "headers": []},
+        "response": {"status": 200, "content": {"mimeType": "application/json"}}
+      }
+    ]
+  }
+}`
+
+func TestFromHAR(t *testing.T) {
+	wf, err := FromHAR(strings.NewReader(sampleHAR), HAROptions{Name: "session"})
+	if err != nil {
+		t.Fatalf("FromHAR failed: %v", err)
+	}
+
+	if wf.Metadata.Name != "session" || wf.Config.BaseURL != "https://api.example.com" {
+		t.Errorf("unexpected header: %+v %+v", wf.Metadata, wf.Config)
+	}
+	if len(wf.Workflow) != 4 {
+		t.Fatalf("expected asset to be dropped leaving 4 steps, got %d", len(wf.Workflow))
+	}
+
+	login := wf.Workflow[0]
+	if login.Step != "post-login" || login.Request.URL != "${base_url}/v1/login" || login.Expect.Status != 200 {
+		t.Errorf("unexpected login step: %+v", login)
+	}
+	if len(login.Request.Headers) != 1 || login.Request.Headers["X-Client"] != "web" {
+		t.Errorf("expected only X-Client header, got %v", login.Request.Headers)
+	}
+	if login.Request.Body["user"] != "ada" || login.Request.Body["remember"] != true {
+		t.Errorf("unexpected JSON body: %v", login.Request.Body)
+	}
+
+	search := wf.Workflow[1]
+	if search.Request.URL != "${base_url}/v1/search?q=widgets" || search.Request.Form["page"] != "2" || search.Request.Form["sort"] != "name" {
+		t.Errorf("unexpected form step: %+v", search.Request)
+	}
+
+	upload := wf.Workflow[2]
+	if upload.Step != "put-search" || upload.Request.URL != "https://uploads.example.com/v1/search" {
+		t.Errorf("expected other origin to keep its full URL: %+v", upload)
+	}
+	if upload.Request.BodyRaw != "a,b\n1,2\n" || upload.Request.ContentType != "text/csv" {
+		t.Errorf("unexpected raw body: %+v", upload.Request)
+	}
+
+	if name := wf.Workflow[3].Step; name != "get-search" {
+		t.Errorf("unexpected step name %q", name)
+	}
+}
+
+func TestFromHARDuplicateNamesAndAssets(t *testing.T) {
+	wf, err := FromHAR(strings.NewReader(sampleHAR), HAROptions{IncludeAssets: true})
+	if err != nil {
+		t.Fatalf("FromHAR failed: %v", err)
+	}
+	if len(wf.Workflow) != 5 {
+		t.Fatalf("expected asset to be kept, got %d steps", len(wf.Workflow))
+	}
+
+	har := `{"log": {"entries": [
+		{"request": {"method": "GET", "url": "http://x.test/items"}, "response": {"status": 200}},
+		{"request": {"method": "GET", "url": "http://x.test/items"}, "response": {"status": 200}}
+	]}}`
+	wf, err = FromHAR(strings.NewReader(har), HAROptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wf.Workflow[0].Step != "get-items" || wf.Workflow[1].Step != "get-items-2" {
+		t.Errorf("expected unique step names, got %s and %s", wf.Workflow[0].Step, wf.Workflow[1].Step)
+	}
+}
+
+func TestFromHAREmpty(t *testing.T) {
+	if _, err := FromHAR(strings.NewReader(`{"log": {"entries": []}}`), HAROptions{}); err == nil {
+		t.Fatal("expected error for empty HAR")
+	}
+	if _, err := FromHAR(strings.NewReader(`not json`), HAROptions{}); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	wf, err := FromHAR(strings.NewReader(sampleHAR), HAROptions{Name: "session"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Marshal(wf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		"metadata:\n  name: session\n",
+		"  base_url: https://api.example.com\n",
+		"  - step: post-login\n    request:\n      method: POST\n      url: ${base_url}/v1/login\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "body_raw: \"\"") || strings.Contains(out, "headers: {}") {
+		t.Errorf("expected empty fields to be omitted:\n%s", out)
+	}
+
+	var back Workflow
+	if err := yaml.Unmarshal(data, &back); err != nil || len(back.Workflow) != 4 {
+		t.Errorf("output does not round-trip: %v", err)
+	}
+}
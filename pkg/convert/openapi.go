@@ -0,0 +1,204 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/michaelmccabe/ramjam/pkg/openapi"
+)
+
+// GeneratedWorkflow is one scaffolded workflow and the file name it should
+// be written to.
+type GeneratedWorkflow struct {
+	File     string
+	Workflow *Workflow
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// FromOpenAPI scaffolds one workflow per tag, with a step for each
+// operation. Operations without tags are grouped under "default". Example
+// bodies and parameter values come from the spec, and each step expects the
+// lowest documented 2xx status.
+func FromOpenAPI(spec *openapi.Spec) ([]GeneratedWorkflow, error) {
+	ops := spec.Operations()
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("openapi spec defines no operations")
+	}
+
+	byTag := map[string]*Workflow{}
+	names := map[string]map[string]int{}
+	var tags []string
+	for _, op := range ops {
+		tag := "default"
+		if len(op.Operation.Tags) > 0 {
+			tag = op.Operation.Tags[0]
+		}
+		wf, ok := byTag[tag]
+		if !ok {
+			title := spec.Info.Title
+			if title == "" {
+				title = "API"
+			}
+			wf = &Workflow{
+				Metadata: Metadata{
+					Name:        title + " - " + tag,
+					Description: "Generated from the OpenAPI spec; review bodies and expectations before relying on it",
+				},
+				Config: Config{BaseURL: spec.BaseURL()},
+			}
+			byTag[tag] = wf
+			names[tag] = map[string]int{}
+			tags = append(tags, tag)
+		}
+
+		step := operationStep(spec, op)
+		names[tag][step.Step]++
+		if n := names[tag][step.Step]; n > 1 {
+			step.Step = fmt.Sprintf("%s-%d", step.Step, n)
+		}
+		wf.Workflow = append(wf.Workflow, step)
+	}
+
+	sort.Strings(tags)
+	generated := make([]GeneratedWorkflow, 0, len(tags))
+	for _, tag := range tags {
+		file := strings.Trim(nonWord.ReplaceAllString(strings.ToLower(tag), "-"), "-")
+		if file == "" {
+			file = "default"
+		}
+		generated = append(generated, GeneratedWorkflow{File: file + ".yaml", Workflow: byTag[tag]})
+	}
+	return generated, nil
+}
+
+func operationStep(spec *openapi.Spec, op openapi.OperationRef) Step {
+	name := op.Operation.OperationID
+	if name == "" {
+		name = op.Method + "-" + strings.ReplaceAll(op.Path, "/", "-")
+	}
+	name = strings.Trim(nonWord.ReplaceAllString(strings.ToLower(kebab(name)), "-"), "-")
+
+	step := Step{
+		Step:    name,
+		Request: Request{Method: op.Method},
+		Expect:  Expect{Status: successStatus(op.Operation.Responses)},
+	}
+
+	values := map[string]string{}
+	for _, p := range op.Parameters {
+		value, ok := parameterExample(spec, p)
+		switch p.In {
+		case "path":
+			if ok {
+				values[p.Name] = url.PathEscape(value)
+			}
+		case "query":
+			if !p.Required {
+				continue
+			}
+			if step.Request.Params == nil {
+				step.Request.Params = map[string]string{}
+			}
+			step.Request.Params[p.Name] = placeholder(p.Name, value, ok)
+		case "header":
+			if !p.Required {
+				continue
+			}
+			if step.Request.Headers == nil {
+				step.Request.Headers = map[string]string{}
+			}
+			step.Request.Headers[p.Name] = placeholder(p.Name, value, ok)
+		}
+	}
+	step.Request.URL = "${base_url}" + pathParam.ReplaceAllStringFunc(op.Path, func(m string) string {
+		param := m[1 : len(m)-1]
+		if v, ok := values[param]; ok {
+			return v
+		}
+		return "${" + param + "}"
+	})
+
+	if body := spec.ResolveRequestBody(op.Operation.RequestBody); body != nil {
+		if mt, ok := openapi.JSONMediaType(body.Content); ok {
+			setExampleBody(&step.Request, spec.Example(mt))
+		}
+	}
+	return step
+}
+
+// placeholder falls back to a variable reference when the spec gives no
+// example, so the generated step makes the missing value obvious.
+func placeholder(name, value string, ok bool) string {
+	if ok {
+		return value
+	}
+	return "${" + name + "}"
+}
+
+func parameterExample(spec *openapi.Spec, p *openapi.Parameter) (string, bool) {
+	v := p.Example
+	if v == nil {
+		v = spec.SchemaExample(p.Schema)
+	}
+	if v == nil {
+		return "", false
+	}
+	switch val := v.(type) {
+	case string:
+		if val == "string" {
+			// A generic schema example is less useful than a variable.
+			return "", false
+		}
+		return val, true
+	case int:
+		return strconv.Itoa(val), true
+	default:
+		return fmt.Sprint(val), true
+	}
+}
+
+func setExampleBody(req *Request, example interface{}) {
+	switch v := example.(type) {
+	case nil:
+	case map[string]interface{}:
+		if len(v) > 0 {
+			req.Body = v
+		}
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return
+		}
+		req.BodyRaw = string(data)
+		req.ContentType = "application/json"
+	}
+}
+
+// successStatus returns the lowest documented 2xx status, or 0 when the spec
+// only documents ranges or a default response.
+func successStatus(responses map[string]*openapi.Response) int {
+	best := 0
+	for code := range responses {
+		n, err := strconv.Atoi(code)
+		if err != nil || n < 200 || n > 299 {
+			continue
+		}
+		if best == 0 || n < best {
+			best = n
+		}
+	}
+	return best
+}
+
+var camelBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// kebab splits camelCase operation IDs such as "getUserById" into words.
+func kebab(s string) string {
+	return camelBoundary.ReplaceAllString(s, "$1-$2")
+}
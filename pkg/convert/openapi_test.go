@@ -0,0 +1,69 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/michaelmccabe/ramjam/pkg/openapi"
+)
+
+func TestFromOpenAPI(t *testing.T) {
+	spec, err := openapi.Load("../openapi/testdata/petstore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := FromOpenAPI(spec)
+	if err != nil {
+		t.Fatalf("FromOpenAPI failed: %v", err)
+	}
+	if len(generated) != 2 || generated[0].File != "default.yaml" || generated[1].File != "pets.yaml" {
+		t.Fatalf("unexpected files: %+v", generated)
+	}
+
+	pets := generated[1].Workflow
+	if pets.Metadata.Name != "Petstore - pets" || pets.Config.BaseURL != "https://petstore.example.com/v1" {
+		t.Errorf("unexpected workflow header: %+v %+v", pets.Metadata, pets.Config)
+	}
+	steps := map[string]Step{}
+	var order []string
+	for _, s := range pets.Workflow {
+		steps[s.Step] = s
+		order = append(order, s.Step)
+	}
+	if strings.Join(order, ",") != "list-pets,create-pet,get-pet-by-id,delete-pets-pet-id" {
+		t.Errorf("unexpected step order %v", order)
+	}
+
+	list := steps["list-pets"]
+	if list.Request.URL != "${base_url}/pets" || list.Request.Params["limit"] != "10" || len(list.Request.Params) != 1 {
+		t.Errorf("unexpected list step: %+v", list.Request)
+	}
+	if list.Expect.Status != 200 {
+		t.Errorf("expected status 200, got %d", list.Expect.Status)
+	}
+
+	create := steps["create-pet"]
+	if create.Request.Method != "POST" || create.Request.Body["name"] != "Rex" || create.Expect.Status != 201 {
+		t.Errorf("unexpected create step: %+v", create)
+	}
+
+	get := steps["get-pet-by-id"]
+	if get.Request.URL != "${base_url}/pets/42" {
+		t.Errorf("expected path parameter example, got %s", get.Request.URL)
+	}
+
+	del := steps["delete-pets-pet-id"]
+	if del.Request.Headers["X-Confirm"] != "${X-Confirm}" || del.Expect.Status != 204 {
+		t.Errorf("expected header placeholder, got %+v", del)
+	}
+}
+
+func TestFromOpenAPIEmpty(t *testing.T) {
+	spec, err := openapi.Parse([]byte(`openapi: "3.0.0"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FromOpenAPI(spec); err == nil {
+		t.Fatal("expected error for spec without operations")
+	}
+}
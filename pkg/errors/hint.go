@@ -0,0 +1,99 @@
+package errors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	stderrors "errors"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// hintRules map known failure patterns to advice, checked in order.
+var hintRules = []struct {
+	match func(error) bool
+	hint  string
+}{
+	{
+		func(err error) bool { return stderrors.Is(err, syscall.ECONNREFUSED) },
+		"Nothing is listening at that address. Check base_url and that the server is running.",
+	},
+	{
+		func(err error) bool {
+			var dnsErr *net.DNSError
+			return stderrors.As(err, &dnsErr)
+		},
+		"The host name couldn't be resolved. Check base_url for typos, or your DNS and VPN settings.",
+	},
+	{
+		func(err error) bool {
+			var headerErr tls.RecordHeaderError
+			return stderrors.As(err, &headerErr)
+		},
+		"The server didn't answer with TLS. Use http:// instead of https:// in base_url.",
+	},
+	{
+		func(err error) bool {
+			var authorityErr x509.UnknownAuthorityError
+			var invalidErr x509.CertificateInvalidError
+			var hostErr x509.HostnameError
+			var verifyErr *tls.CertificateVerificationError
+			return stderrors.As(err, &authorityErr) || stderrors.As(err, &invalidErr) ||
+				stderrors.As(err, &hostErr) || stderrors.As(err, &verifyErr)
+		},
+		"The server's certificate couldn't be verified. Set tls.ca_file or --cacert to trust its CA, or use --insecure for development servers.",
+	},
+	{
+		// json.Unmarshal reports HTML as an invalid '<' at the start.
+		func(err error) bool {
+			return strings.Contains(err.Error(), "invalid character '<' looking for beginning of value")
+		},
+		"The response is HTML, not JSON. Did the request hit a login page, an error page or the wrong URL?",
+	},
+	{
+		func(err error) bool { return stderrors.Is(err, TimeoutError) },
+		"The server didn't respond in time. Check that it is healthy and that base_url points at the right host.",
+	},
+}
+
+// WithHint attaches advice on fixing err, returned by Hint in place of any
+// built-in hint. It returns nil if err is nil.
+func WithHint(err error, hint string) error {
+	if err == nil {
+		return nil
+	}
+	return &hintError{err: err, hint: hint}
+}
+
+// Hint returns advice for fixing err: the hint attached with WithHint, or
+// one for a common failure such as a refused connection, an untrusted
+// certificate or an HTML page where JSON was expected. It returns "" when
+// nothing is known about err.
+func Hint(err error) string {
+	if err == nil {
+		return ""
+	}
+	var he *hintError
+	if stderrors.As(err, &he) {
+		return he.hint
+	}
+	for _, rule := range hintRules {
+		if rule.match(err) {
+			return rule.hint
+		}
+	}
+	return ""
+}
+
+type hintError struct {
+	err  error
+	hint string
+}
+
+func (e *hintError) Error() string {
+	return e.err.Error()
+}
+
+func (e *hintError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,37 @@
+package errors
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestHint(t *testing.T) {
+	var htmlErr error = json.Unmarshal([]byte("<html>"), new(interface{}))
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"unknown", fmt.Errorf("expected status 200, got 500"), ""},
+		{"refused", &url.Error{Op: "Get", URL: "http://localhost:1", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, "Check base_url"},
+		{"dns", Wrap(&net.DNSError{Err: "no such host", Name: "api.exampel.com"}, "request"), "couldn't be resolved"},
+		{"certificate", Wrap(x509.UnknownAuthorityError{}, "request"), "--insecure"},
+		{"html", Wrap(htmlErr, "parse response json"), "login page"},
+		{"timeout", Mark(fmt.Errorf("context deadline exceeded"), TimeoutError), "in time"},
+		{"attached", WithHint(fmt.Errorf("boom"), "Try again."), "Try again."},
+	}
+	for _, tt := range tests {
+		got := Hint(tt.err)
+		if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("%s: Hint() = %q, want it to contain %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package errors
+
+import stderrors "errors"
+
+// Kind is a category of failure with a stable code, so callers and reports
+// can group errors without matching on their messages. Use errors.Is to
+// test an error's kind.
+type Kind struct {
+	code string
+	name string
+}
+
+var (
+	// ParseError is a workflow or config file that can't be decoded.
+	ParseError = &Kind{code: "parse", name: "parse error"}
+	// ConnectionError is a request that couldn't be sent or whose response
+	// couldn't be read, such as a refused connection or a TLS failure.
+	ConnectionError = &Kind{code: "connection", name: "connection error"}
+	// AssertionError is a response that didn't meet an expectation.
+	AssertionError = &Kind{code: "assertion", name: "assertion error"}
+	// TimeoutError is a request that ran out of time.
+	TimeoutError = &Kind{code: "timeout", name: "timeout error"}
+)
+
+// Code returns the kind's stable identifier, such as "assertion".
+func (k *Kind) Code() string {
+	return k.code
+}
+
+func (k *Kind) Error() string {
+	return k.name
+}
+
+// Mark returns err marked with kind, or nil if err is nil. The message is
+// unchanged, and errors.Is(err, kind) reports true.
+func Mark(err error, kind *Kind) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{err: err, kind: kind}
+}
+
+// KindOf returns the kind err was most recently marked with, or nil.
+func KindOf(err error) *Kind {
+	var ke *kindError
+	if stderrors.As(err, &ke) {
+		return ke.kind
+	}
+	var kind *Kind
+	if stderrors.As(err, &kind) {
+		return kind
+	}
+	return nil
+}
+
+type kindError struct {
+	err  error
+	kind *Kind
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.err, e.kind}
+}
@@ -0,0 +1,48 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestMark(t *testing.T) {
+	if Mark(nil, ParseError) != nil {
+		t.Error("Mark(nil) != nil")
+	}
+
+	cause := &fs.PathError{Op: "open", Path: "a.yaml", Err: fs.ErrNotExist}
+	err := Wrap(Mark(cause, ConnectionError), "step")
+	if err.Error() != "step: open a.yaml: file does not exist" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if !stderrors.Is(err, ConnectionError) || stderrors.Is(err, AssertionError) {
+		t.Error("errors.Is doesn't match the marked kind only")
+	}
+	var pathErr *fs.PathError
+	if !stderrors.As(err, &pathErr) {
+		t.Error("errors.As can't reach the cause")
+	}
+	if KindOf(err) != ConnectionError || KindOf(err).Code() != "connection" {
+		t.Errorf("KindOf() = %v", KindOf(err))
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want *Kind
+	}{
+		{fmt.Errorf("plain"), nil},
+		{TimeoutError, TimeoutError},
+		{fmt.Errorf("poll: %w", TimeoutError), TimeoutError},
+		// The outermost mark wins.
+		{Mark(Mark(fmt.Errorf("x"), ConnectionError), TimeoutError), TimeoutError},
+	}
+	for _, tt := range tests {
+		if got := KindOf(tt.err); got != tt.want {
+			t.Errorf("KindOf(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
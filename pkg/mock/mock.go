@@ -0,0 +1,248 @@
+// Package mock serves the responses that workflow files expect, so clients
+// can be developed against the same YAML the API is tested with.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"github.com/michaelmccabe/ramjam/pkg/runner"
+	"gopkg.in/yaml.v3"
+)
+
+// Route describes one mocked endpoint. Path is shown with variables as
+// {name}, for example /users/{user_id}.
+type Route struct {
+	Method string
+	Path   string
+	Steps  []string
+}
+
+// Server is an http.Handler that answers each request with the response
+// expected by the matching workflow step.
+type Server struct {
+	routes []*route
+
+	mu sync.Mutex
+
+	// CORS adds permissive CORS headers and answers preflight requests.
+	CORS bool
+	// Logf, if set, is called once for every request served.
+	Logf func(format string, args ...interface{})
+}
+
+// route is every step that shares a method and path. Successive requests
+// are answered by successive steps, and the last one repeats, so a GET that
+// a workflow makes before and after a DELETE returns 200 and then 404.
+type route struct {
+	method    string
+	path      string
+	pattern   *regexp.Regexp
+	variables int
+	responses []response
+	next      int
+}
+
+type response struct {
+	step   string
+	status int
+	header http.Header
+	body   []byte
+}
+
+var varPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// Load reads workflow files and builds a server from their HTTP steps.
+// WebSocket steps are skipped.
+func Load(files []string) (*Server, error) {
+	s := &Server{}
+	for _, f := range files {
+		if err := s.loadFile(f); err != nil {
+			return nil, err
+		}
+	}
+	if len(s.routes) == 0 {
+		return nil, fmt.Errorf("no HTTP steps to mock")
+	}
+	return s, nil
+}
+
+func (s *Server) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err := e.Wrapf(err, "read %s", path); err != nil {
+		return err
+	}
+	var spec runner.InstructionsFile
+	if err := e.Wrapf(yaml.Unmarshal(data, &spec), "parse %s", path); err != nil {
+		return err
+	}
+
+	// vars holds the mocked value of every capture so later expectations
+	// that refer to it stay consistent.
+	vars := map[string]string{}
+	for _, step := range spec.Workflow {
+		if step.WebSocket != nil {
+			continue
+		}
+		method := strings.ToUpper(strings.TrimSpace(step.Request.Method))
+		if method == "" {
+			method = http.MethodGet
+			if step.Request.GraphQL != nil {
+				method = http.MethodPost
+			}
+		}
+		p, err := routePath(step.Request.URL, spec.Config.BaseURL)
+		if err := e.Wrapf(err, "%s: step %q", path, step.Step); err != nil {
+			return err
+		}
+		resp, err := buildResponse(step, vars)
+		if err := e.Wrapf(err, "%s: step %q", path, step.Step); err != nil {
+			return err
+		}
+		s.add(method, p, resp)
+	}
+	return nil
+}
+
+func (s *Server) add(method, path string, resp response) {
+	for _, rt := range s.routes {
+		if rt.method == method && rt.path == path {
+			rt.responses = append(rt.responses, resp)
+			return
+		}
+	}
+	s.routes = append(s.routes, &route{
+		method:    method,
+		path:      path,
+		pattern:   pathPattern(path),
+		variables: strings.Count(path, "{"),
+		responses: []response{resp},
+	})
+}
+
+// routePath returns the path a step's URL is served on, with base_url
+// applied and variables written as {name}.
+func routePath(rawURL, baseURL string) (string, error) {
+	u := strings.ReplaceAll(rawURL, "${base_url}", baseURL)
+	if !strings.HasPrefix(u, "http") && baseURL != "" && !strings.HasPrefix(u, "${") {
+		u = strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(u, "/")
+	}
+	// A leading variable other than base_url stands for the origin.
+	if loc := varPattern.FindStringIndex(u); loc != nil && loc[0] == 0 {
+		u = u[loc[1]:]
+	}
+	u, _, _ = strings.Cut(u, "?")
+	u = varPattern.ReplaceAllString(u, "{$1}")
+
+	if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		parsed, err := url.Parse(u)
+		if err := e.Wrapf(err, "parse url %s", rawURL); err != nil {
+			return "", err
+		}
+		u = parsed.Path
+	}
+	if !strings.HasPrefix(u, "/") {
+		u = "/" + u
+	}
+	return u, nil
+}
+
+// pathPattern turns /users/{id} into a regexp where each variable matches
+// a single path segment.
+func pathPattern(path string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for {
+		start := strings.Index(path, "{")
+		end := strings.Index(path, "}")
+		if start < 0 || end < start {
+			break
+		}
+		b.WriteString(regexp.QuoteMeta(path[:start]))
+		b.WriteString("[^/]+")
+		path = path[end+1:]
+	}
+	b.WriteString(regexp.QuoteMeta(path))
+	b.WriteString("/?$")
+	return regexp.MustCompile(b.String())
+}
+
+// Routes lists the mocked endpoints in the order they were defined.
+func (s *Server) Routes() []Route {
+	routes := make([]Route, 0, len(s.routes))
+	for _, rt := range s.routes {
+		r := Route{Method: rt.method, Path: rt.path}
+		for _, resp := range rt.responses {
+			r.Steps = append(r.Steps, resp.step)
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.CORS {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Headers", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "*")
+		w.Header().Set("Access-Control-Expose-Headers", "*")
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	resp, ok := s.match(r.Method, r.URL.Path)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		msg, _ := json.Marshal(map[string]string{"error": fmt.Sprintf("no mock for %s %s", r.Method, r.URL.Path)})
+		w.Write(msg)
+		s.logf("%s %s -> 404 (no matching step)", r.Method, r.URL.RequestURI())
+		return
+	}
+
+	for name, values := range resp.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+	s.logf("%s %s -> %d (step %s)", r.Method, r.URL.RequestURI(), resp.status, resp.step)
+}
+
+// match picks the route with the fewest variables that matches, so a
+// literal /users/me wins over /users/{id}, and advances its sequence.
+func (s *Server) match(method, path string) (response, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var best *route
+	for _, rt := range s.routes {
+		if rt.method != method || !rt.pattern.MatchString(path) {
+			continue
+		}
+		if best == nil || rt.variables < best.variables {
+			best = rt
+		}
+	}
+	if best == nil {
+		return response{}, false
+	}
+	resp := best.responses[best.next]
+	if best.next < len(best.responses)-1 {
+		best.next++
+	}
+	return resp, true
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
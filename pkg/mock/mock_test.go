@@ -0,0 +1,229 @@
+package mock
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const usersWorkflow = `
+metadata:
+  name: users
+config:
+  base_url: "http://localhost:8080/api"
+workflow:
+  - step: create-user
+    request:
+      method: POST
+      url: "${base_url}/users"
+    expect:
+      status: 201
+      headers:
+        - name: Content-Type
+          contains: application/json
+      json_path_match:
+        - path: "$.name"
+          value: Ada
+        - path: "$.roles[0]"
+          value: admin
+    capture:
+      - json_path: "$.id"
+        as: user_id
+      - header: Location
+        as: user_location
+  - step: get-user
+    request:
+      url: "/users/${user_id}"
+    expect:
+      status: 200
+      json_path_match:
+        - path: "$.id"
+          value: "${user_id}"
+  - step: get-me
+    request:
+      url: "${base_url}/users/me"
+    expect:
+      body_contains: "it's you"
+  - step: delete-user
+    request:
+      method: DELETE
+      url: "${base_url}/users/${user_id}"
+    expect:
+      status: 204
+  - step: get-deleted-user
+    request:
+      url: "${base_url}/users/${user_id}"
+    expect:
+      status: 404
+  - step: socket
+    websocket:
+      url: "ws://localhost:8080/ws"
+`
+
+func loadServer(t *testing.T, workflow string) *Server {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "users.yaml")
+	if err := os.WriteFile(path, []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load([]string{path})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return s
+}
+
+func do(t *testing.T, s *Server, method, path string) (*http.Response, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	resp := rec.Result()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestServerServesExpectations(t *testing.T) {
+	s := loadServer(t, usersWorkflow)
+
+	resp, body := do(t, s, "POST", "/api/users")
+	if resp.StatusCode != 201 {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var created map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &created); err != nil {
+		t.Fatalf("body is not JSON: %s", body)
+	}
+	want := map[string]interface{}{"name": "Ada", "roles": []interface{}{"admin"}, "id": "mock-user_id"}
+	if !reflect.DeepEqual(created, want) {
+		t.Errorf("unexpected body %s", body)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("unexpected content type %q", got)
+	}
+	if got := resp.Header.Get("Location"); got != "mock-user_location" {
+		t.Errorf("unexpected location %q", got)
+	}
+
+	resp, body = do(t, s, "GET", "/api/users/mock-user_id")
+	if resp.StatusCode != 200 || body != `{"id":"mock-user_id"}` {
+		t.Errorf("get-user: %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = do(t, s, "GET", "/api/users/me")
+	if resp.StatusCode != 200 || body != "it's you" {
+		t.Errorf("literal path should win over a variable: %d %s", resp.StatusCode, body)
+	}
+
+	if resp, _ := do(t, s, "DELETE", "/api/users/42"); resp.StatusCode != 204 {
+		t.Errorf("delete: %d", resp.StatusCode)
+	}
+	// The second GET in the workflow expects 404, and it then repeats.
+	for i := 0; i < 2; i++ {
+		if resp, _ := do(t, s, "GET", "/api/users/42"); resp.StatusCode != 404 {
+			t.Errorf("get after delete: %d", resp.StatusCode)
+		}
+	}
+}
+
+func TestServerUnmatchedRequest(t *testing.T) {
+	s := loadServer(t, usersWorkflow)
+	var logged []string
+	s.Logf = func(format string, args ...interface{}) { logged = append(logged, format) }
+
+	resp, body := do(t, s, "PUT", "/api/users")
+	if resp.StatusCode != 404 || !strings.Contains(body, "no mock for PUT /api/users") {
+		t.Errorf("unexpected response %d %s", resp.StatusCode, body)
+	}
+	if len(logged) != 1 {
+		t.Errorf("expected one log line, got %d", len(logged))
+	}
+}
+
+func TestServerCORS(t *testing.T) {
+	s := loadServer(t, usersWorkflow)
+	s.CORS = true
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("OPTIONS", "/api/users", nil)
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	s.ServeHTTP(rec, req)
+	if rec.Code != 204 || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("unexpected preflight response %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	s := loadServer(t, usersWorkflow)
+	want := []Route{
+		{Method: "POST", Path: "/api/users", Steps: []string{"create-user"}},
+		{Method: "GET", Path: "/api/users/{user_id}", Steps: []string{"get-user", "get-deleted-user"}},
+		{Method: "GET", Path: "/api/users/me", Steps: []string{"get-me"}},
+		{Method: "DELETE", Path: "/api/users/{user_id}", Steps: []string{"delete-user"}},
+	}
+	if got := s.Routes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected routes:\n%+v", got)
+	}
+}
+
+func TestRoutePath(t *testing.T) {
+	cases := []struct{ url, base, want string }{
+		{"${base_url}/users", "http://x.test", "/users"},
+		{"/users/${id}?expand=true", "http://x.test/v1/", "/v1/users/{id}"},
+		{"users", "", "/users"},
+		{"${api}/orders/${order_id}/lines", "", "/orders/{order_id}/lines"},
+		{"https://other.test/health", "http://x.test", "/health"},
+	}
+	for _, c := range cases {
+		got, err := routePath(c.url, c.base)
+		if err != nil || got != c.want {
+			t.Errorf("routePath(%q, %q) = %q, %v; want %q", c.url, c.base, got, err, c.want)
+		}
+	}
+}
+
+func TestSetJSONPath(t *testing.T) {
+	var doc interface{}
+	var err error
+	for _, m := range []struct {
+		path  string
+		value interface{}
+	}{
+		{"$.data.items[1].sku", "B2"},
+		{"data.total", 2},
+		{"$.errors[*].code", "E1"},
+	} {
+		if doc, err = setJSONPath(doc, m.path, m.value); err != nil {
+			t.Fatalf("set %s: %v", m.path, err)
+		}
+	}
+	got, _ := json.Marshal(doc)
+	want := `{"data":{"items":[null,{"sku":"B2"}],"total":2},"errors":[{"code":"E1"}]}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	arr, err := setJSONPath(nil, "$[?(@.name=='Ada')].id", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := json.Marshal(arr); string(got) != `[{"id":7,"name":"Ada"}]` {
+		t.Errorf("filter: got %s", got)
+	}
+	if v, err := getJSONPath(arr, "$[?(@.name=='Ada')].id"); err != nil || v != 7 {
+		t.Errorf("get filter: %v, %v", v, err)
+	}
+}
+
+func TestLoadRejectsEmptyWorkflows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ws.yaml")
+	os.WriteFile(path, []byte("workflow:\n  - step: ws\n    websocket:\n      url: ws://x\n"), 0644)
+	if _, err := Load([]string{path}); err == nil || !strings.Contains(err.Error(), "no HTTP steps") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
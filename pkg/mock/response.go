@@ -0,0 +1,275 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"github.com/michaelmccabe/ramjam/pkg/runner"
+)
+
+// buildResponse derives a response that satisfies a step's expectations:
+// the expected status, headers, a JSON body built from json_path_match and
+// json_path captures, or the body_contains text. Captured values are
+// recorded in vars.
+func buildResponse(step runner.Step, vars map[string]string) (response, error) {
+	resp := response{
+		step:   step.Step,
+		status: step.Expect.Status,
+		header: http.Header{},
+	}
+	if resp.status == 0 {
+		resp.status = http.StatusOK
+	}
+	for _, h := range step.Expect.Headers {
+		value := h.Value
+		if value == "" {
+			value = h.Contains
+		}
+		resp.header.Set(h.Name, applyVars(value, vars))
+	}
+	if loc := step.Expect.RedirectLocation; loc != "" && resp.header.Get("Location") == "" {
+		resp.header.Set("Location", applyVars(loc, vars))
+	}
+
+	var doc interface{}
+	hasJSON := false
+	for _, m := range step.Expect.JSONPathMatch {
+		value := m.Value
+		if s, ok := value.(string); ok {
+			value = applyVars(s, vars)
+		}
+		var err error
+		doc, err = setJSONPath(doc, m.Path, value)
+		if err := e.Wrapf(err, "json_path_match %s", m.Path); err != nil {
+			return resp, err
+		}
+		hasJSON = true
+	}
+	for _, c := range step.Capture {
+		switch {
+		case c.JSONPath != "":
+			if existing, err := getJSONPath(doc, c.JSONPath); err == nil {
+				vars[c.As] = fmt.Sprint(existing)
+				continue
+			}
+			value := "mock-" + c.As
+			var err error
+			doc, err = setJSONPath(doc, c.JSONPath, value)
+			if err := e.Wrapf(err, "capture json_path %s", c.JSONPath); err != nil {
+				return resp, err
+			}
+			vars[c.As] = value
+			hasJSON = true
+		case c.Header != "":
+			if existing := resp.header.Get(c.Header); existing != "" {
+				vars[c.As] = existing
+				continue
+			}
+			value := "mock-" + c.As
+			resp.header.Set(c.Header, value)
+			vars[c.As] = value
+		}
+	}
+
+	switch {
+	case hasJSON:
+		body, err := json.Marshal(doc)
+		if err := e.Wrap(err, "encode body"); err != nil {
+			return resp, err
+		}
+		resp.body = body
+		if resp.header.Get("Content-Type") == "" {
+			resp.header.Set("Content-Type", "application/json")
+		}
+	case step.Expect.BodyContains != "":
+		resp.body = []byte(applyVars(step.Expect.BodyContains, vars))
+		if resp.header.Get("Content-Type") == "" {
+			resp.header.Set("Content-Type", "text/plain; charset=utf-8")
+		}
+	}
+	return resp, nil
+}
+
+func applyVars(input string, vars map[string]string) string {
+	return varPattern.ReplaceAllStringFunc(input, func(m string) string {
+		if v, ok := vars[m[2:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// pathSegment is one step of a JSONPath such as items[0] or
+// [?(@.id==7)].
+type pathSegment struct {
+	name        string
+	index       int // -1 when the segment has no index
+	wildcard    bool
+	filterField string
+	filterValue string
+}
+
+var (
+	filterSegment = regexp.MustCompile(`^\[\?\(@\.([A-Za-z0-9_\-]+)==['"]?([^'"]+)['"]?\)\]$`)
+	indexSegment  = regexp.MustCompile(`^([^\[\]]*)\[([0-9]+|\*)\]$`)
+)
+
+// parsePath splits a JSONPath in the subset ramjam evaluates into segments.
+func parsePath(path string) ([]pathSegment, error) {
+	p := strings.TrimSpace(path)
+	if p == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+	p = strings.TrimPrefix(strings.TrimPrefix(p, "$."), "$")
+
+	var segs []pathSegment
+	for p != "" {
+		// Filters contain dots, so they are split off before the rest.
+		if strings.HasPrefix(p, "[?(") {
+			end := strings.Index(p, ")]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated filter in %s", path)
+			}
+			m := filterSegment.FindStringSubmatch(p[:end+2])
+			if m == nil {
+				return nil, fmt.Errorf("unsupported filter in %s", path)
+			}
+			segs = append(segs, pathSegment{index: -1, filterField: m[1], filterValue: m[2]})
+			p = strings.TrimPrefix(p[end+2:], ".")
+			continue
+		}
+		seg, rest, _ := strings.Cut(p, ".")
+		p = rest
+		if seg == "" {
+			continue
+		}
+		if m := indexSegment.FindStringSubmatch(seg); m != nil {
+			s := pathSegment{name: m[1], index: -1}
+			if m[2] == "*" {
+				s.wildcard = true
+			} else {
+				s.index, _ = strconv.Atoi(m[2])
+			}
+			segs = append(segs, s)
+			continue
+		}
+		if strings.ContainsAny(seg, "[]") {
+			return nil, fmt.Errorf("invalid segment %s in %s", seg, path)
+		}
+		segs = append(segs, pathSegment{name: seg, index: -1})
+	}
+	return segs, nil
+}
+
+// setJSONPath returns doc with value stored at path, creating objects and
+// arrays along the way. A wildcard or filter creates a single element.
+func setJSONPath(doc interface{}, path string, value interface{}) (interface{}, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return setSegments(doc, segs, value), nil
+}
+
+func setSegments(cur interface{}, segs []pathSegment, value interface{}) interface{} {
+	if len(segs) == 0 {
+		return value
+	}
+	seg, rest := segs[0], segs[1:]
+
+	if seg.name != "" {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			obj = map[string]interface{}{}
+		}
+		inner := seg
+		inner.name = ""
+		if inner.index < 0 && !inner.wildcard && inner.filterField == "" {
+			obj[seg.name] = setSegments(obj[seg.name], rest, value)
+		} else {
+			obj[seg.name] = setSegments(obj[seg.name], append([]pathSegment{inner}, rest...), value)
+		}
+		return obj
+	}
+
+	arr, _ := cur.([]interface{})
+	switch {
+	case seg.filterField != "":
+		for i, el := range arr {
+			if obj, ok := el.(map[string]interface{}); ok && fmt.Sprint(obj[seg.filterField]) == seg.filterValue {
+				arr[i] = setSegments(obj, rest, value)
+				return arr
+			}
+		}
+		el := map[string]interface{}{seg.filterField: seg.filterValue}
+		return append(arr, setSegments(el, rest, value))
+	case seg.wildcard:
+		if len(arr) == 0 {
+			arr = append(arr, nil)
+		}
+		for i := range arr {
+			arr[i] = setSegments(arr[i], rest, value)
+		}
+		return arr
+	default:
+		for len(arr) <= seg.index {
+			arr = append(arr, nil)
+		}
+		arr[seg.index] = setSegments(arr[seg.index], rest, value)
+		return arr
+	}
+}
+
+// getJSONPath returns the value at path, or an error if any part of it is
+// missing.
+func getJSONPath(doc interface{}, path string) (interface{}, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := doc
+	for _, seg := range segs {
+		if seg.name != "" {
+			obj, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("missing %s", seg.name)
+			}
+			if cur, ok = obj[seg.name]; !ok {
+				return nil, fmt.Errorf("missing %s", seg.name)
+			}
+		}
+		if seg.index < 0 && !seg.wildcard && seg.filterField == "" {
+			continue
+		}
+		arr, ok := cur.([]interface{})
+		if !ok || len(arr) == 0 {
+			return nil, fmt.Errorf("missing array in %s", path)
+		}
+		switch {
+		case seg.filterField != "":
+			found := false
+			for _, el := range arr {
+				if obj, ok := el.(map[string]interface{}); ok && fmt.Sprint(obj[seg.filterField]) == seg.filterValue {
+					cur, found = el, true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("no match for filter in %s", path)
+			}
+		case seg.wildcard:
+			cur = arr[0]
+		default:
+			if seg.index >= len(arr) {
+				return nil, fmt.Errorf("index out of range in %s", path)
+			}
+			cur = arr[seg.index]
+		}
+	}
+	return cur, nil
+}
@@ -0,0 +1,439 @@
+// Package openapi loads OpenAPI 3 documents for workflow generation and
+// response contract checks. Only the parts ramjam uses are modelled.
+package openapi
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Spec is an OpenAPI 3.0 or 3.1 document. YAML and JSON are both
+	// accepted.
+	Spec struct {
+		OpenAPI string `yaml:"openapi"`
+		Swagger string `yaml:"swagger"`
+		Info    struct {
+			Title   string `yaml:"title"`
+			Version string `yaml:"version"`
+		} `yaml:"info"`
+		Servers    []Server            `yaml:"servers"`
+		Paths      map[string]PathItem `yaml:"paths"`
+		Components Components          `yaml:"components"`
+	}
+
+	Server struct {
+		URL string `yaml:"url"`
+	}
+
+	Components struct {
+		Schemas       map[string]*Schema      `yaml:"schemas"`
+		Parameters    map[string]*Parameter   `yaml:"parameters"`
+		RequestBodies map[string]*RequestBody `yaml:"requestBodies"`
+		Responses     map[string]*Response    `yaml:"responses"`
+	}
+
+	PathItem struct {
+		Parameters []*Parameter `yaml:"parameters"`
+		Get        *Operation   `yaml:"get"`
+		Put        *Operation   `yaml:"put"`
+		Post       *Operation   `yaml:"post"`
+		Delete     *Operation   `yaml:"delete"`
+		Options    *Operation   `yaml:"options"`
+		Head       *Operation   `yaml:"head"`
+		Patch      *Operation   `yaml:"patch"`
+		Trace      *Operation   `yaml:"trace"`
+	}
+
+	Operation struct {
+		OperationID string               `yaml:"operationId"`
+		Summary     string               `yaml:"summary"`
+		Tags        []string             `yaml:"tags"`
+		Parameters  []*Parameter         `yaml:"parameters"`
+		RequestBody *RequestBody         `yaml:"requestBody"`
+		Responses   map[string]*Response `yaml:"responses"`
+	}
+
+	Parameter struct {
+		Ref      string      `yaml:"$ref"`
+		Name     string      `yaml:"name"`
+		In       string      `yaml:"in"`
+		Required bool        `yaml:"required"`
+		Schema   *Schema     `yaml:"schema"`
+		Example  interface{} `yaml:"example"`
+	}
+
+	RequestBody struct {
+		Ref      string               `yaml:"$ref"`
+		Required bool                 `yaml:"required"`
+		Content  map[string]MediaType `yaml:"content"`
+	}
+
+	Response struct {
+		Ref         string               `yaml:"$ref"`
+		Description string               `yaml:"description"`
+		Content     map[string]MediaType `yaml:"content"`
+	}
+
+	MediaType struct {
+		Schema   *Schema            `yaml:"schema"`
+		Example  interface{}        `yaml:"example"`
+		Examples map[string]Example `yaml:"examples"`
+	}
+
+	Example struct {
+		Value interface{} `yaml:"value"`
+	}
+
+	// Schema is the subset of JSON Schema used for examples and validation.
+	Schema struct {
+		Ref        string             `yaml:"$ref"`
+		Type       SchemaType         `yaml:"type"`
+		Format     string             `yaml:"format"`
+		Properties map[string]*Schema `yaml:"properties"`
+		Required   []string           `yaml:"required"`
+		Items      *Schema            `yaml:"items"`
+		Enum       []interface{}      `yaml:"enum"`
+		Example    interface{}        `yaml:"example"`
+		Default    interface{}        `yaml:"default"`
+		Nullable   bool               `yaml:"nullable"`
+		AllOf      []*Schema          `yaml:"allOf"`
+		OneOf      []*Schema          `yaml:"oneOf"`
+		AnyOf      []*Schema          `yaml:"anyOf"`
+
+		AdditionalProperties *AdditionalProperties `yaml:"additionalProperties"`
+		Pattern              string                `yaml:"pattern"`
+		MinLength            *int                  `yaml:"minLength"`
+		MaxLength            *int                  `yaml:"maxLength"`
+		Minimum              *float64              `yaml:"minimum"`
+		Maximum              *float64              `yaml:"maximum"`
+		MinItems             *int                  `yaml:"minItems"`
+		MaxItems             *int                  `yaml:"maxItems"`
+	}
+
+	// AdditionalProperties is either a boolean or a schema for properties
+	// not listed in properties.
+	AdditionalProperties struct {
+		Allowed bool
+		Schema  *Schema
+	}
+
+	// SchemaType holds "type", which OpenAPI 3.1 allows to be a list such
+	// as [string, "null"].
+	SchemaType []string
+)
+
+func (t *SchemaType) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		var types []string
+		if err := node.Decode(&types); err != nil {
+			return err
+		}
+		*t = types
+		return nil
+	}
+	*t = SchemaType{node.Value}
+	return nil
+}
+
+func (a *AdditionalProperties) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&a.Allowed)
+	}
+	a.Allowed = true
+	return node.Decode(&a.Schema)
+}
+
+// Has reports whether the schema allows the given type.
+func (t SchemaType) Has(name string) bool {
+	for _, typ := range t {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads an OpenAPI document from a YAML or JSON file.
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err := e.Wrapf(err, "read %s", path); err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes an OpenAPI document.
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := e.Wrap(yaml.Unmarshal(data, &spec), "parse openapi"); err != nil {
+		return nil, err
+	}
+	if spec.Swagger != "" {
+		return nil, fmt.Errorf("swagger %s documents are not supported; convert to OpenAPI 3 first", spec.Swagger)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %q (expected 3.x)", spec.OpenAPI)
+	}
+	return &spec, nil
+}
+
+// BaseURL returns the first server URL without a trailing slash.
+func (s *Spec) BaseURL() string {
+	if len(s.Servers) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(s.Servers[0].URL, "/")
+}
+
+// Methods lists HTTP methods in the order operations are reported.
+var Methods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "TRACE"}
+
+// Operation returns the operation for method, or nil.
+func (p PathItem) Operation(method string) *Operation {
+	switch strings.ToUpper(method) {
+	case "GET":
+		return p.Get
+	case "PUT":
+		return p.Put
+	case "POST":
+		return p.Post
+	case "DELETE":
+		return p.Delete
+	case "OPTIONS":
+		return p.Options
+	case "HEAD":
+		return p.Head
+	case "PATCH":
+		return p.Patch
+	case "TRACE":
+		return p.Trace
+	}
+	return nil
+}
+
+// OperationRef identifies an operation by its path template and method.
+type OperationRef struct {
+	Path      string
+	Method    string
+	Operation *Operation
+	// Parameters merges path-level and operation-level parameters, with
+	// references resolved.
+	Parameters []*Parameter
+}
+
+// Operations returns every operation sorted by path and then method.
+func (s *Spec) Operations() []OperationRef {
+	paths := make([]string, 0, len(s.Paths))
+	for p := range s.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var ops []OperationRef
+	for _, p := range paths {
+		item := s.Paths[p]
+		for _, method := range Methods {
+			op := item.Operation(method)
+			if op == nil {
+				continue
+			}
+			ops = append(ops, OperationRef{
+				Path:       p,
+				Method:     method,
+				Operation:  op,
+				Parameters: s.mergeParameters(item.Parameters, op.Parameters),
+			})
+		}
+	}
+	return ops
+}
+
+func (s *Spec) mergeParameters(pathParams, opParams []*Parameter) []*Parameter {
+	var merged []*Parameter
+	index := map[string]int{}
+	for _, list := range [][]*Parameter{pathParams, opParams} {
+		for _, p := range list {
+			p = s.ResolveParameter(p)
+			if p == nil {
+				continue
+			}
+			key := p.In + ":" + p.Name
+			if i, ok := index[key]; ok {
+				merged[i] = p
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+func refName(ref, prefix string) (string, bool) {
+	if !strings.HasPrefix(ref, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(ref, prefix), true
+}
+
+// ResolveSchema follows local $ref pointers. Unknown references resolve to
+// nil.
+func (s *Spec) ResolveSchema(schema *Schema) *Schema {
+	for i := 0; schema != nil && schema.Ref != "" && i < 32; i++ {
+		name, ok := refName(schema.Ref, "#/components/schemas/")
+		if !ok {
+			return nil
+		}
+		schema = s.Components.Schemas[name]
+	}
+	return schema
+}
+
+func (s *Spec) ResolveParameter(p *Parameter) *Parameter {
+	if p == nil || p.Ref == "" {
+		return p
+	}
+	name, ok := refName(p.Ref, "#/components/parameters/")
+	if !ok {
+		return nil
+	}
+	return s.Components.Parameters[name]
+}
+
+func (s *Spec) ResolveRequestBody(b *RequestBody) *RequestBody {
+	if b == nil || b.Ref == "" {
+		return b
+	}
+	name, ok := refName(b.Ref, "#/components/requestBodies/")
+	if !ok {
+		return nil
+	}
+	return s.Components.RequestBodies[name]
+}
+
+func (s *Spec) ResolveResponse(r *Response) *Response {
+	if r == nil || r.Ref == "" {
+		return r
+	}
+	name, ok := refName(r.Ref, "#/components/responses/")
+	if !ok {
+		return nil
+	}
+	return s.Components.Responses[name]
+}
+
+// JSONMediaType returns the first JSON media type in content, preferring
+// application/json.
+func JSONMediaType(content map[string]MediaType) (MediaType, bool) {
+	if mt, ok := content["application/json"]; ok {
+		return mt, true
+	}
+	keys := make([]string, 0, len(content))
+	for k := range content {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.HasSuffix(strings.Split(k, ";")[0], "+json") || strings.HasPrefix(k, "application/json") {
+			return content[k], true
+		}
+	}
+	return MediaType{}, false
+}
+
+// Example returns a sample value for a media type: an explicit example, the
+// first named example, or one generated from the schema.
+func (s *Spec) Example(mt MediaType) interface{} {
+	if mt.Example != nil {
+		return mt.Example
+	}
+	names := make([]string, 0, len(mt.Examples))
+	for name := range mt.Examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v := mt.Examples[name].Value; v != nil {
+			return v
+		}
+	}
+	return s.SchemaExample(mt.Schema)
+}
+
+// SchemaExample builds a sample value from a schema, using example, default
+// and enum values where present.
+func (s *Spec) SchemaExample(schema *Schema) interface{} {
+	return s.schemaExample(schema, 0)
+}
+
+func (s *Spec) schemaExample(schema *Schema, depth int) interface{} {
+	schema = s.ResolveSchema(schema)
+	if schema == nil || depth > 8 {
+		return nil
+	}
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.AllOf) > 0:
+		merged := map[string]interface{}{}
+		for _, sub := range schema.AllOf {
+			if obj, ok := s.schemaExample(sub, depth+1).(map[string]interface{}); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	case len(schema.OneOf) > 0:
+		return s.schemaExample(schema.OneOf[0], depth+1)
+	case len(schema.AnyOf) > 0:
+		return s.schemaExample(schema.AnyOf[0], depth+1)
+	}
+
+	switch {
+	case schema.Type.Has("object") || len(schema.Properties) > 0:
+		obj := map[string]interface{}{}
+		for name, prop := range schema.Properties {
+			if v := s.schemaExample(prop, depth+1); v != nil {
+				obj[name] = v
+			}
+		}
+		return obj
+	case schema.Type.Has("array"):
+		if item := s.schemaExample(schema.Items, depth+1); item != nil {
+			return []interface{}{item}
+		}
+		return []interface{}{}
+	case schema.Type.Has("integer"):
+		return 0
+	case schema.Type.Has("number"):
+		return 0.0
+	case schema.Type.Has("boolean"):
+		return true
+	case schema.Type.Has("string"):
+		switch schema.Format {
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "date":
+			return "2024-01-01"
+		case "email":
+			return "user@example.com"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		case "uri", "url":
+			return "https://example.com"
+		}
+		return "string"
+	}
+	return nil
+}
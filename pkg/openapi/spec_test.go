@@ -0,0 +1,87 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	spec, err := Load("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if spec.Info.Title != "Petstore" || spec.BaseURL() != "https://petstore.example.com/v1" {
+		t.Errorf("unexpected spec header: %q %q", spec.Info.Title, spec.BaseURL())
+	}
+
+	var got []string
+	for _, op := range spec.Operations() {
+		got = append(got, op.Method+" "+op.Path)
+	}
+	want := []string{"GET /health", "GET /pets", "POST /pets", "GET /pets/{petId}", "DELETE /pets/{petId}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Operations() = %v, want %v", got, want)
+	}
+
+	del := spec.Operations()[4]
+	if len(del.Parameters) != 2 || del.Parameters[0].Name != "petId" || del.Parameters[1].Name != "X-Confirm" {
+		t.Errorf("expected path and operation parameters to merge, got %+v", del.Parameters)
+	}
+}
+
+func TestParseRejectsOtherVersions(t *testing.T) {
+	if _, err := Parse([]byte(`swagger: "2.0"`)); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected swagger error, got %v", err)
+	}
+	if _, err := Parse([]byte(`{"openapi": "4.0"}`)); err == nil {
+		t.Error("expected version error")
+	}
+	if _, err := Parse([]byte(`{"openapi": "3.1.0", "paths": {}}`)); err != nil {
+		t.Errorf("expected JSON 3.1 document to parse: %v", err)
+	}
+}
+
+func TestSchemaExample(t *testing.T) {
+	spec, err := Load("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pet := spec.SchemaExample(&Schema{Ref: "#/components/schemas/Pet"})
+	want := map[string]interface{}{"name": "Rex", "tag": "string", "id": 0}
+	if !reflect.DeepEqual(pet, want) {
+		t.Errorf("Pet example = %#v, want %#v", pet, want)
+	}
+
+	body := spec.ResolveRequestBody(spec.Paths["/pets"].Post.RequestBody)
+	mt, ok := JSONMediaType(body.Content)
+	if !ok {
+		t.Fatal("expected JSON media type")
+	}
+	if got := spec.Example(mt); !reflect.DeepEqual(got, map[string]interface{}{"name": "Rex", "tag": "string"}) {
+		t.Errorf("request body example = %#v", got)
+	}
+
+	list := spec.SchemaExample(&Schema{Type: SchemaType{"array"}, Items: &Schema{Type: SchemaType{"string"}, Format: "email"}})
+	if !reflect.DeepEqual(list, []interface{}{"user@example.com"}) {
+		t.Errorf("array example = %#v", list)
+	}
+}
+
+func TestSchemaTypeList(t *testing.T) {
+	spec, err := Parse([]byte(`
+openapi: "3.1.0"
+components:
+  schemas:
+    Name:
+      type: [string, "null"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := spec.Components.Schemas["Name"].Type
+	if !typ.Has("string") || !typ.Has("null") || typ.Has("integer") {
+		t.Errorf("unexpected type list %v", typ)
+	}
+}
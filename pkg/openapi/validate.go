@@ -0,0 +1,334 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Match finds the operation for a request. path is the URL path of the
+// request; the path of the matching server URL is stripped before the path
+// templates are compared. Literal segments win over templated ones, so
+// /pets/mine is preferred to /pets/{petId}. ok is false when the URL does not
+// belong to any of the spec's servers; ref is nil when it does but no
+// operation matches.
+func (s *Spec) Match(method string, u *url.URL) (ref *OperationRef, ok bool) {
+	path, ok := s.relativePath(u)
+	if !ok {
+		return nil, false
+	}
+	want := splitPath(path)
+
+	best, bestParams := -1, 0
+	ops := s.Operations()
+	for i, op := range ops {
+		if op.Method != strings.ToUpper(method) {
+			continue
+		}
+		params, matched := matchTemplate(splitPath(op.Path), want)
+		if matched && (best < 0 || params < bestParams) {
+			best, bestParams = i, params
+		}
+	}
+	if best < 0 {
+		return nil, true
+	}
+	return &ops[best], true
+}
+
+// relativePath strips the base path of the first server that u falls under.
+// Relative server URLs match any host.
+func (s *Spec) relativePath(u *url.URL) (string, bool) {
+	if len(s.Servers) == 0 {
+		return u.Path, true
+	}
+	for _, server := range s.Servers {
+		base, err := url.Parse(server.URL)
+		if err != nil {
+			continue
+		}
+		if base.Host != "" && !strings.EqualFold(base.Host, u.Host) {
+			continue
+		}
+		prefix := strings.TrimSuffix(base.Path, "/")
+		if u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/") {
+			return strings.TrimPrefix(u.Path, prefix), true
+		}
+	}
+	return "", false
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func matchTemplate(template, path []string) (params int, ok bool) {
+	if len(template) != len(path) {
+		return 0, false
+	}
+	for i, seg := range template {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			if path[i] == "" {
+				return 0, false
+			}
+			params++
+			continue
+		}
+		if seg != path[i] {
+			return 0, false
+		}
+	}
+	return params, true
+}
+
+// Response returns the documented response for a status code, trying the
+// exact code, then a range such as 2XX, then default.
+func (s *Spec) Response(op *Operation, status int) (*Response, bool) {
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if resp, ok := op.Responses[key]; ok {
+			return s.ResolveResponse(resp), true
+		}
+	}
+	return nil, false
+}
+
+// MediaTypeFor returns the declared content entry for a response Content-Type
+// header, honouring wildcards such as application/* and */*.
+func MediaTypeFor(content map[string]MediaType, contentType string) (MediaType, bool) {
+	actual := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if mt, ok := content[actual]; ok {
+		return mt, true
+	}
+	keys := make([]string, 0, len(content))
+	for k := range content {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	major := strings.Split(actual, "/")[0]
+	for _, k := range keys {
+		declared := strings.ToLower(strings.TrimSpace(strings.Split(k, ";")[0]))
+		if declared == actual || declared == major+"/*" || declared == "*/*" {
+			return content[k], true
+		}
+	}
+	return MediaType{}, false
+}
+
+// Validate checks a decoded JSON value against a schema and returns the first
+// violation, prefixed with the JSON path where it was found.
+func (s *Spec) Validate(schema *Schema, value interface{}) error {
+	return s.validate(schema, value, "$", 0)
+}
+
+func (s *Spec) validate(schema *Schema, value interface{}, path string, depth int) error {
+	if schema != nil && schema.Ref != "" {
+		resolved := s.ResolveSchema(schema)
+		if resolved == nil {
+			return fmt.Errorf("%s: unresolved schema reference %s", path, schema.Ref)
+		}
+		schema = resolved
+	}
+	if schema == nil || depth > 64 {
+		return nil
+	}
+
+	for _, sub := range schema.AllOf {
+		if err := s.validate(sub, value, path, depth+1); err != nil {
+			return err
+		}
+	}
+	if len(schema.OneOf) > 0 {
+		matches := 0
+		for _, sub := range schema.OneOf {
+			if s.validate(sub, value, path, depth+1) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("%s: expected exactly one oneOf schema to match, %d did", path, matches)
+		}
+	}
+	if len(schema.AnyOf) > 0 {
+		var first error
+		for _, sub := range schema.AnyOf {
+			err := s.validate(sub, value, path, depth+1)
+			if err == nil {
+				first = nil
+				break
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		if first != nil {
+			return fmt.Errorf("%s: no anyOf schema matched: %v", path, first)
+		}
+	}
+
+	if value == nil {
+		if len(schema.Type) == 0 || schema.Nullable || schema.Type.Has("null") {
+			return nil
+		}
+		return fmt.Errorf("%s: expected %s, got null", path, strings.Join(schema.Type, " or "))
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return fmt.Errorf("%s: %s is not one of %s", path, describe(value), describeEnum(schema.Enum))
+	}
+
+	if len(schema.Type) > 0 && !typeMatches(schema.Type, value) {
+		return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(schema.Type, " or "), jsonType(value))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return s.validateObject(schema, v, path, depth)
+	case []interface{}:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			return fmt.Errorf("%s: expected at least %d items, got %d", path, *schema.MinItems, len(v))
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			return fmt.Errorf("%s: expected at most %d items, got %d", path, *schema.MaxItems, len(v))
+		}
+		for i, item := range v {
+			if err := s.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+				return err
+			}
+		}
+	case string:
+		n := len([]rune(v))
+		if schema.MinLength != nil && n < *schema.MinLength {
+			return fmt.Errorf("%s: expected at least %d characters, got %d", path, *schema.MinLength, n)
+		}
+		if schema.MaxLength != nil && n > *schema.MaxLength {
+			return fmt.Errorf("%s: expected at most %d characters, got %d", path, *schema.MaxLength, n)
+		}
+		if schema.Pattern != "" {
+			re, err := regexp.Compile(schema.Pattern)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern %q in spec: %v", path, schema.Pattern, err)
+			}
+			if !re.MatchString(v) {
+				return fmt.Errorf("%s: %q does not match pattern %s", path, v, schema.Pattern)
+			}
+		}
+	case float64:
+		if schema.Minimum != nil && v < *schema.Minimum {
+			return fmt.Errorf("%s: %v is less than minimum %v", path, v, *schema.Minimum)
+		}
+		if schema.Maximum != nil && v > *schema.Maximum {
+			return fmt.Errorf("%s: %v is greater than maximum %v", path, v, *schema.Maximum)
+		}
+	}
+	return nil
+}
+
+func (s *Spec) validateObject(schema *Schema, obj map[string]interface{}, path string, depth int) error {
+	for _, name := range schema.Required {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := path + "." + name
+		if prop, ok := schema.Properties[name]; ok {
+			if err := s.validate(prop, obj[name], child, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+		extra := schema.AdditionalProperties
+		if extra == nil {
+			continue
+		}
+		if !extra.Allowed {
+			return fmt.Errorf("%s: unexpected property %q", path, name)
+		}
+		if err := s.validate(extra.Schema, obj[name], child, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func typeMatches(types SchemaType, value interface{}) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a value decoded by encoding/json. Whole
+// numbers report as integer.
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) && typeMatches(SchemaType{jsonType(normalise(allowed))}, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalise converts YAML-decoded numbers to float64 so they compare with
+// JSON-decoded values.
+func normalise(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	}
+	return v
+}
+
+func describe(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(value)
+}
+
+func describeEnum(enum []interface{}) string {
+	parts := make([]string, len(enum))
+	for i, v := range enum {
+		parts[i] = describe(v)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -0,0 +1,140 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	spec, err := Load("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.Paths["/pets/mine"] = PathItem{Get: &Operation{OperationID: "myPets"}}
+
+	tests := []struct {
+		method, url string
+		want        string
+		inSpec      bool
+	}{
+		{"GET", "https://petstore.example.com/v1/pets", "GET /pets", true},
+		{"get", "https://petstore.example.com/v1/pets/7", "GET /pets/{petId}", true},
+		{"GET", "https://petstore.example.com/v1/pets/mine", "GET /pets/mine", true},
+		{"PUT", "https://petstore.example.com/v1/pets/7", "", true},
+		{"GET", "https://petstore.example.com/v2/pets", "", false},
+		{"GET", "https://auth.example.com/v1/pets", "", false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		ref, inSpec := spec.Match(tt.method, u)
+		got := ""
+		if ref != nil {
+			got = ref.Method + " " + ref.Path
+		}
+		if got != tt.want || inSpec != tt.inSpec {
+			t.Errorf("Match(%s %s) = %q, %v; want %q, %v", tt.method, tt.url, got, inSpec, tt.want, tt.inSpec)
+		}
+	}
+}
+
+func TestResponse(t *testing.T) {
+	spec, err := Load("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := spec.Paths["/pets/{petId}"].Get
+	if resp, ok := spec.Response(op, 404); !ok || resp.Description != "Error" {
+		t.Errorf("expected 404 to resolve the Error response, got %+v", resp)
+	}
+	if _, ok := spec.Response(op, 500); ok {
+		t.Error("expected 500 to be undocumented")
+	}
+	op.Responses["5XX"] = &Response{Description: "Server error"}
+	if resp, ok := spec.Response(op, 503); !ok || resp.Description != "Server error" {
+		t.Errorf("expected 503 to match 5XX, got %+v", resp)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	spec, err := Parse([]byte(`
+openapi: "3.1.0"
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      additionalProperties: false
+      properties:
+        id: {type: integer, minimum: 1}
+        name: {type: string, minLength: 1, pattern: "^[A-Z]"}
+        status: {type: string, enum: [available, sold]}
+        tag: {type: [string, "null"]}
+        tags:
+          type: array
+          maxItems: 2
+          items: {type: string}
+        meta:
+          type: object
+          additionalProperties: {type: number}
+        owner:
+          oneOf:
+          - {type: string}
+          - {type: integer}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pet := &Schema{Ref: "#/components/schemas/Pet"}
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"id": 1, "name": "Rex", "status": "sold", "tag": null, "tags": ["a"], "meta": {"weight": 4.5}, "owner": 7}`, ""},
+		{`{"name": "Rex"}`, `$: missing required property "id"`},
+		{`{"id": 1.5, "name": "Rex"}`, "$.id: expected integer, got number"},
+		{`{"id": 0, "name": "Rex"}`, "$.id: 0 is less than minimum 1"},
+		{`{"id": 1, "name": "rex"}`, `$.name: "rex" does not match pattern ^[A-Z]`},
+		{`{"id": 1, "name": "Rex", "status": "lost"}`, `$.status: "lost" is not one of ["available", "sold"]`},
+		{`{"id": 1, "name": "Rex", "tags": ["a", "b", 3]}`, "$.tags: expected at most 2 items, got 3"},
+		{`{"id": 1, "name": "Rex", "tags": [3]}`, "$.tags[0]: expected string, got integer"},
+		{`{"id": 1, "name": "Rex", "meta": {"weight": "heavy"}}`, "$.meta.weight: expected number, got string"},
+		{`{"id": 1, "name": "Rex", "owner": true}`, "$.owner: expected exactly one oneOf schema to match, 0 did"},
+		{`{"id": 1, "name": "Rex", "colour": "brown"}`, `$: unexpected property "colour"`},
+		{`[]`, "$: expected object, got array"},
+	}
+	for _, tt := range tests {
+		var value interface{}
+		if err := json.Unmarshal([]byte(tt.body), &value); err != nil {
+			t.Fatal(err)
+		}
+		err := spec.Validate(pet, value)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("Validate(%s) failed: %v", tt.body, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate(%s) = %v, want %q", tt.body, err, tt.want)
+		}
+	}
+}
+
+func TestValidateNullable(t *testing.T) {
+	spec, err := Load("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value interface{}
+	json.Unmarshal([]byte(`{"id": 1, "name": "Rex", "tag": null}`), &value)
+	if err := spec.Validate(&Schema{Ref: "#/components/schemas/Pet"}, value); err != nil {
+		t.Errorf("expected nullable tag to accept null: %v", err)
+	}
+	json.Unmarshal([]byte(`{"id": 1, "name": null}`), &value)
+	if err := spec.Validate(&Schema{Ref: "#/components/schemas/Pet"}, value); err == nil {
+		t.Error("expected null name to fail")
+	}
+}
@@ -0,0 +1,105 @@
+// Package record captures live traffic through a reverse proxy so it can be
+// converted into a workflow.
+package record
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+
+	"github.com/michaelmccabe/ramjam/pkg/convert"
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+type bodyKey struct{}
+
+// Recorder is an http.Handler that forwards every request to a target API
+// and records the exchange.
+type Recorder struct {
+	target *url.URL
+	proxy  *httputil.ReverseProxy
+
+	mu        sync.Mutex
+	exchanges []convert.Exchange
+
+	// OnExchange, if set, is called after each exchange is recorded.
+	OnExchange func(convert.Exchange)
+}
+
+// New returns a Recorder that proxies to target, an absolute http(s) URL.
+func New(target string) (*Recorder, error) {
+	u, err := url.Parse(target)
+	if err := e.Wrapf(err, "parse target %s", target); err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("target must be an absolute http or https URL, got %q", target)
+	}
+
+	rec := &Recorder{target: u}
+	rec.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(u)
+			// Let the transport negotiate and decode compression so the
+			// recorded body is readable.
+			pr.Out.Header.Del("Accept-Encoding")
+		},
+		ModifyResponse: rec.record,
+	}
+	return rec, nil
+}
+
+func (rec *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "read request body: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), bodyKey{}, body))
+	rec.proxy.ServeHTTP(w, r)
+}
+
+// record buffers the response body, restores it for the client and stores
+// the exchange.
+func (rec *Recorder) record(resp *http.Response) error {
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err := e.Wrap(err, "read response body"); err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	out := resp.Request
+	reqBody, _ := out.Context().Value(bodyKey{}).([]byte)
+	ex := convert.Exchange{
+		Method:         out.Method,
+		URL:            out.URL.String(),
+		Header:         out.Header.Clone(),
+		Body:           reqBody,
+		Status:         resp.StatusCode,
+		ResponseHeader: resp.Header.Clone(),
+		ResponseBody:   data,
+	}
+
+	rec.mu.Lock()
+	rec.exchanges = append(rec.exchanges, ex)
+	rec.mu.Unlock()
+	if rec.OnExchange != nil {
+		rec.OnExchange(ex)
+	}
+	return nil
+}
+
+// Exchanges returns the exchanges recorded so far, in the order their
+// responses arrived.
+func (rec *Recorder) Exchanges() []convert.Exchange {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]convert.Exchange(nil), rec.exchanges...)
+}
@@ -0,0 +1,99 @@
+package record
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/michaelmccabe/ramjam/pkg/convert"
+)
+
+func TestRecorderForwardsAndRecords(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"path":"` + r.URL.Path + `","got":` + string(body) + `}`))
+	}))
+	defer backend.Close()
+
+	rec, err := New(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen int
+	rec.OnExchange = func(ex convert.Exchange) { seen++ }
+	proxy := httptest.NewServer(rec)
+	defer proxy.Close()
+
+	resp, err := http.Post(proxy.URL+"/users?x=1", "application/json", strings.NewReader(`{"name":"Ada"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	want := `{"path":"/users","got":{"name":"Ada"}}`
+	if resp.StatusCode != http.StatusCreated || string(data) != want {
+		t.Fatalf("client got %d %s", resp.StatusCode, data)
+	}
+
+	exchanges := rec.Exchanges()
+	if len(exchanges) != 1 || seen != 1 {
+		t.Fatalf("expected 1 exchange, got %d (callback %d)", len(exchanges), seen)
+	}
+	ex := exchanges[0]
+	if ex.Method != "POST" || ex.URL != backend.URL+"/users?x=1" {
+		t.Errorf("unexpected request %s %s", ex.Method, ex.URL)
+	}
+	if string(ex.Body) != `{"name":"Ada"}` || ex.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected request body %q or headers %v", ex.Body, ex.Header)
+	}
+	if ex.Status != http.StatusCreated || string(ex.ResponseBody) != want {
+		t.Errorf("unexpected response %d %s", ex.Status, ex.ResponseBody)
+	}
+}
+
+func TestRecorderDecodesCompressedResponses(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Write([]byte(`{"plain":true}`))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"id":"abc123"}`))
+		gz.Close()
+	}))
+	defer backend.Close()
+
+	rec, err := New(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httptest.NewServer(rec)
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL + "/items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(data) != `{"id":"abc123"}` {
+		t.Errorf("client got %s", data)
+	}
+	if got := string(rec.Exchanges()[0].ResponseBody); got != `{"id":"abc123"}` {
+		t.Errorf("recorded %s", got)
+	}
+}
+
+func TestNewRejectsRelativeTarget(t *testing.T) {
+	for _, target := range []string{"localhost:8080", "/api", "ftp://example.com"} {
+		if _, err := New(target); err == nil {
+			t.Errorf("expected error for %q", target)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// Response is the response a custom assertion checks. JSON is the decoded
+// body, or nil if the body isn't JSON.
+type Response struct {
+	Status   int
+	Proto    string
+	Header   http.Header
+	Body     []byte
+	JSON     interface{}
+	Duration time.Duration
+}
+
+// AssertionFunc checks a response against arg, the value given for it
+// under expect.custom with variables substituted, and returns an error
+// describing any mismatch.
+type AssertionFunc func(resp *Response, arg interface{}) error
+
+// RegisterAssertion makes fn available to workflows as expect.custom.name,
+// so a domain check written once in Go can be reused from YAML:
+//
+//	expect:
+//	  custom:
+//	    valid_jwt: { issuer: "https://auth.example.com" }
+//
+// Registering a name again replaces its function. Assertions must be
+// registered before the runner starts.
+func (r *Runner) RegisterAssertion(name string, fn AssertionFunc) {
+	if r.assertions == nil {
+		r.assertions = map[string]AssertionFunc{}
+	}
+	r.assertions[name] = fn
+}
+
+// checkCustom runs a step's expect.custom assertions in name order.
+func (r *Runner) checkCustom(resp *http.Response, body *bodyStream, jsonObj interface{}, took time.Duration, custom map[string]interface{}, vars map[string]string, log func(string, ...interface{})) error {
+	if len(custom) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if r.assertions[name] == nil {
+			return fmt.Errorf("unknown custom assertion %q", name)
+		}
+	}
+
+	data, err := body.Bytes()
+	if err != nil {
+		return err
+	}
+	if jsonObj == nil && json.Valid(data) {
+		json.Unmarshal(data, &jsonObj)
+	}
+	response := &Response{
+		Status:   resp.StatusCode,
+		Proto:    resp.Proto,
+		Header:   resp.Header,
+		Body:     data,
+		JSON:     jsonObj,
+		Duration: took,
+	}
+	for _, name := range names {
+		if r.verbose() {
+			log("Asserting custom %s", name)
+		}
+		arg, err := r.applyVarsToInterface(custom[name], vars)
+		if err != nil {
+			return err
+		}
+		if err := e.Wrapf(r.assertions[name](response, arg), "custom assertion %s", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
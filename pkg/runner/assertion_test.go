@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCustomAssertions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Region", "eu")
+		w.Write([]byte(`{"token": "abc.def.ghi"}`))
+	}))
+	defer srv.Close()
+
+	workflow := func(region string) string {
+		return fmt.Sprintf(`
+metadata:
+  name: "Custom"
+config:
+  base_url: "%s"
+workflow:
+- step: "token"
+  request:
+    method: "GET"
+    url: "/"
+  expect:
+    custom:
+      jwt_shape: 3
+      region: "%s"
+`, srv.URL, region)
+	}
+
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "custom.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	var calls []string
+	r := New(5*time.Second, false)
+	r.RegisterAssertion("jwt_shape", func(resp *Response, arg interface{}) error {
+		calls = append(calls, "jwt_shape")
+		token, _ := resp.JSON.(map[string]interface{})["token"].(string)
+		if parts := strings.Count(token, ".") + 1; parts != arg.(int) {
+			return fmt.Errorf("expected %d parts, got %d", arg, parts)
+		}
+		return nil
+	})
+	r.RegisterAssertion("region", func(resp *Response, arg interface{}) error {
+		calls = append(calls, "region")
+		if got := resp.Header.Get("X-Region"); got != arg {
+			return fmt.Errorf("expected region %v, got %s", arg, got)
+		}
+		return nil
+	})
+
+	r.out = new(strings.Builder)
+	r.vars = map[string]string{"want_region": "eu"}
+	if err := r.RunPaths([]string{write(workflow("${want_region}"))}); err != nil {
+		t.Fatalf("expected custom assertions to pass, got %v", err)
+	}
+	if strings.Join(calls, ",") != "jwt_shape,region" {
+		t.Errorf("expected assertions in name order, got %v", calls)
+	}
+
+	err := r.RunPaths([]string{write(workflow("us"))})
+	if err == nil || !strings.Contains(err.Error(), "custom assertion region: expected region us, got eu") {
+		t.Errorf("expected the region assertion to fail, got %v", err)
+	}
+
+	r = New(5*time.Second, false)
+	r.out = new(strings.Builder)
+	err = r.RunPaths([]string{write(workflow("eu"))})
+	if err == nil || !strings.Contains(err.Error(), `unknown custom assertion "jwt_shape"`) {
+		t.Errorf("expected an unknown assertion error, got %v", err)
+	}
+}
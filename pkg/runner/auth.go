@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// Auth describes how a request authenticates. It may be set for a whole file
+// under config.auth or per request; request-level auth is applied last, and
+// headers set explicitly on a step take precedence over both.
+type Auth struct {
+	Basic  *BasicAuth  `yaml:"basic,omitempty"`
+	Bearer string      `yaml:"bearer,omitempty"`
+	OAuth2 *OAuth2Auth `yaml:"oauth2,omitempty"`
+	HMAC   *HMACAuth   `yaml:"hmac,omitempty"`
+	APIKey *APIKeyAuth `yaml:"api_key,omitempty"`
+}
+
+// BasicAuth holds HTTP Basic credentials; both fields support variables.
+type BasicAuth struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// APIKeyAuth sends a key as a header or query parameter. In defaults to
+// "header".
+type APIKeyAuth struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+	In    string `yaml:"in,omitempty"`
+}
+
+func (r *Runner) applyAuth(req *http.Request, auth *Auth, client *http.Client, vars map[string]string) error {
+	if auth == nil {
+		return nil
+	}
+
+	if auth.Basic != nil {
+		username, err := r.applyVars(auth.Basic.Username, vars)
+		if err != nil {
+			return err
+		}
+		if username == "" {
+			return fmt.Errorf("basic auth requires a username")
+		}
+		password, err := r.applyVars(auth.Basic.Password, vars)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(username, password)
+	}
+
+	if auth.Bearer != "" {
+		token, err := r.applyVars(auth.Bearer, vars)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	if auth.APIKey != nil {
+		if err := r.applyAPIKey(req, auth.APIKey, vars); err != nil {
+			return err
+		}
+	}
+
+	if auth.OAuth2 != nil && r.dryRun {
+		req.Header.Set("Authorization", "Bearer "+dryRunOAuth2Token)
+	} else if auth.OAuth2 != nil {
+		cfg, err := r.oauth2Config(auth.OAuth2, vars)
+		if err != nil {
+			return err
+		}
+		tok, err := r.tokens.token(req.Context(), client, cfg, req.Header.Get("User-Agent"))
+		if err := e.Wrap(err, "oauth2 token"); err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", tok.tokenType+" "+tok.accessToken)
+	}
+	return nil
+}
+
+func (r *Runner) applyAPIKey(req *http.Request, key *APIKeyAuth, vars map[string]string) error {
+	name, err := r.applyVars(key.Name, vars)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("api_key auth requires a name")
+	}
+	value, err := r.applyVars(key.Value, vars)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(key.In) {
+	case "", "header":
+		req.Header.Set(name, value)
+	case "query":
+		query := req.URL.Query()
+		query.Set(name, value)
+		req.URL.RawQuery = query.Encode()
+	default:
+		return fmt.Errorf("unknown api_key location %q (expected header or query)", key.In)
+	}
+	return nil
+}
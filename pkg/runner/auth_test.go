@@ -0,0 +1,313 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUserAgentAndRunnerHeaders(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[r.URL.Path] = r.Header.Get("User-Agent") + "|" + r.Header.Get("X-Run") + "|" + r.Header.Get("X-Tenant")
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	write := func(name, config, headers string) string {
+		path := filepath.Join(dir, name+".yaml")
+		os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+%s
+workflow:
+- step: "%s"
+  request:
+    url: "/%s"
+%s
+`, srv.URL, config, name, name, headers)), 0644)
+		return path
+	}
+	files := []string{
+		write("plain", "", ""),
+		write("custom", "  user_agent: \"shop/${version}\"\n  headers:\n    X-Tenant: acme", ""),
+		write("step", "", "    headers:\n      X-Run: step\n      User-Agent: curl/8"),
+	}
+
+	r := New(10*time.Second, false,
+		WithUserAgent("nightly"),
+		WithHeaders(map[string]string{"X-Run": "${run}", "X-Tenant": "default"}),
+		WithVars(map[string]string{"version": "2", "run": "42"}))
+	if err := r.RunPaths(files); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+	want := map[string]string{
+		"/plain":  "nightly|42|default",
+		"/custom": "shop/2|42|acme",
+		"/step":   "curl/8|step|default",
+	}
+	for path, w := range want {
+		if seen[path] != w {
+			t.Errorf("%s: User-Agent|X-Run|X-Tenant = %q, want %q", path, seen[path], w)
+		}
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/config" {
+			w.Write([]byte(`{"password": "s3cr:t"}`))
+			return
+		}
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "admin" || pass != "s3cr:t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Basic Auth"
+config:
+  base_url: "%s"
+workflow:
+- step: "get-password"
+  request:
+    url: "/config"
+  capture:
+  - json_path: "password"
+    as: "admin_password"
+- step: "protected"
+  request:
+    url: "/admin"
+    auth:
+      basic:
+        username: "admin"
+        password: "${admin_password}"
+  expect:
+    status: 200
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
+
+func TestConfigHeadersAndBearer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte(`{"token": "tok-1"}`))
+			return
+		case "/override":
+			if got := r.Header.Get("X-Tenant"); got != "beta" {
+				t.Errorf("expected step header to override X-Tenant, got %s", got)
+			}
+			if got := r.Header.Get("Authorization"); got != "Bearer custom" {
+				t.Errorf("expected step Authorization to override bearer, got %s", got)
+			}
+		default:
+			if got := r.Header.Get("X-Tenant"); got != "acme" {
+				t.Errorf("expected X-Tenant acme, got %s", got)
+			}
+			if got := r.Header.Get("Authorization"); got != "Bearer tok-1" {
+				t.Errorf("expected bearer token, got %s", got)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Config Headers"
+config:
+  base_url: "%s"
+  headers:
+    X-Tenant: "acme"
+  auth:
+    bearer: "${token}"
+workflow:
+- step: "login"
+  request:
+    url: "/login"
+  capture:
+  - json_path: "token"
+    as: "token"
+- step: "profile"
+  request:
+    url: "/profile"
+  expect:
+    status: 200
+- step: "override"
+  request:
+    url: "/override"
+    headers:
+      X-Tenant: "beta"
+      Authorization: "Bearer custom"
+  expect:
+    status: 200
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
+
+func newTokenServer(t *testing.T, expiresIn int, fetches *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if err := r.ParseForm(); err != nil {
+				t.Errorf("parse token form: %v", err)
+			}
+			if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_id") != "cli" ||
+				r.Form.Get("client_secret") != "shh" || r.Form.Get("scope") != "read write" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error": "invalid_client"}`))
+				return
+			}
+			n := atomic.AddInt32(fetches, 1)
+			fmt.Fprintf(w, `{"access_token": "tok-%d", "token_type": "bearer", "expires_in": %d}`, n, expiresIn)
+		default:
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"auth": %q}`, r.Header.Get("Authorization"))
+		}
+	}))
+}
+
+func oauthWorkflow(baseURL, secret string) string {
+	return fmt.Sprintf(`
+metadata:
+  name: "OAuth2"
+config:
+  base_url: "%s"
+  auth:
+    oauth2:
+      token_url: "%s/token"
+      client_id: "cli"
+      client_secret: "%s"
+      scopes: ["read", "write"]
+workflow:
+- step: "first"
+  request:
+    url: "/a"
+  expect:
+    status: 200
+- step: "second"
+  request:
+    url: "/b"
+  expect:
+    status: 200
+`, baseURL, baseURL, secret)
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var fetches int32
+	srv := newTokenServer(t, 3600, &fetches)
+	defer srv.Close()
+
+	yamlContent := oauthWorkflow(srv.URL, "shh") + `
+- step: "token-value"
+  request:
+    url: "/c"
+  expect:
+    json_path_match:
+    - path: "auth"
+      value: "Bearer tok-1"
+`
+	runTest(t, yamlContent)
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Errorf("expected token to be fetched once, got %d", got)
+	}
+}
+
+func TestOAuth2RefreshesExpiredToken(t *testing.T) {
+	var fetches int32
+	// Tokens expiring inside the refresh margin are fetched again on every use.
+	srv := newTokenServer(t, 1, &fetches)
+	defer srv.Close()
+
+	runTest(t, oauthWorkflow(srv.URL, "shh"))
+	if got := atomic.LoadInt32(&fetches); got != 3 {
+		t.Errorf("expected a token fetch per use, got %d", got)
+	}
+}
+
+func TestOAuth2TokenFailure(t *testing.T) {
+	var fetches int32
+	srv := newTokenServer(t, 3600, &fetches)
+	defer srv.Close()
+
+	err := runTestError(t, oauthWorkflow(srv.URL, "wrong"))
+	if err == nil || !strings.Contains(err.Error(), "token endpoint returned status 401") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&fetches); got != 0 {
+		t.Errorf("expected no tokens to be issued, got %d", got)
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/header":
+			if got := r.Header.Get("X-API-Key"); got != "k-123" {
+				t.Errorf("expected X-API-Key header, got %q", got)
+			}
+		case "/query":
+			if got := r.URL.Query().Get("api_key"); got != "k-456" {
+				t.Errorf("expected api_key query param, got %q", got)
+			}
+			if got := r.URL.Query().Get("page"); got != "2" {
+				t.Errorf("expected page param to be kept, got %q", got)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "API Key"
+config:
+  base_url: "%s"
+  auth:
+    api_key:
+      name: "X-API-Key"
+      value: "k-123"
+workflow:
+- step: "header"
+  request:
+    url: "/header"
+  expect:
+    status: 200
+- step: "query"
+  request:
+    url: "/query"
+    params:
+      page: "2"
+    auth:
+      api_key:
+        name: "api_key"
+        value: "k-456"
+        in: "query"
+  expect:
+    status: 200
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
@@ -0,0 +1,154 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// BenchMetrics lists the latency statistics CompareBench can compare.
+var BenchMetrics = []string{"min", "mean", "p50", "p95", "p99", "max"}
+
+// BenchOptions configures CompareBench.
+type BenchOptions struct {
+	// Metric is the latency statistic to compare, one of BenchMetrics.
+	Metric string
+	// Threshold is the percentage increase in Metric that counts as a
+	// regression.
+	Threshold float64
+}
+
+// BenchComparison is the result of comparing two JSON reports.
+type BenchComparison struct {
+	Metric      string       `json:"metric"`
+	Threshold   float64      `json:"threshold_percent"`
+	Steps       []BenchDelta `json:"steps"`
+	Regressions int          `json:"regressions"`
+}
+
+// BenchDelta compares one step across two reports. Base or Current is nil
+// when the step only appears in the other report.
+type BenchDelta struct {
+	Step             string        `json:"step"`
+	Base             *LatencyStats `json:"base,omitempty"`
+	Current          *LatencyStats `json:"current,omitempty"`
+	BaseErrorRate    float64       `json:"base_error_rate"`
+	CurrentErrorRate float64       `json:"current_error_rate"`
+	// ChangePercent is the change in the compared metric.
+	ChangePercent float64 `json:"change_percent"`
+	// NewlyFailing is set when the step failed in the current report but
+	// never in the base.
+	NewlyFailing bool `json:"newly_failing"`
+	Regressed    bool `json:"regressed"`
+}
+
+// benchReport is the part of a load test or soak run JSON report that
+// CompareBench reads.
+type benchReport struct {
+	Steps []struct {
+		File     string `json:"file"`
+		Name     string `json:"name"`
+		Requests int    `json:"requests"`
+		Failed   int    `json:"failed"`
+		LatencyStats
+	} `json:"steps"`
+}
+
+// CompareBench compares the per-step latency and failures of two JSON
+// reports written by ramjam load --json. A step regresses when its metric
+// grows by more than the threshold, or when it starts failing.
+func CompareBench(basePath, currentPath string, opts BenchOptions) (*BenchComparison, error) {
+	if !slices.Contains(BenchMetrics, opts.Metric) {
+		return nil, fmt.Errorf("unknown metric %q (expected %s)", opts.Metric, strings.Join(BenchMetrics, ", "))
+	}
+	base, err := readBenchReport(basePath)
+	if err != nil {
+		return nil, err
+	}
+	current, err := readBenchReport(currentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := &BenchComparison{Metric: opts.Metric, Threshold: opts.Threshold}
+	index := map[string]int{}
+	delta := func(name string) *BenchDelta {
+		if i, ok := index[name]; ok {
+			return &cmp.Steps[i]
+		}
+		index[name] = len(cmp.Steps)
+		cmp.Steps = append(cmp.Steps, BenchDelta{Step: name})
+		return &cmp.Steps[len(cmp.Steps)-1]
+	}
+	for _, s := range base.Steps {
+		d := delta(benchStepName(s.File, s.Name))
+		stats := s.LatencyStats
+		d.Base, d.BaseErrorRate = &stats, rate(s.Failed, s.Requests)
+	}
+	for _, s := range current.Steps {
+		d := delta(benchStepName(s.File, s.Name))
+		stats := s.LatencyStats
+		d.Current, d.CurrentErrorRate = &stats, rate(s.Failed, s.Requests)
+	}
+
+	for i := range cmp.Steps {
+		d := &cmp.Steps[i]
+		if d.Base == nil || d.Current == nil {
+			continue
+		}
+		before, after := d.Base.Metric(opts.Metric), d.Current.Metric(opts.Metric)
+		if before > 0 {
+			d.ChangePercent = (after - before) / before * 100
+		}
+		d.NewlyFailing = d.BaseErrorRate == 0 && d.CurrentErrorRate > 0
+		d.Regressed = d.NewlyFailing || d.ChangePercent > opts.Threshold
+		if d.Regressed {
+			cmp.Regressions++
+		}
+	}
+	return cmp, nil
+}
+
+func readBenchReport(path string) (*benchReport, error) {
+	data, err := os.ReadFile(path)
+	if err := e.Wrapf(err, "read %s", path); err != nil {
+		return nil, err
+	}
+	var report benchReport
+	if err := e.Wrapf(json.Unmarshal(data, &report), "parse %s", path); err != nil {
+		return nil, err
+	}
+	if len(report.Steps) == 0 {
+		return nil, fmt.Errorf("%s has no step results; write one with ramjam load --json", path)
+	}
+	return &report, nil
+}
+
+func benchStepName(file, step string) string {
+	if file == "" {
+		return step
+	}
+	return file + " > " + step
+}
+
+// Metric returns the statistic called name, one of BenchMetrics.
+func (s LatencyStats) Metric(name string) float64 {
+	switch name {
+	case "min":
+		return s.MinMS
+	case "mean":
+		return s.MeanMS
+	case "p50":
+		return s.P50MS
+	case "p95":
+		return s.P95MS
+	case "p99":
+		return s.P99MS
+	default:
+		return s.MaxMS
+	}
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareBench(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.json")
+	os.WriteFile(base, []byte(`{"steps": [
+		{"name": "create cart", "requests": 100, "failed": 0, "p95_ms": 80, "mean_ms": 50},
+		{"name": "add item", "requests": 100, "failed": 0, "p95_ms": 100, "mean_ms": 60},
+		{"name": "checkout", "requests": 100, "failed": 2, "p95_ms": 90, "mean_ms": 70},
+		{"name": "legacy", "requests": 100, "failed": 0, "p95_ms": 10}
+	]}`), 0644)
+	current := filepath.Join(dir, "current.json")
+	os.WriteFile(current, []byte(`{"steps": [
+		{"name": "create cart", "requests": 100, "failed": 0, "p95_ms": 96, "mean_ms": 52},
+		{"name": "add item", "requests": 100, "failed": 3, "p95_ms": 95, "mean_ms": 61},
+		{"name": "checkout", "requests": 100, "failed": 5, "p95_ms": 92, "mean_ms": 70},
+		{"name": "search", "requests": 100, "failed": 0, "p95_ms": 40}
+	]}`), 0644)
+
+	cmp, err := CompareBench(base, current, BenchOptions{Metric: "p95", Threshold: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmp.Steps) != 5 || cmp.Regressions != 2 {
+		t.Fatalf("comparison = %+v", cmp)
+	}
+	steps := map[string]BenchDelta{}
+	for _, d := range cmp.Steps {
+		steps[d.Step] = d
+	}
+	if d := steps["create cart"]; d.ChangePercent != 20 || !d.Regressed || d.NewlyFailing {
+		t.Errorf("create cart = %+v", d)
+	}
+	if d := steps["add item"]; d.ChangePercent != -5 || !d.Regressed || !d.NewlyFailing || d.CurrentErrorRate != 0.03 {
+		t.Errorf("add item = %+v", d)
+	}
+	// Already failing steps and small changes are not regressions.
+	if d := steps["checkout"]; d.Regressed || d.NewlyFailing {
+		t.Errorf("checkout = %+v", d)
+	}
+	if d := steps["legacy"]; d.Current != nil || d.Regressed {
+		t.Errorf("legacy = %+v", d)
+	}
+	if d := steps["search"]; d.Base != nil || d.Regressed {
+		t.Errorf("search = %+v", d)
+	}
+
+	cmp, _ = CompareBench(base, current, BenchOptions{Metric: "mean", Threshold: 1})
+	if d := cmp.Steps[0]; d.ChangePercent != 4 || !d.Regressed {
+		t.Errorf("create cart mean = %+v", d)
+	}
+}
+
+func TestCompareBenchErrors(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.json")
+	os.WriteFile(empty, []byte(`{"status": "passed"}`), 0644)
+
+	tests := []struct {
+		base, current, metric, want string
+	}{
+		{empty, empty, "p90", `unknown metric "p90"`},
+		{filepath.Join(dir, "missing.json"), empty, "p95", "read "},
+		{empty, empty, "p95", "has no step results"},
+	}
+	for _, tt := range tests {
+		_, err := CompareBench(tt.base, tt.current, BenchOptions{Metric: tt.metric})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("CompareBench(%s, %s, %s) error = %v, want %q", tt.base, tt.current, tt.metric, err, tt.want)
+		}
+	}
+}
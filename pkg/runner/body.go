@@ -0,0 +1,241 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// MultipartPart is a single field of a multipart/form-data request. A part
+// carries either an inline value or the contents of a file.
+type MultipartPart struct {
+	Name        string `yaml:"name"`
+	Value       string `yaml:"value,omitempty"`
+	File        string `yaml:"file,omitempty"`
+	Filename    string `yaml:"filename,omitempty"`
+	ContentType string `yaml:"content_type,omitempty"`
+}
+
+// GraphQLRequest describes a GraphQL operation sent as a JSON POST body.
+type GraphQLRequest struct {
+	Query         string                 `yaml:"query"`
+	Variables     map[string]interface{} `yaml:"variables,omitempty"`
+	OperationName string                 `yaml:"operation_name,omitempty"`
+}
+
+const defaultRawContentType = "text/plain; charset=utf-8"
+
+// buildBody returns the request body for a step along with the Content-Type
+// it should be sent with. A nil reader means the request has no body.
+func (r *Runner) buildBody(step Step, vars map[string]string, log func(string, ...interface{})) (io.Reader, string, error) {
+	req := step.Request
+	body, contentType, err := r.encodeBody(req, step.file.baseDir, vars, log)
+	if err != nil || body == nil {
+		return body, contentType, err
+	}
+	if req.ContentType != "" {
+		if contentType, err = r.applyVars(req.ContentType, vars); err != nil {
+			if c, ok := body.(io.Closer); ok {
+				c.Close()
+			}
+			return nil, "", err
+		}
+	}
+	return body, contentType, nil
+}
+
+func (r *Runner) encodeBody(req StepRequest, baseDir string, vars map[string]string, log func(string, ...interface{})) (io.Reader, string, error) {
+	if err := checkSingleBody(req); err != nil {
+		return nil, "", err
+	}
+
+	if req.GraphQL != nil {
+		return r.graphQLBody(req.GraphQL, vars)
+	}
+
+	if req.BodyRaw != "" {
+		raw, err := r.applyVars(req.BodyRaw, vars)
+		if err != nil {
+			return nil, "", err
+		}
+		return strings.NewReader(raw), defaultRawContentType, nil
+	}
+
+	if len(req.Multipart) > 0 {
+		return r.multipartBody(req, baseDir, vars, log)
+	}
+
+	if len(req.Form) > 0 {
+		form := url.Values{}
+		for key, value := range req.Form {
+			value, err := r.applyVars(value, vars)
+			if err != nil {
+				return nil, "", err
+			}
+			form.Set(key, value)
+		}
+		return strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil
+	}
+
+	if len(req.bodyData) > 0 {
+		body, err := r.applyVarsToInterface(req.bodyData, vars)
+		if err != nil {
+			return nil, "", err
+		}
+		payload, err := json.Marshal(body)
+		if err := e.Wrap(err, "marshal body"); err != nil {
+			return nil, "", err
+		}
+		if r.verbose() && req.bodySource != "" {
+			log("Using body from: %s", req.bodySource)
+		}
+		return bytes.NewReader(payload), "application/json", nil
+	}
+
+	return nil, "", nil
+}
+
+func (r *Runner) graphQLBody(gql *GraphQLRequest, vars map[string]string) (io.Reader, string, error) {
+	if strings.TrimSpace(gql.Query) == "" {
+		return nil, "", fmt.Errorf("graphql request must specify a query")
+	}
+	query, err := r.applyVars(gql.Query, vars)
+	if err != nil {
+		return nil, "", err
+	}
+	payload := map[string]interface{}{
+		"query": query,
+	}
+	if len(gql.Variables) > 0 {
+		if payload["variables"], err = r.applyVarsToInterface(gql.Variables, vars); err != nil {
+			return nil, "", err
+		}
+	}
+	if gql.OperationName != "" {
+		if payload["operationName"], err = r.applyVars(gql.OperationName, vars); err != nil {
+			return nil, "", err
+		}
+	}
+	data, err := json.Marshal(payload)
+	if err := e.Wrap(err, "marshal graphql body"); err != nil {
+		return nil, "", err
+	}
+	return bytes.NewReader(data), "application/json", nil
+}
+
+// checkSingleBody rejects requests that declare more than one kind of body.
+func checkSingleBody(req StepRequest) error {
+	var kinds []string
+	if len(req.bodyData) > 0 {
+		kinds = append(kinds, "body")
+	}
+	if req.BodyRaw != "" {
+		kinds = append(kinds, "body_raw")
+	}
+	if req.GraphQL != nil {
+		kinds = append(kinds, "graphql")
+	}
+	if len(req.Form) > 0 {
+		kinds = append(kinds, "form")
+	}
+	if len(req.Multipart) > 0 {
+		kinds = append(kinds, "multipart")
+	}
+	if len(kinds) > 1 {
+		return fmt.Errorf("request must specify only one body type, got %s", strings.Join(kinds, " and "))
+	}
+	return nil
+}
+
+// multipartBody streams the parts through a pipe so that uploaded files are
+// never read fully into memory.
+func (r *Runner) multipartBody(req StepRequest, baseDir string, vars map[string]string, log func(string, ...interface{})) (io.Reader, string, error) {
+	parts := make([]MultipartPart, len(req.Multipart))
+	for i, part := range req.Multipart {
+		if part.Name == "" {
+			return nil, "", fmt.Errorf("multipart part %d must specify a name", i)
+		}
+		if part.File != "" && part.Value != "" {
+			return nil, "", fmt.Errorf("multipart part %s must specify value or file, not both", part.Name)
+		}
+		var err error
+		if part.Value, err = r.applyVars(part.Value, vars); err != nil {
+			return nil, "", err
+		}
+		if part.File != "" {
+			if part.File, err = r.applyVars(part.File, vars); err != nil {
+				return nil, "", err
+			}
+			if !filepath.IsAbs(part.File) {
+				part.File = filepath.Join(baseDir, part.File)
+			}
+			if _, err := os.Stat(part.File); err != nil {
+				return nil, "", e.Wrapf(err, "multipart file for %s", part.Name)
+			}
+			if r.verbose() {
+				log("Attaching file %s as %s", part.File, part.Name)
+			}
+		}
+		parts[i] = part
+	}
+
+	// The parts are written as the request is sent, so large files aren't
+	// held in memory. Closing pr stops the writer.
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeMultipart(mw, parts))
+	}()
+	return pr, mw.FormDataContentType(), nil
+}
+
+func writeMultipart(mw *multipart.Writer, parts []MultipartPart) error {
+	for _, part := range parts {
+		if part.File == "" {
+			if err := mw.WriteField(part.Name, part.Value); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeMultipartFile(mw, part); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+func writeMultipartFile(mw *multipart.Writer, part MultipartPart) error {
+	f, err := os.Open(part.File)
+	if err := e.Wrapf(err, "open multipart file %s", part.File); err != nil {
+		return err
+	}
+	defer f.Close()
+
+	filename := part.Filename
+	if filename == "" {
+		filename = filepath.Base(part.File)
+	}
+	contentType := part.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, part.Name, filename))
+	header.Set("Content-Type", contentType)
+	w, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
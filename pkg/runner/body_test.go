@@ -0,0 +1,309 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMultipartUpload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data; boundary=") {
+			t.Errorf("unexpected content type %s", r.Header.Get("Content-Type"))
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("parse multipart: %v", err)
+		}
+		if got := r.FormValue("origin"); got != "http://"+r.Host {
+			t.Errorf("expected substituted origin field, got %s", got)
+		}
+		file, header, err := r.FormFile("upload")
+		if err != nil {
+			t.Fatalf("form file: %v", err)
+		}
+		defer file.Close()
+		if header.Filename != "data.csv" {
+			t.Errorf("expected filename data.csv, got %s", header.Filename)
+		}
+		if ct := header.Header.Get("Content-Type"); ct != "text/csv" {
+			t.Errorf("expected part content type text/csv, got %s", ct)
+		}
+		data, _ := io.ReadAll(file)
+		if string(data) != "id,name\n1,alice\n" {
+			t.Errorf("unexpected file content %q", data)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "data.csv"), []byte("id,name\n1,alice\n"), 0644); err != nil {
+		t.Fatalf("failed to write upload file: %v", err)
+	}
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Multipart Upload"
+config:
+  base_url: "%s"
+workflow:
+- step: "upload"
+  request:
+    method: "POST"
+    url: "/upload"
+    multipart:
+    - name: "origin"
+      value: "${base_url}"
+    - name: "upload"
+      file: "data.csv"
+      content_type: "text/csv"
+  expect:
+    status: 201
+`, srv.URL)
+
+	yamlFilePath := filepath.Join(tmpDir, "upload.yaml")
+	if err := os.WriteFile(yamlFilePath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write yaml file: %v", err)
+	}
+
+	r := New(10*time.Second, true)
+	if err := r.RunPaths([]string{yamlFilePath}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+}
+
+func TestMultipartMissingFile(t *testing.T) {
+	yamlContent := `
+metadata:
+  name: "Multipart Missing"
+workflow:
+- step: "upload"
+  request:
+    method: "POST"
+    url: "http://127.0.0.1:1/upload"
+    multipart:
+    - name: "upload"
+      file: "does-not-exist.bin"
+`
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), "multipart file for upload") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestMultipartBodyClosedOnEarlyError(t *testing.T) {
+	upload := filepath.Join(t.TempDir(), "upload.bin")
+	os.WriteFile(upload, []byte(strings.Repeat("x", 64<<10)), 0644)
+	before := runtime.NumGoroutine()
+
+	err := runTestError(t, fmt.Sprintf(`
+workflow:
+- step: "upload"
+  request:
+    method: "POST"
+    url: "http://127.0.0.1:1/upload"
+    auth:
+      basic:
+        username: ""
+    multipart:
+    - name: "upload"
+      file: %q
+`, upload))
+	if err == nil || !strings.Contains(err.Error(), "basic auth requires a username") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForGoroutines(t, before)
+}
+
+// waitForGoroutines fails t if the number of goroutines doesn't fall back
+// to n, as it should once a failed step's body writer has stopped.
+func waitForGoroutines(t *testing.T, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if runtime.NumGoroutine() <= n {
+			return
+		}
+	}
+	t.Errorf("expected %d goroutines, got %d", n, runtime.NumGoroutine())
+}
+
+func TestFormURLEncodedBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/client" {
+			w.Write([]byte(`{"suffix": "123"}`))
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+			t.Errorf("unexpected content type %s", ct)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("parse form: %v", err)
+		}
+		if got := r.PostForm.Get("grant_type"); got != "client_credentials" {
+			t.Errorf("expected grant_type client_credentials, got %s", got)
+		}
+		if got := r.PostForm.Get("scope"); got != "read write" {
+			t.Errorf("expected scope 'read write', got %s", got)
+		}
+		if got := r.PostForm.Get("client_id"); got != "abc-123" {
+			t.Errorf("expected client_id abc-123, got %s", got)
+		}
+		w.Write([]byte(`{"access_token": "tok"}`))
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Form Body"
+config:
+  base_url: "%s"
+workflow:
+- step: "client-id"
+  request:
+    url: "/client"
+  capture:
+  - json_path: "suffix"
+    as: "suffix"
+- step: "token"
+  request:
+    method: "POST"
+    url: "/token"
+    form:
+      grant_type: "client_credentials"
+      scope: "read write"
+      client_id: "abc-${suffix}"
+  expect:
+    status: 200
+    json_path_match:
+    - path: "access_token"
+      value: "tok"
+`, srv.URL)
+	runTest(t, yamlContent)
+}
+
+func TestMultipleBodyTypesRejected(t *testing.T) {
+	yamlContent := `
+metadata:
+  name: "Conflicting Bodies"
+workflow:
+- step: "conflict"
+  request:
+    method: "POST"
+    url: "http://127.0.0.1:1/"
+    body:
+      a: "b"
+    form:
+      c: "d"
+`
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), "only one body type, got body and form") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRawBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/notes":
+			if ct := r.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+				t.Errorf("expected default text/plain content type, got %s", ct)
+			}
+			if string(body) != "hello from "+"http://"+r.Host {
+				t.Errorf("unexpected raw body %q", body)
+			}
+		case "/events":
+			if ct := r.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+				t.Errorf("expected application/x-ndjson, got %s", ct)
+			}
+			if string(body) != "{\"id\":1}\n{\"id\":2}\n" {
+				t.Errorf("unexpected ndjson body %q", body)
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Raw Body"
+config:
+  base_url: "%s"
+workflow:
+- step: "plain-text"
+  request:
+    method: "POST"
+    url: "/notes"
+    body_raw: "hello from ${base_url}"
+  expect:
+    status: 202
+- step: "ndjson"
+  request:
+    method: "POST"
+    url: "/events"
+    content_type: "application/x-ndjson"
+    body_raw: |
+      {"id":1}
+      {"id":2}
+  expect:
+    status: 202
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
+
+func TestGraphQLRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected application/json, got %s", ct)
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodyStr := string(body)
+		if !strings.Contains(bodyStr, `"query":"query GetUser($id: ID!) { user(id: $id) { name } }"`) {
+			t.Errorf("query mismatch: %s", bodyStr)
+		}
+		if !strings.Contains(bodyStr, `"variables":{"id":"7"}`) {
+			t.Errorf("variables mismatch: %s", bodyStr)
+		}
+		if !strings.Contains(bodyStr, `"operationName":"GetUser"`) {
+			t.Errorf("operationName mismatch: %s", bodyStr)
+		}
+		w.Write([]byte(`{"data": {"user": {"name": "Ada"}}, "errors": [{"message": "deprecated field"}, {"message": "slow query"}]}`))
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "GraphQL"
+config:
+  base_url: "%s"
+workflow:
+- step: "get-user"
+  request:
+    url: "/graphql"
+    graphql:
+      query: "query GetUser($id: ID!) { user(id: $id) { name } }"
+      operation_name: "GetUser"
+      variables:
+        id: "7"
+  expect:
+    status: 200
+    json_path_match:
+    - path: "data.user.name"
+      value: "Ada"
+    - path: "errors[*].message"
+      value: "[deprecated field slow query]"
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
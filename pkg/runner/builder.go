@@ -0,0 +1,200 @@
+package runner
+
+import "time"
+
+// WorkflowBuilder builds a workflow in code, for Go tests that would rather
+// not write YAML files:
+//
+//	wf := runner.NewWorkflow().Name("Users").BaseURL(srv.URL).
+//		Step("create").Request("POST", "/users").Body(map[string]interface{}{"name": "Ada"}).
+//		ExpectStatus(201).Capture("id", "user_id").
+//		Step("fetch").Request("GET", "/users/${user_id}").ExpectStatus(200).
+//		Build()
+//	result, err := r.RunWorkflow(ctx, wf)
+//
+// Methods named after a request or expect field apply to the step added by
+// the most recent call to Step, and panic if there is none.
+type WorkflowBuilder struct {
+	wf InstructionsFile
+}
+
+// NewWorkflow starts an empty workflow.
+func NewWorkflow() *WorkflowBuilder {
+	return &WorkflowBuilder{}
+}
+
+// Build returns the workflow.
+func (b *WorkflowBuilder) Build() *InstructionsFile {
+	wf := b.wf
+	return &wf
+}
+
+// Name sets metadata.name, which reports show in place of a file name.
+func (b *WorkflowBuilder) Name(name string) *WorkflowBuilder {
+	b.wf.Metadata.Name = name
+	return b
+}
+
+// BaseURL sets config.base_url.
+func (b *WorkflowBuilder) BaseURL(url string) *WorkflowBuilder {
+	b.wf.Config.BaseURL = url
+	return b
+}
+
+// Header sets a config.headers entry, sent with every request.
+func (b *WorkflowBuilder) Header(name, value string) *WorkflowBuilder {
+	if b.wf.Config.Headers == nil {
+		b.wf.Config.Headers = map[string]string{}
+	}
+	b.wf.Config.Headers[name] = value
+	return b
+}
+
+// Var sets a variable before the first step, as the file's vars block
+// would. --var and profile variables override it.
+func (b *WorkflowBuilder) Var(name, value string) *WorkflowBuilder {
+	if b.wf.Vars == nil {
+		b.wf.Vars = map[string]string{}
+	}
+	b.wf.Vars[name] = value
+	return b
+}
+
+// StepVar sets a variable for the current step only.
+func (b *WorkflowBuilder) StepVar(name, value string) *WorkflowBuilder {
+	step := b.step()
+	if step.Vars == nil {
+		step.Vars = map[string]string{}
+	}
+	step.Vars[name] = value
+	return b
+}
+
+// Step adds a step with the given name.
+func (b *WorkflowBuilder) Step(name string) *WorkflowBuilder {
+	b.wf.Workflow = append(b.wf.Workflow, Step{Step: name})
+	return b
+}
+
+// Describe sets the current step's description.
+func (b *WorkflowBuilder) Describe(description string) *WorkflowBuilder {
+	b.step().Description = description
+	return b
+}
+
+// Needs adds earlier steps that run with the current step when it is
+// picked with WithSteps.
+func (b *WorkflowBuilder) Needs(steps ...string) *WorkflowBuilder {
+	step := b.step()
+	step.Needs = append(step.Needs, steps...)
+	return b
+}
+
+// When sets the condition the current step runs on, such as
+// "page < total_pages".
+func (b *WorkflowBuilder) When(condition string) *WorkflowBuilder {
+	b.step().When = condition
+	return b
+}
+
+// Request sets the current step's method and URL.
+func (b *WorkflowBuilder) Request(method, url string) *WorkflowBuilder {
+	step := b.step()
+	step.Request.Method, step.Request.URL = method, url
+	return b
+}
+
+// RequestHeader sets a header on the current step's request.
+func (b *WorkflowBuilder) RequestHeader(name, value string) *WorkflowBuilder {
+	step := b.step()
+	if step.Request.Headers == nil {
+		step.Request.Headers = map[string]string{}
+	}
+	step.Request.Headers[name] = value
+	return b
+}
+
+// Param sets a query parameter on the current step's request.
+func (b *WorkflowBuilder) Param(name, value string) *WorkflowBuilder {
+	step := b.step()
+	if step.Request.Params == nil {
+		step.Request.Params = map[string]string{}
+	}
+	step.Request.Params[name] = value
+	return b
+}
+
+// Body sets the current step's JSON request body.
+func (b *WorkflowBuilder) Body(body map[string]interface{}) *WorkflowBuilder {
+	b.step().Request.Body = body
+	return b
+}
+
+// ExpectStatus expects the current step's response to have the given
+// status.
+func (b *WorkflowBuilder) ExpectStatus(status int) *WorkflowBuilder {
+	b.step().Expect.Status = status
+	return b
+}
+
+// ExpectJSONPath expects the value at path in the current step's response
+// to equal value.
+func (b *WorkflowBuilder) ExpectJSONPath(path string, value interface{}) *WorkflowBuilder {
+	step := b.step()
+	step.Expect.JSONPathMatch = append(step.Expect.JSONPathMatch, JSONPathVal{Path: path, Value: value})
+	return b
+}
+
+// ExpectHeader expects the current step's response header to equal value.
+func (b *WorkflowBuilder) ExpectHeader(name, value string) *WorkflowBuilder {
+	step := b.step()
+	step.Expect.Headers = append(step.Expect.Headers, HeaderExpectation{Name: name, Value: value})
+	return b
+}
+
+// ExpectBodyContains expects the current step's response body to contain
+// text.
+func (b *WorkflowBuilder) ExpectBodyContains(text string) *WorkflowBuilder {
+	b.step().Expect.BodyContains = text
+	return b
+}
+
+// ExpectMaxDuration expects the current step's response within d.
+func (b *WorkflowBuilder) ExpectMaxDuration(d time.Duration) *WorkflowBuilder {
+	b.step().Expect.MaxDuration = d
+	return b
+}
+
+// ExpectCustom runs the assertion registered as name with arg on the
+// current step's response.
+func (b *WorkflowBuilder) ExpectCustom(name string, arg interface{}) *WorkflowBuilder {
+	step := b.step()
+	if step.Expect.Custom == nil {
+		step.Expect.Custom = map[string]interface{}{}
+	}
+	step.Expect.Custom[name] = arg
+	return b
+}
+
+// Capture stores the value at jsonPath in the current step's response as
+// the variable as.
+func (b *WorkflowBuilder) Capture(jsonPath, as string) *WorkflowBuilder {
+	step := b.step()
+	step.Capture = append(step.Capture, Capture{JSONPath: jsonPath, As: as})
+	return b
+}
+
+// CaptureHeader stores the current step's response header as the variable
+// as.
+func (b *WorkflowBuilder) CaptureHeader(header, as string) *WorkflowBuilder {
+	step := b.step()
+	step.Capture = append(step.Capture, Capture{Header: header, As: as})
+	return b
+}
+
+func (b *WorkflowBuilder) step() *Step {
+	if len(b.wf.Workflow) == 0 {
+		panic("runner: WorkflowBuilder.Step must be called before setting step fields")
+	}
+	return &b.wf.Workflow[len(b.wf.Workflow)-1]
+}
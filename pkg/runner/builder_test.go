@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunWorkflow(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/nope" && r.Header.Get("X-Team") != "qa" {
+			t.Errorf("expected X-Team header qa, got %q", r.Header.Get("X-Team"))
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/users":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["name"] != "Ada" {
+				t.Errorf("expected name Ada in body, got %v", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 7}`))
+		case r.URL.Path == "/users/7":
+			if r.URL.Query().Get("fields") != "name" {
+				t.Errorf("expected fields=name, got %q", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"name": "Ada", "org": "acme"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	wf := NewWorkflow().Name("Users").BaseURL(srv.URL).Header("X-Team", "qa").Var("org", "acme").
+		Step("create").Request("POST", "/users").Body(map[string]interface{}{"name": "Ada"}).
+		ExpectStatus(201).Capture("id", "user_id").
+		Step("fetch").Request("GET", "/users/${user_id}").Param("fields", "name").
+		ExpectStatus(200).ExpectJSONPath("name", "Ada").ExpectJSONPath("org", "${org}").
+		Build()
+
+	r := New(5*time.Second, false)
+	r.out = io.Discard
+	result, err := r.RunWorkflow(context.Background(), wf)
+	if err != nil {
+		t.Fatalf("RunWorkflow() error = %v", err)
+	}
+	if len(result.Files) != 1 || result.Files[0].Name != "Users" || result.Passed != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if got := result.Files[0].Vars["user_id"]; got != "7" {
+		t.Errorf("expected captured user_id 7, got %q", got)
+	}
+
+	wf = NewWorkflow().Step("missing").Request("GET", srv.URL+"/nope").ExpectStatus(200).Build()
+	result, err = r.RunWorkflow(context.Background(), wf)
+	if err == nil || result.Failed != 1 || result.Files[0].Path != "workflow" {
+		t.Errorf("expected one failed step in an unnamed workflow, got %+v, %v", result, err)
+	}
+}
+
+func TestWorkflowBuilderNeedsStep(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic when setting a step field before Step")
+		}
+	}()
+	NewWorkflow().ExpectStatus(200)
+}
+
+func TestRunWorkflowTwice(t *testing.T) {
+	var names []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			User struct{ Name string }
+			Tags []string
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		names = append(names, body.User.Name+","+body.Tags[0])
+	}))
+	defer srv.Close()
+
+	wf := NewWorkflow().BaseURL(srv.URL).
+		Step("create").Request("POST", "/users").Body(map[string]interface{}{
+		"user": map[string]interface{}{"name": "${name}"},
+		"tags": []interface{}{"${name}"},
+	}).Build()
+
+	for _, name := range []string{"alice", "bob"} {
+		r := New(5*time.Second, false, WithVars(map[string]string{"name": name}))
+		r.out = io.Discard
+		if _, err := r.RunWorkflow(context.Background(), wf); err != nil {
+			t.Fatalf("RunWorkflow() error = %v", err)
+		}
+	}
+	if len(names) != 2 || names[0] != "alice,alice" || names[1] != "bob,bob" {
+		t.Errorf("expected each run to substitute its own name, got %q", names)
+	}
+}
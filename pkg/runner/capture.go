@@ -0,0 +1,199 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// Capture modes. A capture normally takes the first match, but with
+// mode: all it stores every match as a JSON array, which later
+// substitutions can index as ${ids[2]}.
+const (
+	CaptureFirst = "first"
+	CaptureAll   = "all"
+)
+
+// captureAll returns every value c matches, as a JSON array.
+func captureAll(c Capture, jsonObj interface{}, header http.Header) (string, error) {
+	var all []interface{}
+	switch {
+	case c.JSONPath != "":
+		matches, err := evalJSONPathAll(jsonObj, c.JSONPath)
+		if err := e.Wrapf(err, "capture json_path %s", c.JSONPath); err != nil {
+			return "", err
+		}
+		all = matches
+	case c.Header != "":
+		var re *regexp.Regexp
+		if c.Regex != "" {
+			var err error
+			if re, err = regexp.Compile(c.Regex); err != nil {
+				return "", e.Wrapf(err, "invalid regex %s", c.Regex)
+			}
+		}
+		for _, v := range header.Values(c.Header) {
+			if re == nil {
+				all = append(all, v)
+				continue
+			}
+			// As with a single capture, the first group if there is one.
+			for _, m := range re.FindAllStringSubmatch(v, -1) {
+				all = append(all, m[min(1, len(m)-1)])
+			}
+		}
+	default:
+		return "", fmt.Errorf("capture must specify json_path, header or an aggregate such as length_of")
+	}
+	if all == nil {
+		all = []interface{}{}
+	}
+	data, err := json.Marshal(all)
+	return string(data), err
+}
+
+// evalJSONPathAll returns every value path matches: each object a filter
+// selects, with the rest of the path applied to it, each element a
+// wildcard selects, or the elements of an array the path points to. A
+// filter that matches nothing and a null give no values, but a path
+// through a key that isn't there is an error.
+func evalJSONPathAll(obj interface{}, path string) ([]interface{}, error) {
+	p := strings.TrimSpace(path)
+	if m := jsonPathFilter.FindStringSubmatch(p); m != nil {
+		matches, err := filterJSON(obj, path, m[1], m[2])
+		if err != nil || m[3] == "" {
+			return matches, err
+		}
+		all := make([]interface{}, 0, len(matches))
+		for _, match := range matches {
+			v, err := lookupJSONPath(match, m[3], false)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, v)
+		}
+		return all, nil
+	}
+	v, err := lookupJSONPath(obj, p, false)
+	switch arr := v.(type) {
+	case nil:
+		return nil, err
+	case []interface{}:
+		return arr, nil
+	}
+	return []interface{}{v}, nil
+}
+
+var indexExpr = regexp.MustCompile(`^([^\[\]\s]+)(\[[0-9]+\].*)$`)
+
+// indexVar evaluates name[i], or a path such as name[i].id, where the
+// variable name holds a JSON array, such as one captured with mode: all.
+func indexVar(expr string, vars map[string]string) (string, bool) {
+	m := indexExpr.FindStringSubmatch(expr)
+	if m == nil {
+		return "", false
+	}
+	value, ok := vars[m[1]]
+	if !ok {
+		return "", false
+	}
+	var doc interface{}
+	if json.Unmarshal([]byte(value), &doc) != nil {
+		return "", false
+	}
+	v, err := evalJSONPath(doc, "$"+m[2])
+	if err != nil {
+		return "", false
+	}
+	switch v.(type) {
+	case []interface{}, map[string]interface{}:
+		data, err := json.Marshal(v)
+		return string(data), err == nil
+	}
+	return fmt.Sprint(v), true
+}
+
+// Aggregate computes one number from the values a JSONPath matches, for a
+// capture or a json_path_match. Only one of its fields may be set.
+type Aggregate struct {
+	// LengthOf counts the values, so length_of: items is the number of
+	// items.
+	LengthOf string `yaml:"length_of,omitempty"`
+	SumOf    string `yaml:"sum_of,omitempty"`
+	MinOf    string `yaml:"min_of,omitempty"`
+	MaxOf    string `yaml:"max_of,omitempty"`
+}
+
+// fields returns a's fields with their names.
+func (a Aggregate) fields() []struct{ name, path string } {
+	return []struct{ name, path string }{
+		{"length_of", a.LengthOf},
+		{"sum_of", a.SumOf},
+		{"min_of", a.MinOf},
+		{"max_of", a.MaxOf},
+	}
+}
+
+// aggregate returns the aggregate a asks for, such as length_of, and the
+// JSONPath it is over, or "" if it asks for none.
+func (a Aggregate) aggregate() (string, string) {
+	for _, f := range a.fields() {
+		if f.path != "" {
+			return f.name, f.path
+		}
+	}
+	return "", ""
+}
+
+// check rejects an Aggregate with more than one field set.
+func (a Aggregate) check() error {
+	var set []string
+	for _, f := range a.fields() {
+		if f.path != "" {
+			set = append(set, f.name)
+		}
+	}
+	if len(set) > 1 {
+		return fmt.Errorf("only one aggregate may be set, got %s", strings.Join(set, " and "))
+	}
+	return nil
+}
+
+// aggregate computes the aggregate agg over the values path matches in
+// jsonObj. An array the path points to counts as its elements, so
+// length_of: items is the number of items.
+func aggregate(agg, path string, jsonObj interface{}) (string, error) {
+	values, err := evalJSONPathAll(jsonObj, path)
+	if err != nil {
+		return "", err
+	}
+	if agg == "length_of" {
+		return strconv.Itoa(len(values)), nil
+	}
+	if len(values) == 0 && agg != "sum_of" {
+		return "", fmt.Errorf("no values")
+	}
+	var result float64
+	for i, v := range values {
+		n, ok := toNumber(v)
+		if !ok {
+			data, _ := json.Marshal(v)
+			return "", fmt.Errorf("%s is not a number", data)
+		}
+		switch {
+		case agg == "sum_of", i == 0:
+			result += n
+		case agg == "min_of":
+			result = math.Min(result, n)
+		case agg == "max_of":
+			result = math.Max(result, n)
+		}
+	}
+	return formatValue(result), nil
+}
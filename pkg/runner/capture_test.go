@@ -0,0 +1,259 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaptureAll(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.String())
+		w.Header().Add("Set-Cookie", "session=abc; Path=/")
+		w.Header().Add("Set-Cookie", "theme=dark; Path=/")
+		w.Write([]byte(`[
+			{"id": 7, "status": "open", "lines": [1, 2]},
+			{"id": 8, "status": "paid", "lines": [3]},
+			{"id": 9, "status": "open", "lines": []}
+		]`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "all.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "list"
+  request:
+    url: "/orders"
+  capture:
+  - json_path: "[*].id"
+    as: "ids"
+    mode: "all"
+  - json_path: "$"
+    as: "orders"
+    mode: "all"
+  - json_path: "$[?(@.status=='open')].id"
+    as: "open"
+    mode: "all"
+  - json_path: "$[?(@.status=='refunded')].id"
+    as: "refunded"
+    mode: "all"
+  - header: "Set-Cookie"
+    regex: "^(\\w+)="
+    as: "cookies"
+    mode: "all"
+- step: "fetch"
+  request:
+    url: "/orders/${ids[1]}?first=${orders[0].id}&lines=${orders[0].lines}&missing=${ids[5]}"
+  output:
+    print: "open ${open}, refunded ${refunded}, cookies ${cookies}"
+`, srv.URL)), 0644)
+
+	r := New(10*time.Second, false)
+	r.out = io.Discard
+	result, err := r.Run(t.Context(), []string{path})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	vars := result.Files[0].Vars
+	for name, want := range map[string]string{
+		"ids":      "[7,8,9]",
+		"open":     "[7,9]",
+		"refunded": "[]",
+		"cookies":  `["session","theme"]`,
+	} {
+		if vars[name] != want {
+			t.Errorf("%s = %q, want %q", name, vars[name], want)
+		}
+	}
+	want := "/orders/8?first=7&lines=[1,2]&missing=${ids[5]}"
+	if len(seen) != 2 || seen[1] != want {
+		t.Errorf("requests = %q, want the second to be %q", seen, want)
+	}
+
+	problems, err := r.Lint([]string{path})
+	if err != nil || len(problems) != 0 {
+		t.Errorf("expected no lint problems, got %v, %v", problems, err)
+	}
+}
+
+func TestCaptureAllPaths(t *testing.T) {
+	doc := map[string]interface{}{"none": nil, "one": 5.0, "items": []interface{}{map[string]interface{}{"id": 1.0}, map[string]interface{}{}}}
+	for _, tt := range []struct {
+		path, want, err string
+	}{
+		{"none", "[]", ""},
+		{"one", "[5]", ""},
+		{"missing", "", "capture json_path missing: no field missing"},
+		{"items[*].id", "", "capture json_path items[*].id: no field id"},
+	} {
+		r := New(10*time.Second, false)
+		vars := map[string]string{}
+		err := r.captureValues([]Capture{{JSONPath: tt.path, As: "x", Mode: CaptureAll}}, doc, nil, vars, nil)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: expected %q, got %v", tt.path, tt.err, err)
+			}
+			if _, ok := vars["x"]; ok {
+				t.Errorf("%s: expected nothing to be captured, got %q", tt.path, vars["x"])
+			}
+			continue
+		}
+		if err != nil || vars["x"] != tt.want {
+			t.Errorf("%s: captured %q, %v, want %q", tt.path, vars["x"], err, tt.want)
+		}
+	}
+}
+
+func TestCaptureAggregates(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items": [{"price": 10.5}, {"price": 4}, {"price": 25}], "tags": [], "names": ["a"]}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "aggregates.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "cart"
+  request:
+    url: "/cart"
+  capture:
+  - length_of: "items"
+    as: "count"
+  - sum_of: "items[*].price"
+    as: "total"
+  - min_of: "items[*].price"
+    as: "cheapest"
+  - max_of: "items[*].price"
+    as: "dearest"
+  - sum_of: "tags"
+    as: "tag_total"
+- step: "summary"
+  when: "count > 0"
+  request:
+    url: "/cart?total=${total}"
+  expect:
+    json_path_match:
+    - length_of: "items"
+      value: 3
+    - sum_of: "items[*].price"
+      value: 39.5
+    - length_of: "tags"
+      value: "${expected_tags}"
+  output:
+    print: "${cheapest} to ${dearest}, tags ${tag_total}"
+`, srv.URL)), 0644)
+
+	r := New(10*time.Second, false, WithVars(map[string]string{"expected_tags": "0"}))
+	r.out = io.Discard
+	result, err := r.Run(t.Context(), []string{path})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	vars := result.Files[0].Vars
+	for name, want := range map[string]string{
+		"count":     "3",
+		"total":     "39.5",
+		"cheapest":  "4",
+		"dearest":   "25",
+		"tag_total": "0",
+	} {
+		if vars[name] != want {
+			t.Errorf("%s = %q, want %q", name, vars[name], want)
+		}
+	}
+	if problems, err := r.Lint([]string{path}); err != nil || len(problems) != 0 {
+		t.Errorf("expected no lint problems, got %v, %v", problems, err)
+	}
+
+	for _, tt := range []struct {
+		agg  Aggregate
+		want string
+	}{
+		{Aggregate{MinOf: "tags"}, "min_of tags: no values"},
+		{Aggregate{MaxOf: "names"}, `max_of names: "a" is not a number`},
+		{Aggregate{SumOf: "missing.field"}, "sum_of missing.field: no field missing"},
+		{Aggregate{LengthOf: "missing"}, "length_of missing: no field missing"},
+		{Aggregate{MinOf: "none"}, "min_of none: no values"},
+		{Aggregate{LengthOf: "tags", SumOf: "tags"}, "capture x: only one aggregate may be set, got length_of and sum_of"},
+	} {
+		r := New(10*time.Second, false)
+		err := r.captureValues([]Capture{{As: "x", Aggregate: tt.agg}}, map[string]interface{}{"tags": []interface{}{}, "names": []interface{}{"a"}, "none": nil}, nil, map[string]string{}, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("aggregate %+v: expected %q, got %v", tt.agg, tt.want, err)
+		}
+	}
+	doc := map[string]interface{}{"none": nil, "one": 5.0, "items": []interface{}{1.0, 2.0}}
+	for _, tt := range []struct {
+		agg  Aggregate
+		want string
+	}{
+		{Aggregate{LengthOf: "none"}, "0"},
+		{Aggregate{SumOf: "none"}, "0"},
+		{Aggregate{LengthOf: "one"}, "1"},
+		{Aggregate{SumOf: "one"}, "5"},
+		{Aggregate{MaxOf: "one"}, "5"},
+		{Aggregate{LengthOf: "items"}, "2"},
+	} {
+		r := New(10*time.Second, false)
+		vars := map[string]string{}
+		if err := r.captureValues([]Capture{{As: "x", Aggregate: tt.agg}}, doc, nil, vars, nil); err != nil || vars["x"] != tt.want {
+			t.Errorf("aggregate %+v = %q, %v, want %q", tt.agg, vars["x"], err, tt.want)
+		}
+	}
+}
+
+func TestAggregateOneField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aggregates.yaml")
+	os.WriteFile(path, []byte(`
+workflow:
+- step: "cart"
+  request:
+    url: "http://localhost/cart"
+  expect:
+    json_path_match:
+    - length_of: "items"
+      max_of: "items[*].price"
+      value: 3
+  capture:
+  - length_of: "items"
+    sum_of: "items[*].price"
+    as: "count"
+  output:
+    print: "${count} items"
+`), 0644)
+
+	r := New(10*time.Second, false)
+	problems, err := r.Lint([]string{path})
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	var messages []string
+	for _, p := range problems {
+		messages = append(messages, p.Message)
+	}
+	want := []string{
+		"only one aggregate may be set, got length_of and max_of",
+		`capture "count": only one aggregate may be set, got length_of and sum_of`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems = %q, want %q", messages, want)
+	}
+
+	doc := map[string]interface{}{"items": []interface{}{}}
+	err = r.matchJSONPaths(doc, []JSONPathVal{{Aggregate: Aggregate{LengthOf: "items", MaxOf: "items"}, Value: 0}}, map[string]string{}, nil)
+	if err == nil || err.Error() != "only one aggregate may be set, got length_of and max_of" {
+		t.Errorf("expected json_path_match to reject two aggregates, got %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// DefaultStateFile is where ramjam run keeps its checkpoint.
+const DefaultStateFile = ".ramjam-state.json"
+
+// WithCheckpoint records the progress of each run in the state file at
+// path: the files that passed, and for the others the steps that passed
+// and the variables they captured. With resume, a run first reads the file
+// and continues from where the previous run stopped, skipping files that
+// passed and steps that passed before the first failure. The file is
+// removed once a run finishes without failures.
+func WithCheckpoint(path string, resume bool) Option {
+	return func(r *Runner) {
+		r.stateFile = path
+		r.resume = resume
+	}
+}
+
+// runState is the content of the state file.
+type runState struct {
+	Files map[string]*fileState `json:"files"`
+	// Globals are the variables captured with scope: global.
+	Globals map[string]string `json:"globals,omitempty"`
+}
+
+// fileState is the progress of one workflow file.
+type fileState struct {
+	// Passed names the leading steps that passed.
+	Passed []string `json:"passed,omitempty"`
+	// Vars are the file's variables after the last of them.
+	Vars map[string]string `json:"vars,omitempty"`
+	// Done is set once every step has passed.
+	Done bool `json:"done,omitempty"`
+
+	failed bool // a step failed in this run, so Passed can't grow
+}
+
+// checkpoint keeps the state file up to date as a run progresses.
+type checkpoint struct {
+	path    string
+	globals *globalVars
+
+	mu    sync.Mutex
+	state runState
+	err   error // the first write error
+}
+
+// loadCheckpoint starts a checkpoint at path for a run with globals. When
+// resuming, the state of the previous run is read from it, if it exists.
+func loadCheckpoint(path string, resume bool, globals *globalVars) (*checkpoint, error) {
+	c := &checkpoint{path: path, globals: globals, state: runState{Files: map[string]*fileState{}}}
+	if !resume {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err := e.Wrapf(err, "read %s", path); err != nil {
+		return nil, err
+	}
+	if err := e.Wrapf(json.Unmarshal(data, &c.state), "parse %s", path); err != nil {
+		return nil, e.Mark(err, e.ParseError)
+	}
+	if c.state.Files == nil {
+		c.state.Files = map[string]*fileState{}
+	}
+	for k, v := range c.state.Globals {
+		globals.set(k, v)
+	}
+	return c, nil
+}
+
+// pending returns the files that still have to run, marking those that
+// passed in the previous run as finished so files importing from them
+// don't wait.
+func (c *checkpoint) pending(files []string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var left []string
+	for _, f := range files {
+		if st := c.state.Files[fileKey(f)]; st != nil && st.Done {
+			c.globals.finished(f, false)
+			continue
+		}
+		left = append(left, f)
+	}
+	return left
+}
+
+// resumeAt returns how many of steps passed in the previous run and the
+// variables they left, or 0 and nil if the file starts afresh, such as when
+// its steps have been renamed or reordered since.
+func (c *checkpoint) resumeAt(path string, steps []Step) (int, map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.file(path)
+	if len(st.Passed) > len(steps) {
+		st.Passed, st.Vars = nil, nil
+		return 0, nil
+	}
+	for i, name := range st.Passed {
+		if steps[i].Step != name {
+			st.Passed, st.Vars = nil, nil
+			return 0, nil
+		}
+	}
+	return len(st.Passed), copyVars(st.Vars)
+}
+
+func (c *checkpoint) file(path string) *fileState {
+	key := fileKey(path)
+	st := c.state.Files[key]
+	if st == nil {
+		st = &fileState{}
+		c.state.Files[key] = st
+	}
+	return st
+}
+
+func (c *checkpoint) OnStepStart(StepStart) {}
+
+func (c *checkpoint) OnStepEnd(ev StepEnd) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.file(ev.File)
+	if ev.Err != nil {
+		st.failed = true
+	} else if !st.failed {
+		st.Passed = append(st.Passed, ev.Step)
+		st.Vars = copyVars(ev.vars)
+	}
+	c.write()
+}
+
+func (c *checkpoint) OnFileEnd(ev FileEnd) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.file(ev.File)
+	st.Done = !st.failed && len(ev.Errs) == 0 && ev.Skipped == 0
+	c.write()
+}
+
+// write saves the state, replacing the file in one step so an interrupted
+// write can't leave it truncated. The caller holds c.mu.
+func (c *checkpoint) write() {
+	c.state.Globals = c.globals.snapshot()
+	data, err := json.MarshalIndent(c.state, "", "  ")
+	if err == nil {
+		tmp := c.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, c.path)
+		}
+	}
+	if c.err == nil {
+		c.err = e.Wrapf(err, "write %s", c.path)
+	}
+}
+
+// finish removes the state file after a clean run, or writes it out for
+// the next run to resume from.
+func (c *checkpoint) finish(clean bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !clean {
+		c.write()
+		return c.err
+	}
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return e.Wrapf(err, "remove %s", c.path)
+	}
+	return nil
+}
+
+func copyVars(vars map[string]string) map[string]string {
+	if vars == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(vars))
+	for k, v := range vars {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	var seen []string
+	broken := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Method+" "+r.URL.Path)
+		switch {
+		case r.URL.Path == "/orders":
+			w.Write([]byte(`{"id": 42}`))
+		case strings.HasSuffix(r.URL.Path, "/pay") && broken:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	health := filepath.Join(dir, "health.yaml")
+	os.WriteFile(health, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "health"
+  request:
+    url: "/health"
+`, srv.URL)), 0644)
+	orders := filepath.Join(dir, "orders.yaml")
+	os.WriteFile(orders, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "create"
+  request:
+    method: "POST"
+    url: "/orders"
+  capture:
+  - json_path: "id"
+    as: "order"
+- step: "pay"
+  request:
+    method: "POST"
+    url: "/orders/${order}/pay"
+  expect:
+    status: 200
+- step: "ship"
+  request:
+    method: "POST"
+    url: "/orders/${order}/ship"
+`, srv.URL)), 0644)
+	state := filepath.Join(dir, "state.json")
+
+	r := New(10*time.Second, false, WithCheckpoint(state, false), WithSequential(true))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{health, orders}); err == nil {
+		t.Fatal("expected the pay step to fail")
+	}
+	data, err := os.ReadFile(state)
+	if err != nil {
+		t.Fatalf("expected a state file: %v", err)
+	}
+	if !strings.Contains(string(data), `"passed": [`) || !strings.Contains(string(data), `"order": "42"`) {
+		t.Errorf("unexpected state:\n%s", data)
+	}
+
+	// Once pay is fixed, only it and the steps after it run again.
+	broken = false
+	seen = nil
+	r = New(10*time.Second, false, WithCheckpoint(state, true), WithSequential(true))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{health, orders}); err != nil {
+		t.Fatalf("resumed run failed: %v", err)
+	}
+	if got := strings.Join(seen, ","); got != "POST /orders/42/pay,POST /orders/42/ship" {
+		t.Errorf("resumed requests = %s", got)
+	}
+	if _, err := os.Stat(state); !os.IsNotExist(err) {
+		t.Errorf("expected the state file to be removed after a clean run, got %v", err)
+	}
+}
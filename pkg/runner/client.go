@@ -0,0 +1,294 @@
+package runner
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"github.com/michaelmccabe/ramjam/pkg/openapi"
+)
+
+// fileContext holds settings resolved once per workflow file and shared by
+// all of its steps.
+type fileContext struct {
+	path     string
+	baseDir  string
+	config   Config
+	client   *http.Client
+	contract *openapi.Spec // config.openapi, if set
+	span     *span         // the running step's span when tracing
+	exchange exchange      // the running step's request and response
+}
+
+// TLSConfig configures client certificates, trusted CAs and certificate
+// verification. Relative paths in a workflow file are resolved against the
+// file's directory.
+type TLSConfig struct {
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	CAFile             string `yaml:"ca_file,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+}
+
+func (c TLSConfig) isZero() bool {
+	return c == TLSConfig{}
+}
+
+// TransportOptions tunes the connection pool behind every request. Zero
+// values keep Go's defaults.
+type TransportOptions struct {
+	// MaxIdleConnsPerHost is how many idle connections to keep open to each
+	// host. Load tests and soak runs default it to their concurrency.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes connections that have been idle this long.
+	IdleConnTimeout time.Duration
+	// DisableKeepAlives opens a new connection for every request.
+	DisableKeepAlives bool
+	// TLSHandshakeTimeout limits how long a TLS handshake may take.
+	TLSHandshakeTimeout time.Duration
+}
+
+// WithTransportOptions tunes the transport used for workflow requests.
+func WithTransportOptions(opts TransportOptions) Option {
+	return func(r *Runner) {
+		r.transport = opts
+	}
+}
+
+// WithHTTPClient sends workflow requests with a copy of client, so tests can
+// inject a transport and programs can add instrumentation. The client's own
+// Timeout is kept unless it is zero. Files that set config.http_version,
+// config.tls or config.proxy need client's Transport to be nil or an
+// *http.Transport, which is cloned for them.
+func WithHTTPClient(client *http.Client) Option {
+	return func(r *Runner) {
+		c := *client
+		if c.Timeout == 0 {
+			c.Timeout = r.client.Timeout
+		}
+		r.client = &c
+	}
+}
+
+// newTransport returns a copy of the runner's transport, or Go's default
+// one, with the runner's transport options applied.
+func (r *Runner) newTransport() *http.Transport {
+	base, ok := r.client.Transport.(*http.Transport)
+	if !ok {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	opts := r.transport
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	if opts.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = opts.TLSHandshakeTimeout
+	}
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+	return transport
+}
+
+// Middleware wraps the transport that sends workflow requests, to log,
+// sign, measure or rewrite them.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// WithMiddleware sends every workflow request, including redirects and OAuth2
+// token requests, through mw. The first middleware is outermost. Requests the
+// HAR recorder captures include any changes made by middleware.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(r *Runner) {
+		r.middleware = append(r.middleware, mw...)
+	}
+}
+
+// middlewareTransport sends requests through the middleware chain built
+// around base, keeping base so its TLS and proxy settings can be found.
+type middlewareTransport struct {
+	base  http.RoundTripper
+	chain http.RoundTripper
+}
+
+func (t *middlewareTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.chain.RoundTrip(req)
+}
+
+func (r *Runner) middlewareClient(c *http.Client) *http.Client {
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	chain := base
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		chain = r.middleware[i](chain)
+	}
+	wrapped := *c
+	wrapped.Transport = &middlewareTransport{base: base, chain: chain}
+	return &wrapped
+}
+
+// fileClient returns the HTTP client for the workflow file at path. When the
+// runner caches clients, repeated runs of a file share one client and its
+// connection pool.
+func (r *Runner) fileClient(path string, cfg Config) (*http.Client, error) {
+	if r.clients == nil {
+		return r.newClient(cfg, workflowDir(path))
+	}
+	if client, ok := r.clients.Load(path); ok {
+		return client.(*http.Client), nil
+	}
+	client, err := r.newClient(cfg, workflowDir(path))
+	if err != nil {
+		return nil, err
+	}
+	cached, _ := r.clients.LoadOrStore(path, client)
+	return cached.(*http.Client), nil
+}
+
+// newClient returns the HTTP client for a workflow file. Files that need no
+// transport customization share the runner's default client.
+func (r *Runner) newClient(cfg Config, baseDir string) (*http.Client, error) {
+	fileTLS := resolveTLSPaths(cfg.TLS, baseDir)
+	tlsCfg := mergeTLS(fileTLS, r.tls)
+	proxy := cfg.Proxy
+	if proxy == "" {
+		proxy = r.proxy
+	}
+	if cfg.HTTPVersion == "" && tlsCfg.isZero() && proxy == "" {
+		return r.client, nil
+	}
+
+	if !r.ownTransport() {
+		return nil, fmt.Errorf("config.http_version, tls and proxy can't be used with a custom http.RoundTripper")
+	}
+	transport := r.newTransport()
+
+	if proxy != "" {
+		proxyFunc, err := proxyFor(proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = proxyFunc
+	}
+
+	if cfg.HTTPVersion != "" {
+		protocols := new(http.Protocols)
+		switch cfg.HTTPVersion {
+		case "1.1":
+			protocols.SetHTTP1(true)
+		case "2":
+			protocols.SetHTTP2(true)
+		case "2-prior-knowledge":
+			protocols.SetUnencryptedHTTP2(true)
+			protocols.SetHTTP2(true)
+		default:
+			return nil, fmt.Errorf("unsupported http_version %q (expected 1.1, 2 or 2-prior-knowledge)", cfg.HTTPVersion)
+		}
+		transport.Protocols = protocols
+	}
+
+	if !tlsCfg.isZero() {
+		clientTLS, err := buildTLSConfig(tlsCfg)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = clientTLS
+	}
+
+	client := *r.client
+	client.Transport = transport
+	return &client, nil
+}
+
+// ownTransport reports whether the runner's client uses an *http.Transport
+// that newTransport can copy, rather than a custom http.RoundTripper.
+func (r *Runner) ownTransport() bool {
+	switch r.client.Transport.(type) {
+	case nil, *http.Transport:
+		return true
+	}
+	return false
+}
+
+// proxyFor parses config.proxy. http, https, socks5 and socks5h URLs are
+// supported; "direct" bypasses any proxy from the environment. Without a
+// configured proxy, HTTP_PROXY, HTTPS_PROXY and NO_PROXY are honored.
+func proxyFor(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	if proxy == "direct" {
+		return nil, nil
+	}
+	u, err := url.Parse(proxy)
+	if err := e.Wrapf(err, "invalid proxy %s", proxy); err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q in %s", u.Scheme, proxy)
+	}
+	return http.ProxyURL(u), nil
+}
+
+func resolveTLSPaths(cfg TLSConfig, baseDir string) TLSConfig {
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(baseDir, p)
+	}
+	cfg.CertFile = resolve(cfg.CertFile)
+	cfg.KeyFile = resolve(cfg.KeyFile)
+	cfg.CAFile = resolve(cfg.CAFile)
+	return cfg
+}
+
+// mergeTLS fills fields missing from the file's settings with the defaults.
+func mergeTLS(file, defaults TLSConfig) TLSConfig {
+	if file.CertFile == "" && file.KeyFile == "" {
+		file.CertFile = defaults.CertFile
+		file.KeyFile = defaults.KeyFile
+	}
+	if file.CAFile == "" {
+		file.CAFile = defaults.CAFile
+	}
+	file.InsecureSkipVerify = file.InsecureSkipVerify || defaults.InsecureSkipVerify
+	return file
+}
+
+func buildTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, fmt.Errorf("tls requires both cert_file and key_file for client certificates")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err := e.Wrap(err, "load client certificate"); err != nil {
+			return nil, err
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err := e.Wrapf(err, "read ca_file %s", cfg.CAFile); err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in ca_file %s", cfg.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	return tlsCfg, nil
+}
@@ -0,0 +1,482 @@
+package runner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newH2CServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fmt.Sprintf(`{"proto": %q}`, r.Proto)))
+	}))
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetHTTP1(true)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	return srv
+}
+
+func TestHTTPVersion(t *testing.T) {
+	srv := newH2CServer(t)
+	defer srv.Close()
+
+	tests := []struct {
+		version string
+		proto   string
+	}{
+		{version: "1.1", proto: "HTTP/1.1"},
+		{version: "2-prior-knowledge", proto: "HTTP/2.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			yamlContent := fmt.Sprintf(`
+metadata:
+  name: "HTTP Version"
+config:
+  base_url: "%s"
+  http_version: "%s"
+workflow:
+- step: "proto"
+  request:
+    url: "/"
+  expect:
+    proto: "%s"
+    json_path_match:
+    - path: "proto"
+      value: "${last_proto}"
+`, srv.URL, tt.version, tt.proto)
+
+			runTest(t, yamlContent)
+		})
+	}
+}
+
+func TestUnsupportedHTTPVersion(t *testing.T) {
+	yamlContent := `
+metadata:
+  name: "Bad Version"
+config:
+  http_version: "3"
+workflow:
+- step: "proto"
+  request:
+    url: "http://127.0.0.1:1/"
+`
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), `unsupported http_version "3"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// writeSelfSignedCert generates a self-signed client certificate and writes
+// the certificate and key as PEM files into dir.
+func writeSelfSignedCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ramjam-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return cert, certPath, keyPath
+}
+
+func writeServerCA(t *testing.T, srv *httptest.Server, dir string) string {
+	t.Helper()
+	caPath := filepath.Join(dir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caPath, caPEM, 0600); err != nil {
+		t.Fatalf("write ca: %v", err)
+	}
+	return caPath
+}
+
+func newMTLSServer(t *testing.T, clientCert *x509.Certificate) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fmt.Sprintf(`{"client": %q}`, r.TLS.PeerCertificates[0].Subject.CommonName)))
+	}))
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCert)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	return srv
+}
+
+func TestMutualTLS(t *testing.T) {
+	tmpDir := t.TempDir()
+	clientCert, _, _ := writeSelfSignedCert(t, tmpDir)
+	srv := newMTLSServer(t, clientCert)
+	defer srv.Close()
+	writeServerCA(t, srv, tmpDir)
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "mTLS"
+config:
+  base_url: "%s"
+  tls:
+    cert_file: "client.crt"
+    key_file: "client.key"
+    ca_file: "ca.pem"
+workflow:
+- step: "whoami"
+  request:
+    url: "/whoami"
+  expect:
+    status: 200
+    json_path_match:
+    - path: "client"
+      value: "ramjam-client"
+`, srv.URL)
+
+	yamlFilePath := filepath.Join(tmpDir, "mtls.yaml")
+	if err := os.WriteFile(yamlFilePath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write yaml file: %v", err)
+	}
+
+	r := New(10*time.Second, true)
+	if err := r.RunPaths([]string{yamlFilePath}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+}
+
+func TestMutualTLSFromRunnerDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	clientCert, certPath, keyPath := writeSelfSignedCert(t, tmpDir)
+	srv := newMTLSServer(t, clientCert)
+	defer srv.Close()
+	caPath := writeServerCA(t, srv, tmpDir)
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "mTLS Defaults"
+config:
+  base_url: "%s"
+workflow:
+- step: "whoami"
+  request:
+    url: "/whoami"
+  expect:
+    status: 200
+`, srv.URL)
+
+	yamlFilePath := filepath.Join(tmpDir, "mtls.yaml")
+	if err := os.WriteFile(yamlFilePath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write yaml file: %v", err)
+	}
+
+	without := New(10*time.Second, false)
+	if err := without.RunPaths([]string{yamlFilePath}); err == nil {
+		t.Fatal("expected failure without client certificate")
+	}
+
+	r := New(10*time.Second, true, WithTLS(TLSConfig{CertFile: certPath, KeyFile: keyPath, CAFile: caPath}))
+	if err := r.RunPaths([]string{yamlFilePath}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+}
+
+func TestInsecureSkipVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	workflow := func(insecure bool) string {
+		return fmt.Sprintf(`
+metadata:
+  name: "Self Signed"
+config:
+  base_url: "%s"
+  tls:
+    insecure_skip_verify: %t
+workflow:
+- step: "ping"
+  request:
+    url: "/"
+  expect:
+    status: 200
+`, srv.URL, insecure)
+	}
+
+	err := runTestError(t, workflow(false))
+	if err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected certificate verification error, got %v", err)
+	}
+
+	runTest(t, workflow(true))
+
+	tmpFile := filepath.Join(t.TempDir(), "insecure.yaml")
+	if err := os.WriteFile(tmpFile, []byte(workflow(false)), 0644); err != nil {
+		t.Fatalf("failed to write yaml file: %v", err)
+	}
+	r := New(10*time.Second, false, WithTLS(TLSConfig{InsecureSkipVerify: true}))
+	if err := r.RunPaths([]string{tmpFile}); err != nil {
+		t.Fatalf("RunPaths with insecure default failed: %v", err)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Header().Set("X-Proxied", "yes")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	workflow := func(proxyURL string) string {
+		return fmt.Sprintf(`
+metadata:
+  name: "Proxy"
+config:
+  base_url: "http://upstream.invalid"
+  proxy: "%s"
+workflow:
+- step: "through-proxy"
+  request:
+    url: "/users"
+  expect:
+    status: 200
+    headers:
+    - name: "X-Proxied"
+      value: "yes"
+`, proxyURL)
+	}
+
+	runTest(t, workflow(proxy.URL))
+	if len(proxied) != 1 || proxied[0] != "http://upstream.invalid/users" {
+		t.Errorf("expected proxied request to upstream, got %v", proxied)
+	}
+
+	err := runTestError(t, workflow("ftp://proxy.invalid"))
+	if err == nil || !strings.Contains(err.Error(), `unsupported proxy scheme "ftp"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestProxyFromRunnerDefault(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer proxy.Close()
+
+	tmpFile := filepath.Join(t.TempDir(), "proxy.yaml")
+	yamlContent := `
+metadata:
+  name: "Proxy Default"
+workflow:
+- step: "teapot"
+  request:
+    url: "http://upstream.invalid/"
+  expect:
+    status: 418
+`
+	if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write yaml file: %v", err)
+	}
+	r := New(10*time.Second, false, WithProxy(proxy.URL))
+	if err := r.RunPaths([]string{tmpFile}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestMiddleware(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen", strings.Join(r.Header.Values("X-Via"), ","))
+	}))
+	defer srv.Close()
+
+	var statuses []int
+	via := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req.Header.Add("X-Via", name)
+				resp, err := next.RoundTrip(req)
+				if err == nil && name == "outer" {
+					statuses = append(statuses, resp.StatusCode)
+				}
+				return resp, err
+			})
+		}
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "middleware.yaml")
+	os.WriteFile(tmpFile, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "one"
+  request:
+    url: "/"
+  expect:
+    headers:
+    - name: "X-Seen"
+      value: "outer,inner"
+- step: "two"
+  request:
+    url: "/"
+`, srv.URL)), 0644)
+	r := New(10*time.Second, false, WithMiddleware(via("outer"), via("inner")))
+	if err := r.RunPaths([]string{tmpFile}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Errorf("middleware saw %d responses, want 2", len(statuses))
+	}
+}
+
+func TestTransportOptions(t *testing.T) {
+	opts := TransportOptions{MaxIdleConnsPerHost: 7, IdleConnTimeout: time.Minute, TLSHandshakeTimeout: 3 * time.Second}
+	r := New(10*time.Second, false, WithTransportOptions(opts))
+	transport, ok := httpTransport(r.client.Transport)
+	if !ok {
+		t.Fatalf("runner client transport = %T", r.client.Transport)
+	}
+	if transport.MaxIdleConnsPerHost != 7 || transport.IdleConnTimeout != time.Minute || transport.TLSHandshakeTimeout != 3*time.Second {
+		t.Errorf("transport not tuned: %d, %s, %s", transport.MaxIdleConnsPerHost, transport.IdleConnTimeout, transport.TLSHandshakeTimeout)
+	}
+	client, err := r.newClient(Config{Proxy: "http://proxy.invalid"}, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport, _ := httpTransport(client.Transport); transport.MaxIdleConnsPerHost != 7 {
+		t.Errorf("file client MaxIdleConnsPerHost = %d, want 7", transport.MaxIdleConnsPerHost)
+	}
+	if transport, _ := httpTransport(r.loadRunner(50).client.Transport); transport.MaxIdleConnsPerHost != 7 {
+		t.Errorf("load runner overrode MaxIdleConnsPerHost: %d", transport.MaxIdleConnsPerHost)
+	}
+	if transport, _ := httpTransport(New(time.Second, false).loadRunner(50).client.Transport); transport.MaxIdleConnsPerHost != 50 {
+		t.Errorf("load runner MaxIdleConnsPerHost = %d, want 50", transport.MaxIdleConnsPerHost)
+	}
+}
+
+func TestDisableKeepAlives(t *testing.T) {
+	var mu sync.Mutex
+	conns := 0
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	tmpFile := filepath.Join(t.TempDir(), "keepalive.yaml")
+	os.WriteFile(tmpFile, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "one"
+  request:
+    url: "/"
+- step: "two"
+  request:
+    url: "/"
+- step: "three"
+  request:
+    url: "/"
+`, srv.URL)), 0644)
+	r := New(10*time.Second, false, WithTransportOptions(TransportOptions{DisableKeepAlives: true}))
+	if err := r.RunPaths([]string{tmpFile}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if conns != 3 {
+		t.Errorf("opened %d connections for 3 requests, want 3", conns)
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	var urls []string
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		urls = append(urls, req.URL.String())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Proto:      "HTTP/1.1",
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"ok": true}`)),
+			Request:    req,
+		}, nil
+	})}
+
+	tmpFile := filepath.Join(t.TempDir(), "client.yaml")
+	os.WriteFile(tmpFile, []byte(`
+config:
+  base_url: "http://api.invalid"
+workflow:
+- step: "ok"
+  request:
+    url: "/health"
+  expect:
+    status: 200
+    json_path_match:
+    - path: "ok"
+      value: true
+`), 0644)
+	r := New(3*time.Second, false, WithHTTPClient(client), WithTransportOptions(TransportOptions{MaxIdleConnsPerHost: 4}))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{tmpFile}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+	if len(urls) != 1 || urls[0] != "http://api.invalid/health" {
+		t.Errorf("injected transport saw %v", urls)
+	}
+	if r.client.Timeout != 3*time.Second || client.Timeout != 0 {
+		t.Errorf("expected the runner's copy to get the 3s timeout, got %s (caller's client %s)", r.client.Timeout, client.Timeout)
+	}
+	if _, ok := r.loadRunner(10).client.Transport.(roundTripperFunc); !ok {
+		t.Error("load runner replaced the injected transport")
+	}
+	if _, err := r.newClient(Config{Proxy: "http://proxy.invalid"}, "."); err == nil {
+		t.Error("expected config.proxy to fail with a custom RoundTripper")
+	}
+}
@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// acceptEncoding is advertised on every request. Setting it explicitly stops
+// net/http from transparently decoding gzip and stripping Content-Encoding,
+// so the runner decodes responses itself and can assert on the encoding.
+const acceptEncoding = "gzip, deflate, br"
+
+// decodedBody closes both the decoder and the underlying response body.
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *decodedBody) Close() error {
+	var first error
+	for _, c := range d.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// decodeBody wraps the response body in decoders for each Content-Encoding,
+// applied in reverse order of how the server encoded them.
+func decodeBody(resp *http.Response) (io.ReadCloser, error) {
+	header := resp.Header.Get("Content-Encoding")
+	if header == "" {
+		return resp.Body, nil
+	}
+
+	encodings := strings.Split(header, ",")
+	body := &decodedBody{Reader: resp.Body, closers: []io.Closer{resp.Body}}
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch enc := strings.ToLower(strings.TrimSpace(encodings[i])); enc {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(body.Reader)
+			if err := e.Wrap(err, "decode gzip body"); err != nil {
+				return nil, err
+			}
+			body.Reader = zr
+			body.closers = append([]io.Closer{zr}, body.closers...)
+		case "deflate":
+			zr, err := zlib.NewReader(body.Reader)
+			if err := e.Wrap(err, "decode deflate body"); err != nil {
+				return nil, err
+			}
+			body.Reader = zr
+			body.closers = append([]io.Closer{zr}, body.closers...)
+		case "br":
+			body.Reader = brotli.NewReader(body.Reader)
+		default:
+			return nil, fmt.Errorf("unsupported content encoding %q", enc)
+		}
+	}
+	return body, nil
+}
+
+// checkContentEncoding verifies expect.content_encoding. "identity" matches
+// a response that declares no encoding.
+func (r *Runner) checkContentEncoding(resp *http.Response, expect StepExpect, vars map[string]string, log func(string, ...interface{})) error {
+	if expect.ContentEncoding == "" {
+		return nil
+	}
+	expected, err := r.applyVars(expect.ContentEncoding, vars)
+	if err != nil {
+		return err
+	}
+	expected = strings.ToLower(expected)
+	actual := strings.ToLower(resp.Header.Get("Content-Encoding"))
+	if actual == "" {
+		actual = "identity"
+	}
+	if r.verbose() {
+		log("Asserting content encoding == %s", expected)
+	}
+	if actual != expected {
+		return &ExpectationError{fmt.Sprintf("expected content encoding %q, got %q", expected, actual), expected, actual}
+	}
+	return nil
+}
+
+// gzipBody compresses a request body on the fly for request.compress_body.
+// Closing the returned reader stops the compression and closes body, if it
+// can be closed.
+func gzipBody(body io.Reader) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		if c, ok := body.(io.Closer); ok {
+			defer c.Close()
+		}
+		zw := gzip.NewWriter(pw)
+		if _, err := io.Copy(zw, body); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(zw.Close())
+	}()
+	return pr
+}
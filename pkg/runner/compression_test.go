@@ -0,0 +1,154 @@
+package runner
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestCompressedResponses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "br") {
+			t.Errorf("expected Accept-Encoding to advertise br, got %s", r.Header.Get("Accept-Encoding"))
+		}
+		switch r.URL.Path {
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte(`{"encoding": "gzip"}`))
+			zw.Close()
+		case "/br":
+			w.Header().Set("Content-Encoding", "br")
+			bw := brotli.NewWriter(w)
+			bw.Write([]byte(`{"encoding": "br"}`))
+			bw.Close()
+		default:
+			w.Write([]byte(`{"encoding": "identity"}`))
+		}
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Compression"
+config:
+  base_url: "%s"
+workflow:
+- step: "gzip"
+  request:
+    url: "/gzip"
+  expect:
+    content_encoding: "gzip"
+    json_path_match:
+    - path: "encoding"
+      value: "gzip"
+- step: "brotli"
+  request:
+    url: "/br"
+  expect:
+    content_encoding: "br"
+    json_path_match:
+    - path: "encoding"
+      value: "br"
+- step: "plain"
+  request:
+    url: "/plain"
+  expect:
+    content_encoding: "identity"
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
+
+func TestContentEncodingMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Encoding Mismatch"
+config:
+  base_url: "%s"
+workflow:
+- step: "expect-gzip"
+  request:
+    url: "/"
+  expect:
+    content_encoding: "gzip"
+`, srv.URL)
+
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), `expected content encoding "gzip", got "identity"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCompressRequestBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("expected Content-Encoding gzip, got %s", r.Header.Get("Content-Encoding"))
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatalf("request body is not gzip: %v", err)
+		}
+		body, _ := io.ReadAll(zr)
+		if string(body) != `{"name":"compressed"}` {
+			t.Errorf("unexpected body %s", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Compressed Request"
+config:
+  base_url: "%s"
+workflow:
+- step: "upload"
+  request:
+    method: "POST"
+    url: "/items"
+    compress_body: true
+    body:
+      name: "compressed"
+  expect:
+    status: 201
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
+
+func TestCompressedBodyClosedOnEarlyError(t *testing.T) {
+	before := runtime.NumGoroutine()
+	err := runTestError(t, `
+workflow:
+- step: "upload"
+  request:
+    method: "POST"
+    url: "http://127.0.0.1:1/items"
+    compress_body: true
+    auth:
+      api_key:
+        name: "X-Key"
+        value: "k"
+        in: "cookie"
+    multipart:
+    - name: "note"
+      value: "compressed"
+`)
+	if err == nil || !strings.Contains(err.Error(), "unknown api_key location") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForGoroutines(t, before)
+}
@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"github.com/michaelmccabe/ramjam/pkg/openapi"
+)
+
+// loadContract reads the spec named by config.openapi, resolved relative to
+// the workflow file.
+func loadContract(cfg Config, baseDir string) (*openapi.Spec, error) {
+	if cfg.OpenAPI == "" {
+		return nil, nil
+	}
+	path := cfg.OpenAPI
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	return openapi.Load(path)
+}
+
+// checkContract verifies that a response is documented by the file's OpenAPI
+// spec: the operation must exist, the status must be declared, and JSON
+// bodies must match the declared schema. Requests to hosts outside the
+// spec's servers are not checked.
+func (r *Runner) checkContract(resp *http.Response, body *bodyStream, step Step, log func(string, ...interface{})) error {
+	spec := step.file.contract
+	if spec == nil {
+		return nil
+	}
+	req := resp.Request
+	ref, ok := spec.Match(req.Method, req.URL)
+	if !ok {
+		if r.verbose() {
+			log("Skipping OpenAPI check: %s is not one of the spec's servers", req.URL.Host)
+		}
+		return nil
+	}
+	if ref == nil {
+		return fmt.Errorf("openapi: no operation matches %s %s", req.Method, req.URL.Path)
+	}
+	operation := fmt.Sprintf("%s %s", ref.Method, ref.Path)
+
+	documented, ok := spec.Response(ref.Operation, resp.StatusCode)
+	if !ok {
+		return fmt.Errorf("openapi: status %d is not documented for %s", resp.StatusCode, operation)
+	}
+	if documented == nil {
+		return fmt.Errorf("openapi: unresolved response reference for %s %d", operation, resp.StatusCode)
+	}
+	if r.verbose() {
+		log("Checking response against OpenAPI operation %s", operation)
+	}
+	if len(documented.Content) == 0 || body.size == 0 {
+		return nil
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mt, ok := openapi.MediaTypeFor(documented.Content, contentType)
+	if !ok {
+		return fmt.Errorf("openapi: content type %q is not documented for %s %d", contentType, operation, resp.StatusCode)
+	}
+	if mt.Schema == nil || !strings.Contains(strings.ToLower(contentType), "json") {
+		return nil
+	}
+	if body.truncated {
+		if r.verbose() {
+			log("Skipping OpenAPI schema check: body exceeds max_body_size")
+		}
+		return nil
+	}
+
+	var value interface{}
+	if err := e.Wrap(json.Unmarshal(body.buf.Bytes(), &value), "openapi: parse response json"); err != nil {
+		return err
+	}
+	return e.Wrapf(spec.Validate(mt.Schema, value), "openapi: %s %d response", operation, resp.StatusCode)
+}
@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const contractSpec = `
+openapi: "3.0.3"
+info:
+  title: "Users"
+servers:
+- url: "/v1"
+paths:
+  /users/{id}:
+    get:
+      responses:
+        "200":
+          description: "A user"
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id, name]
+                additionalProperties: false
+                properties:
+                  id:
+                    type: integer
+                  name:
+                    type: string
+        "404":
+          description: "Not found"
+`
+
+func TestOpenAPIContract(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/users/1":
+			w.Write([]byte(`{"id": 1, "name": "Ada"}`))
+		case "/v1/users/2":
+			w.Write([]byte(`{"id": "2", "name": "Grace"}`))
+		case "/v1/users/3":
+			w.Write([]byte(`{"id": 3, "name": "Alan", "admin": true}`))
+		case "/v1/users/4":
+			w.WriteHeader(http.StatusTeapot)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"valid", "/users/1", ""},
+		{"documented status without body", "/users/99", ""},
+		{"wrong type", "/users/2", "openapi: GET /users/{id} 200 response: $.id: expected integer, got string"},
+		{"additional property", "/users/3", `$: unexpected property "admin"`},
+		{"undocumented status", "/users/4", "openapi: status 418 is not documented for GET /users/{id}"},
+		{"unknown operation", "/accounts/1", "openapi: no operation matches GET /v1/accounts/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runContractTest(t, fmt.Sprintf(`
+config:
+  base_url: "%s/v1"
+  openapi: "spec.yaml"
+workflow:
+- step: "get-user"
+  request:
+    url: "%s"
+`, srv.URL, tt.url))
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("expected contract check to pass: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestOpenAPIContractMissingSpec(t *testing.T) {
+	err := runContractTest(t, `
+config:
+  openapi: "missing.yaml"
+workflow:
+- step: "never-runs"
+  request:
+    url: "http://127.0.0.1:1/"
+`)
+	if err == nil || !strings.Contains(err.Error(), "load openapi spec") {
+		t.Fatalf("expected spec load error, got %v", err)
+	}
+}
+
+// runContractTest writes the workflow next to contractSpec so config.openapi
+// resolves relative to it.
+func runContractTest(t *testing.T, workflow string) error {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "spec.yaml"), []byte(contractSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "workflow.yaml")
+	if err := os.WriteFile(path, []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := New(10*time.Second, false)
+	r.out = io.Discard
+	return r.RunPaths([]string{path})
+}
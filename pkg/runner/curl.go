@@ -0,0 +1,155 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// WithPrintCurl logs every request as an equivalent curl command, as if each
+// step set output.curl.
+func WithPrintCurl(enabled bool) Option {
+	return func(r *Runner) {
+		r.printCurl = enabled
+	}
+}
+
+// bufferBody reads the request body into memory and replaces it with a
+// rewindable copy, so the body can be inspected before it is sent.
+func bufferBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err := e.Wrap(err, "read request body"); err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	return body, nil
+}
+
+// curlCommand renders a fully built request as a copy-pasteable curl
+// invocation, including the client settings that change how it is sent.
+func (r *Runner) curlCommand(req *http.Request, step Step) (string, error) {
+	body, err := bufferBody(req)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"curl"}
+	if req.Method != http.MethodGet {
+		args = append(args, "-X", req.Method)
+	}
+	args = append(args, shellQuote(req.URL.String()))
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// curl negotiates and decodes compression itself with --compressed.
+		if name == "Accept-Encoding" {
+			continue
+		}
+		for _, v := range req.Header[name] {
+			args = append(args, "-H", shellQuote(name+": "+v))
+		}
+	}
+	if req.Header.Get("Accept-Encoding") != "" {
+		args = append(args, "--compressed")
+	}
+	// A shell argument can't hold a NUL byte, so such a body is left out
+	// and a comment at the end says so.
+	omitted := bytes.IndexByte(body, 0) >= 0
+	if body != nil && !omitted {
+		args = append(args, "--data-binary", shellQuote(string(body)))
+	}
+
+	if step.Request.FollowRedirects == nil || *step.Request.FollowRedirects {
+		args = append(args, "-L")
+	}
+	tls := mergeTLS(resolveTLSPaths(step.file.config.TLS, step.file.baseDir), r.tls)
+	if tls.InsecureSkipVerify {
+		args = append(args, "-k")
+	}
+	for _, opt := range []struct{ flag, value string }{
+		{"--cert", tls.CertFile},
+		{"--key", tls.KeyFile},
+		{"--cacert", tls.CAFile},
+	} {
+		if opt.value != "" {
+			args = append(args, opt.flag, shellQuote(opt.value))
+		}
+	}
+	proxy := step.file.config.Proxy
+	if proxy == "" {
+		proxy = r.proxy
+	}
+	if proxy != "" && proxy != "direct" {
+		args = append(args, "--proxy", shellQuote(proxy))
+	}
+	switch step.file.config.HTTPVersion {
+	case "1.1":
+		args = append(args, "--http1.1")
+	case "2":
+		args = append(args, "--http2")
+	case "2-prior-knowledge":
+		args = append(args, "--http2-prior-knowledge")
+	}
+	if omitted {
+		args = append(args, fmt.Sprintf("# %d-byte body omitted: it contains NUL bytes", len(body)))
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+// shellQuote quotes s for POSIX shells. Text that is not printable UTF-8 uses
+// bash's $'...' form. s must not contain a NUL byte, which no shell argument
+// can hold.
+func shellQuote(s string) string {
+	if printable(s) {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	var b strings.Builder
+	b.WriteString("$'")
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c >= 0x20 && c < 0x7f:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+func printable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, c := range s {
+		if c < 0x20 && c != '\n' && c != '\t' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
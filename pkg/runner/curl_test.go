@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://api.example.com/items?id=1", "'https://api.example.com/items?id=1'"},
+		{"plain-value", "'plain-value'"},
+		{"", "''"},
+		{"it's", `'it'\''s'`},
+		{`{"a": 1}`, `'{"a": 1}'`},
+		{"\x1f\x8b\x01'", `$'\x1f\x8b\x01\''`},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCurlCommandNULBody(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/upload", strings.NewReader("\x1f\x8b\x00\x01"))
+	step := Step{file: &fileContext{}}
+	step.Request.FollowRedirects = new(bool)
+	got, err := New(5*time.Second, false).curlCommand(req, step)
+	if err != nil {
+		t.Fatalf("curlCommand() error = %v", err)
+	}
+	if want := "curl -X POST 'http://localhost/upload' # 4-byte body omitted: it contains NUL bytes"; got != want {
+		t.Errorf("curlCommand() = %s, want %s", got, want)
+	}
+}
+
+func TestOutputCurl(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "create"
+  request:
+    method: "POST"
+    url: "/items"
+    follow_redirects: false
+    headers:
+      X-Request-Id: "abc"
+    body:
+      name: "it's"
+  expect:
+    status: 201
+  output:
+    curl: true
+- step: "quiet"
+  request:
+    url: "/items"
+`, srv.URL)
+
+	out := runCaptured(t, New(5*time.Second, false), yamlContent)
+	want := "curl -X POST '" + srv.URL + "/items' -H 'Content-Type: application/json' -H 'User-Agent: ramjam-cli' -H 'X-Request-Id: abc' --compressed --data-binary '{\"name\":\"it'\\''s\"}'"
+	if !strings.Contains(out, want) {
+		t.Errorf("expected curl command\n%s\nin output:\n%s", want, out)
+	}
+	if strings.Count(out, "curl ") != 1 {
+		t.Errorf("expected only the step with output.curl to print a command:\n%s", out)
+	}
+}
+
+func TestPrintCurlOption(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+config:
+  base_url: "%s"
+  http_version: "1.1"
+workflow:
+- step: "list"
+  request:
+    url: "/items"
+    params:
+      page: "2"
+`, srv.URL)
+
+	out := runCaptured(t, New(5*time.Second, false, WithPrintCurl(true), WithTLS(TLSConfig{InsecureSkipVerify: true})), yamlContent)
+	want := "curl '" + srv.URL + "/items?page=2' -H 'User-Agent: ramjam-cli' --compressed -L -k --http1.1"
+	if !strings.Contains(out, want) {
+		t.Errorf("expected curl command\n%s\nin output:\n%s", want, out)
+	}
+}
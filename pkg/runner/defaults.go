@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// DefaultsFileName is the file in a workflow directory whose settings apply
+// to every workflow in that directory.
+const DefaultsFileName = "_defaults.yaml"
+
+// Defaults holds the config, headers and variables shared by the workflows in
+// a directory, so base_url and auth don't have to be repeated in each file.
+// A workflow's own config takes precedence.
+type Defaults struct {
+	Config Config `yaml:"config"`
+	// Headers are added to config.headers.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Variables are set before each workflow runs, below any from a profile
+	// or WithVars.
+	Variables map[string]string `yaml:"variables,omitempty"`
+	// Order lists files and subdirectories of the directory that come
+	// first, in this order. The rest follow in lexical order.
+	Order []string `yaml:"order,omitempty"`
+}
+
+// loadDefaults reads the defaults file in dir, returning nil if there isn't
+// one.
+func (r *Runner) loadDefaults(dir string) (*Defaults, error) {
+	path := filepath.Join(dir, DefaultsFileName)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err := e.Wrapf(err, "read %s", path); err != nil {
+		return nil, err
+	}
+	var d Defaults
+	if err := e.Wrapf(r.decode(data, &d), "parse %s", path); err != nil {
+		return nil, e.Mark(err, e.ParseError)
+	}
+	return &d, nil
+}
+
+// apply returns cfg with the defaults beneath it: settings cfg leaves unset
+// come from the defaults, and its headers are added to theirs.
+func (d *Defaults) apply(cfg Config) Config {
+	base := d.Config
+	base.Headers = mergeHeaders(base.Headers, d.Headers)
+	return mergeConfig(base, cfg)
+}
+
+// orderFiles sorts files, found under root, by the order lists of the
+// defaults files in the directories between root and each file. Names a
+// list doesn't mention keep their lexical order after those it does.
+func (r *Runner) orderFiles(root string, files []string) {
+	ranks := map[string]map[string]int{}
+	rank := func(dir, name string) int {
+		names, ok := ranks[dir]
+		if !ok {
+			names = map[string]int{}
+			// A defaults file that can't be loaded fails the files in its
+			// directory when they run, so it is reported there.
+			if d, err := r.loadDefaults(dir); err == nil && d != nil {
+				for i, n := range d.Order {
+					if _, dup := names[filepath.Clean(n)]; !dup {
+						names[filepath.Clean(n)] = i
+					}
+				}
+			}
+			ranks[dir] = names
+		}
+		if i, ok := names[name]; ok {
+			return i
+		}
+		return math.MaxInt
+	}
+	split := func(path string) []string {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return []string{path}
+		}
+		return strings.Split(rel, string(filepath.Separator))
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := split(files[i]), split(files[j])
+		dir := root
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				if ra, rb := rank(dir, a[k]), rank(dir, b[k]); ra != rb {
+					return ra < rb
+				}
+				return a[k] < b[k]
+			}
+			dir = filepath.Join(dir, a[k])
+		}
+		return len(a) < len(b)
+	})
+}
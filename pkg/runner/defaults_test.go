@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaults(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Path+" "+r.Header.Get("X-Team")+" "+r.Header.Get("X-Trace")+" "+r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, DefaultsFileName), []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+  auth:
+    bearer: "${token}"
+headers:
+  X-Team: "payments"
+variables:
+  token: "shared-token"
+  region: "eu"
+`, srv.URL)), 0644)
+	os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(`
+workflow:
+- step: "a"
+  request:
+    url: "/a/${region}"
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "b.yaml"), []byte(`
+config:
+  headers:
+    X-Team: "billing"
+    X-Trace: "on"
+workflow:
+- step: "b"
+  request:
+    url: "/b/${region}"
+`), 0644)
+
+	r := New(10*time.Second, false, WithVars(map[string]string{"region": "us"}))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{dir}); err != nil {
+		t.Fatalf("RunPaths() error = %v", err)
+	}
+	sort.Strings(seen)
+	want := []string{"/a/us payments  Bearer shared-token", "/b/us billing on Bearer shared-token"}
+	if strings.Join(seen, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = %q, want %q", seen, want)
+	}
+}
+
+func TestDefaultsUnknownField(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, DefaultsFileName), []byte("config:\n  base_url: http://localhost:1\nheader:\n  X-Team: payments\n"), 0644)
+	workflow := filepath.Join(dir, "a.yaml")
+	os.WriteFile(workflow, []byte("workflow: []\n"), 0644)
+
+	r := New(time.Second, false)
+	r.out = io.Discard
+	err := r.RunPaths([]string{workflow})
+	if err == nil || !strings.Contains(err.Error(), `unknown field "header"`) {
+		t.Errorf("RunPaths() error = %v", err)
+	}
+}
+
+func TestDefaultsOrder(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		DefaultsFileName:              fmt.Sprintf("config:\n  base_url: %q\norder: [setup.yaml, billing, orders.yaml, setup.yaml]\n", srv.URL),
+		"billing/" + DefaultsFileName: fmt.Sprintf("config:\n  base_url: %q\norder: [refund.yaml]\n", srv.URL),
+		"setup.yaml":                  "workflow:\n- step: setup\n  request:\n    url: /setup\n",
+		"orders.yaml":                 "workflow:\n- step: orders\n  request:\n    url: /orders\n",
+		"alerts.yaml":                 "workflow:\n- step: alerts\n  request:\n    url: /alerts\n",
+		"teardown.yaml":               "workflow:\n- step: teardown\n  request:\n    url: /teardown\n",
+		"billing/invoice.yaml":        "workflow:\n- step: invoice\n  request:\n    url: /invoice\n",
+		"billing/refund.yaml":         "workflow:\n- step: refund\n  request:\n    url: /refund\n",
+		"auth/login.yaml":             fmt.Sprintf("config:\n  base_url: %q\nworkflow:\n- step: login\n  request:\n    url: /login\n", srv.URL),
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	r := New(10*time.Second, false, WithRecursive(true), WithSequential(true))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{dir}); err != nil {
+		t.Fatalf("RunPaths() error = %v", err)
+	}
+	want := "/setup,/refund,/invoice,/orders,/alerts,/login,/teardown"
+	if strings.Join(seen, ",") != want {
+		t.Errorf("requests = %s, want %s", strings.Join(seen, ","), want)
+	}
+}
@@ -0,0 +1,259 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StepResponse is the response a step received, kept for comparison. Body
+// is the decoded JSON document, or the body as a string when it is not
+// JSON.
+type StepResponse struct {
+	File   string
+	Step   string
+	Status int
+	Body   interface{}
+}
+
+// StepDiff lists the differences between two runs of the same step.
+type StepDiff struct {
+	File        string       `json:"file"`
+	Step        string       `json:"step"`
+	Differences []Difference `json:"differences,omitempty"`
+	// Missing is "a" or "b" when only the other run received a response.
+	Missing string `json:"missing,omitempty"`
+}
+
+// Difference is a single changed value. Path is "status", "body" for
+// non-JSON bodies, or a JSONPath such as $.items[0].id. Kind is changed,
+// added (only in b) or removed (only in a).
+type Difference struct {
+	Path string      `json:"path"`
+	Kind string      `json:"kind"`
+	A    interface{} `json:"a,omitempty"`
+	B    interface{} `json:"b,omitempty"`
+}
+
+// responseLog collects the last response of every step, so a polled step
+// is compared on its final attempt. Bodies are only kept when bodies is set.
+type responseLog struct {
+	mu      sync.Mutex
+	bodies  bool
+	order   []string
+	entries map[string]*StepResponse
+}
+
+func newResponseLog(bodies bool) *responseLog {
+	return &responseLog{bodies: bodies, entries: map[string]*StepResponse{}}
+}
+
+func (l *responseLog) add(resp *StepResponse) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := resp.File + "\x00" + resp.Step
+	if _, ok := l.entries[key]; !ok {
+		l.order = append(l.order, key)
+	}
+	l.entries[key] = resp
+}
+
+// responseValue decodes a recorded body for comparison.
+func responseValue(body *bodyStream) interface{} {
+	data, err := body.Bytes()
+	if err != nil {
+		return "<" + err.Error() + ">"
+	}
+	var doc interface{}
+	if json.Unmarshal(data, &doc) == nil {
+		return doc
+	}
+	return string(data)
+}
+
+// unreadBody decodes whatever is left of a response body.
+func unreadBody(resp *http.Response) interface{} {
+	decoded, err := decodeBody(resp)
+	if err != nil {
+		return "<" + err.Error() + ">"
+	}
+	bs := &bodyStream{}
+	if _, err := io.Copy(bs, decoded); err != nil {
+		return "<" + err.Error() + ">"
+	}
+	if bs.size == 0 {
+		return nil
+	}
+	return responseValue(bs)
+}
+
+// Diff runs the workflows under paths twice, once with each set of
+// variables, and compares the responses step by step. Differences at or
+// below a path in ignore, such as $.updated_at or $.items[*].id, are left
+// out. Failed expectations do not stop the comparison; only files that
+// could not be run are returned as an error.
+func (r *Runner) Diff(paths []string, a, b map[string]string, ignore []string) ([]StepDiff, error) {
+	ignored := ignorePatterns(ignore)
+	logA, errA := r.diffRun(paths, a)
+	logB, errB := r.diffRun(paths, b)
+	if err := errors.Join(errA, errB); err != nil {
+		return nil, err
+	}
+
+	var diffs []StepDiff
+	for _, key := range logA.order {
+		ra := logA.entries[key]
+		sd := StepDiff{File: ra.File, Step: ra.Step}
+		rb, ok := logB.entries[key]
+		if !ok {
+			sd.Missing = "b"
+			diffs = append(diffs, sd)
+			continue
+		}
+		if ra.Status != rb.Status {
+			sd.Differences = append(sd.Differences, Difference{Path: "status", Kind: "changed", A: ra.Status, B: rb.Status})
+		}
+		_, aText := ra.Body.(string)
+		_, bText := rb.Body.(string)
+		if aText || bText {
+			if ra.Body != rb.Body {
+				sd.Differences = append(sd.Differences, Difference{Path: "body", Kind: "changed", A: ra.Body, B: rb.Body})
+			}
+		} else {
+			diffValues("$", ra.Body, rb.Body, &sd.Differences)
+		}
+		sd.Differences = withoutIgnored(sd.Differences, ignored)
+		diffs = append(diffs, sd)
+	}
+	for _, key := range logB.order {
+		if _, ok := logA.entries[key]; !ok {
+			rb := logB.entries[key]
+			diffs = append(diffs, StepDiff{File: rb.File, Step: rb.Step, Missing: "a"})
+		}
+	}
+	return diffs, nil
+}
+
+// diffRun runs paths quietly with vars and returns the responses. Step
+// failures are expected when environments differ, so only errors that
+// stopped a file are returned.
+func (r *Runner) diffRun(paths []string, vars map[string]string) (*responseLog, error) {
+	run := *r
+	run.vars = vars
+	run.responses = newResponseLog(true)
+	run.out = io.Discard
+	run.progress = nil
+	run.har = nil
+	run.notify = nil
+	run.metrics = nil
+	run.tracing = nil
+
+	err := run.RunPaths(paths)
+	var fileErrs []error
+	if errs, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range errs.Unwrap() {
+			var se *StepError
+			if !errors.As(err, &se) {
+				fileErrs = append(fileErrs, err)
+			}
+		}
+	} else if err != nil {
+		fileErrs = append(fileErrs, err)
+	}
+	return run.responses, errors.Join(fileErrs...)
+}
+
+// diffValues appends the differences between two decoded JSON documents.
+func diffValues(path string, a, b interface{}, out *[]Difference) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := path + "." + k
+			x, inA := av[k]
+			y, inB := bv[k]
+			switch {
+			case !inB:
+				*out = append(*out, Difference{Path: child, Kind: "removed", A: x})
+			case !inA:
+				*out = append(*out, Difference{Path: child, Kind: "added", B: y})
+			default:
+				diffValues(child, x, y, out)
+			}
+		}
+		return
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(av) || i < len(bv); i++ {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(bv):
+				*out = append(*out, Difference{Path: child, Kind: "removed", A: av[i]})
+			case i >= len(av):
+				*out = append(*out, Difference{Path: child, Kind: "added", B: bv[i]})
+			default:
+				diffValues(child, av[i], bv[i], out)
+			}
+		}
+		return
+	default:
+		if a == b {
+			return
+		}
+	}
+	*out = append(*out, Difference{Path: path, Kind: "changed", A: a, B: b})
+}
+
+// ignorePatterns compiles --ignore paths into patterns that match the path
+// itself and everything below it, with [*] matching any index.
+func ignorePatterns(paths []string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, len(paths))
+	for _, p := range paths {
+		pattern := regexp.QuoteMeta(strings.TrimSpace(p))
+		pattern = strings.ReplaceAll(pattern, `\[\*\]`, `\[[0-9]+\]`)
+		patterns = append(patterns, regexp.MustCompile(`^`+pattern+`(\.|\[|$)`))
+	}
+	return patterns
+}
+
+func withoutIgnored(diffs []Difference, ignored []*regexp.Regexp) []Difference {
+	if len(ignored) == 0 {
+		return diffs
+	}
+	kept := diffs[:0]
+	for _, d := range diffs {
+		skip := false
+		for _, re := range ignored {
+			if re.MatchString(d.Path) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
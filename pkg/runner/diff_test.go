@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const diffWorkflow = `
+config:
+  base_url: "http://unused.invalid"
+workflow:
+- step: "get-user"
+  request:
+    url: "/users/1"
+  expect:
+    status: 200
+- step: "health"
+  request:
+    url: "/health"
+`
+
+func diffServer(user string, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.Write([]byte("ok"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(user))
+	}))
+}
+
+func TestDiff(t *testing.T) {
+	a := diffServer(`{"id":1,"name":"Ada","roles":["admin"],"updated_at":"mon"}`, 200)
+	defer a.Close()
+	b := diffServer(`{"id":1,"name":"Ada L.","roles":["admin","dev"],"updated_at":"tue","team":"core"}`, 500)
+	defer b.Close()
+
+	path := filepath.Join(t.TempDir(), "users.yaml")
+	os.WriteFile(path, []byte(diffWorkflow), 0644)
+
+	diffs, err := New(10*time.Second, false).Diff([]string{path},
+		map[string]string{"base_url": a.URL},
+		map[string]string{"base_url": b.URL},
+		[]string{"$.updated_at"})
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	want := []StepDiff{
+		{File: path, Step: "get-user", Differences: []Difference{
+			{Path: "status", Kind: "changed", A: 200, B: 500},
+			{Path: "$.name", Kind: "changed", A: "Ada", B: "Ada L."},
+			{Path: "$.roles[1]", Kind: "added", B: "dev"},
+			{Path: "$.team", Kind: "added", B: "core"},
+		}},
+		{File: path, Step: "health"},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("unexpected diffs:\n%+v\nwant:\n%+v", diffs, want)
+	}
+}
+
+func TestDiffMissingResponse(t *testing.T) {
+	a := diffServer(`{}`, 200)
+	defer a.Close()
+
+	path := filepath.Join(t.TempDir(), "users.yaml")
+	os.WriteFile(path, []byte(diffWorkflow), 0644)
+
+	diffs, err := New(time.Second, false).Diff([]string{path},
+		map[string]string{"base_url": a.URL},
+		map[string]string{"base_url": "http://127.0.0.1:1"},
+		nil)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(diffs) != 2 || diffs[0].Missing != "b" || diffs[1].Missing != "b" {
+		t.Errorf("expected both steps to be missing from b, got %+v", diffs)
+	}
+}
+
+func TestDiffIgnoreWildcard(t *testing.T) {
+	var diffs []Difference
+	diffValues("$", map[string]interface{}{
+		"items": []interface{}{map[string]interface{}{"id": "x", "qty": 1.0}},
+	}, map[string]interface{}{
+		"items": []interface{}{map[string]interface{}{"id": "y", "qty": 2.0}},
+	}, &diffs)
+	diffs = withoutIgnored(diffs, ignorePatterns([]string{"$.items[*].id"}))
+	want := []Difference{{Path: "$.items[0].qty", Kind: "changed", A: 1.0, B: 2.0}}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("unexpected diffs: %+v", diffs)
+	}
+}
+
+func TestWithVarsOverridesConfig(t *testing.T) {
+	srv := diffServer(`{}`, 200)
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "health.yaml")
+	os.WriteFile(path, []byte(`
+config:
+  base_url: "http://unused.invalid"
+workflow:
+- step: "health"
+  request:
+    url: "/health"
+  expect:
+    body_contains: "${expected}"
+`), 0644)
+
+	r := New(10*time.Second, false, WithVars(map[string]string{"base_url": srv.URL, "expected": "ok"}))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{path}); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// dryRunOAuth2Token stands in for OAuth2 access tokens, which are never
+// fetched during a dry run.
+const dryRunOAuth2Token = "<oauth2 token>"
+
+// WithDryRun resolves and prints every request instead of sending it.
+// Captures are not run, so variables captured by earlier steps stay as
+// ${name} placeholders.
+func WithDryRun(enabled bool) Option {
+	return func(r *Runner) {
+		r.dryRun = enabled
+	}
+}
+
+// logDryRun prints a request as it would be sent: method and full URL,
+// headers and body.
+func (r *Runner) logDryRun(req *http.Request, log func(string, ...interface{})) error {
+	dump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return err
+	}
+	// Replace the request line and Host header with the absolute URL,
+	// unescaped so unresolved ${name} placeholders stay readable.
+	target := req.URL.String()
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	lines := strings.Split(formatDump(dump), "\n")
+	out := []string{req.Method + " " + target}
+	for _, line := range lines[1:] {
+		if !strings.HasPrefix(line, "Host: ") {
+			out = append(out, line)
+		}
+	}
+	log("%s", strings.Join(out, "\n"))
+	return nil
+}
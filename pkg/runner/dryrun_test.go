@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "workflow.yaml")
+	os.WriteFile(path, []byte(`
+config:
+  base_url: "http://127.0.0.1:1"
+  auth:
+    oauth2:
+      token_url: "http://127.0.0.1:1/token"
+      client_id: "id"
+      client_secret: "secret"
+workflow:
+- step: "create"
+  request:
+    method: POST
+    url: "/orders"
+    params:
+      dry: "yes"
+    body:
+      sku: "abc"
+  capture:
+  - json_path: "id"
+    as: "order_id"
+- step: "fetch"
+  request:
+    url: "/orders/${order_id}"
+- step: "feed"
+  websocket:
+    url: "/feed"
+    messages:
+    - send: '{"subscribe": "orders"}'
+`), 0644)
+
+	var out bytes.Buffer
+	r := New(time.Second, false, WithDryRun(true))
+	r.out = &out
+	if err := r.RunPaths([]string{path}); err != nil {
+		t.Fatalf("dry run should not send requests: %v", err)
+	}
+
+	for _, want := range []string{
+		"POST http://127.0.0.1:1/orders?dry=yes",
+		"Authorization: Bearer <oauth2 token>",
+		`{"sku":"abc"}`,
+		"GET http://127.0.0.1:1/orders/${order_id}",
+		"WebSocket ws://127.0.0.1:1/feed",
+		`Send: {"subscribe": "orders"}`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Host:") {
+		t.Errorf("expected Host header to be folded into the URL:\n%s", out.String())
+	}
+}
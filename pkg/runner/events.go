@@ -0,0 +1,152 @@
+package runner
+
+import (
+	"sync"
+	"time"
+)
+
+// Events observes a run as it happens, for progress displays, metrics or
+// recording. Files run in parallel, so the methods may be called from
+// several goroutines at once; calls for one file are made in order from a
+// single goroutine. The built-in reports are Events too.
+type Events interface {
+	OnStepStart(StepStart)
+	OnStepEnd(StepEnd)
+	OnFileEnd(FileEnd)
+}
+
+// StepStart describes a step that is about to run.
+type StepStart struct {
+	File  string // workflow path
+	Step  string
+	Index int // position in the workflow, from 0
+}
+
+// StepEnd describes a step that has finished. Err is nil if the step
+// passed, otherwise a *StepError.
+type StepEnd struct {
+	File     string
+	Step     string
+	Index    int
+	Duration time.Duration
+	Logs     []string
+	Err      error
+
+	vars map[string]string // the file's variables after the step
+}
+
+// FileEnd describes a workflow file that has finished. Errs holds every
+// failure, including those of its steps; Skipped counts steps that never
+// ran because the file failed first or the run was cancelled.
+type FileEnd struct {
+	File     string
+	Name     string
+	Passed   int
+	Failed   int
+	Skipped  int
+	Duration time.Duration
+	Errs     []error
+
+	result fileResult
+}
+
+// EventFuncs implements Events with optional functions, so callers only
+// need to set the callbacks they use.
+type EventFuncs struct {
+	StepStart func(StepStart)
+	StepEnd   func(StepEnd)
+	FileEnd   func(FileEnd)
+}
+
+func (f EventFuncs) OnStepStart(ev StepStart) {
+	if f.StepStart != nil {
+		f.StepStart(ev)
+	}
+}
+
+func (f EventFuncs) OnStepEnd(ev StepEnd) {
+	if f.StepEnd != nil {
+		f.StepEnd(ev)
+	}
+}
+
+func (f EventFuncs) OnFileEnd(ev FileEnd) {
+	if f.FileEnd != nil {
+		f.FileEnd(ev)
+	}
+}
+
+// WithEvents calls events as RunPaths runs each step and file. It can be
+// given more than once; handlers are called in the order they were added,
+// after the runner's own report.
+func WithEvents(events Events) Option {
+	return func(r *Runner) {
+		r.events = append(r.events, events)
+	}
+}
+
+func (r *Runner) stepStarted(ev StepStart) {
+	for _, events := range r.events {
+		events.OnStepStart(ev)
+	}
+}
+
+func (r *Runner) stepEnded(ev StepEnd) {
+	for _, events := range r.events {
+		events.OnStepEnd(ev)
+	}
+}
+
+func (r *Runner) fileEnded(ev FileEnd) {
+	for _, events := range r.events {
+		events.OnFileEnd(ev)
+	}
+}
+
+// fileEnd builds the FileEnd event for a finished file.
+func fileEnd(res fileResult) FileEnd {
+	ev := FileEnd{
+		File:     res.path,
+		Name:     res.name,
+		Skipped:  res.skipped,
+		Duration: res.duration,
+		Errs:     res.errs,
+		result:   res,
+	}
+	for _, step := range res.steps {
+		if step.err != nil {
+			ev.Failed++
+		} else {
+			ev.Passed++
+		}
+	}
+	return ev
+}
+
+// reportEvents writes finished files to a reporter, one at a time, keeping
+// the progress line out of the way.
+type reportEvents struct {
+	mu       sync.Mutex
+	report   reporter
+	progress *progressLine
+}
+
+func (re *reportEvents) OnStepStart(StepStart) {}
+
+func (re *reportEvents) OnStepEnd(StepEnd) {}
+
+func (re *reportEvents) OnFileEnd(ev FileEnd) {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	re.progress.clear()
+	re.report.file(ev.result)
+	re.progress.add(ev.result)
+}
+
+// done finishes the report once every file has ended.
+func (re *reportEvents) done() {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	re.progress.clear()
+	re.report.done()
+}
@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "events.yaml")
+	workflow := fmt.Sprintf(`
+metadata:
+  name: "Events"
+config:
+  base_url: "%s"
+workflow:
+- step: "ok"
+  request:
+    method: "GET"
+    url: "/"
+  expect:
+    status: 200
+- step: "missing"
+  request:
+    method: "GET"
+    url: "/missing"
+  expect:
+    status: 200
+`, srv.URL)
+	if err := os.WriteFile(path, []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var calls []string
+	var out bytes.Buffer
+	events := EventFuncs{
+		StepStart: func(ev StepStart) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, fmt.Sprintf("start %s %d", ev.Step, ev.Index))
+		},
+		StepEnd: func(ev StepEnd) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, fmt.Sprintf("end %s %t", ev.Step, ev.Err == nil))
+		},
+		FileEnd: func(ev FileEnd) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, fmt.Sprintf("file %s %d/%d %d", ev.Name, ev.Passed, ev.Failed, len(ev.Errs)))
+			if !strings.Contains(out.String(), "✗ missing") {
+				t.Errorf("expected the report to be written before FileEnd, got %q", out.String())
+			}
+		},
+	}
+
+	r := New(5*time.Second, false, WithEvents(events))
+	r.out = &out
+	if err := r.RunPaths([]string{path}); err == nil {
+		t.Fatal("expected the missing step to fail")
+	}
+	want := []string{"start ok 0", "end ok true", "start missing 1", "end missing false", "file Events 1/1 1"}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected events:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,426 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// An expression is arithmetic, comparison and logic over variables,
+// numbers, quoted strings and true and false, as in ${count + 1} or
+// when: page < total_pages. Operators, from lowest to highest precedence:
+//
+//	||
+//	&&
+//	== != < <= > >=
+//	+ -
+//	* / %
+//	! and unary -
+//
+// Variable names may contain - and ., so subtraction needs spaces around
+// the -. Variables holding numbers are compared and added as numbers, and
+// other values as strings.
+
+// exprFunc evaluates a parsed expression against vars.
+type exprFunc func(vars map[string]string) (interface{}, error)
+
+// parseExpr parses expr, returning its evaluator and the variables it
+// refers to.
+func parseExpr(expr string) (exprFunc, []string, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	fn, err := p.or()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, nil, fmt.Errorf("unexpected %s", p.tokens[p.pos].text)
+	}
+	return fn, p.vars, nil
+}
+
+// evalArith returns the value of expr if it is an expression with at least
+// one operator. ok is false if it isn't one or can't be evaluated, such as
+// when a variable isn't set.
+func evalArith(expr string, vars map[string]string) (string, bool) {
+	fn, _, ok := parseArith(expr)
+	if !ok {
+		return "", false
+	}
+	v, err := fn(vars)
+	if err != nil {
+		return "", false
+	}
+	return formatValue(v), true
+}
+
+// arithVars returns the variables in expr if it is an expression with at
+// least one operator, rather than a single name or value.
+func arithVars(expr string) ([]string, bool) {
+	_, names, ok := parseArith(expr)
+	return names, ok
+}
+
+func parseArith(expr string) (exprFunc, []string, bool) {
+	if tokens, err := tokenize(expr); err != nil || len(tokens) < 2 {
+		return nil, nil, false
+	}
+	fn, names, err := parseExpr(expr)
+	return fn, names, err == nil
+}
+
+// evalCondition evaluates a step's when expression. It may be written bare
+// or wrapped in ${...}.
+func evalCondition(when string, vars map[string]string) (bool, error) {
+	expr, _ := conditionExpr(when)
+	fn, _, err := parseExpr(expr)
+	if err != nil {
+		return false, err
+	}
+	v, err := fn(vars)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+// stepWhen reports whether step should send its request, which it should
+// unless its when expression is false.
+func stepWhen(step Step, vars map[string]string) (bool, error) {
+	if step.When == "" {
+		return true, nil
+	}
+	run, err := evalCondition(step.When, vars)
+	return run, e.Wrapf(err, "when %s", step.When)
+}
+
+// conditionExpr returns the expression in when, and whether it was
+// wrapped in ${...}.
+func conditionExpr(when string) (string, bool) {
+	when = strings.TrimSpace(when)
+	if strings.HasPrefix(when, "${") && strings.HasSuffix(when, "}") {
+		return when[2 : len(when)-1], true
+	}
+	return when, false
+}
+
+type exprToken struct {
+	kind byte // 'n' number, 's' string, 'i' identifier, 'o' operator
+	text string
+}
+
+// exprOps are the operators, longest first so <= isn't read as <.
+var exprOps = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")"}
+
+func tokenize(expr string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(expr) && (expr[j] >= '0' && expr[j] <= '9' || expr[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, exprToken{'n', expr[i:j]})
+			i = j
+		case c == '"' || c == '\'':
+			j := strings.IndexByte(expr[i+1:], c)
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, exprToken{'s', expr[i+1 : i+1+j]})
+			i += j + 2
+		case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			j := i
+			for j < len(expr) && isNameByte(expr[j]) {
+				j++
+			}
+			tokens = append(tokens, exprToken{'i', expr[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, o := range exprOps {
+				if strings.HasPrefix(expr[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			tokens = append(tokens, exprToken{'o', op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// exprParser is a recursive descent parser with one method per precedence
+// level.
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	vars   []string
+}
+
+func (p *exprParser) accept(ops ...string) string {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == 'o' {
+		for _, op := range ops {
+			if p.tokens[p.pos].text == op {
+				p.pos++
+				return op
+			}
+		}
+	}
+	return ""
+}
+
+// binary parses operands with next, joined by any of ops.
+func (p *exprParser) binary(next func() (exprFunc, error), ops ...string) (exprFunc, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.accept(ops...); op != ""; op = p.accept(ops...) {
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryOp(op, left, right)
+	}
+	return left, nil
+}
+
+func (p *exprParser) or() (exprFunc, error)  { return p.binary(p.and, "||") }
+func (p *exprParser) and() (exprFunc, error) { return p.binary(p.cmp, "&&") }
+func (p *exprParser) cmp() (exprFunc, error) {
+	return p.binary(p.add, "==", "!=", "<=", ">=", "<", ">")
+}
+func (p *exprParser) add() (exprFunc, error) { return p.binary(p.mul, "+", "-") }
+func (p *exprParser) mul() (exprFunc, error) { return p.binary(p.unary, "*", "/", "%") }
+
+func (p *exprParser) unary() (exprFunc, error) {
+	switch p.accept("!", "-") {
+	case "!":
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(vars map[string]string) (interface{}, error) {
+			v, err := operand(vars)
+			return !truthy(v), err
+		}, nil
+	case "-":
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(vars map[string]string) (interface{}, error) {
+			v, err := operand(vars)
+			if err != nil {
+				return nil, err
+			}
+			n, ok := toNumber(v)
+			if !ok {
+				return nil, fmt.Errorf("%s is not a number", formatValue(v))
+			}
+			return -n, nil
+		}, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (exprFunc, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.kind {
+	case 'n':
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", tok.text)
+		}
+		return constant(n), nil
+	case 's':
+		return constant(tok.text), nil
+	case 'i':
+		switch tok.text {
+		case "true", "false":
+			return constant(tok.text == "true"), nil
+		}
+		p.vars = append(p.vars, tok.text)
+		return func(vars map[string]string) (interface{}, error) {
+			v, ok := vars[tok.text]
+			if !ok {
+				return nil, fmt.Errorf("variable %s is not set", tok.text)
+			}
+			return v, nil
+		}, nil
+	}
+	if tok.text == "(" {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.accept(")") == "" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return inner, nil
+	}
+	return nil, fmt.Errorf("unexpected %s", tok.text)
+}
+
+func constant(v interface{}) exprFunc {
+	return func(map[string]string) (interface{}, error) { return v, nil }
+}
+
+func binaryOp(op string, left, right exprFunc) exprFunc {
+	return func(vars map[string]string) (interface{}, error) {
+		a, err := left(vars)
+		if err != nil {
+			return nil, err
+		}
+		// && and || only evaluate their right side when it decides the
+		// result, so when: has_more && cursor != '' doesn't fail on the
+		// last page, where no cursor was captured.
+		switch op {
+		case "&&":
+			if !truthy(a) {
+				return false, nil
+			}
+		case "||":
+			if truthy(a) {
+				return true, nil
+			}
+		}
+		b, err := right(vars)
+		if err != nil {
+			return nil, err
+		}
+		x, xNum := toNumber(a)
+		y, yNum := toNumber(b)
+		numeric := xNum && yNum
+
+		switch op {
+		case "&&", "||":
+			return truthy(b), nil
+		case "==":
+			if numeric {
+				return x == y, nil
+			}
+			return formatValue(a) == formatValue(b), nil
+		case "!=":
+			if numeric {
+				return x != y, nil
+			}
+			return formatValue(a) != formatValue(b), nil
+		case "<", "<=", ">", ">=":
+			c := strings.Compare(formatValue(a), formatValue(b))
+			if numeric {
+				c = cmpFloat(x, y)
+			}
+			switch op {
+			case "<":
+				return c < 0, nil
+			case "<=":
+				return c <= 0, nil
+			case ">":
+				return c > 0, nil
+			}
+			return c >= 0, nil
+		case "+":
+			if !numeric {
+				return formatValue(a) + formatValue(b), nil
+			}
+			return x + y, nil
+		}
+
+		if !xNum {
+			return nil, fmt.Errorf("%s is not a number", formatValue(a))
+		}
+		if !yNum {
+			return nil, fmt.Errorf("%s is not a number", formatValue(b))
+		}
+		switch op {
+		case "-":
+			return x - y, nil
+		case "*":
+			return x * y, nil
+		}
+		if y == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if op == "%" {
+			return math.Mod(x, y), nil
+		}
+		return x / y, nil
+	}
+}
+
+func cmpFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// toNumber returns v as a number, if it is one or is a string holding one.
+func toNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// truthy reports whether v counts as true: a true boolean, a number other
+// than 0, or a string other than "", "0" and "false".
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != "" && v != "0" && v != "false"
+	}
+	return false
+}
+
+// formatValue formats a result for substitution. Numbers are written
+// without an exponent and rounded to 15 significant digits, so
+// ${price * 1.2} gives 23.988 rather than 23.987999999999996.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		s := strconv.FormatFloat(v, 'g', 15, 64)
+		if strings.ContainsAny(s, "e") {
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return s
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
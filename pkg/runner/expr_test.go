@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpressions(t *testing.T) {
+	vars := map[string]string{
+		"count":       "3",
+		"price":       "19.99",
+		"page":        "2",
+		"total-pages": "5",
+		"status":      "active",
+		"empty":       "",
+	}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"${count + 1}", "4"},
+		{"${price * 1.2}", "23.988"},
+		{"${(count + 1) * 2 - 1}", "7"},
+		{"${count / 2}", "1.5"},
+		{"${count % 2}", "1"},
+		{"${-count}", "-3"},
+		{"${total-pages - page}", "3"},
+		{"${page < total-pages}", "true"},
+		{"${status == 'active' && count >= 3}", "true"},
+		{"${!empty || count > 10}", "true"},
+		{"${status + '-' + page}", "active-2"},
+		{"${count / 0}", "${count / 0}"},
+		{"${status * 2}", "${status * 2}"},
+		{"${missing + 1}", "${missing + 1}"},
+		{"${count +}", "${count +}"},
+		{"${5}", "${5}"},
+	}
+	for _, tt := range tests {
+		if got, err := (&Runner{}).applyVars(tt.input, vars); err != nil || got != tt.want {
+			t.Errorf("applyVars(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		when string
+		want bool
+		err  bool
+	}{
+		{"page < total-pages", true, false},
+		{"${count == 3}", true, false},
+		{"empty", false, false},
+		{"status != 'active' && missing > 1", false, false},
+		{"missing > 1", false, true},
+		{"page <", false, true},
+	} {
+		got, err := evalCondition(tt.when, vars)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("evalCondition(%q) = %v, %v", tt.when, got, err)
+		}
+	}
+}
+
+func TestStepWhen(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.String())
+		w.Write([]byte(`{"page": 1, "total_pages": 1}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "when.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "first page"
+  request:
+    url: "/items?page=1"
+  capture:
+  - json_path: "page"
+    as: "page"
+  - json_path: "total_pages"
+    as: "total_pages"
+- step: "next page"
+  when: "page < total_pages"
+  request:
+    url: "/items?page=${page + 1}"
+- step: "summary"
+  when: "${page == total_pages}"
+  request:
+    url: "/summary?pages=${total_pages}"
+`, srv.URL)), 0644)
+
+	r := New(10*time.Second, false)
+	r.out = io.Discard
+	result, err := r.Run(t.Context(), []string{path})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := strings.Join(seen, ","); got != "/items?page=1,/summary?pages=1" {
+		t.Errorf("requests = %s", got)
+	}
+	if logs := result.Files[0].Steps[1].Logs; len(logs) == 0 || logs[0] != "Skipped: when page < total_pages is false" {
+		t.Errorf("next page logs = %q", logs)
+	}
+
+	problems, err := r.Lint([]string{path})
+	if err != nil || len(problems) != 0 {
+		t.Errorf("expected no lint problems, got %v, %v", problems, err)
+	}
+}
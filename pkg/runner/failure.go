@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// bodyExcerptSize is how much of a response body a StepError keeps.
+const bodyExcerptSize = 1024
+
+// ExpectationError is a response value that didn't match what the
+// workflow expected. When either value spans several lines, such as a
+// pretty-printed JSON object, the error text ends with a line diff of the
+// two.
+type ExpectationError struct {
+	Message  string
+	Expected string
+	Actual   string
+}
+
+func (x *ExpectationError) Error() string {
+	if diff := x.Diff(); diff != nil {
+		return x.Message + "\n" + strings.Join(diff, "\n")
+	}
+	return x.Message
+}
+
+// Diff returns a line diff of the expected and actual values, with "-"
+// marking expected lines and "+" actual ones, or nil if both fit on one
+// line.
+func (x *ExpectationError) Diff() []string {
+	if !strings.Contains(x.Expected, "\n") && !strings.Contains(x.Actual, "\n") {
+		return nil
+	}
+	return lineDiff(x.Expected, x.Actual)
+}
+
+// exchange records what a step sent and received, so a failure can be
+// reported with the request and response that caused it.
+type exchange struct {
+	request string
+	status  int
+	body    string
+	hasBody bool
+}
+
+// setBody keeps the start of a response body.
+func (x *exchange) setBody(data []byte) {
+	x.body, x.hasBody = excerpt(data), true
+}
+
+// readBody keeps the start of a response body that the step didn't read.
+func (x *exchange) readBody(resp *http.Response) {
+	decoded, err := decodeBody(resp)
+	if err != nil {
+		return
+	}
+	data, _ := io.ReadAll(io.LimitReader(decoded, bodyExcerptSize+1))
+	x.setBody(data)
+}
+
+// excerpt returns data as text, cut to bodyExcerptSize bytes without
+// splitting a character.
+func excerpt(data []byte) string {
+	if len(data) <= bodyExcerptSize {
+		return string(data)
+	}
+	cut := bodyExcerptSize
+	for cut > 0 && !utf8.RuneStart(data[cut]) {
+		cut--
+	}
+	return string(data[:cut]) + "…"
+}
+
+// MarshalJSON encodes the failure with its request, response and, for
+// failed comparisons, the expected and actual values.
+func (e *StepError) MarshalJSON() ([]byte, error) {
+	var msg string
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	return json.Marshal(struct {
+		File        string   `json:"file"`
+		Step        string   `json:"step"`
+		Description string   `json:"description,omitempty"`
+		Error       string   `json:"error"`
+		Code        string   `json:"code,omitempty"`
+		Hint        string   `json:"hint,omitempty"`
+		Request     string   `json:"request,omitempty"`
+		Status      int      `json:"status,omitempty"`
+		Body        string   `json:"body,omitempty"`
+		Expected    string   `json:"expected,omitempty"`
+		Actual      string   `json:"actual,omitempty"`
+		DurationMS  *float64 `json:"duration_ms,omitempty"`
+	}{
+		File:        e.File,
+		Step:        e.Step,
+		Description: e.Description,
+		Error:       msg,
+		Code:        errorCode(e.Err),
+		Hint:        errorHint(e.Err),
+		Request:     e.Request,
+		Status:      e.Status,
+		Body:        e.Body,
+		Expected:    e.Expected,
+		Actual:      e.Actual,
+		DurationMS:  durationMS(e.Duration),
+	})
+}
+
+func durationMS(d time.Duration) *float64 {
+	if d == 0 {
+		return nil
+	}
+	ms := float64(d) / float64(time.Millisecond)
+	return &ms
+}
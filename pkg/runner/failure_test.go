@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStepErrorDetails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"no such user"}`))
+			return
+		}
+		w.Write([]byte(`{"name":"bob"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		expect string
+		url    string
+		want   StepError
+	}{
+		{"status", "status: 200", "/missing", StepError{Status: 404, Body: `{"error":"no such user"}`, Expected: "200", Actual: "404"}},
+		{"jsonpath", "json_path_match:\n    - path: name\n      value: alice", "/users/1", StepError{Status: 200, Body: `{"name":"bob"}`, Expected: "alice", Actual: "bob"}},
+		{"content encoding", "content_encoding: gzip", "/users/1", StepError{Status: 200, Body: `{"name":"bob"}`, Expected: "gzip", Actual: "identity"}},
+		{"content length", "content_length: 20", "/users/1", StepError{Status: 200, Body: `{"name":"bob"}`, Expected: "20", Actual: "14"}},
+		{"sha256", "sha256: abc", "/users/1", StepError{Status: 200, Body: `{"name":"bob"}`, Expected: "abc", Actual: "91a73e713f3ccae0a3f290f976316ffdc5b7182fb96ebac574c92c424473b5c6"}},
+		{"body contains", "body_contains: alice", "/users/1", StepError{Status: 200, Body: `{"name":"bob"}`, Expected: "alice", Actual: `{"name":"bob"}`}},
+		{"body regex", "body_regex: ^alice", "/users/1", StepError{Status: 200, Body: `{"name":"bob"}`, Expected: "^alice", Actual: `{"name":"bob"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "w.yaml")
+			os.WriteFile(path, []byte(fmt.Sprintf("config:\n  base_url: %q\nworkflow:\n- step: get\n  request:\n    url: %s\n  expect:\n    %s\n", srv.URL, tt.url, tt.expect)), 0644)
+			r := New(5*time.Second, false)
+			r.out = io.Discard
+			var se *StepError
+			if err := r.RunPaths([]string{path}); !errors.As(err, &se) {
+				t.Fatalf("RunPaths() error = %v", err)
+			}
+			if se.Request != "GET "+srv.URL+tt.url || se.Status != tt.want.Status || se.Body != tt.want.Body ||
+				se.Expected != tt.want.Expected || se.Actual != tt.want.Actual || se.Duration <= 0 {
+				t.Errorf("StepError = %+v", se)
+			}
+		})
+	}
+}
+
+func TestStepErrorJSON(t *testing.T) {
+	se := &StepError{
+		File:     "users.yaml",
+		Step:     "get",
+		Err:      &ExpectationError{"expected status 200, got 404", "200", "404"},
+		Request:  "GET http://api/users/1",
+		Status:   404,
+		Expected: "200",
+		Actual:   "404",
+		Duration: 1500 * time.Microsecond,
+	}
+	data, err := json.Marshal(se)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"file":"users.yaml","step":"get","error":"expected status 200, got 404","request":"GET http://api/users/1","status":404,"expected":"200","actual":"404","duration_ms":1.5}`
+	if string(data) != want {
+		t.Errorf("json = %s\nwant %s", data, want)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	if got := excerpt([]byte("short")); got != "short" {
+		t.Errorf("excerpt = %q", got)
+	}
+	long := strings.Repeat("a", bodyExcerptSize-1) + "é" + "tail"
+	got := excerpt([]byte(long))
+	if got != strings.Repeat("a", bodyExcerptSize-1)+"…" {
+		t.Errorf("excerpt cut a character: %q", got[len(got)-8:])
+	}
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Format rewrites a workflow file in canonical form: keys in the order the
+// workflow types declare them, unknown keys after known ones, two-space
+// indentation, block style, and double-quoted string values (literal blocks
+// for multi-line strings). Comments are preserved.
+func Format(data []byte) ([]byte, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var docs []*yaml.Node
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err := e.Wrap(err, "parse workflow"); err != nil {
+			return nil, err
+		}
+		formatNode(&doc, reflect.TypeOf(InstructionsFile{}))
+		docs = append(docs, &doc)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := e.Wrap(enc.Encode(doc), "format workflow"); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return spaceSections(buf.String()), nil
+}
+
+// spaceSections separates top-level sections and workflow steps with a blank
+// line, as the example workflows do. Comments stay attached to the line
+// they precede.
+func spaceSections(out string) []byte {
+	var lines []string
+	inWorkflow, firstStep := false, false
+	for _, line := range strings.SplitAfter(out, "\n") {
+		topLevel := line != "" && !strings.ContainsAny(line[:1], " #-\n")
+		boundary := topLevel && len(lines) > 0
+		if topLevel {
+			inWorkflow = strings.HasPrefix(line, "workflow:")
+			firstStep = true
+		} else if inWorkflow && strings.HasPrefix(line, "  - ") {
+			boundary = !firstStep
+			firstStep = false
+		}
+
+		if boundary {
+			at := len(lines)
+			for at > 0 && strings.HasPrefix(strings.TrimSpace(lines[at-1]), "#") {
+				at--
+			}
+			if at > 0 && lines[at-1] != "\n" {
+				lines = append(lines[:at], append([]string{"\n"}, lines[at:]...)...)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// formatNode normalises node in place. typ is the Go type the node decodes
+// into, used to order keys; it is nil below free-form values such as bodies.
+func formatNode(node *yaml.Node, typ reflect.Type) {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			formatNode(child, typ)
+		}
+	case yaml.SequenceNode:
+		node.Style = 0
+		var elem reflect.Type
+		if typ != nil && typ.Kind() == reflect.Slice {
+			elem = typ.Elem()
+		}
+		for _, child := range node.Content {
+			formatNode(child, elem)
+		}
+	case yaml.MappingNode:
+		node.Style = 0
+		formatMapping(node, typ)
+	case yaml.ScalarNode:
+		formatScalar(node)
+	}
+}
+
+func formatMapping(node *yaml.Node, typ reflect.Type) {
+	type pair struct {
+		key, value *yaml.Node
+		order      int
+	}
+	pairs := make([]pair, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		key.Style = 0
+		order, valueType := fieldFor(typ, key.Value)
+		formatNode(value, valueType)
+		pairs = append(pairs, pair{key: key, value: value, order: order})
+	}
+
+	if typ != nil && typ.Kind() == reflect.Struct {
+		// Stable so unknown keys keep their relative order.
+		sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].order < pairs[j].order })
+	}
+
+	node.Content = node.Content[:0]
+	for _, p := range pairs {
+		node.Content = append(node.Content, p.key, p.value)
+	}
+}
+
+// fieldFor returns the declaration index and type of the field a key maps to.
+// Keys of maps keep their order; unknown struct keys sort last.
+func fieldFor(typ reflect.Type, key string) (int, reflect.Type) {
+	if typ == nil {
+		return 0, nil
+	}
+	switch typ.Kind() {
+	case reflect.Map:
+		return 0, typ.Elem()
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if f.IsExported() && name == key {
+				return i, f.Type
+			}
+		}
+		return typ.NumField(), nil
+	}
+	return 0, nil
+}
+
+func formatScalar(node *yaml.Node) {
+	if node.Tag != "!!str" {
+		node.Style = 0
+		return
+	}
+	if strings.Contains(strings.TrimSuffix(node.Value, "\n"), "\n") {
+		node.Style = yaml.LiteralStyle
+		return
+	}
+	node.Style = yaml.DoubleQuotedStyle
+}
@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"os"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFormat(t *testing.T) {
+	input := `workflow:
+- expect: {status: 200}
+  request:
+    url: /users/${id}
+    headers: {X-B: b, X-A: a}
+    method: GET
+    retries: 3
+    body_raw: "line one\nline two\n"
+  # fetch the user
+  step: get-user
+  capture:
+  - {as: name, json_path: name}
+config:
+  headers:
+    "200": ok
+  base_url: 'https://api.example.com'
+metadata:
+  name: Users
+`
+	want := `metadata:
+  name: "Users"
+
+config:
+  base_url: "https://api.example.com"
+  headers:
+    "200": "ok"
+
+workflow:
+  - # fetch the user
+    step: "get-user"
+    request:
+      method: "GET"
+      url: "/users/${id}"
+      headers:
+        X-B: "b"
+        X-A: "a"
+      body_raw: |
+        line one
+        line two
+      retries: 3
+    expect:
+      status: 200
+    capture:
+      - json_path: "name"
+        as: "name"
+`
+	got, err := Format([]byte(input))
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+
+	again, err := Format(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(got) {
+		t.Errorf("Format is not idempotent:\n%s", again)
+	}
+}
+
+func TestFormatPreservesWorkflow(t *testing.T) {
+	data, err := os.ReadFile("../../resources/testdata/success/bodyFileDemo.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Format(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec, formatted InstructionsFile
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(got, &formatted); err != nil {
+		t.Fatalf("formatted output does not parse: %v\n%s", err, got)
+	}
+	if len(formatted.Workflow) != len(spec.Workflow) || formatted.Workflow[1].Request.BodyFile != spec.Workflow[1].Request.BodyFile {
+		t.Errorf("formatting changed the workflow:\n%s", got)
+	}
+}
+
+func TestFormatInvalidYAML(t *testing.T) {
+	if _, err := Format([]byte("workflow: [")); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
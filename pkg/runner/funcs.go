@@ -0,0 +1,249 @@
+package runner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// Func is a function that workflows can call in a substitution, such as
+// ${sign(body, "sha256")}. It receives its arguments as strings.
+type Func func(args ...string) (string, error)
+
+// builtinFuncs are the string and encoding functions every workflow can
+// use, usually as filters, as in ${token | base64}.
+var builtinFuncs = map[string]Func{
+	"upper":        filter(func(s string) (string, error) { return strings.ToUpper(s), nil }),
+	"lower":        filter(func(s string) (string, error) { return strings.ToLower(s), nil }),
+	"trim":         filter(func(s string) (string, error) { return strings.TrimSpace(s), nil }),
+	"base64":       filter(func(s string) (string, error) { return base64.StdEncoding.EncodeToString([]byte(s)), nil }),
+	"base64decode": filter(base64Decode),
+	"urlencode":    filter(func(s string) (string, error) { return url.QueryEscape(s), nil }),
+	"jsonescape":   filter(jsonEscape),
+	"sha256":       filter(func(s string) (string, error) { return fmt.Sprintf("%x", sha256.Sum256([]byte(s))), nil }),
+}
+
+var (
+	funcName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	funcCall = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\((.*)\)\s*$`)
+)
+
+// RegisterFunc makes fn available to the runner's workflows as
+// ${name(...)}, so an embedding program can expose its own ID generators,
+// signing and the like. Arguments are quoted strings, numbers or variable
+// names, which are replaced by their values. Each call in a step runs once,
+// when the step gets to it. If fn returns an error the step fails with it,
+// so a call in the request fails the step before the request is sent.
+//
+// Registering a name again replaces its function, including a built-in
+// one. Functions must be registered before the runner starts. It panics if
+// name isn't a valid identifier.
+func (r *Runner) RegisterFunc(name string, fn Func) {
+	if !funcName.MatchString(name) {
+		panic(fmt.Sprintf("runner: invalid function name %q", name))
+	}
+	if r.funcs == nil {
+		r.funcs = map[string]Func{}
+	}
+	r.funcs[name] = fn
+}
+
+func (r *Runner) lookupFunc(name string) Func {
+	if fn, ok := r.funcs[name]; ok {
+		return fn
+	}
+	return builtinFuncs[name]
+}
+
+// errUnresolved is returned by evalExpr for an expression that can't be
+// resolved, such as an unset variable or a call to an unknown function, as
+// opposed to one whose function fails.
+var errUnresolved = errors.New("unresolved")
+
+// call runs the function registered as name with the values of args,
+// after any values piped into it. It returns errUnresolved if there is no
+// such function or an argument names an unset variable, or the error the
+// function returns.
+func (r *Runner) call(name string, args []string, vars map[string]string, piped ...string) (string, error) {
+	fn := r.lookupFunc(name)
+	if fn == nil {
+		return "", errUnresolved
+	}
+	values := piped
+	for _, arg := range args {
+		value, ok := argValue(arg, vars)
+		if !ok {
+			return "", errUnresolved
+		}
+		values = append(values, value)
+	}
+	result, err := fn(values...)
+	return result, e.Wrapf(err, "%s", name)
+}
+
+// splitPipe splits value | filter | filter(args) into the expression before
+// the first | and the filters after it. ok is false if there is no |.
+func splitPipe(expr string) (head string, filters []string, ok bool) {
+	parts, closed := splitQuoted(expr, '|')
+	if !closed || len(parts) < 2 {
+		return "", nil, false
+	}
+	return parts[0], parts[1:], true
+}
+
+// pipe evaluates head and passes its value through each filter in turn. A
+// filter names a function, which is called with the value, or is a call,
+// which gets the value before its own arguments, so ${id | pad(8)} calls
+// pad(id, 8).
+func (r *Runner) pipe(head string, filters []string, vars map[string]string) (string, error) {
+	value, err := r.evalExpr(head, vars)
+	if err != nil {
+		return "", err
+	}
+	for _, filter := range filters {
+		name, args, isCall := parseCall(filter)
+		if !isCall {
+			if name = filter; !funcName.MatchString(name) {
+				return "", errUnresolved
+			}
+		}
+		if value, err = r.call(name, args, vars, value); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
+
+// parseCall splits name(arg, "arg", 1) into its name and arguments.
+func parseCall(expr string) (name string, args []string, ok bool) {
+	m := funcCall.FindStringSubmatch(expr)
+	if m == nil {
+		return "", nil, false
+	}
+	args, ok = splitArgs(m[2])
+	return m[1], args, ok
+}
+
+// splitArgs splits a comma-separated argument list, keeping commas inside
+// quotes.
+func splitArgs(list string) ([]string, bool) {
+	if strings.TrimSpace(list) == "" {
+		return nil, true
+	}
+	return splitQuoted(list, ',')
+}
+
+// splitQuoted splits s at each sep outside quotes, trimming the parts. A
+// doubled sep, as in the || of an expression, isn't split. ok is false if a
+// quote is left open.
+func splitQuoted(s string, sep rune) ([]string, bool) {
+	var parts []string
+	var quote rune
+	start := 0
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == sep && !strings.HasPrefix(s[i+1:], string(sep)) && !strings.HasSuffix(s[:i], string(sep)):
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, false
+	}
+	return append(parts, strings.TrimSpace(s[start:])), true
+}
+
+// argValue returns the value of a quoted string, a number or a variable.
+func argValue(arg string, vars map[string]string) (string, bool) {
+	if len(arg) >= 2 && (arg[0] == '"' || arg[0] == '\'') && arg[len(arg)-1] == arg[0] {
+		return arg[1 : len(arg)-1], true
+	}
+	if _, err := strconv.ParseFloat(arg, 64); err == nil {
+		return arg, true
+	}
+	value, ok := vars[arg]
+	return value, ok
+}
+
+// exprVars returns the variables a substitution refers to: the expression
+// itself, the name before a :- or :? fallback or an [index], the variable
+// arguments of a function call or the variables in an expression such as
+// count + 1, along with those of the filters in a pipeline.
+func exprVars(expr string) []string {
+	if head, filters, ok := splitPipe(expr); ok {
+		names := exprVars(head)
+		for _, filter := range filters {
+			if _, args, isCall := parseCall(filter); isCall {
+				names = append(names, argVars(args)...)
+			}
+		}
+		return names
+	}
+	if name, _, _, ok := splitFallback(expr); ok {
+		return []string{name}
+	}
+	if m := indexExpr.FindStringSubmatch(expr); m != nil {
+		return []string{m[1]}
+	}
+	_, args, isCall := parseCall(expr)
+	if isCall {
+		return argVars(args)
+	}
+	if names, ok := arithVars(expr); ok {
+		return names
+	}
+	return []string{expr}
+}
+
+// argVars returns the arguments that name variables.
+func argVars(args []string) []string {
+	var names []string
+	for _, arg := range args {
+		if funcName.MatchString(arg) {
+			names = append(names, arg)
+		}
+	}
+	return names
+}
+
+// filter makes fn a Func that takes exactly one argument.
+func filter(fn func(string) (string, error)) Func {
+	return func(args ...string) (string, error) {
+		if len(args) != 1 {
+			return "", fmt.Errorf("expected 1 argument, got %d", len(args))
+		}
+		return fn(args[0])
+	}
+}
+
+func base64Decode(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	return string(data), err
+}
+
+// jsonEscape escapes s for use inside a JSON string, without the quotes.
+func jsonEscape(s string) (string, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return "", err
+	}
+	quoted := bytes.TrimSpace(b.Bytes())
+	return string(quoted[1 : len(quoted)-1]), nil
+}
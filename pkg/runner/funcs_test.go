@@ -0,0 +1,194 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegisterFunc(t *testing.T) {
+	r := New(10*time.Second, false)
+	r.RegisterFunc("test_join", func(args ...string) (string, error) {
+		return strings.Join(args, "|"), nil
+	})
+	r.RegisterFunc("test_fail", func(args ...string) (string, error) {
+		return "", fmt.Errorf("boom")
+	})
+
+	vars := map[string]string{"user": "ada"}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`${test_join(user, "a, b", 'c', 42)}`, "ada|a, b|c|42"},
+		{`id-${test_join()}`, "id-"},
+		{`${test_join(missing)}`, `${test_join(missing)}`},
+		{`${unknown_func(user)}`, `${unknown_func(user)}`},
+		{`${test_join("unterminated)}`, `${test_join("unterminated)}`},
+	}
+	for _, tt := range tests {
+		if got, err := r.applyVars(tt.input, vars); err != nil || got != tt.want {
+			t.Errorf("applyVars(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+	if got, err := r.applyVars("id-${test_fail(user)}", vars); got != "id-${test_fail(user)}" || err == nil || err.Error() != "${test_fail(user)}: test_fail: boom" {
+		t.Errorf("expected the function's error, got %q, %v", got, err)
+	}
+	if got, _ := New(10*time.Second, false).applyVars("${test_join(user)}", vars); got != "${test_join(user)}" {
+		t.Errorf("expected functions to belong to the runner they were registered on, got %q", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected RegisterFunc to panic on an invalid name")
+		}
+	}()
+	r.RegisterFunc("not-valid", nil)
+}
+
+func TestFuncErrorFailsStep(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.String())
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "sign.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "sign"
+  request:
+    url: "/sign"
+    headers:
+      X-Signature: "${tenant | sign}"
+- step: "list"
+  request:
+    url: "/list"
+`, srv.URL)), 0644)
+
+	r := New(10*time.Second, false, WithVars(map[string]string{"tenant": "acme"}))
+	r.out = io.Discard
+	r.RegisterFunc("sign", func(args ...string) (string, error) {
+		return "", fmt.Errorf("no key for %s", args[0])
+	})
+	err := r.RunPaths([]string{path})
+	if err == nil || !strings.Contains(err.Error(), `step "sign" in `+path+` failed: ${tenant | sign}: sign: no key for acme`) {
+		t.Errorf("expected the function's error to fail the step, got %v", err)
+	}
+	if got := strings.Join(seen, ","); got != "/list" {
+		t.Errorf("requests = %s", got)
+	}
+}
+
+func TestFuncCalledOncePerSubstitution(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Path)
+		fmt.Fprintf(w, `{"path": %q}`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "ids.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "create"
+  request:
+    url: "/x/${next_id()}"
+  capture:
+  - json_path: "path"
+    as: "created"
+  output:
+    print: "created ${created}"
+`, srv.URL)), 0644)
+
+	r := New(10*time.Second, false)
+	r.out = io.Discard
+	calls := 0
+	r.RegisterFunc("next_id", func(args ...string) (string, error) {
+		calls++
+		return strconv.Itoa(calls), nil
+	})
+	if err := r.RunPaths([]string{path}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("next_id called %d times, want 1", calls)
+	}
+	if got := strings.Join(seen, ","); got != "/x/1" {
+		t.Errorf("requests = %s", got)
+	}
+}
+
+func TestLintFuncArgs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sign.yaml")
+	os.WriteFile(path, []byte(`
+workflow:
+- step: "sign"
+  request:
+    url: "/sign"
+    headers:
+      X-Signature: "${hmac(tenant, \"sha256\", user)}"
+`), 0644)
+
+	r := New(10*time.Second, false, WithVars(map[string]string{"tenant": "acme"}))
+	problems, err := r.Lint([]string{path})
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Message != "undefined variable ${user}" {
+		t.Errorf("expected only ${user} to be undefined, got %v", problems)
+	}
+}
+
+func TestPipeFilters(t *testing.T) {
+	r := New(10*time.Second, false)
+	r.RegisterFunc("test_pad", func(args ...string) (string, error) {
+		n, _ := strconv.Atoi(args[1])
+		return fmt.Sprintf("%0*s", n, args[0]), nil
+	})
+
+	vars := map[string]string{
+		"user":    "Ada Lovelace",
+		"secret":  "s3cret",
+		"payload": `say "hi" & <bye>`,
+		"id":      "42",
+	}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"${user | upper}", "ADA LOVELACE"},
+		{"${user|lower}", "ada lovelace"},
+		{"${secret | base64}", "czNjcmV0"},
+		{"${secret | base64 | base64decode}", "s3cret"},
+		{"/q?name=${user | urlencode}", "/q?name=Ada+Lovelace"},
+		{`{"note": "${payload | jsonescape}"}`, `{"note": "say \"hi\" & <bye>"}`},
+		{"${secret | sha256}", "1ec1c26b50d5d3c58d9583181af8076655fe00756bf7285940ba3670f99fcba0"},
+		{"${id | test_pad(6)}", "000042"},
+		{"${region:-eu | upper}", "EU"},
+		{"${missing | upper}", "${missing | upper}"},
+		{"${user | no_such_filter}", "${user | no_such_filter}"},
+	}
+	for _, tt := range tests {
+		if got, err := r.applyVars(tt.input, vars); err != nil || got != tt.want {
+			t.Errorf("applyVars(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+	if _, err := r.applyVars("${user | upper(1)}", vars); err == nil || err.Error() != "${user | upper(1)}: upper: expected 1 argument, got 2" {
+		t.Errorf("expected an error for a filter given too many arguments, got %v", err)
+	}
+	if got := exprVars("token | test_pad(width) | base64"); strings.Join(got, ",") != "token,width" {
+		t.Errorf("exprVars = %v", got)
+	}
+}
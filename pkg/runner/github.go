@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// githubReporter prints the text report and adds GitHub Actions workflow
+// commands, so failed steps are annotated on the workflow file in pull
+// requests. When GITHUB_STEP_SUMMARY is set, a Markdown summary of the run
+// is appended to it.
+type githubReporter struct {
+	*textReporter
+	summaryPath string
+	rows        []summaryRow
+	failures    []summaryFailure
+}
+
+type summaryRow struct {
+	name, path              string
+	passed, failed, skipped int
+	duration                time.Duration
+}
+
+// summaryFailure is a failed step, or a file that could not be run when
+// Step is empty.
+type summaryFailure struct {
+	File     string `json:"file"`
+	Workflow string `json:"workflow"`
+	Step     string `json:"step,omitempty"`
+	Error    string `json:"error"`
+}
+
+func newGitHubReporter(text *textReporter) *githubReporter {
+	return &githubReporter{textReporter: text, summaryPath: os.Getenv("GITHUB_STEP_SUMMARY")}
+}
+
+func (g *githubReporter) file(res fileResult) {
+	g.textReporter.file(res)
+
+	row := summaryRow{name: res.name, path: res.path, skipped: res.skipped}
+	for _, err := range res.fileErrors() {
+		g.annotate(res.path, 0, res.name, err.Error())
+		g.failures = append(g.failures, summaryFailure{File: res.path, Workflow: res.name, Error: err.Error()})
+	}
+	lines := stepLines(res.path)
+	for i, step := range res.steps {
+		row.duration += step.duration
+		if step.err == nil {
+			row.passed++
+			continue
+		}
+		row.failed++
+		line := 0
+		if i < len(lines) {
+			line = lines[i]
+		}
+		msg := step.failure().Error()
+		g.annotate(res.path, line, res.name+" > "+step.name, msg)
+		g.failures = append(g.failures, summaryFailure{File: res.path, Workflow: res.name, Step: step.name, Error: msg})
+	}
+	g.rows = append(g.rows, row)
+}
+
+// annotate writes an ::error workflow command. line is omitted when it is
+// not known.
+func (g *githubReporter) annotate(path string, line int, title, msg string) {
+	props := "file=" + escapeProperty(path)
+	if line > 0 {
+		props += fmt.Sprintf(",line=%d", line)
+	}
+	props += ",title=" + escapeProperty(title)
+	fmt.Fprintf(g.out, "::error %s::%s\n", props, escapeData(msg))
+}
+
+func (g *githubReporter) done() {
+	g.textReporter.done()
+	if g.summaryPath == "" {
+		return
+	}
+	f, err := os.OpenFile(g.summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Fprintf(g.out, "::warning::%s\n", escapeData("write step summary: "+err.Error()))
+		return
+	}
+	defer f.Close()
+	g.writeSummary(f)
+}
+
+// writeSummary writes a Markdown table of results per file, followed by
+// each failure.
+func (g *githubReporter) writeSummary(w io.Writer) {
+	status := "✅"
+	if g.failed > 0 || g.failedFiles > 0 {
+		status = "❌"
+	}
+	fmt.Fprintf(w, "### %s ramjam: %d passed, %d failed, %d skipped\n\n", status, g.passed, g.failed, g.skippedSteps)
+	fmt.Fprintln(w, "| Workflow | File | Passed | Failed | Skipped | Time |")
+	fmt.Fprintln(w, "|---|---|---|---|---|---|")
+	for _, row := range g.rows {
+		fmt.Fprintf(w, "| %s | `%s` | %d | %d | %d | %s |\n",
+			escapeCell(row.name), row.path, row.passed, row.failed, row.skipped, formatDuration(row.duration))
+	}
+	if len(g.failures) == 0 {
+		fmt.Fprintln(w)
+		return
+	}
+	fmt.Fprint(w, "\n#### Failures\n\n")
+	fmt.Fprintln(w, "| Workflow | Step | Error |")
+	fmt.Fprintln(w, "|---|---|---|")
+	for _, f := range g.failures {
+		fmt.Fprintf(w, "| %s | %s | %s |\n", escapeCell(f.Workflow), escapeCell(f.Step), escapeCell(f.Error))
+	}
+	fmt.Fprintln(w)
+}
+
+// stepLines returns the line of each step in a workflow file, or nil if the
+// file cannot be read.
+func stepLines(path string) []int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var root yaml.Node
+	if yaml.Unmarshal(data, &root) != nil {
+		return nil
+	}
+	_, nodes := workflowNodes(&root)
+	lines := make([]int, len(nodes))
+	for i := range nodes {
+		lines[i] = nodeLine(nodes, i)
+	}
+	return lines
+}
+
+// escapeData and escapeProperty follow the escaping rules for GitHub
+// Actions workflow commands.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func escapeCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", "<br>").Replace(s)
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitHubReport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`metadata:
+  name: "Orders, v2"
+config:
+  base_url: "%s"
+workflow:
+- step: "found"
+  request:
+    url: "/ok"
+- step: "missing"
+  request:
+    url: "/missing"
+  expect:
+    status: 200
+`, srv.URL)), 0644)
+	os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("workflow: ["), 0644)
+	summary := filepath.Join(dir, "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summary)
+
+	var out bytes.Buffer
+	r := New(5*time.Second, false, WithReport(ReportGitHub))
+	r.out = &out
+	if err := r.RunPaths([]string{dir}); err == nil {
+		t.Fatal("expected failing step to fail the run")
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"✗ missing",
+		"::error file=" + path + ",line=9,title=Orders%2C v2 > missing::expected status 200, got 404\n",
+		"::error file=" + filepath.Join(dir, "b.yaml") + ",title=b.yaml::parse ",
+		"Summary\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+
+	data, err := os.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"### ❌ ramjam: 1 passed, 1 failed, 0 skipped\n",
+		"| Orders, v2 | `" + path + "` | 1 | 1 | 0 |",
+		"| Orders, v2 | missing | expected status 200, got 404 |\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("summary missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestEscapeWorkflowCommand(t *testing.T) {
+	if got := escapeData("50% done\nnext"); got != "50%25 done%0Anext" {
+		t.Errorf("escapeData = %q", got)
+	}
+	if got := escapeProperty("a:b,c"); got != "a%3Ab%2Cc" {
+		t.Errorf("escapeProperty = %q", got)
+	}
+}
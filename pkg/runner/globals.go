@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// Capture scopes. Variables are captured into the file's own variables
+// unless the capture sets scope: global, which also makes them available
+// to the files that run after it in the same run.
+const (
+	ScopeFile   = "file"
+	ScopeGlobal = "global"
+)
+
+// globalVars holds the variables captured with scope: global during one
+// run, and lets a file wait for the files it imports variables from.
+type globalVars struct {
+	mu         sync.Mutex
+	vars       map[string]string
+	done       map[string]chan struct{} // closed when the file has finished
+	failed     map[string]bool
+	order      map[string]int    // position of each file in the run
+	waiting    map[string]string // file each waiting file waits for
+	sequential bool
+}
+
+func newGlobalVars(files []string, sequential bool) *globalVars {
+	g := &globalVars{
+		vars:       map[string]string{},
+		done:       map[string]chan struct{}{},
+		failed:     map[string]bool{},
+		order:      map[string]int{},
+		waiting:    map[string]string{},
+		sequential: sequential,
+	}
+	for i, f := range files {
+		key := fileKey(f)
+		if _, ok := g.done[key]; !ok {
+			g.done[key] = make(chan struct{})
+			g.order[key] = i
+		}
+	}
+	return g
+}
+
+// fileKey identifies a workflow file however its path was written.
+func fileKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// set stores a globally scoped variable.
+func (g *globalVars) set(name, value string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.vars[name] = value
+}
+
+// snapshot returns a copy of the global variables captured so far.
+func (g *globalVars) snapshot() map[string]string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	vars := make(map[string]string, len(g.vars))
+	for k, v := range g.vars {
+		vars[k] = v
+	}
+	return vars
+}
+
+// finished records that path has run, releasing the files waiting for it.
+func (g *globalVars) finished(path string, failed bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := fileKey(path)
+	done, ok := g.done[key]
+	if !ok {
+		return
+	}
+	select {
+	case <-done:
+		// The same file was given more than once.
+	default:
+		g.failed[key] = failed
+		close(done)
+	}
+}
+
+// wait blocks until imported, a file path relative to path's directory,
+// has finished. It fails if imported isn't part of the run, failed, or
+// could never finish first: when it runs later in a sequential run or
+// imports from path itself.
+func (g *globalVars) wait(ctx context.Context, path, imported string) error {
+	from := fileKey(path)
+	key := fileKey(filepath.Join(filepath.Dir(path), imported))
+
+	g.mu.Lock()
+	done, ok := g.done[key]
+	if !ok {
+		g.mu.Unlock()
+		return fmt.Errorf("imports_vars %s is not part of this run", imported)
+	}
+	if g.sequential && g.order[key] > g.order[from] {
+		g.mu.Unlock()
+		return fmt.Errorf("imports_vars %s runs after this file; list it first with order in %s", imported, DefaultsFileName)
+	}
+	for f := key; f != ""; f = g.waiting[f] {
+		if f == from {
+			g.mu.Unlock()
+			return fmt.Errorf("imports_vars %s imports from this file", imported)
+		}
+	}
+	g.waiting[from] = key
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.waiting, from)
+		g.mu.Unlock()
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.failed[key] {
+		return fmt.Errorf("imports_vars %s failed", imported)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGlobalVars(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			// Give the importing files time to start first.
+			time.Sleep(50 * time.Millisecond)
+			w.Write([]byte(`{"token": "t-123"}`))
+			return
+		}
+		seen = append(seen, r.URL.Path+" "+r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(fmt.Sprintf(content, srv.URL)), 0644)
+		return path
+	}
+	login := write("login.yaml", `
+config:
+  base_url: "%s"
+workflow:
+- step: "login"
+  request:
+    url: "/login"
+  capture:
+  - json_path: "token"
+    as: "token"
+    scope: "global"
+`)
+	orders := write("orders.yaml", `
+config:
+  base_url: "%s"
+  imports_vars: ["login.yaml"]
+  headers:
+    Authorization: "Bearer ${token}"
+workflow:
+- step: "orders"
+  request:
+    url: "/orders"
+`)
+
+	r := New(10*time.Second, false)
+	r.out = io.Discard
+	if err := r.RunPaths([]string{orders, login}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+	if strings.Join(seen, ",") != "/orders Bearer t-123" {
+		t.Errorf("requests = %q", seen)
+	}
+
+	problems, err := r.Lint([]string{orders, login})
+	if err != nil || len(problems) != 0 {
+		t.Errorf("expected no lint problems, got %v, %v", problems, err)
+	}
+
+	// Without login.yaml in the run there is nothing to import.
+	err = r.RunPaths([]string{orders})
+	if err == nil || !strings.Contains(err.Error(), "imports_vars login.yaml is not part of this run") {
+		t.Errorf("expected a missing import error, got %v", err)
+	}
+
+	// Sequential runs can't wait for a file that comes later.
+	r.sequential = true
+	err = r.RunPaths([]string{orders, login})
+	if err == nil || !strings.Contains(err.Error(), "imports_vars login.yaml runs after this file") {
+		t.Errorf("expected an ordering error, got %v", err)
+	}
+	r.sequential = false
+
+	a := write("a.yaml", "config:\n  base_url: %q\n  imports_vars: [b.yaml]\nworkflow: []\n")
+	b := write("b.yaml", "config:\n  base_url: %q\n  imports_vars: [a.yaml]\nworkflow: []\n")
+	err = r.RunPaths([]string{a, b})
+	if err == nil || !strings.Contains(err.Error(), "imports from this file") || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("expected an import cycle to fail both files, got %v", err)
+	}
+}
@@ -0,0 +1,320 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// harMaxBodySize caps how much of each body is kept in the archive.
+const harMaxBodySize = 10 << 20
+
+// WithHAR records every request and response, including OAuth2 token
+// requests and redirect hops, and writes them to path in HTTP Archive (HAR
+// 1.2) format when RunPaths finishes.
+func WithHAR(path string) Option {
+	return func(r *Runner) {
+		r.har = &harRecorder{path: path}
+	}
+}
+
+type (
+	harLog struct {
+		Log harContent `json:"log"`
+	}
+
+	harContent struct {
+		Version string      `json:"version"`
+		Creator harCreator  `json:"creator"`
+		Entries []*harEntry `json:"entries"`
+	}
+
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	harEntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+
+	harRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *harPostData   `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int64          `json:"bodySize"`
+	}
+
+	harResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		Content     harBody        `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int64          `json:"bodySize"`
+	}
+
+	harNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+
+	harBody struct {
+		Size     int64  `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Encoding string `json:"encoding,omitempty"`
+	}
+
+	harTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+// harRecorder collects entries from every file's client. Files run
+// concurrently, so entries are guarded by mu.
+type harRecorder struct {
+	path    string
+	mu      sync.Mutex
+	entries []*harEntry
+}
+
+// client returns a copy of c whose transport records into the archive.
+func (h *harRecorder) client(c *http.Client) *http.Client {
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *c
+	wrapped.Transport = &harTransport{base: base, rec: h}
+	return &wrapped
+}
+
+func (h *harRecorder) write() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	doc := harLog{Log: harContent{
+		Version: "1.2",
+		Creator: harCreator{Name: "ramjam", Version: buildVersion()},
+		Entries: h.entries,
+	}}
+	if doc.Log.Entries == nil {
+		doc.Log.Entries = []*harEntry{}
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err := e.Wrap(err, "encode har"); err != nil {
+		return err
+	}
+	return e.Wrapf(os.WriteFile(h.path, data, 0644), "write har %s", h.path)
+}
+
+type harTransport struct {
+	base http.RoundTripper
+	rec  *harRecorder
+}
+
+// httpTransport returns the *http.Transport behind rt, looking through the
+// retry, rate limiter, HAR recorder and middleware, so callers such as the WebSocket
+// dialer can reuse its TLS and proxy settings.
+func httpTransport(rt http.RoundTripper) (*http.Transport, bool) {
+	for rt != nil {
+		if t, ok := rt.(*http.Transport); ok {
+			return t, true
+		}
+		rt = innerTransport(rt)
+	}
+	return nil, false
+}
+
+// innerTransport returns the transport wrapped by one of the runner's own
+// round trippers, or nil for any other transport.
+func innerTransport(rt http.RoundTripper) http.RoundTripper {
+	switch t := rt.(type) {
+	case *retryTransport:
+		return t.base
+	case *limitedTransport:
+		return t.base
+	case *harTransport:
+		return t.base
+	case *middlewareTransport:
+		return t.base
+	}
+	return nil
+}
+
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	entry := &harEntry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.Header),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    req.ContentLength,
+		},
+	}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: v})
+		}
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		entry.Request.BodySize = int64(len(body))
+		text, _ := harText(body)
+		entry.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: text}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	sent := time.Now()
+	if err != nil {
+		entry.Comment = err.Error()
+		entry.Response = harResponse{Cookies: []harNameValue{}, Headers: []harNameValue{}, HeadersSize: -1, BodySize: -1}
+		entry.Time = millis(sent.Sub(start))
+		entry.Timings.Wait = entry.Time
+		t.rec.add(entry)
+		return nil, err
+	}
+
+	entry.Request.HTTPVersion = resp.Proto
+	entry.Response = harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(resp.Header),
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		Content:     harBody{MimeType: resp.Header.Get("Content-Type")},
+	}
+	entry.Timings.Wait = millis(sent.Sub(start))
+	t.rec.add(entry)
+
+	resp.Body = &harBodyRecorder{ReadCloser: resp.Body, entry: entry, header: resp.Header, start: start, headersAt: sent, rec: t.rec}
+	return resp, nil
+}
+
+func (h *harRecorder) add(entry *harEntry) {
+	h.mu.Lock()
+	h.entries = append(h.entries, entry)
+	h.mu.Unlock()
+}
+
+// harBodyRecorder copies the response body as the runner reads it and
+// completes the entry's content and timings when the body is closed.
+type harBodyRecorder struct {
+	io.ReadCloser
+	entry     *harEntry
+	header    http.Header
+	buf       bytes.Buffer
+	size      int64
+	start     time.Time
+	headersAt time.Time
+	rec       *harRecorder
+	once      sync.Once
+}
+
+func (b *harBodyRecorder) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if room := harMaxBodySize - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+	return n, err
+}
+
+func (b *harBodyRecorder) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.finish)
+	return err
+}
+
+func (b *harBodyRecorder) finish() {
+	done := time.Now()
+	content := b.buf.Bytes()
+	if b.header.Get("Content-Encoding") != "" {
+		fake := &http.Response{Header: b.header, Body: io.NopCloser(bytes.NewReader(content))}
+		if decoded, err := decodeBody(fake); err == nil {
+			if plain, err := io.ReadAll(decoded); err == nil {
+				content = plain
+			}
+		}
+	}
+
+	b.rec.mu.Lock()
+	defer b.rec.mu.Unlock()
+	b.entry.Response.BodySize = b.size
+	b.entry.Response.Content.Size = int64(len(content))
+	b.entry.Response.Content.Text, b.entry.Response.Content.Encoding = harText(content)
+	b.entry.Timings.Receive = millis(done.Sub(b.headersAt))
+	b.entry.Time = millis(done.Sub(b.start))
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range h {
+		for _, v := range values {
+			headers = append(headers, harNameValue{Name: name, Value: v})
+		}
+	}
+	return headers
+}
+
+// harText returns body as text, base64 encoding it when it is not UTF-8.
+func harText(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "unknown"
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHARExport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/items", http.StatusFound)
+		case "/items":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte(`{"items": [1, 2]}`))
+			zw.Close()
+		default:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("created"))
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	workflow := fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "create"
+  request:
+    method: "POST"
+    url: "/create"
+    params:
+      dry_run: "true"
+    body:
+      name: "widget"
+  expect:
+    status: 201
+- step: "list"
+  request:
+    url: "/old"
+  expect:
+    status: 200
+`, srv.URL)
+	yamlPath := filepath.Join(dir, "har.yaml")
+	if err := os.WriteFile(yamlPath, []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+	harPath := filepath.Join(dir, "out.har")
+
+	r := New(5*time.Second, false, WithHAR(harPath))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{yamlPath}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+
+	data, err := os.ReadFile(harPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc harLog
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid HAR: %v", err)
+	}
+	if doc.Log.Version != "1.2" || doc.Log.Creator.Name != "ramjam" {
+		t.Errorf("unexpected log header: %+v", doc.Log)
+	}
+	if len(doc.Log.Entries) != 3 {
+		t.Fatalf("expected 3 entries (create, redirect, list), got %d", len(doc.Log.Entries))
+	}
+
+	create := doc.Log.Entries[0]
+	if create.Request.Method != "POST" || create.Request.PostData == nil || create.Request.PostData.Text != `{"name":"widget"}` {
+		t.Errorf("unexpected create request: %+v", create.Request)
+	}
+	if len(create.Request.QueryString) != 1 || create.Request.QueryString[0].Name != "dry_run" {
+		t.Errorf("expected dry_run query string, got %+v", create.Request.QueryString)
+	}
+	if create.Response.Status != 201 || create.Response.Content.Text != "created" {
+		t.Errorf("unexpected create response: %+v", create.Response)
+	}
+
+	redirect := doc.Log.Entries[1]
+	if redirect.Response.Status != http.StatusFound || redirect.Response.RedirectURL != "/items" {
+		t.Errorf("unexpected redirect entry: %+v", redirect.Response)
+	}
+
+	list := doc.Log.Entries[2]
+	if list.Response.Content.Text != `{"items": [1, 2]}` {
+		t.Errorf("expected decoded body, got %q", list.Response.Content.Text)
+	}
+	if list.Time <= 0 || list.StartedDateTime == "" {
+		t.Errorf("expected timings, got time=%v started=%q", list.Time, list.StartedDateTime)
+	}
+}
@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+const defaultStringToSign = "${method}\n${path}\n${timestamp}\n${body_sha256}"
+
+// HMACAuth signs each request with a keyed hash. The string to sign is a
+// template which, in addition to workflow variables, can reference
+// ${method}, ${path}, ${query}, ${host}, ${content_type}, ${body_sha256} and
+// ${timestamp} (Unix seconds) for the request being sent.
+type HMACAuth struct {
+	Algorithm       string `yaml:"algorithm,omitempty"`
+	Key             string `yaml:"key"`
+	KeyEncoding     string `yaml:"key_encoding,omitempty"`
+	StringToSign    string `yaml:"string_to_sign,omitempty"`
+	Header          string `yaml:"header,omitempty"`
+	Prefix          string `yaml:"prefix,omitempty"`
+	Encoding        string `yaml:"encoding,omitempty"`
+	TimestampHeader string `yaml:"timestamp_header,omitempty"`
+}
+
+// stepHMAC returns the signing config for a step, preferring request-level
+// auth over the file's config.auth.
+func stepHMAC(step Step) *HMACAuth {
+	if step.Request.Auth != nil && step.Request.Auth.HMAC != nil {
+		return step.Request.Auth.HMAC
+	}
+	if step.file != nil && step.file.config.Auth != nil {
+		return step.file.config.Auth.HMAC
+	}
+	return nil
+}
+
+// signHMAC adds the signature header to a fully built request. The body is
+// buffered so that its hash can be included in the signature.
+func (r *Runner) signHMAC(req *http.Request, cfg *HMACAuth, vars map[string]string) error {
+	newHash, err := hmacAlgorithm(cfg.Algorithm)
+	if err != nil {
+		return err
+	}
+	encodedKey, err := r.applyVars(cfg.Key, vars)
+	if err != nil {
+		return err
+	}
+	key, err := decodeHMACKey(encodedKey, cfg.KeyEncoding)
+	if err != nil {
+		return err
+	}
+
+	body, err := bufferBody(req)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	bodySum := sha256.Sum256(body)
+	signVars := make(map[string]string, len(vars)+7)
+	for k, v := range vars {
+		signVars[k] = v
+	}
+	signVars["method"] = req.Method
+	signVars["path"] = req.URL.EscapedPath()
+	signVars["query"] = req.URL.RawQuery
+	signVars["host"] = req.URL.Host
+	signVars["content_type"] = req.Header.Get("Content-Type")
+	signVars["body_sha256"] = hex.EncodeToString(bodySum[:])
+	signVars["timestamp"] = timestamp
+
+	template := cfg.StringToSign
+	if template == "" {
+		template = defaultStringToSign
+	}
+	stringToSign, err := r.applyVars(template, signVars)
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(newHash, key)
+	mac.Write([]byte(stringToSign))
+	sum := mac.Sum(nil)
+
+	var signature string
+	switch strings.ToLower(cfg.Encoding) {
+	case "", "hex":
+		signature = hex.EncodeToString(sum)
+	case "base64":
+		signature = base64.StdEncoding.EncodeToString(sum)
+	default:
+		return fmt.Errorf("unknown hmac encoding %q (expected hex or base64)", cfg.Encoding)
+	}
+
+	header := cfg.Header
+	if header == "" {
+		header = "X-Signature"
+	}
+	prefix, err := r.applyVars(cfg.Prefix, vars)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(header, prefix+signature)
+	if cfg.TimestampHeader != "" {
+		req.Header.Set(cfg.TimestampHeader, timestamp)
+	}
+	return nil
+}
+
+func hmacAlgorithm(name string) (func() hash.Hash, error) {
+	switch strings.ToLower(strings.ReplaceAll(name, "-", "")) {
+	case "", "sha256", "hmacsha256":
+		return sha256.New, nil
+	case "sha512", "hmacsha512":
+		return sha512.New, nil
+	case "sha1", "hmacsha1":
+		return sha1.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported hmac algorithm %q (expected sha1, sha256 or sha512)", name)
+	}
+}
+
+func decodeHMACKey(key, encoding string) ([]byte, error) {
+	if key == "" {
+		return nil, fmt.Errorf("hmac auth requires a key")
+	}
+	switch strings.ToLower(encoding) {
+	case "", "raw":
+		return []byte(key), nil
+	case "hex":
+		b, err := hex.DecodeString(key)
+		return b, e.Wrap(err, "decode hmac key")
+	case "base64":
+		b, err := base64.StdEncoding.DecodeString(key)
+		return b, e.Wrap(err, "decode hmac key")
+	default:
+		return nil, fmt.Errorf("unknown hmac key_encoding %q (expected raw, hex or base64)", encoding)
+	}
+}
@@ -0,0 +1,136 @@
+package runner
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHMACSigning(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodySum := sha256.Sum256(body)
+		toSign := strings.Join([]string{r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("X-Ts"), hex.EncodeToString(bodySum[:])}, "|")
+		mac := hmac.New(sha512.New, []byte("topsecret"))
+		mac.Write([]byte(toSign))
+		want := "HMAC " + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if got := r.Header.Get("Authorization"); got != want {
+			t.Errorf("signature mismatch for %s:\n got  %s\n want %s", toSign, got, want)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "HMAC"
+config:
+  base_url: "%s"
+workflow:
+- step: "signed-post"
+  request:
+    method: "POST"
+    url: "/orders"
+    params:
+      page: "2"
+    body:
+      item: "widget"
+    auth:
+      hmac:
+        algorithm: "sha512"
+        key: "topsecret"
+        header: "Authorization"
+        prefix: "HMAC "
+        encoding: "base64"
+        timestamp_header: "X-Ts"
+        string_to_sign: "${method}|${path}|${query}|${timestamp}|${body_sha256}"
+  expect:
+    status: 200
+- step: "signed-get"
+  request:
+    url: "/orders"
+    auth:
+      hmac:
+        algorithm: "sha512"
+        key: "746f70736563726574"
+        key_encoding: "hex"
+        header: "Authorization"
+        prefix: "HMAC "
+        encoding: "base64"
+        timestamp_header: "X-Ts"
+        string_to_sign: "${method}|${path}|${query}|${timestamp}|${body_sha256}"
+  expect:
+    status: 200
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
+
+func TestHMACDefaultsFromConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		emptySum := sha256.Sum256(nil)
+		toSign := r.Method + "\n" + r.URL.Path + "\n" + r.Header.Get("X-Timestamp") + "\n" + hex.EncodeToString(emptySum[:])
+		mac := hmac.New(sha256.New, []byte("k"))
+		mac.Write([]byte(toSign))
+		if got := r.Header.Get("X-Signature"); got != hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "HMAC Config"
+config:
+  base_url: "%s"
+  auth:
+    hmac:
+      key: "k"
+      timestamp_header: "X-Timestamp"
+workflow:
+- step: "signed"
+  request:
+    url: "/status"
+  expect:
+    status: 200
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
+
+func TestHMACUnsupportedAlgorithm(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "HMAC Bad"
+config:
+  base_url: "%s"
+workflow:
+- step: "signed"
+  request:
+    url: "/"
+    auth:
+      hmac:
+        algorithm: "md5"
+        key: "k"
+`, srv.URL)
+
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), `unsupported hmac algorithm "md5"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// IgnoreFileName is the file that lists, in gitignore syntax, paths in its
+// directory and below that are not workflows, such as fixtures and
+// environment files kept next to them.
+const IgnoreFileName = ".ramjamignore"
+
+// ignoreRule is one pattern from an ignore file.
+type ignoreRule struct {
+	dir     string // directory of the ignore file
+	pattern string // doublestar pattern relative to dir
+	negate  bool   // the pattern started with !
+	dirOnly bool   // the pattern ended with /
+}
+
+// match reports whether the rule matches path, which must be below the
+// rule's directory.
+func (rule ignoreRule) match(path string, isDir bool) bool {
+	if rule.dirOnly && !isDir {
+		return false
+	}
+	rel, err := filepath.Rel(rule.dir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	ok, _ := doublestar.Match(rule.pattern, filepath.ToSlash(rel))
+	return ok
+}
+
+// parseIgnore parses the gitignore-style patterns in data, read from the
+// ignore file in dir.
+func parseIgnore(dir string, data []byte) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{dir: dir}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// A pattern without a slash, apart from a trailing one, matches at
+		// any depth; otherwise it is relative to the ignore file's directory.
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ignorer decides which paths below root the ignore files in root and its
+// subdirectories exclude. Files are read once, as directories are reached.
+type ignorer struct {
+	root  string
+	rules map[string][]ignoreRule // ignore file rules by directory
+}
+
+func newIgnorer(root string) *ignorer {
+	return &ignorer{root: filepath.Clean(root), rules: map[string][]ignoreRule{}}
+}
+
+// load returns the rules from the ignore file in dir, if it has one.
+func (ig *ignorer) load(dir string) ([]ignoreRule, error) {
+	if rules, ok := ig.rules[dir]; ok {
+		return rules, nil
+	}
+	path := filepath.Join(dir, IgnoreFileName)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		ig.rules[dir] = nil
+		return nil, nil
+	}
+	if err := e.Wrapf(err, "read %s", path); err != nil {
+		return nil, err
+	}
+	rules := parseIgnore(dir, data)
+	ig.rules[dir] = rules
+	return rules, nil
+}
+
+// ignored reports whether path, a file or directory below the root, is
+// excluded. As in git, a file in an excluded directory cannot be included
+// again, and later patterns, including those in deeper ignore files,
+// override earlier ones.
+func (ig *ignorer) ignored(path string, isDir bool) (bool, error) {
+	rel, err := filepath.Rel(ig.root, filepath.Clean(path))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false, nil
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	var rules []ignoreRule
+	dir := ig.root
+	for i, part := range parts {
+		more, err := ig.load(dir)
+		if err != nil {
+			return false, err
+		}
+		rules = append(rules, more...)
+		dir = filepath.Join(dir, part)
+		last := i == len(parts)-1
+		excluded := false
+		for _, rule := range rules {
+			if rule.match(dir, isDir || !last) {
+				excluded = !rule.negate
+			}
+		}
+		if excluded {
+			return true, nil
+		}
+	}
+	return false, nil
+}
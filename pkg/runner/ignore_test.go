@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".ramjamignore": `
+# fixtures and environments are not workflows
+fixtures/
+env-*.yaml
+!env-smoke.yaml
+/local.yaml
+`,
+		"orders.yaml":              "workflow: []\n",
+		"env-dev.yaml":             "base_url: http://localhost\n",
+		"env-smoke.yaml":           "workflow: []\n",
+		"local.yaml":               "workflow: []\n",
+		"fixtures/user.yaml":       "name: ada\n",
+		"auth/local.yaml":          "workflow: []\n",
+		"auth/env-prod.yaml":       "base_url: https://example.com\n",
+		"auth/.ramjamignore":       "helpers.yml\n",
+		"auth/helpers.yml":         "token: abc\n",
+		"auth/login.yaml":          "workflow: []\n",
+		"auth/fixtures/token.yaml": "token: abc\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	rel := func(files []string) string {
+		var names []string
+		for _, f := range files {
+			name, _ := filepath.Rel(dir, f)
+			names = append(names, filepath.ToSlash(name))
+		}
+		return strings.Join(names, ",")
+	}
+
+	got, err := New(10*time.Second, false).Files([]string{dir})
+	if err != nil {
+		t.Fatalf("Files failed: %v", err)
+	}
+	if want := "env-smoke.yaml,orders.yaml"; rel(got) != want {
+		t.Errorf("Files(dir) = %s, want %s", rel(got), want)
+	}
+
+	got, err = New(10*time.Second, false, WithRecursive(true)).Files([]string{dir})
+	if err != nil {
+		t.Fatalf("Files failed: %v", err)
+	}
+	if want := "auth/local.yaml,auth/login.yaml,env-smoke.yaml,orders.yaml"; rel(got) != want {
+		t.Errorf("recursive Files(dir) = %s, want %s", rel(got), want)
+	}
+
+	got, err = New(10*time.Second, false).Files([]string{filepath.Join(dir, "**", "*.{yaml,yml}")})
+	if err != nil {
+		t.Fatalf("Files failed: %v", err)
+	}
+	if want := "auth/local.yaml,auth/login.yaml,env-smoke.yaml,orders.yaml"; rel(got) != want {
+		t.Errorf("Files(glob) = %s, want %s", rel(got), want)
+	}
+
+	// A file named on the command line runs even if it is ignored.
+	env := filepath.Join(dir, "env-dev.yaml")
+	if got, err := New(10*time.Second, false).Files([]string{env}); err != nil || len(got) != 1 {
+		t.Errorf("Files(%s) = %v, %v", env, got, err)
+	}
+}
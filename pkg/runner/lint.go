@@ -0,0 +1,600 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is an issue found by Lint. Warnings are reported but do not make a
+// file invalid.
+type Problem struct {
+	File    string
+	Line    int
+	Step    string
+	Message string
+	Warning bool
+}
+
+func (p Problem) String() string {
+	var b strings.Builder
+	b.WriteString(p.File)
+	if p.Line > 0 {
+		fmt.Fprintf(&b, ":%d", p.Line)
+	}
+	b.WriteString(": ")
+	if p.Warning {
+		b.WriteString("warning: ")
+	}
+	if p.Step != "" {
+		fmt.Fprintf(&b, "step %q: ", p.Step)
+	}
+	b.WriteString(p.Message)
+	return b.String()
+}
+
+// builtinVars are set by the runner rather than by captures.
+var builtinVars = []string{"base_url", "last_proto"}
+
+// hmacPlaceholders are only defined inside hmac.string_to_sign; see HMACAuth.
+var hmacPlaceholders = []string{"method", "path", "query", "host", "content_type", "body_sha256", "timestamp"}
+
+var yamlErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+var unknownField = regexp.MustCompile(`^field (\S+) not found in type \S+$`)
+
+// Lint checks workflow files without sending any requests. It reports
+// unknown fields, variables that are never captured or are used before the
+// step that captures them, captures that can never succeed or are never
+// used, invalid JSONPath and regex syntax, and missing files. The returned
+// error is only set when the paths themselves cannot be read.
+func (r *Runner) Lint(paths []string) ([]Problem, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no paths provided")
+	}
+	files, err := r.Files(paths)
+	if err != nil {
+		return nil, err
+	}
+	// Any file may use a variable another file in the run captures with
+	// scope: global.
+	globals := globalCaptures(files)
+	var problems []Problem
+	for _, f := range files {
+		vars := r.vars
+		defaults, err := r.loadDefaults(filepath.Dir(f))
+		if err != nil {
+			problems = append(problems, Problem{File: f, Message: err.Error()})
+			continue
+		}
+		if len(globals) > 0 || defaults != nil && len(defaults.Variables) > 0 {
+			vars = make(map[string]string, len(globals)+len(r.vars))
+			for k, v := range globals {
+				vars[k] = v
+			}
+			if defaults != nil {
+				for k, v := range defaults.Variables {
+					vars[k] = v
+				}
+			}
+			for k, v := range r.vars {
+				vars[k] = v
+			}
+		}
+		problems = append(problems, lintFile(f, vars)...)
+	}
+	return problems, nil
+}
+
+// globalCaptures returns the variables that files capture with scope:
+// global. Files that can't be parsed are reported when they are linted.
+func globalCaptures(files []string) map[string]string {
+	vars := map[string]string{}
+	for _, f := range files {
+		data, err := readPath(f)
+		if err != nil {
+			continue
+		}
+		var spec InstructionsFile
+		if yaml.Unmarshal(data, &spec) != nil {
+			continue
+		}
+		for _, step := range spec.Workflow {
+			for _, c := range allCaptures(step) {
+				if c.Scope == ScopeGlobal && c.As != "" {
+					vars[c.As] = ""
+				}
+			}
+		}
+	}
+	return vars
+}
+
+// fileLinter accumulates problems for one workflow file.
+type fileLinter struct {
+	path     string
+	baseDir  string
+	problems []Problem
+	// captured maps each captured variable to the index of the first step
+	// that captures it.
+	captured map[string]int
+	used     map[string]bool
+	// global holds the variables captured with scope: global.
+	global map[string]bool
+	// steps maps each step name to the index of its first step.
+	steps map[string]int
+	// vars are set outside the file, such as with --var.
+	vars map[string]string
+}
+
+func (l *fileLinter) add(line int, step, format string, args ...interface{}) {
+	l.problems = append(l.problems, Problem{File: l.path, Line: line, Step: step, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *fileLinter) warn(line int, step, format string, args ...interface{}) {
+	l.add(line, step, format, args...)
+	l.problems[len(l.problems)-1].Warning = true
+}
+
+func lintFile(path string, vars map[string]string) []Problem {
+	l := &fileLinter{
+		path:     path,
+		vars:     vars,
+		baseDir:  workflowDir(path),
+		captured: map[string]int{},
+		used:     map[string]bool{},
+		global:   map[string]bool{},
+		steps:    map[string]int{},
+	}
+
+	data, err := readPath(path)
+	if err := e.Wrapf(err, "read %s", path); err != nil {
+		l.add(0, "", "%v", err)
+		return l.problems
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		l.add(0, "", "%v", err)
+		return l.problems
+	}
+
+	// Unknown fields and values outside a fixed set are found with the
+	// schema, which points at the offending key.
+	l.checkSchema(&root, workflowSchema, "")
+
+	var spec InstructionsFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&spec); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			l.add(0, "", "%v", err)
+			return l.problems
+		}
+		for _, msg := range typeErr.Errors {
+			l.addYAMLError(msg)
+		}
+	}
+
+	if len(spec.Vars) > 0 {
+		vars := make(map[string]string, len(l.vars)+len(spec.Vars))
+		for k, v := range l.vars {
+			vars[k] = v
+		}
+		for k, v := range spec.Vars {
+			vars[k] = v
+		}
+		l.vars = vars
+	}
+
+	configNode, stepNodes := workflowNodes(&root)
+	for i, step := range spec.Workflow {
+		if _, ok := l.steps[step.Step]; !ok {
+			l.steps[step.Step] = i
+		}
+		for _, c := range allCaptures(step) {
+			if _, ok := l.captured[c.As]; !ok && c.As != "" {
+				l.captured[c.As] = i
+			}
+			if c.Scope == ScopeGlobal {
+				l.global[c.As] = true
+			}
+		}
+	}
+
+	l.lintConfig(spec.Config, configNode)
+	for i, step := range spec.Workflow {
+		var node *yaml.Node
+		if i < len(stepNodes) {
+			node = stepNodes[i]
+		}
+		l.lintStep(i, step, node)
+	}
+
+	names := make([]string, 0, len(l.captured))
+	for name := range l.captured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Global captures are there for other files to use.
+		if !l.used[name] && !l.global[name] {
+			i := l.captured[name]
+			l.warn(nodeLine(stepNodes, i), spec.Workflow[i].Step, "capture %q is never used", name)
+		}
+	}
+	return l.problems
+}
+
+// unknownFieldError rewrites yaml's "field x not found in type y" messages
+// as unknown field "x".
+func unknownFieldError(err error) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+	msgs := make([]string, len(typeErr.Errors))
+	for i, msg := range typeErr.Errors {
+		line := ""
+		if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+			line, msg = "line "+m[1]+": ", m[2]
+		}
+		if m := unknownField.FindStringSubmatch(msg); m != nil {
+			msg = fmt.Sprintf("unknown field %q", m[1])
+		}
+		msgs[i] = line + msg
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// checkSchema reports keys the schema doesn't allow and values missing from
+// a field's enum. Type mismatches are left to the YAML decoder.
+func (l *fileLinter) checkSchema(node *yaml.Node, s *jsonSchema, field string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			l.checkSchema(child, s, field)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if prop, ok := s.Properties[key.Value]; ok {
+				l.checkSchema(value, prop, key.Value)
+				continue
+			}
+			switch extra := s.AdditionalProperties.(type) {
+			case *jsonSchema:
+				l.checkSchema(value, extra, key.Value)
+			case bool:
+				l.add(key.Line, "", "unknown field %q", key.Value)
+			}
+		}
+	case yaml.SequenceNode:
+		if s.Items != nil {
+			for _, child := range node.Content {
+				l.checkSchema(child, s.Items, field)
+			}
+		}
+	case yaml.ScalarNode:
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, node.Value) && !strings.Contains(node.Value, "${") {
+			l.add(node.Line, "", "invalid %s %q (expected %s)", field, node.Value, strings.Join(s.Enum, ", "))
+		}
+	}
+}
+
+func (l *fileLinter) addYAMLError(msg string) {
+	line := 0
+	if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+		line, _ = strconv.Atoi(m[1])
+		msg = m[2]
+	}
+	if m := unknownField.FindStringSubmatch(msg); m != nil {
+		msg = fmt.Sprintf("unknown field %q", m[1])
+	}
+	l.add(line, "", "%s", msg)
+}
+
+// workflowNodes returns the config node and one node per workflow step, for
+// line numbers and variable references.
+func workflowNodes(root *yaml.Node) (*yaml.Node, []*yaml.Node) {
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	doc := root.Content[0]
+	var config *yaml.Node
+	var steps []*yaml.Node
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		switch doc.Content[i].Value {
+		case "config":
+			config = doc.Content[i+1]
+		case "workflow":
+			if doc.Content[i+1].Kind == yaml.SequenceNode {
+				steps = doc.Content[i+1].Content
+			}
+		}
+	}
+	return config, steps
+}
+
+func nodeLine(nodes []*yaml.Node, i int) int {
+	if i < len(nodes) {
+		return nodes[i].Line
+	}
+	return 0
+}
+
+// allCaptures lists every capture a step can make, including captures on
+// SSE events and WebSocket messages.
+func allCaptures(step Step) []Capture {
+	captures := append([]Capture(nil), step.Capture...)
+	if step.Expect.SSE != nil {
+		for _, ev := range step.Expect.SSE.Events {
+			captures = append(captures, ev.Capture...)
+		}
+	}
+	if step.WebSocket != nil {
+		for _, msg := range step.WebSocket.Messages {
+			captures = append(captures, msg.Capture...)
+		}
+	}
+	return captures
+}
+
+type varRef struct {
+	name string
+	line int
+	key  string // the mapping key the value was found under
+	op   string // "-" for ${name:-default}, "?" for ${name:?message}
+	arg  string // the default or message
+}
+
+// varRefs collects ${name} references from every scalar value under node.
+func varRefs(node *yaml.Node, key string, refs []varRef) []varRef {
+	if node == nil {
+		return refs
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		refs = append(refs, textRefs(node.Value, node.Line, key)...)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			refs = varRefs(node.Content[i+1], node.Content[i].Value, refs)
+		}
+	default:
+		for _, child := range node.Content {
+			refs = varRefs(child, key, refs)
+		}
+	}
+	return refs
+}
+
+// textRefs collects the ${name} references in text.
+func textRefs(text string, line int, key string) []varRef {
+	var refs []varRef
+	for _, m := range varPattern.FindAllStringSubmatch(text, -1) {
+		head := m[1]
+		if h, _, ok := splitPipe(head); ok {
+			head = h
+		}
+		_, op, arg, _ := splitFallback(head)
+		for _, name := range exprVars(m[1]) {
+			refs = append(refs, varRef{name: name, line: line, key: key, op: op, arg: arg})
+		}
+	}
+	return refs
+}
+
+func (l *fileLinter) isBuiltin(ref varRef) bool {
+	if _, ok := l.vars[ref.name]; ok {
+		return true
+	}
+	for _, name := range builtinVars {
+		if ref.name == name {
+			return true
+		}
+	}
+	if ref.key == "string_to_sign" {
+		for _, name := range hmacPlaceholders {
+			if ref.name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (l *fileLinter) lintConfig(cfg Config, node *yaml.Node) {
+	// Config headers and auth are applied to every step, so any capture in
+	// the file counts as defined.
+	for _, ref := range varRefs(node, "", nil) {
+		l.used[ref.name] = true
+		if _, ok := l.captured[ref.name]; !ok && ref.op != "-" && !l.isBuiltin(ref) {
+			l.add(ref.line, "", "undefined variable ${%s}", ref.name)
+		}
+	}
+
+	line := 0
+	if node != nil {
+		line = node.Line
+	}
+	for _, f := range []string{cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.CAFile} {
+		l.checkFile(line, "", f)
+	}
+	if cfg.OpenAPI != "" {
+		if _, err := loadContract(cfg, l.baseDir); err != nil {
+			l.add(line, "", "openapi: %v", err)
+		}
+	}
+	for _, f := range cfg.ImportsVars {
+		l.checkFile(line, "", f)
+	}
+}
+
+func (l *fileLinter) lintStep(i int, step Step, node *yaml.Node) {
+	line := 0
+	if node != nil {
+		line = node.Line
+	}
+	name := step.Step
+	if name == "" {
+		name = fmt.Sprintf("#%d", i+1)
+	}
+
+	refs := varRefs(node, "", nil)
+	if step.Request.BodyFile != "" && l.checkFile(line, name, step.Request.BodyFile) {
+		data, err := os.ReadFile(l.resolve(step.Request.BodyFile))
+		if err == nil {
+			refs = append(refs, textRefs(string(data), line, "body_file")...)
+		}
+	}
+	if step.When != "" {
+		expr, wrapped := conditionExpr(step.When)
+		_, names, err := parseExpr(expr)
+		switch {
+		case err != nil:
+			l.add(line, name, "when: %v", err)
+		case !wrapped:
+			// Variables inside ${...} are already among refs.
+			for _, n := range names {
+				refs = append(refs, varRef{name: n, line: line, key: "when"})
+			}
+		}
+	}
+	for _, ref := range refs {
+		l.used[ref.name] = true
+		if _, ok := step.Vars[ref.name]; ok || ref.op == "-" || l.isBuiltin(ref) {
+			continue
+		}
+		first, ok := l.captured[ref.name]
+		switch {
+		case !ok:
+			l.add(ref.line, name, "undefined variable ${%s}", ref.name)
+		case first > i:
+			l.add(ref.line, name, "${%s} is used before it is captured", ref.name)
+		}
+	}
+
+	for _, part := range step.Request.Multipart {
+		l.checkFile(line, name, part.File)
+	}
+
+	if step.Request.URL == "" && step.WebSocket == nil {
+		l.add(line, name, "request.url is required")
+	}
+	for _, need := range step.Needs {
+		if j, ok := l.steps[need]; !ok {
+			l.add(line, name, "needs unknown step %q", need)
+		} else if j >= i {
+			l.add(line, name, "needs %q, which comes after it", need)
+		}
+	}
+	if step.Expect.BodyFormat != "" && step.Expect.BodyFormat != "json" && step.Expect.BodyFormat != "text" && step.Expect.BodyFormat != "none" {
+		l.add(line, name, "unknown body_format %q (expected text, json or none)", step.Expect.BodyFormat)
+	}
+	l.checkRegex(line, name, "body_regex", step.Expect.BodyRegex)
+
+	l.checkMatchers(line, name, step.Expect.JSONPathMatch)
+	l.checkCaptures(line, name, step.Capture, step.Expect.BodyFormat)
+	if step.Expect.SSE != nil {
+		for _, ev := range step.Expect.SSE.Events {
+			l.checkMatchers(line, name, ev.JSONPathMatch)
+			l.checkCaptures(line, name, ev.Capture, "")
+		}
+	}
+	if step.WebSocket != nil {
+		for _, msg := range step.WebSocket.Messages {
+			if msg.Expect != nil {
+				l.checkMatchers(line, name, msg.Expect.JSONPathMatch)
+			}
+			l.checkCaptures(line, name, msg.Capture, "")
+		}
+	}
+}
+
+func (l *fileLinter) checkMatchers(line int, step string, matchers []JSONPathVal) {
+	for _, m := range matchers {
+		if err := m.check(); err != nil {
+			l.add(line, step, "%v", err)
+		}
+		if agg, path := m.aggregate(); agg != "" {
+			if err := checkJSONPath(path); err != nil {
+				l.add(line, step, "invalid %s %q: %v", agg, path, err)
+			}
+		} else if err := checkJSONPath(m.Path); err != nil {
+			l.add(line, step, "invalid json_path %q: %v", m.Path, err)
+		}
+	}
+}
+
+// checkCaptures reports malformed captures and JSONPath captures that can
+// never run because the step does not parse its body as JSON.
+func (l *fileLinter) checkCaptures(line int, step string, captures []Capture, bodyFormat string) {
+	for _, c := range captures {
+		if c.As == "" {
+			l.add(line, step, "capture must specify as")
+		}
+		if err := c.check(); err != nil {
+			l.add(line, step, "capture %q: %v", c.As, err)
+		}
+		agg, aggPath := c.aggregate()
+		switch {
+		case agg != "":
+			if err := checkJSONPath(aggPath); err != nil {
+				l.add(line, step, "invalid %s %q: %v", agg, aggPath, err)
+			}
+			if bodyFormat == "text" || bodyFormat == "none" {
+				l.add(line, step, "capture %q uses %s but body_format is %s", c.As, agg, bodyFormat)
+			}
+		case c.JSONPath != "":
+			if err := checkJSONPath(c.JSONPath); err != nil {
+				l.add(line, step, "invalid json_path %q: %v", c.JSONPath, err)
+			}
+			if bodyFormat == "text" || bodyFormat == "none" {
+				l.add(line, step, "capture %q uses json_path but body_format is %s", c.As, bodyFormat)
+			}
+		case c.Header != "":
+			l.checkRegex(line, step, "capture regex", c.Regex)
+		default:
+			l.add(line, step, "capture %q must specify json_path, header or an aggregate such as length_of", c.As)
+		}
+	}
+}
+
+// checkRegex compiles patterns that do not depend on variables.
+func (l *fileLinter) checkRegex(line int, step, field, pattern string) {
+	if pattern == "" || varPattern.MatchString(pattern) {
+		return
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		l.add(line, step, "invalid %s: %v", field, err)
+	}
+}
+
+func (l *fileLinter) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(l.baseDir, path)
+}
+
+// checkFile reports a missing file. Paths built from variables are skipped.
+func (l *fileLinter) checkFile(line int, step, path string) bool {
+	if path == "" || varPattern.MatchString(path) {
+		return false
+	}
+	if _, err := os.Stat(l.resolve(path)); err != nil {
+		l.add(line, step, "file %s not found", path)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "body.json"), []byte(`{"owner": "${owner}"}`), 0644)
+	path := filepath.Join(dir, "workflow.yaml")
+	os.WriteFile(path, []byte(`
+config:
+  base_url: "https://api.example.com"
+  headers:
+    Authorization: "Bearer ${token}"
+workflow:
+- step: "login"
+  request:
+    method: POST
+    url: "/login"
+    timeout: 5s
+  capture:
+  - json_path: "token"
+    as: "token"
+  - json_path: "user.id"
+    as: "unused"
+- step: "create"
+  request:
+    method: POST
+    url: "/orders/${order_id}"
+    body_file: "body.json"
+  expect:
+    json_path_match:
+    - path: "items[x].id"
+      value: 1
+- step: "fetch"
+  request:
+    url: "${base_url}/orders"
+    multipart:
+    - name: "file"
+      file: "missing.csv"
+  expect:
+    body_format: text
+    body_regex: "total: ("
+  capture:
+  - json_path: "id"
+    as: "order_id"
+  - header: "X-Owner"
+    as: "owner"
+`), 0644)
+
+	problems, err := New(10*time.Second, false).Lint([]string{dir})
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, strings.TrimPrefix(p.String(), path))
+	}
+
+	want := []string{
+		`:11: unknown field "timeout"`,
+		`:20: step "create": ${order_id} is used before it is captured`,
+		`:17: step "create": ${owner} is used before it is captured`,
+		`:17: step "create": invalid json_path "items[x].id": invalid index in segment items[x]`,
+		`:26: step "fetch": file missing.csv not found`,
+		`:26: step "fetch": invalid body_regex: error parsing regexp: missing closing ): ` + "`total: (`",
+		`:26: step "fetch": capture "order_id" uses json_path but body_format is text`,
+		`:7: warning: step "login": capture "unused" is never used`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintWithVars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenant.yaml")
+	os.WriteFile(path, []byte(`
+workflow:
+- step: "whoami"
+  request:
+    url: "/tenants/${tenant}/users/${user}"
+`), 0644)
+
+	r := New(10*time.Second, false, WithVars(map[string]string{"tenant": "acme"}))
+	problems, err := r.Lint([]string{path})
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Message != "undefined variable ${user}" {
+		t.Errorf("expected only ${user} to be undefined, got %v", problems)
+	}
+}
+
+func TestLintValidFile(t *testing.T) {
+	problems, err := New(10*time.Second, false).Lint([]string{"../../resources/testdata/fail"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range problems {
+		if !p.Warning {
+			t.Errorf("unexpected problem: %s", p)
+		}
+	}
+}
+
+func TestCheckJSONPath(t *testing.T) {
+	valid := []string{"id", "$.user.name", "items[0].id", "errors[*].message", "$[0].name", "$[?(@.id==${user_id})].name"}
+	for _, p := range valid {
+		if err := checkJSONPath(p); err != nil {
+			t.Errorf("checkJSONPath(%q) = %v", p, err)
+		}
+	}
+	invalid := []string{"", "items[0", "items]0[", "$[abc]", "a[1][2]", "a.b]"}
+	for _, p := range invalid {
+		if err := checkJSONPath(p); err == nil {
+			t.Errorf("expected %q to be invalid", p)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// WorkflowInfo describes a workflow file without running it.
+type WorkflowInfo struct {
+	Path        string     `json:"path"`
+	Name        string     `json:"name,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Steps       []StepInfo `json:"steps"`
+	// Error is set when the file could not be read or parsed.
+	Error string `json:"error,omitempty"`
+}
+
+// StepInfo describes a single step. Method and URL are shown as written,
+// before variable substitution.
+type StepInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Method      string `json:"method,omitempty"`
+	URL         string `json:"url,omitempty"`
+}
+
+// List reads the metadata and steps of every workflow file under paths.
+// Files that fail to parse are included with Error set.
+func (r *Runner) List(paths []string) ([]WorkflowInfo, error) {
+	files, err := r.Files(paths)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]WorkflowInfo, 0, len(files))
+	for _, f := range files {
+		infos = append(infos, describeFile(f))
+	}
+	return infos, nil
+}
+
+func describeFile(path string) WorkflowInfo {
+	info := WorkflowInfo{Path: path, Steps: []StepInfo{}}
+	data, err := readPath(path)
+	if err := e.Wrapf(err, "read %s", path); err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	var spec InstructionsFile
+	if err := e.Wrapf(yaml.Unmarshal(data, &spec), "parse %s", path); err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	info.Name = spec.Metadata.Name
+	info.Author = spec.Metadata.Author
+	info.Description = spec.Metadata.Description
+	info.Tags = spec.Metadata.Tags
+	for _, step := range spec.Workflow {
+		s := StepInfo{Name: step.Step, Description: step.Description}
+		switch {
+		case step.WebSocket != nil:
+			s.Method, s.URL = "WS", step.WebSocket.URL
+		default:
+			s.Method, s.URL = strings.ToUpper(strings.TrimSpace(step.Request.Method)), step.Request.URL
+			if s.Method == "" {
+				s.Method = "GET"
+				if step.Request.GraphQL != nil {
+					s.Method = "POST"
+				}
+			}
+		}
+		info.Steps = append(info.Steps, s)
+	}
+	return info
+}
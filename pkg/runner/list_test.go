@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(`
+metadata:
+  name: "Orders"
+  description: "Order lifecycle"
+  tags: [smoke, orders]
+workflow:
+- step: "create"
+  request:
+    method: post
+    url: "/orders"
+- step: "query"
+  request:
+    graphql:
+      query: "{ orders { id } }"
+    url: "/graphql"
+- step: "feed"
+  websocket:
+    url: "/feed"
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("workflow: ["), 0644)
+
+	infos, err := New(10*time.Second, false).List([]string{dir})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(infos))
+	}
+
+	a := infos[0]
+	if a.Name != "Orders" || a.Description != "Order lifecycle" || !reflect.DeepEqual(a.Tags, []string{"smoke", "orders"}) {
+		t.Errorf("unexpected metadata: %+v", a)
+	}
+	want := []StepInfo{
+		{Name: "create", Method: "POST", URL: "/orders"},
+		{Name: "query", Method: "POST", URL: "/graphql"},
+		{Name: "feed", Method: "WS", URL: "/feed"},
+	}
+	if !reflect.DeepEqual(a.Steps, want) {
+		t.Errorf("unexpected steps: %+v", a.Steps)
+	}
+
+	if infos[1].Error == "" {
+		t.Error("expected parse error for b.yaml")
+	}
+}
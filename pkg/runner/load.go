@@ -0,0 +1,211 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LoadOptions configures a load test.
+type LoadOptions struct {
+	// VUs is the number of virtual users, each running the workflow in a
+	// loop with its own variables.
+	VUs int
+	// Duration is how long virtual users start new iterations for. An
+	// iteration in progress when it ends is allowed to finish.
+	Duration time.Duration
+	// Warmup and WarmupIterations run the workflow before the test starts,
+	// without recording results, until the time has passed or that many
+	// iterations have started.
+	Warmup           time.Duration
+	WarmupIterations int
+}
+
+// LoadResult summarizes a load test. Each executed step counts as one
+// request, including any retries or polling within it.
+type LoadResult struct {
+	VUs               int           `json:"vus"`
+	WarmupIterations  int           `json:"warmup_iterations,omitempty"`
+	Duration          time.Duration `json:"-"`
+	DurationSeconds   float64       `json:"duration_seconds"`
+	Iterations        int           `json:"iterations"`
+	FailedIterations  int           `json:"failed_iterations"`
+	Requests          int           `json:"requests"`
+	FailedRequests    int           `json:"failed_requests"`
+	RequestsPerSecond float64       `json:"requests_per_second"`
+	ErrorRate         float64       `json:"error_rate"`
+	// Latency covers every request in the test.
+	Latency LatencyStats `json:"latency"`
+	Steps   []*LoadStep  `json:"steps"`
+	// Errors counts each distinct failure message.
+	Errors map[string]int `json:"errors,omitempty"`
+	// ThresholdFailures describes each config.thresholds entry the test
+	// exceeded.
+	ThresholdFailures []string `json:"threshold_failures,omitempty"`
+}
+
+// LoadStep is the result of one workflow step across every iteration.
+type LoadStep struct {
+	Name      string  `json:"name"`
+	Requests  int     `json:"requests"`
+	Failed    int     `json:"failed"`
+	ErrorRate float64 `json:"error_rate"`
+	LatencyStats
+
+	samples []time.Duration
+}
+
+// Load runs the workflow file at path repeatedly across concurrent virtual
+// users until opts.Duration has passed. Failed steps don't stop the test;
+// they are counted in the result, and config.thresholds are checked against
+// every request in the test. An error is returned only if the file can't be
+// run at all.
+func (r *Runner) Load(path string, opts LoadOptions) (*LoadResult, error) {
+	if opts.VUs < 1 {
+		return nil, fmt.Errorf("load test needs at least one virtual user")
+	}
+	if opts.Duration <= 0 {
+		return nil, fmt.Errorf("load test needs a positive duration")
+	}
+	spec, err := r.parseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	run := r.loadRunner(opts.VUs)
+	result := &LoadResult{VUs: opts.VUs, Errors: map[string]int{}}
+	steps := map[string]*LoadStep{}
+	for _, step := range spec.Workflow {
+		if steps[step.Step] == nil {
+			steps[step.Step] = &LoadStep{Name: step.Step}
+			result.Steps = append(result.Steps, steps[step.Step])
+		}
+	}
+
+	var mu sync.Mutex
+	record := func(res fileResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Iterations++
+		if len(res.errs) > 0 {
+			result.FailedIterations++
+		}
+		for _, err := range res.fileErrors() {
+			result.Errors[err.Error()]++
+		}
+		for _, sr := range res.steps {
+			step := steps[sr.name]
+			if step == nil {
+				step = &LoadStep{Name: sr.name}
+				steps[sr.name] = step
+				result.Steps = append(result.Steps, step)
+			}
+			step.Requests++
+			step.samples = append(step.samples, sr.duration)
+			result.Requests++
+			if sr.err != nil {
+				step.Failed++
+				result.FailedRequests++
+				result.Errors[sr.failure().Error()]++
+			}
+		}
+	}
+
+	result.WarmupIterations = warmup(context.Background(), opts.VUs, opts.Warmup, opts.WarmupIterations, func() {
+		run.runFile(context.Background(), path)
+	})
+
+	start := time.Now()
+	deadline := start.Add(opts.Duration)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.VUs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				record(run.runFile(context.Background(), path))
+			}
+		}()
+	}
+	wg.Wait()
+
+	result.Duration = time.Since(start)
+	result.DurationSeconds = result.Duration.Seconds()
+	result.RequestsPerSecond = float64(result.Requests) / result.DurationSeconds
+	result.ErrorRate = rate(result.FailedRequests, result.Requests)
+	var all []time.Duration
+	for _, step := range result.Steps {
+		step.ErrorRate = rate(step.Failed, step.Requests)
+		all = append(all, step.samples...)
+		step.LatencyStats = latencyStats(step.samples)
+	}
+	result.Latency = latencyStats(all)
+	result.ThresholdFailures = checkThresholds(path, spec.Config.Thresholds, result.Latency, result.ErrorRate)
+	return result, nil
+}
+
+// loadRunner returns a copy of r for running files over and over, in a
+// load test or soak run: output and run-level exports are off, and enough
+// connections are pooled for each concurrent user to keep one open.
+func (r *Runner) loadRunner(concurrency int) *Runner {
+	run := *r
+	run.out = io.Discard
+	run.progress = nil
+	run.har = nil
+	run.notify = nil
+	run.metrics = nil
+	run.tracing = nil
+	run.responses = nil
+	run.events = nil
+	run.clients = &sync.Map{}
+
+	if run.transport.MaxIdleConnsPerHost == 0 {
+		run.transport.MaxIdleConnsPerHost = concurrency
+	}
+	client := *r.client
+	if r.ownTransport() {
+		client.Transport = run.newTransport()
+	}
+	run.client = &client
+	return &run
+}
+
+// warmup calls iterate from concurrency goroutines until d has passed or
+// count iterations have started, whichever is set and comes first, and
+// returns the number of iterations run.
+func warmup(ctx context.Context, concurrency int, d time.Duration, count int, iterate func()) int {
+	if d <= 0 && count <= 0 {
+		return 0
+	}
+	deadline := time.Now().Add(d)
+	var started, finished int64
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if d > 0 && !time.Now().Before(deadline) {
+					return
+				}
+				if count > 0 && atomic.AddInt64(&started, 1) > int64(count) {
+					return
+				}
+				iterate()
+				atomic.AddInt64(&finished, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	return int(finished)
+}
+
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
@@ -0,0 +1,152 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	var next, mismatched int64
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/carts":
+			id := fmt.Sprint(atomic.AddInt64(&next, 1))
+			fmt.Fprintf(w, `{"id":"%s"}`, id)
+		case strings.HasPrefix(r.URL.Path, "/carts/"):
+			// Every other cart is unavailable.
+			var id int64
+			fmt.Sscan(strings.TrimPrefix(r.URL.Path, "/carts/"), &id)
+			if id == 0 {
+				atomic.AddInt64(&mismatched, 1)
+			}
+			if id%2 == 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		default:
+			atomic.AddInt64(&mismatched, 1)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	path := filepath.Join(t.TempDir(), "checkout.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "create cart"
+  request:
+    method: "POST"
+    url: "/carts"
+  capture:
+  - json_path: "id"
+    as: "cart_id"
+- step: "get cart"
+  request:
+    url: "/carts/${cart_id}"
+  expect:
+    status: 200
+`, api.URL)), 0644)
+
+	res, err := New(5*time.Second, false).Load(path, LoadOptions{VUs: 4, Duration: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.VUs != 4 || res.Iterations < 2 || res.Requests != 2*res.Iterations {
+		t.Fatalf("unexpected totals: %+v", res)
+	}
+	if res.Duration < 200*time.Millisecond {
+		t.Errorf("duration = %s", res.Duration)
+	}
+	if mismatched != 0 {
+		t.Errorf("%d requests did not use a captured cart id", mismatched)
+	}
+	if len(res.Steps) != 2 || res.Steps[0].Name != "create cart" || res.Steps[0].Failed != 0 {
+		t.Fatalf("unexpected steps: %+v", res.Steps)
+	}
+	get := res.Steps[1]
+	if get.Requests != res.Iterations || get.Failed != res.FailedRequests || res.FailedIterations != res.FailedRequests {
+		t.Errorf("get cart = %+v, result = %+v", get, res)
+	}
+	for _, s := range append([]LatencyStats{res.Latency}, res.Steps[0].LatencyStats, get.LatencyStats) {
+		if s.MinMS <= 0 || s.MinMS > s.P50MS || s.P50MS > s.P99MS || s.P99MS > s.MaxMS {
+			t.Errorf("inconsistent latency stats: %+v", s)
+		}
+	}
+	if want := float64(res.FailedRequests) / float64(res.Requests); res.ErrorRate != want {
+		t.Errorf("error rate = %v, want %v", res.ErrorRate, want)
+	}
+	if res.FailedRequests == 0 || res.Errors["expected status 200, got 503"] != res.FailedRequests {
+		t.Errorf("errors = %v", res.Errors)
+	}
+}
+
+func TestLoadParseError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.yaml")
+	os.WriteFile(path, []byte("workflow: ["), 0644)
+	if _, err := New(time.Second, false).Load(path, LoadOptions{VUs: 1, Duration: time.Second}); err == nil || !strings.Contains(err.Error(), "parse") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadWarmup(t *testing.T) {
+	// The first requests are slow and fail, as if caches were cold.
+	var calls int64
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&calls, 1) <= 4 {
+			time.Sleep(20 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer api.Close()
+
+	path := filepath.Join(t.TempDir(), "ping.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "ping"
+  request:
+    url: "/ping"
+  expect:
+    status: 200
+`, api.URL)), 0644)
+
+	res, err := New(5*time.Second, false).Load(path, LoadOptions{VUs: 2, Duration: 30 * time.Millisecond, WarmupIterations: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.WarmupIterations != 4 || res.FailedRequests != 0 || res.Latency.MaxMS >= 20 {
+		t.Errorf("warm-up samples were counted: %+v", res)
+	}
+
+	calls = 0
+	res, _ = New(5*time.Second, false).Load(path, LoadOptions{VUs: 2, Duration: 30 * time.Millisecond, Warmup: 50 * time.Millisecond})
+	if res.WarmupIterations < 4 || res.FailedRequests != 0 {
+		t.Errorf("warm-up samples were counted: %+v", res)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	var n int64
+	iterate := func() { atomic.AddInt64(&n, 1) }
+	if got := warmup(context.Background(), 3, 0, 10, iterate); got != 10 || n != 10 {
+		t.Errorf("warmup ran %d iterations (%d counted), want 10", n, got)
+	}
+	if got := warmup(context.Background(), 3, 0, 0, iterate); got != 0 {
+		t.Errorf("warmup without a limit ran %d iterations", got)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := warmup(ctx, 3, time.Hour, 0, iterate); got != 0 {
+		t.Errorf("cancelled warmup ran %d iterations", got)
+	}
+}
@@ -0,0 +1,59 @@
+package runner
+
+import "log/slog"
+
+// WithLogger sends structured records to logger as the run progresses: a
+// debug record for each log line as it is written, including poll and
+// retry attempts, an info record for each passed step and an error record
+// for each failed step or file, with the file, step, duration and error
+// code as attributes. The log lines depend on the runner's verbosity, as
+// they do in the text report.
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *Runner) {
+		r.logger = logger
+		WithEvents(&logEvents{logger: logger})(r)
+	}
+}
+
+// logLine sends a line logged by file, or by its step if step isn't "", to
+// the logger from WithLogger.
+func (r *Runner) logLine(file, step, line string) {
+	if r.logger == nil {
+		return
+	}
+	if step == "" {
+		r.logger.Debug(line, "file", file)
+		return
+	}
+	r.logger.Debug(line, "file", file, "step", step)
+}
+
+// logEvents writes run events to a slog.Logger.
+type logEvents struct {
+	logger *slog.Logger
+}
+
+func (l *logEvents) OnStepStart(ev StepStart) {
+	l.logger.Debug("step started", "file", ev.File, "step", ev.Step)
+}
+
+func (l *logEvents) OnStepEnd(ev StepEnd) {
+	if ev.Err == nil {
+		l.logger.Info("step passed", "file", ev.File, "step", ev.Step, "duration", ev.Duration)
+		return
+	}
+	l.logger.Error("step failed", "file", ev.File, "step", ev.Step, "duration", ev.Duration,
+		"error", stepFailure(ev.Err), "code", errorCode(ev.Err))
+}
+
+func (l *logEvents) OnFileEnd(ev FileEnd) {
+	for _, err := range ev.result.fileErrors() {
+		l.logger.Error("file failed", "file", ev.File, "error", err.Error(), "code", errorCode(err))
+	}
+}
+
+// stepFailure returns a failed step's error without the file and step
+// context, which the record already carries.
+func stepFailure(err error) string {
+	return stepResult{err: err}.failure().Error()
+}
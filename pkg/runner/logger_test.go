@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logged.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "ok"
+  request:
+    url: "/"
+- step: "missing"
+  request:
+    url: "/missing"
+  expect:
+    status: 200
+`, srv.URL)), 0644)
+	broken := filepath.Join(dir, "broken.yaml")
+	os.WriteFile(broken, []byte("workflow: [\n"), 0644)
+
+	var logs bytes.Buffer
+	handler := slog.NewTextHandler(&logs, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	r := New(5*time.Second, true, WithOutput(io.Discard), WithLogger(slog.New(handler)))
+	r.RunPaths([]string{path, broken})
+
+	for _, want := range []string{
+		fmt.Sprintf(`level=DEBUG msg="step started" file=%s step=ok`, path),
+		fmt.Sprintf(`level=DEBUG msg="Received status: 200 (HTTP/1.1)" file=%s step=ok`, path),
+		fmt.Sprintf(`level=INFO msg="step passed" file=%s step=ok`, path),
+		fmt.Sprintf(`level=ERROR msg="step failed" file=%s step=missing error="expected status 200, got 404" code=assertion`, path),
+		fmt.Sprintf(`level=ERROR msg="file failed" file=%s`, broken),
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs missing %q:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestLoggerPollAttempts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "pending"}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "poll.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "job"
+  request:
+    url: "/job"
+  poll:
+    interval: 1ms
+    max_attempts: 2
+  expect:
+    json_path_match:
+    - path: "status"
+      value: "done"
+`, srv.URL)), 0644)
+
+	var logs bytes.Buffer
+	handler := slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})
+	r := New(5*time.Second, true, WithOutput(io.Discard), WithLogger(slog.New(handler)))
+	r.RunPaths([]string{path})
+
+	attempt := strings.Index(logs.String(), fmt.Sprintf(`msg="Poll attempt 2: jsonpath status expected \"done\", got \"pending\"" file=%s step=job`, path))
+	failed := strings.Index(logs.String(), `msg="step failed"`)
+	if attempt < 0 || failed < attempt {
+		t.Errorf("expected a record for each poll attempt before the step failed:\n%s", logs.String())
+	}
+	if n := strings.Count(logs.String(), "Poll attempt 1:"); n != 1 {
+		t.Errorf("expected one record for the first attempt, got %d", n)
+	}
+}
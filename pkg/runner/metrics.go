@@ -0,0 +1,173 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// Metrics publishes the results of each run in the Prometheus text format.
+type Metrics struct {
+	// PushURL is a Pushgateway address. Metrics are pushed to the ramjam job
+	// unless the URL already names one with /metrics/job/.
+	PushURL string
+	// File is written for the node exporter's textfile collector.
+	File string
+}
+
+// WithMetrics publishes per-step latency, status codes and pass/fail gauges
+// when RunPaths finishes. Metrics that can't be written or pushed are
+// reported as a warning.
+func WithMetrics(m Metrics) Option {
+	return func(r *Runner) {
+		r.metrics = &m
+	}
+}
+
+// metricFamilies lists each metric's help text, in the order they are
+// written.
+var metricFamilies = []struct{ name, help string }{
+	{"ramjam_step_success", "Whether the step passed (1) or failed (0)."},
+	{"ramjam_step_duration_seconds", "How long the step took, including retries and polling."},
+	{"ramjam_step_status_code", "HTTP status code of the step's last response."},
+	{"ramjam_run_steps", "Steps in the run by result."},
+	{"ramjam_run_success", "Whether every step in the run passed (1) or not (0)."},
+	{"ramjam_run_duration_seconds", "How long the run took."},
+	{"ramjam_run_timestamp_seconds", "Unix time the run finished."},
+}
+
+// metricsText renders the results of a run in the Prometheus text format.
+// Steps are labelled with their index as well as their name, so steps
+// that share a name get a sample each.
+func metricsText(results []fileResult, duration time.Duration, now time.Time) []byte {
+	samples := map[string][]string{}
+	add := func(name string, labels []string, value float64) {
+		line := name
+		if len(labels) > 0 {
+			line += "{" + strings.Join(labels, ",") + "}"
+		}
+		samples[name] = append(samples[name], fmt.Sprintf("%s %g", line, value))
+	}
+
+	sorted := append([]fileResult(nil), results...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].path < sorted[j].path })
+	for _, res := range sorted {
+		for i, step := range res.steps {
+			labels := []string{
+				label("file", res.path),
+				label("workflow", res.name),
+				label("step", step.name),
+				label("index", strconv.Itoa(i)),
+			}
+			success := 1.0
+			if step.err != nil {
+				success = 0
+			}
+			add("ramjam_step_success", labels, success)
+			add("ramjam_step_duration_seconds", labels, step.duration.Seconds())
+			if step.status != 0 {
+				add("ramjam_step_status_code", labels, float64(step.status))
+			}
+		}
+	}
+
+	s := summarize(results, duration)
+	add("ramjam_run_steps", []string{label("result", "passed")}, float64(s.Passed))
+	add("ramjam_run_steps", []string{label("result", "failed")}, float64(s.Failed))
+	add("ramjam_run_steps", []string{label("result", "skipped")}, float64(s.Skipped))
+	success := 1.0
+	if s.Status != "passed" {
+		success = 0
+	}
+	add("ramjam_run_success", nil, success)
+	add("ramjam_run_duration_seconds", nil, duration.Seconds())
+	add("ramjam_run_timestamp_seconds", nil, float64(now.Unix()))
+
+	var b bytes.Buffer
+	for _, family := range metricFamilies {
+		if len(samples[family.name]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n", family.name, family.help)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", family.name)
+		for _, line := range samples[family.name] {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.Bytes()
+}
+
+func label(name, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return name + `="` + value + `"`
+}
+
+// publishMetrics writes the metrics file and pushes to the Pushgateway,
+// whichever are configured.
+func (r *Runner) publishMetrics(results []fileResult, duration time.Duration) error {
+	text := metricsText(results, duration, time.Now())
+	if r.metrics.File != "" {
+		if err := writeFileAtomic(r.metrics.File, text); err != nil {
+			return err
+		}
+	}
+	if r.metrics.PushURL != "" {
+		if err := r.pushMetrics(text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic renames a temporary file into place so the textfile
+// collector never reads a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ramjam-metrics-*")
+	if err := e.Wrap(err, "write metrics"); err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return e.Wrap(err, "write metrics")
+	}
+	if err := e.Wrap(tmp.Close(), "write metrics"); err != nil {
+		return err
+	}
+	if err := e.Wrap(os.Chmod(tmp.Name(), 0644), "write metrics"); err != nil {
+		return err
+	}
+	return e.Wrap(os.Rename(tmp.Name(), path), "write metrics")
+}
+
+// pushMetrics replaces the job's metrics on the Pushgateway.
+func (r *Runner) pushMetrics(text []byte) error {
+	url := r.metrics.PushURL
+	if !strings.Contains(url, "/metrics/job/") {
+		url = strings.TrimSuffix(url, "/") + "/metrics/job/ramjam"
+	}
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(text))
+	if err := e.Wrap(err, "push metrics"); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := r.client.Do(req)
+	if err := e.Wrap(err, "push metrics"); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("push metrics: pushgateway returned %s", resp.Status)
+	}
+	return nil
+}
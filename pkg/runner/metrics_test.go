@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	var pushed, pushPath, pushMethod string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		pushed, pushPath, pushMethod = string(body), r.URL.Path, r.Method
+	}))
+	defer gateway.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orders.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+metadata:
+  name: "Orders"
+config:
+  base_url: "%s"
+workflow:
+- step: "list"
+  request:
+    url: "/orders"
+- step: "missing"
+  request:
+    url: "/missing"
+  expect:
+    status: 200
+- step: "list"
+  request:
+    url: "/missing"
+  expect:
+    status: 404
+`, api.URL)), 0644)
+	metricsFile := filepath.Join(dir, "ramjam.prom")
+
+	r := New(5*time.Second, false, WithMetrics(Metrics{PushURL: gateway.URL, File: metricsFile}))
+	r.out = io.Discard
+	r.RunPaths([]string{path})
+
+	data, err := os.ReadFile(metricsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	labels := `{file="` + path + `",workflow="Orders",step="%s",index="%d"}`
+	for _, want := range []string{
+		"# HELP ramjam_step_success Whether the step passed (1) or failed (0).\n# TYPE ramjam_step_success gauge\n",
+		"ramjam_step_success" + fmt.Sprintf(labels, "list", 0) + " 1\n",
+		"ramjam_step_success" + fmt.Sprintf(labels, "missing", 1) + " 0\n",
+		"ramjam_step_success" + fmt.Sprintf(labels, "list", 2) + " 1\n",
+		"ramjam_step_status_code" + fmt.Sprintf(labels, "list", 0) + " 200\n",
+		"ramjam_step_status_code" + fmt.Sprintf(labels, "missing", 1) + " 404\n",
+		"ramjam_step_status_code" + fmt.Sprintf(labels, "list", 2) + " 404\n",
+		"ramjam_step_duration_seconds" + fmt.Sprintf(labels, "list", 0) + " ",
+		`ramjam_run_steps{result="passed"} 2` + "\n",
+		`ramjam_run_steps{result="failed"} 1` + "\n",
+		"ramjam_run_success 0\n",
+		"ramjam_run_timestamp_seconds ",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("metrics missing %q:\n%s", want, text)
+		}
+	}
+
+	if pushMethod != http.MethodPut || pushPath != "/metrics/job/ramjam" {
+		t.Errorf("unexpected push %s %s", pushMethod, pushPath)
+	}
+	if !strings.Contains(pushed, "ramjam_run_success 0\n") {
+		t.Errorf("unexpected pushed metrics:\n%s", pushed)
+	}
+}
+
+func TestMetricsPushError(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer gateway.Close()
+
+	var warnings strings.Builder
+	r := New(5*time.Second, false, WithMetrics(Metrics{PushURL: gateway.URL}), WithWarnings(&warnings))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{notifyWorkflow(t, 200)}); err != nil {
+		t.Fatalf("expected a metrics failure not to fail the run, got %v", err)
+	}
+	if got := warnings.String(); got != "warning: push metrics: pushgateway returned 503 Service Unavailable\n" {
+		t.Errorf("warnings = %q", got)
+	}
+}
+
+func TestMetricLabelEscaping(t *testing.T) {
+	if got := label("step", "say \"hi\"\\\n"); got != `step="say \"hi\"\\\n"` {
+		t.Errorf("label = %s", got)
+	}
+}
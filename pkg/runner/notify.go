@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// Notification payload formats accepted by WithNotify.
+const (
+	NotifyJSON  = "json"
+	NotifySlack = "slack"
+)
+
+// NotifyFormats lists the supported --notify-format values.
+var NotifyFormats = []string{NotifyJSON, NotifySlack}
+
+// Notification posts a summary of each run to a webhook.
+type Notification struct {
+	URL string
+	// Format is NotifyJSON for the summary as JSON, or NotifySlack for a
+	// Slack incoming-webhook message.
+	Format string
+	// OnFailure skips the notification when every step passed.
+	OnFailure bool
+}
+
+// WithNotify posts a run summary to a webhook when RunPaths finishes. A
+// summary that can't be sent is reported as a warning.
+func WithNotify(n Notification) Option {
+	return func(r *Runner) {
+		r.notify = &n
+	}
+}
+
+// runSummary is the JSON notification payload.
+type runSummary struct {
+	Status      string           `json:"status"`
+	Files       int              `json:"files"`
+	FailedFiles int              `json:"failed_files"`
+	Passed      int              `json:"passed"`
+	Failed      int              `json:"failed"`
+	Skipped     int              `json:"skipped"`
+	DurationMS  float64          `json:"duration_ms"`
+	Failures    []summaryFailure `json:"failures"`
+}
+
+func summarize(results []fileResult, duration time.Duration) runSummary {
+	s := runSummary{
+		Status:     "passed",
+		DurationMS: float64(duration) / float64(time.Millisecond),
+		Failures:   []summaryFailure{},
+	}
+	for _, res := range results {
+		s.Files++
+		if len(res.errs) > 0 {
+			s.FailedFiles++
+			s.Status = "failed"
+		}
+		s.Skipped += res.skipped
+		for _, err := range res.fileErrors() {
+			s.Failures = append(s.Failures, summaryFailure{File: res.path, Workflow: res.name, Error: err.Error()})
+		}
+		for _, step := range res.steps {
+			if step.err == nil {
+				s.Passed++
+				continue
+			}
+			s.Failed++
+			s.Failures = append(s.Failures, summaryFailure{File: res.path, Workflow: res.name, Step: step.name, Error: step.failure().Error()})
+		}
+	}
+	return s
+}
+
+// slackMessage formats a summary as a Slack incoming-webhook payload.
+func slackMessage(s runSummary) map[string]string {
+	icon := ":white_check_mark:"
+	if s.Status == "failed" {
+		icon = ":x:"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s *ramjam %s*: %d passed, %d failed, %d skipped in %s",
+		icon, s.Status, s.Passed, s.Failed, s.Skipped, formatDuration(time.Duration(s.DurationMS*float64(time.Millisecond))))
+	for _, f := range s.Failures {
+		name := f.Workflow
+		if f.Step != "" {
+			name += " > " + f.Step
+		}
+		fmt.Fprintf(&b, "\n• *%s*: %s", name, f.Error)
+	}
+	return map[string]string{"text": b.String()}
+}
+
+// sendNotification posts the summary of a run, unless the run passed and
+// only failures are wanted.
+func (r *Runner) sendNotification(results []fileResult, duration time.Duration) error {
+	s := summarize(results, duration)
+	if r.notify.OnFailure && s.Status == "passed" {
+		return nil
+	}
+	var payload interface{} = s
+	if r.notify.Format == NotifySlack {
+		payload = slackMessage(s)
+	}
+	data, err := json.Marshal(payload)
+	if err := e.Wrap(err, "encode notification"); err != nil {
+		return err
+	}
+	resp, err := r.client.Post(r.notify.URL, "application/json", bytes.NewReader(data))
+	if err := e.Wrap(err, "send notification"); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("send notification: webhook returned %s", resp.Status)
+	}
+	return nil
+}
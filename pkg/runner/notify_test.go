@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func notifyWorkflow(t *testing.T, status int) string {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(api.Close)
+	path := filepath.Join(t.TempDir(), "orders.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+metadata:
+  name: "Orders"
+config:
+  base_url: "%s"
+workflow:
+- step: "list"
+  request:
+    url: "/orders"
+  expect:
+    status: 200
+`, api.URL)), 0644)
+	return path
+}
+
+func TestNotifyJSON(t *testing.T) {
+	var got runSummary
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer hook.Close()
+
+	path := notifyWorkflow(t, 500)
+	r := New(5*time.Second, false, WithNotify(Notification{URL: hook.URL, Format: NotifyJSON}))
+	r.out = io.Discard
+	r.RunPaths([]string{path})
+
+	if got.Status != "failed" || got.Files != 1 || got.Passed != 0 || got.Failed != 1 {
+		t.Errorf("unexpected summary: %+v", got)
+	}
+	want := summaryFailure{File: path, Workflow: "Orders", Step: "list", Error: "expected status 200, got 500"}
+	if len(got.Failures) != 1 || got.Failures[0] != want {
+		t.Errorf("unexpected failures: %+v", got.Failures)
+	}
+}
+
+func TestNotifySlackOnFailureOnly(t *testing.T) {
+	var messages []string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]string
+		json.NewDecoder(r.Body).Decode(&msg)
+		messages = append(messages, msg["text"])
+	}))
+	defer hook.Close()
+
+	n := Notification{URL: hook.URL, Format: NotifySlack, OnFailure: true}
+	for _, status := range []int{200, 500} {
+		r := New(5*time.Second, false, WithNotify(n))
+		r.out = io.Discard
+		r.RunPaths([]string{notifyWorkflow(t, status)})
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("expected only the failing run to notify, got %d messages", len(messages))
+	}
+	for _, want := range []string{":x: *ramjam failed*: 0 passed, 1 failed, 0 skipped in ", "\n• *Orders > list*: expected status 200, got 500"} {
+		if !strings.Contains(messages[0], want) {
+			t.Errorf("message missing %q:\n%s", want, messages[0])
+		}
+	}
+}
+
+func TestNotifyWebhookError(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer hook.Close()
+
+	var warnings strings.Builder
+	r := New(5*time.Second, false, WithNotify(Notification{URL: hook.URL, Format: NotifyJSON}), WithWarnings(&warnings))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{notifyWorkflow(t, 200)}); err != nil {
+		t.Fatalf("expected a notification failure not to fail the run, got %v", err)
+	}
+	if got := warnings.String(); got != "warning: send notification: webhook returned 403 Forbidden\n" {
+		t.Errorf("warnings = %q", got)
+	}
+}
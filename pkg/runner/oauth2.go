@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// tokenExpiryMargin refreshes tokens slightly before they expire so that a
+// token is never sent in the final seconds of its lifetime.
+const tokenExpiryMargin = 30 * time.Second
+
+// OAuth2Auth configures the OAuth2 client-credentials grant. Tokens are
+// fetched once, cached for the whole run and refreshed when they expire.
+type OAuth2Auth struct {
+	TokenURL     string   `yaml:"token_url"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+	Audience     string   `yaml:"audience,omitempty"`
+	// AuthStyle is "body" (default) to send credentials as form fields or
+	// "header" to send them as HTTP Basic auth.
+	AuthStyle string `yaml:"auth_style,omitempty"`
+}
+
+type oauthToken struct {
+	accessToken string
+	tokenType   string
+	expiry      time.Time
+}
+
+func (t *oauthToken) valid() bool {
+	return t != nil && (t.expiry.IsZero() || time.Now().Add(tokenExpiryMargin).Before(t.expiry))
+}
+
+// tokenCache shares tokens between concurrently running workflow files.
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]*oauthToken
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{tokens: make(map[string]*oauthToken)}
+}
+
+// oauth2Config returns cfg with vars substituted into it.
+func (r *Runner) oauth2Config(cfg *OAuth2Auth, vars map[string]string) (OAuth2Auth, error) {
+	resolved := OAuth2Auth{AuthStyle: cfg.AuthStyle}
+	fields := []struct {
+		dst *string
+		src string
+	}{
+		{&resolved.TokenURL, cfg.TokenURL},
+		{&resolved.ClientID, cfg.ClientID},
+		{&resolved.ClientSecret, cfg.ClientSecret},
+		{&resolved.Audience, cfg.Audience},
+	}
+	for _, f := range fields {
+		var err error
+		if *f.dst, err = r.applyVars(f.src, vars); err != nil {
+			return OAuth2Auth{}, err
+		}
+	}
+	for _, scope := range cfg.Scopes {
+		scope, err := r.applyVars(scope, vars)
+		if err != nil {
+			return OAuth2Auth{}, err
+		}
+		resolved.Scopes = append(resolved.Scopes, scope)
+	}
+	return resolved, nil
+}
+
+// token returns a token for resolved, an OAuth2 config with its variables
+// already substituted.
+func (c *tokenCache) token(ctx context.Context, client *http.Client, resolved OAuth2Auth, userAgent string) (*oauthToken, error) {
+	key := strings.Join([]string{resolved.TokenURL, resolved.ClientID, strings.Join(resolved.Scopes, " "), resolved.Audience}, "|")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tok := c.tokens[key]; tok.valid() {
+		return tok, nil
+	}
+	tok, err := fetchToken(ctx, client, resolved, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	c.tokens[key] = tok
+	return tok, nil
+}
+
+func fetchToken(ctx context.Context, client *http.Client, cfg OAuth2Auth, userAgent string) (*oauthToken, error) {
+	if cfg.TokenURL == "" || cfg.ClientID == "" {
+		return nil, fmt.Errorf("oauth2 requires token_url and client_id")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	if cfg.Audience != "" {
+		form.Set("audience", cfg.Audience)
+	}
+	switch cfg.AuthStyle {
+	case "", "body":
+		form.Set("client_id", cfg.ClientID)
+		form.Set("client_secret", cfg.ClientSecret)
+	case "header":
+	default:
+		return nil, fmt.Errorf("unknown oauth2 auth_style %q (expected body or header)", cfg.AuthStyle)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err := e.Wrap(err, "build token request"); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	if cfg.AuthStyle == "header" {
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	resp, err := client.Do(req)
+	if err := e.Wrapf(err, "request token from %s", cfg.TokenURL); err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := e.Wrap(err, "read token response"); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var payload struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := e.Wrap(json.Unmarshal(body, &payload), "parse token response"); err != nil {
+		return nil, err
+	}
+	if payload.AccessToken == "" {
+		return nil, fmt.Errorf("token response did not include an access_token")
+	}
+
+	tok := &oauthToken{accessToken: payload.AccessToken, tokenType: "Bearer"}
+	if strings.EqualFold(payload.TokenType, "bearer") || payload.TokenType == "" {
+		tok.tokenType = "Bearer"
+	} else {
+		tok.tokenType = payload.TokenType
+	}
+	if payload.ExpiresIn > 0 {
+		tok.expiry = time.Now().Add(time.Duration(payload.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultPollMaxWait  = 30 * time.Second
+)
+
+// Poll repeats a step's request until its expectations pass, for async APIs
+// where a resource transitions between states (e.g. a job becoming "done").
+type Poll struct {
+	Interval    time.Duration `yaml:"interval"`
+	MaxWait     time.Duration `yaml:"max_wait"`
+	MaxAttempts int           `yaml:"max_attempts,omitempty"`
+}
+
+func (r *Runner) pollStep(ctx context.Context, step Step, vars map[string]string, log func(string, ...interface{})) error {
+	interval := step.Poll.Interval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	maxWait := step.Poll.MaxWait
+	if maxWait <= 0 {
+		maxWait = defaultPollMaxWait
+	}
+
+	deadline := time.Now().Add(maxWait)
+	attempts := 0
+	for {
+		attempts++
+		err := r.attemptStep(ctx, step, vars, log)
+		if err == nil {
+			if r.verbose() {
+				log("Poll condition met after %d attempt(s)", attempts)
+			}
+			return nil
+		}
+
+		if r.verbose() {
+			log("Poll attempt %d: %v", attempts, err)
+		}
+
+		if step.Poll.MaxAttempts > 0 && attempts >= step.Poll.MaxAttempts {
+			return fmt.Errorf("condition not met after %d attempts: %w", attempts, err)
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("condition not met after %s (%d attempts): %w", maxWait, attempts, err)
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
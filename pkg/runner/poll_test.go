@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPollUntilConditionMet(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Write([]byte(`{"status": "pending"}`))
+			return
+		}
+		w.Write([]byte(`{"status": "done", "result": "42"}`))
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Poll Job"
+config:
+  base_url: "%s"
+workflow:
+- step: "wait-for-job"
+  request:
+    url: "/jobs/1"
+  poll:
+    interval: 10ms
+    max_wait: 2s
+  expect:
+    status: 200
+    json_path_match:
+    - path: "status"
+      value: "done"
+  capture:
+  - json_path: "result"
+    as: "result"
+`, srv.URL)
+
+	runTest(t, yamlContent)
+
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("expected 3 requests, got %d", got)
+	}
+}
+
+func TestPollTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "pending"}`))
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Poll Timeout"
+config:
+  base_url: "%s"
+workflow:
+- step: "never-done"
+  request:
+    url: "/jobs/1"
+  poll:
+    interval: 10ms
+    max_wait: 50ms
+  expect:
+    json_path_match:
+    - path: "status"
+      value: "done"
+`, srv.URL)
+
+	err := runTestError(t, yamlContent)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "condition not met after 50ms") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+	if !strings.Contains(err.Error(), `expected "done", got "pending"`) {
+		t.Errorf("expected last failure in error, got: %v", err)
+	}
+}
+
+func TestPollMaxAttempts(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Poll Attempts"
+config:
+  base_url: "%s"
+workflow:
+- step: "limited"
+  request:
+    url: "/jobs/1"
+  poll:
+    interval: 5ms
+    max_attempts: 4
+  expect:
+    status: 200
+`, srv.URL)
+
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), "condition not met after 4 attempts") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 4 {
+		t.Errorf("expected 4 requests, got %d", got)
+	}
+}
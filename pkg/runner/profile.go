@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"path/filepath"
+
+	"github.com/michaelmccabe/ramjam/pkg/config"
+)
+
+// Profile is a named set of config settings and variables from a project's
+// .ramjam.yaml, such as the base URL, headers and auth for staging.
+type Profile struct {
+	Config `yaml:",inline"`
+	Vars   map[string]string `yaml:"vars,omitempty"`
+}
+
+// LoadProfile loads the profile called name from the project file at path.
+// TLS and OpenAPI paths in the profile are relative to the project file.
+func LoadProfile(path, name string) (Profile, error) {
+	var p Profile
+	if err := config.LoadProfile(path, name, &p); err != nil {
+		return Profile{}, err
+	}
+	p.TLS = resolveTLSPaths(p.TLS, filepath.Dir(path))
+	if p.OpenAPI != "" && !filepath.IsAbs(p.OpenAPI) {
+		p.OpenAPI = filepath.Join(filepath.Dir(path), p.OpenAPI)
+	}
+	return p, nil
+}
+
+// WithProfile merges a profile into every workflow file's config. Settings
+// the profile sets replace the file's, and its headers are added to the
+// file's. Its variables are set before each file runs, below any from
+// WithVars.
+func WithProfile(p Profile) Option {
+	return func(r *Runner) {
+		r.profile = &p
+	}
+}
+
+// apply returns cfg with the profile's settings merged in.
+func (p *Profile) apply(cfg Config) Config {
+	return mergeConfig(cfg, p.Config)
+}
+
+// mergeConfig returns cfg with the settings over sets replacing its own.
+// Headers are merged, with over's taking precedence.
+func mergeConfig(cfg, over Config) Config {
+	if over.BaseURL != "" {
+		cfg.BaseURL = over.BaseURL
+	}
+	if over.MaxBodySize != 0 {
+		cfg.MaxBodySize = over.MaxBodySize
+	}
+	if over.HTTPVersion != "" {
+		cfg.HTTPVersion = over.HTTPVersion
+	}
+	if !over.TLS.isZero() {
+		cfg.TLS = over.TLS
+	}
+	if over.Proxy != "" {
+		cfg.Proxy = over.Proxy
+	}
+	if over.UserAgent != "" {
+		cfg.UserAgent = over.UserAgent
+	}
+	if over.OpenAPI != "" {
+		cfg.OpenAPI = over.OpenAPI
+	}
+	cfg.Headers = mergeHeaders(cfg.Headers, over.Headers)
+	if over.Auth != nil {
+		cfg.Auth = over.Auth
+	}
+	if !over.RateLimit.IsZero() {
+		cfg.RateLimit = over.RateLimit
+	}
+	if over.Retry != nil {
+		cfg.Retry = over.Retry
+	}
+	if len(over.Thresholds) > 0 {
+		cfg.Thresholds = over.Thresholds
+	}
+	if len(over.ImportsVars) > 0 {
+		cfg.ImportsVars = over.ImportsVars
+	}
+	return cfg
+}
+
+// mergeHeaders returns a new map with the headers in over added to those in
+// base. base is returned as is when over is empty.
+func mergeHeaders(base, over map[string]string) map[string]string {
+	if len(over) == 0 {
+		return base
+	}
+	headers := make(map[string]string, len(base)+len(over))
+	for k, v := range base {
+		headers[k] = v
+	}
+	for k, v := range over {
+		headers[k] = v
+	}
+	return headers
+}
@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfile(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Path+" "+r.Header.Get("X-Tenant")+" "+r.Header.Get("X-Trace")+" "+r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	project := filepath.Join(dir, ".ramjam.yaml")
+	os.WriteFile(project, []byte(fmt.Sprintf(`
+profiles:
+  staging:
+    base_url: "%s"
+    headers:
+      X-Tenant: "acme"
+    auth:
+      bearer: "${token}"
+    vars:
+      token: "staging-token"
+      region: "eu"
+`, srv.URL)), 0644)
+	workflow := filepath.Join(dir, "users.yaml")
+	os.WriteFile(workflow, []byte(`
+config:
+  base_url: "http://localhost:1"
+  headers:
+    X-Tenant: "local"
+    X-Trace: "on"
+workflow:
+- step: "users"
+  request:
+    url: "/users/${region}"
+`), 0644)
+
+	profile, err := LoadProfile(project, "staging")
+	if err != nil {
+		t.Fatalf("LoadProfile() error = %v", err)
+	}
+	r := New(10*time.Second, false, WithProfile(profile), WithVars(map[string]string{"region": "us"}))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{workflow}); err != nil {
+		t.Fatalf("RunPaths() error = %v", err)
+	}
+	if len(seen) != 1 || seen[0] != "/users/us acme on Bearer staging-token" {
+		t.Errorf("requests = %q", seen)
+	}
+}
+
+func TestProfileTLSPaths(t *testing.T) {
+	dir := t.TempDir()
+	project := filepath.Join(dir, ".ramjam.yaml")
+	os.WriteFile(project, []byte("profiles:\n  mtls:\n    tls:\n      ca_file: certs/ca.pem\n"), 0644)
+	profile, err := LoadProfile(project, "mtls")
+	if err != nil {
+		t.Fatalf("LoadProfile() error = %v", err)
+	}
+	if want := filepath.Join(dir, "certs", "ca.pem"); profile.TLS.CAFile != want {
+		t.Errorf("ca_file = %q, want %q", profile.TLS.CAFile, want)
+	}
+}
+
+func TestProfileOpenAPI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "2", "name": "Grace"}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "api"), 0755)
+	os.Mkdir(filepath.Join(dir, "tests"), 0755)
+	os.WriteFile(filepath.Join(dir, "api", "spec.yaml"), []byte(contractSpec), 0644)
+	project := filepath.Join(dir, ".ramjam.yaml")
+	os.WriteFile(project, []byte("profiles:\n  contract:\n    openapi: api/spec.yaml\n"), 0644)
+	workflow := filepath.Join(dir, "tests", "users.yaml")
+	os.WriteFile(workflow, []byte(fmt.Sprintf(`
+workflow:
+- step: "user"
+  request:
+    url: "%s/v1/users/2"
+`, srv.URL)), 0644)
+
+	profile, err := LoadProfile(project, "contract")
+	if err != nil {
+		t.Fatalf("LoadProfile() error = %v", err)
+	}
+	r := New(10*time.Second, false, WithProfile(profile))
+	r.out = io.Discard
+	err = r.RunPaths([]string{workflow})
+	if err == nil || !strings.Contains(err.Error(), "openapi: GET /users/{id} 200 response: $.id: expected integer, got string") {
+		t.Errorf("expected the profile's spec to check the response, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const progressBarWidth = 20
+
+// WithProgress writes a live "12/40 files, 3 failed" line to w while
+// several files run. It redraws the line in place, so w should be a terminal.
+func WithProgress(w io.Writer) Option {
+	return func(r *Runner) {
+		r.progress = w
+	}
+}
+
+// progressLine tracks completed files for the live progress display. A nil
+// *progressLine is valid and draws nothing.
+type progressLine struct {
+	out    io.Writer
+	total  int
+	done   int
+	failed int
+}
+
+func newProgressLine(out io.Writer, total int) *progressLine {
+	if out == nil || total < 2 {
+		return nil
+	}
+	p := &progressLine{out: out, total: total}
+	p.draw()
+	return p
+}
+
+// clear erases the line so report output is not interleaved with it.
+func (p *progressLine) clear() {
+	if p == nil {
+		return
+	}
+	fmt.Fprint(p.out, "\r\033[K")
+}
+
+func (p *progressLine) add(res fileResult) {
+	if p == nil {
+		return
+	}
+	p.done++
+	if len(res.errs) > 0 {
+		p.failed++
+	}
+	p.draw()
+}
+
+func (p *progressLine) draw() {
+	filled := 0
+	if p.total > 0 {
+		filled = p.done * progressBarWidth / p.total
+	}
+	bar := strings.Repeat("#", filled) + strings.Repeat(".", progressBarWidth-filled)
+	fmt.Fprintf(p.out, "\r\033[K[%s] %d/%d files, %d failed", bar, p.done, p.total, p.failed)
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressLine(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	for i, path := range []string{"/ok", "/ok", "/bad"} {
+		workflow := fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "call"
+  request:
+    url: "%s"
+  expect:
+    status: 200
+`, srv.URL, path)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.yaml", i)), []byte(workflow), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out, progress bytes.Buffer
+	r := New(5*time.Second, false, WithProgress(&progress))
+	r.out = &out
+	if err := r.RunPaths([]string{dir}); err == nil {
+		t.Fatal("expected a failing file")
+	}
+
+	got := progress.String()
+	for _, want := range []string{
+		"[....................] 0/3 files, 0 failed",
+		"[####################] 3/3 files, 1 failed",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("progress output missing %q: %q", want, got)
+		}
+	}
+	if !strings.HasSuffix(got, "\r\033[K") {
+		t.Errorf("expected progress line to be cleared at the end: %q", got)
+	}
+	if strings.Contains(out.String(), "files,") {
+		t.Error("progress should not be written to the report output")
+	}
+}
+
+func TestProgressLineSingleFile(t *testing.T) {
+	if p := newProgressLine(&bytes.Buffer{}, 1); p != nil {
+		t.Error("expected no progress line for a single file")
+	}
+}
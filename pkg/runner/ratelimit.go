@@ -0,0 +1,155 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RateLimit is a maximum request rate, written as requests per unit of
+// time, such as 10/s, 600/m or 1/500ms. A plain number is per second.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l *RateLimit) UnmarshalYAML(node *yaml.Node) error {
+	limit, err := ParseRateLimit(node.Value)
+	if err != nil {
+		return err
+	}
+	*l = limit
+	return nil
+}
+
+// ParseRateLimit parses a rate such as 10/s.
+func ParseRateLimit(s string) (RateLimit, error) {
+	count, unit, found := strings.Cut(strings.TrimSpace(s), "/")
+	per := time.Second
+	if found {
+		unit = strings.TrimSpace(unit)
+		if unit != "" && (unit[0] < '0' || unit[0] > '9') {
+			unit = "1" + unit
+		}
+		d, err := time.ParseDuration(unit)
+		if err != nil || d <= 0 {
+			return RateLimit{}, fmt.Errorf("invalid rate limit %q (expected requests per unit, such as 10/s)", s)
+		}
+		per = d
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q (expected requests per unit, such as 10/s)", s)
+	}
+	return RateLimit{Requests: n, Per: per}, nil
+}
+
+func (l RateLimit) MarshalYAML() (interface{}, error) {
+	return l.String(), nil
+}
+
+// IsZero reports whether no limit is set, so omitempty leaves it out.
+func (l RateLimit) IsZero() bool {
+	return l.Requests == 0
+}
+
+func (l RateLimit) String() string {
+	unit := l.Per.String()
+	switch l.Per {
+	case time.Second:
+		unit = "s"
+	case time.Minute:
+		unit = "m"
+	case time.Hour:
+		unit = "h"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, unit)
+}
+
+// WithRateLimit caps the rate of requests across every file the runner
+// runs, including concurrent files and load test virtual users.
+func WithRateLimit(limit RateLimit) Option {
+	return func(r *Runner) {
+		r.rateLimit = newRateLimiter(limit)
+	}
+}
+
+// rateLimiter is a token bucket holding a single token, so requests are
+// spaced evenly and never burst above the limit.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	return &rateLimiter{interval: limit.Per / time.Duration(limit.Requests)}
+}
+
+// wait blocks until the next request may be sent, or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleep(ctx, time.Until(at))
+}
+
+// rateLimiters returns the limiters that apply to the workflow file at path:
+// the runner's global limit and the file's config.rate_limit. A file's
+// limiter is shared by every run of the file.
+func (r *Runner) rateLimiters(path string, cfg Config) []*rateLimiter {
+	var limiters []*rateLimiter
+	if r.rateLimit != nil {
+		limiters = append(limiters, r.rateLimit)
+	}
+	if !cfg.RateLimit.IsZero() {
+		l, _ := r.limiters.LoadOrStore(path, newRateLimiter(cfg.RateLimit))
+		limiters = append(limiters, l.(*rateLimiter))
+	}
+	return limiters
+}
+
+// limitedTransport waits for every limiter before each request, including
+// redirects and OAuth2 token requests.
+type limitedTransport struct {
+	base     http.RoundTripper
+	limiters []*rateLimiter
+}
+
+func limitClient(c *http.Client, limiters []*rateLimiter) *http.Client {
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	limited := *c
+	limited.Transport = &limitedTransport{base: base, limiters: limiters}
+	return &limited
+}
+
+func (t *limitedTransport) wait(ctx context.Context) error {
+	for _, l := range t.limiters {
+		if err := l.wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
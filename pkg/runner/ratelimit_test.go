@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want RateLimit
+		err  bool
+	}{
+		{in: "10/s", want: RateLimit{10, time.Second}},
+		{in: "600/m", want: RateLimit{600, time.Minute}},
+		{in: "1/500ms", want: RateLimit{1, 500 * time.Millisecond}},
+		{in: " 5 / 2s ", want: RateLimit{5, 2 * time.Second}},
+		{in: "20", want: RateLimit{20, time.Second}},
+		{in: "0/s", err: true},
+		{in: "ten/s", err: true},
+		{in: "10/fortnight", err: true},
+		{in: "10/-1s", err: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRateLimit(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseRateLimit(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestRateLimitYAML(t *testing.T) {
+	var cfg Config
+	if err := yaml.Unmarshal([]byte("rate_limit: 10/s\n"), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RateLimit != (RateLimit{10, time.Second}) {
+		t.Errorf("rate_limit = %v", cfg.RateLimit)
+	}
+	out, _ := yaml.Marshal(cfg)
+	if !strings.Contains(string(out), "rate_limit: 10/s\n") {
+		t.Errorf("marshalled config:\n%s", out)
+	}
+	out, _ = yaml.Marshal(Config{BaseURL: "http://api"})
+	if strings.Contains(string(out), "rate_limit") {
+		t.Errorf("unset rate_limit was marshalled:\n%s", out)
+	}
+}
+
+// gaps returns the sorted intervals between consecutive request times.
+func gaps(times []time.Time) []time.Duration {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	var d []time.Duration
+	for i := 1; i < len(times); i++ {
+		d = append(d, times[i].Sub(times[i-1]))
+	}
+	return d
+}
+
+func TestRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+	}))
+	defer api.Close()
+
+	workflow := func(rateLimit string) string {
+		path := filepath.Join(t.TempDir(), "ping.yaml")
+		os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+  rate_limit: "%s"
+workflow:
+- step: "one"
+  request:
+    url: "/ping"
+- step: "two"
+  request:
+    url: "/ping"
+- step: "three"
+  request:
+    url: "/ping"
+`, api.URL, rateLimit)), 0644)
+		return path
+	}
+
+	t.Run("global across files", func(t *testing.T) {
+		times = nil
+		limit, _ := ParseRateLimit("1/40ms")
+		r := New(5*time.Second, false, WithRateLimit(limit))
+		r.out = io.Discard
+		if err := r.RunPaths([]string{workflow("1000/s"), workflow("1000/s")}); err != nil {
+			t.Fatal(err)
+		}
+		if len(times) != 6 {
+			t.Fatalf("got %d requests", len(times))
+		}
+		for _, gap := range gaps(times) {
+			if gap < 30*time.Millisecond {
+				t.Errorf("requests %s apart, want at least 40ms", gap)
+			}
+		}
+	})
+
+	t.Run("per file", func(t *testing.T) {
+		times = nil
+		r := New(5*time.Second, false)
+		r.out = io.Discard
+		start := time.Now()
+		if err := r.RunPaths([]string{workflow("1/40ms")}); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+			t.Errorf("3 requests at 1/40ms took %s", elapsed)
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RepeatOptions configures a soak run. The run stops at whichever limit is
+// reached first; at least one must be set.
+type RepeatOptions struct {
+	// For is how long to keep starting iterations.
+	For time.Duration
+	// Count is the maximum number of iterations.
+	Count int
+	// Warmup and WarmupIterations run the files before the soak run
+	// starts, without recording results, until the time has passed or
+	// that many iterations have run.
+	Warmup           time.Duration
+	WarmupIterations int
+}
+
+// RepeatResult summarizes a soak run, in which every file is run once per
+// iteration.
+type RepeatResult struct {
+	Start            time.Time `json:"start"`
+	WarmupIterations int       `json:"warmup_iterations,omitempty"`
+	Iterations       int       `json:"iterations"`
+	FailedIterations int       `json:"failed_iterations"`
+	// Failed counts failed steps and files that could not be run.
+	Failed          int           `json:"failed"`
+	Duration        time.Duration `json:"-"`
+	DurationSeconds float64       `json:"duration_seconds"`
+	// FirstFailure is the first step or file that failed, or nil.
+	FirstFailure *RepeatFailure `json:"first_failure,omitempty"`
+	Steps        []*RepeatStep  `json:"steps"`
+	// ThresholdFailures describes each config.thresholds entry a file
+	// exceeded.
+	ThresholdFailures []string `json:"threshold_failures,omitempty"`
+}
+
+// RepeatFailure records when a soak run first failed.
+type RepeatFailure struct {
+	Iteration int       `json:"iteration"`
+	Time      time.Time `json:"time"`
+	File      string    `json:"file"`
+	Step      string    `json:"step,omitempty"`
+	Error     string    `json:"error"`
+}
+
+// RepeatStep is the result of one step across every iteration. DriftPercent
+// compares the mean latency of the last tenth of its samples with the first
+// tenth, so a service that slows down over the run shows a positive drift.
+type RepeatStep struct {
+	File         string  `json:"file"`
+	Name         string  `json:"name"`
+	Requests     int     `json:"requests"`
+	Failed       int     `json:"failed"`
+	DriftPercent float64 `json:"drift_percent"`
+	LatencyStats
+
+	samples []time.Duration
+}
+
+// Repeat runs paths over and over until opts.For has passed, opts.Count
+// iterations have run or ctx is cancelled. Each iteration runs every file
+// in parallel, as RunPaths does, and writes a one-line summary unless the
+// runner is quiet. Failures don't stop the run; they are counted in the
+// result, and each file's config.thresholds are checked against all of its
+// requests.
+func (r *Runner) Repeat(ctx context.Context, paths []string, opts RepeatOptions) (*RepeatResult, error) {
+	if opts.For <= 0 && opts.Count <= 0 {
+		return nil, fmt.Errorf("repeat needs a duration or an iteration count")
+	}
+	files, err := r.Files(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	run := r.loadRunner(len(files))
+	warmed := warmup(ctx, 1, opts.Warmup, opts.WarmupIterations, func() {
+		run.runFiles(ctx, files)
+	})
+	start := time.Now()
+	result := &RepeatResult{Start: start, WarmupIterations: warmed}
+	steps := map[string]*RepeatStep{}
+	for i := 1; opts.Count <= 0 || i <= opts.Count; i++ {
+		if opts.For > 0 && time.Since(start) >= opts.For || ctx.Err() != nil {
+			break
+		}
+		iterStart := time.Now()
+		passed, failed := 0, 0
+		for _, res := range run.runFiles(ctx, files) {
+			if len(res.errs) > 0 && result.FirstFailure == nil {
+				result.FirstFailure = firstFailure(i, res)
+			}
+			failed += len(res.fileErrors())
+			for _, sr := range res.steps {
+				key := res.path + "\x00" + sr.name
+				step := steps[key]
+				if step == nil {
+					step = &RepeatStep{File: res.path, Name: sr.name}
+					steps[key] = step
+					result.Steps = append(result.Steps, step)
+				}
+				step.Requests++
+				step.samples = append(step.samples, sr.duration)
+				if sr.err != nil {
+					step.Failed++
+					failed++
+				} else {
+					passed++
+				}
+			}
+		}
+		result.Iterations++
+		result.Failed += failed
+		if failed > 0 {
+			result.FailedIterations++
+		}
+		if r.verbosity > VerbosityQuiet || failed > 0 {
+			fmt.Fprintf(r.out, "[%s] iteration %d: %d passed, %d failed in %s\n",
+				time.Now().Format("15:04:05"), i, passed, failed, formatDuration(time.Since(iterStart)))
+		}
+	}
+
+	result.Duration = time.Since(start)
+	result.DurationSeconds = result.Duration.Seconds()
+	for _, step := range result.Steps {
+		step.DriftPercent = drift(step.samples)
+	}
+	for _, f := range files {
+		var samples []time.Duration
+		requests, failed := 0, 0
+		for _, step := range result.Steps {
+			if step.File == f {
+				samples = append(samples, step.samples...)
+				requests += step.Requests
+				failed += step.Failed
+			}
+		}
+		result.ThresholdFailures = append(result.ThresholdFailures,
+			checkThresholds(f, r.fileThresholds(f), latencyStats(samples), rate(failed, requests))...)
+	}
+	for _, step := range result.Steps {
+		step.LatencyStats = latencyStats(step.samples)
+	}
+	return result, nil
+}
+
+// runFiles runs files in parallel, or in order if the runner is sequential,
+// and returns their results in order.
+func (r *Runner) runFiles(ctx context.Context, files []string) []fileResult {
+	run := *r
+	run.globals = newGlobalVars(files, r.sequential)
+	results := make([]fileResult, len(files))
+	var wg sync.WaitGroup
+	for i, f := range files {
+		if r.sequential {
+			results[i] = run.runFile(ctx, f)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, f string) {
+			defer wg.Done()
+			results[i] = run.runFile(ctx, f)
+		}(i, f)
+	}
+	wg.Wait()
+	return results
+}
+
+func firstFailure(iteration int, res fileResult) *RepeatFailure {
+	f := &RepeatFailure{Iteration: iteration, Time: time.Now(), File: res.path}
+	if errs := res.fileErrors(); len(errs) > 0 {
+		f.Error = errs[0].Error()
+		return f
+	}
+	for _, step := range res.steps {
+		if step.err != nil {
+			f.Step, f.Error = step.name, step.failure().Error()
+			break
+		}
+	}
+	return f
+}
+
+// drift returns the percentage change in mean latency between the first and
+// last tenth of samples, which must be in the order they were taken.
+func drift(samples []time.Duration) float64 {
+	window := len(samples) / 10
+	if window == 0 {
+		window = 1
+	}
+	if len(samples) < 2*window {
+		return 0
+	}
+	first, last := mean(samples[:window]), mean(samples[len(samples)-window:])
+	if first == 0 {
+		return 0
+	}
+	return (last - first) / first * 100
+}
+
+func mean(samples []time.Duration) float64 {
+	var sum float64
+	for _, d := range samples {
+		sum += milliseconds(d)
+	}
+	return sum / float64(len(samples))
+}
@@ -0,0 +1,126 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRepeat(t *testing.T) {
+	// The service slows down with every request and starts failing on the
+	// fourth.
+	var calls int64
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&calls, 1)
+		time.Sleep(time.Duration(n) * time.Millisecond)
+		if n >= 4 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer api.Close()
+
+	path := filepath.Join(t.TempDir(), "health.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+  thresholds:
+  - error_rate < 50%%
+  - max < 1m
+workflow:
+- step: "health"
+  request:
+    url: "/health"
+  expect:
+    status: 200
+`, api.URL)), 0644)
+
+	var out strings.Builder
+	r := New(5*time.Second, false, WithVerbosity(VerbosityQuiet))
+	r.out = &out
+	res, err := r.Repeat(context.Background(), []string{path}, RepeatOptions{Count: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Iterations != 10 || res.FailedIterations != 7 || res.Failed != 7 {
+		t.Errorf("iterations = %d, failed iterations = %d, failed = %d", res.Iterations, res.FailedIterations, res.Failed)
+	}
+	f := res.FirstFailure
+	if f == nil || f.Iteration != 4 || f.File != path || f.Step != "health" || f.Error != "expected status 200, got 500" {
+		t.Fatalf("first failure = %+v", f)
+	}
+	if f.Time.Before(res.Start) {
+		t.Errorf("first failure at %s, before the run started at %s", f.Time, res.Start)
+	}
+	if len(res.Steps) != 1 || res.Steps[0].Requests != 10 || res.Steps[0].Failed != 7 {
+		t.Fatalf("steps = %+v", res.Steps)
+	}
+	if want := path + ": threshold error_rate < 50% failed: error_rate was 70.00%"; len(res.ThresholdFailures) != 1 || res.ThresholdFailures[0] != want {
+		t.Errorf("threshold failures = %q", res.ThresholdFailures)
+	}
+	if d := res.Steps[0].DriftPercent; d < 100 {
+		t.Errorf("drift = %.1f%%, want a large increase", d)
+	}
+	// Quiet runs only log failing iterations.
+	if lines := strings.Count(out.String(), "\n"); lines != 7 || !strings.Contains(out.String(), "iteration 4: 0 passed, 1 failed") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestRepeatFor(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer api.Close()
+	path := filepath.Join(t.TempDir(), "ping.yaml")
+	os.WriteFile(path, []byte("workflow:\n- step: ping\n  request:\n    url: "+api.URL+"\n"), 0644)
+
+	r := New(5*time.Second, false)
+	r.out = io.Discard
+	res, err := r.Repeat(context.Background(), []string{path}, RepeatOptions{For: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Iterations < 2 || res.FirstFailure != nil || res.Duration < 50*time.Millisecond {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, _ = r.Repeat(ctx, []string{path}, RepeatOptions{For: time.Hour})
+	if res.Iterations != 0 {
+		t.Errorf("cancelled run did %d iterations", res.Iterations)
+	}
+
+	if _, err := r.Repeat(context.Background(), []string{path}, RepeatOptions{}); err == nil {
+		t.Error("expected an error without a duration or count")
+	}
+}
+
+func TestDrift(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		var d []time.Duration
+		for _, v := range values {
+			d = append(d, time.Duration(v)*time.Millisecond)
+		}
+		return d
+	}
+	tests := []struct {
+		samples []time.Duration
+		want    float64
+	}{
+		{ms(10), 0},
+		{ms(10, 15), 50},
+		{ms(10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 20, 20, 20, 20, 20, 20, 20, 20, 5, 5), -50},
+	}
+	for _, tt := range tests {
+		if got := drift(tt.samples); got != tt.want {
+			t.Errorf("drift(%v) = %v, want %v", tt.samples, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,458 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// Report formats accepted by WithReport.
+const (
+	ReportText   = "text"
+	ReportTAP    = "tap"
+	ReportGitHub = "github"
+)
+
+// ReportFormats lists the supported --report values.
+var ReportFormats = []string{ReportText, ReportTAP, ReportGitHub}
+
+// WithColor enables ANSI colors in text output. Callers should only enable
+// it when writing to a terminal.
+func WithColor(enabled bool) Option {
+	return func(r *Runner) {
+		r.color = enabled
+	}
+}
+
+// WithJSONLogs writes text-report events as one JSON object per line
+// instead of the human-readable layout, for log shippers.
+func WithJSONLogs(enabled bool) Option {
+	return func(r *Runner) {
+		r.logJSON = enabled
+	}
+}
+
+// WithReport selects how RunPaths writes results. The default, ReportText,
+// prints each workflow with a pass/fail line per step.
+func WithReport(format string) Option {
+	return func(r *Runner) {
+		r.report = format
+	}
+}
+
+// WithOutput writes reports and other run output to w instead of standard
+// output.
+func WithOutput(w io.Writer) Option {
+	return func(r *Runner) {
+		r.out = w
+	}
+}
+
+// WithWarnings writes warnings, such as a notification that couldn't be
+// sent, to w instead of standard error. They don't fail the run.
+func WithWarnings(w io.Writer) Option {
+	return func(r *Runner) {
+		r.warnings = w
+	}
+}
+
+// warn reports a problem that doesn't fail the run.
+func (r *Runner) warn(err error) {
+	fmt.Fprintf(r.warnings, "warning: %v\n", err)
+}
+
+// fileResult is everything RunPaths needs to report on one workflow file.
+// errs holds every failure, including the step errors in steps; logs holds
+// lines written outside of any step. skipped counts steps that never ran
+// because the file failed before its workflow started. vars holds the
+// file's variables, including captured values, as they were at the end.
+type fileResult struct {
+	path     string
+	name     string
+	logs     []string
+	steps    []stepResult
+	skipped  int
+	errs     []error
+	duration time.Duration
+	vars     map[string]string
+}
+
+type stepResult struct {
+	name        string
+	description string
+	logs        []string
+	err         error
+	duration    time.Duration
+	request     string
+	status      int
+}
+
+// failure returns the underlying error for a failed step, without the
+// file and step context that the report already shows.
+func (s stepResult) failure() error {
+	if se, ok := s.err.(*StepError); ok {
+		return se.Err
+	}
+	return s.err
+}
+
+// fileErrors returns failures that stopped the file before or outside of
+// its steps, such as parse errors.
+func (f fileResult) fileErrors() []error {
+	var errs []error
+	for _, err := range f.errs {
+		if _, ok := err.(*StepError); !ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// reporter receives file results as they complete.
+type reporter interface {
+	file(res fileResult)
+	done()
+}
+
+func (r *Runner) newReporter() reporter {
+	switch {
+	case r.report == ReportTAP:
+		return newTAPReporter(r.out)
+	case r.report == ReportGitHub:
+		return newGitHubReporter(&textReporter{out: r.out, quiet: r.verbosity <= VerbosityQuiet, start: time.Now()})
+	case r.logJSON:
+		return &jsonReporter{enc: json.NewEncoder(r.out), quiet: r.verbosity <= VerbosityQuiet}
+	default:
+		return &textReporter{out: r.out, color: r.color, quiet: r.verbosity <= VerbosityQuiet, start: time.Now()}
+	}
+}
+
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiDim    = "\033[2m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+)
+
+// textReporter prints each workflow as a heading followed by one ✓ or ✗
+// line per step, with the step's log lines and error indented beneath it.
+// In quiet mode only failing files and steps are printed. A summary of the
+// whole run follows the last file.
+type textReporter struct {
+	out   io.Writer
+	color bool
+	quiet bool
+	start time.Time
+
+	files, failedFiles           int
+	passed, failed, skippedSteps int
+	timings                      []stepTiming
+}
+
+type stepTiming struct {
+	name     string
+	duration time.Duration
+}
+
+// slowestSteps is how many steps the run summary lists.
+const slowestSteps = 5
+
+func (t *textReporter) paint(code, text string) string {
+	if !t.color {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+func (t *textReporter) file(res fileResult) {
+	t.record(res)
+	if t.quiet && len(res.errs) == 0 {
+		return
+	}
+	fmt.Fprintf(t.out, "%s %s\n", t.paint(ansiBold, res.name), t.paint(ansiDim, "("+res.path+")"))
+	for _, l := range res.logs {
+		if !t.quiet {
+			fmt.Fprintf(t.out, "    %s\n", l)
+		}
+	}
+	for _, err := range res.fileErrors() {
+		fmt.Fprintf(t.out, "  %s %s\n", t.paint(ansiRed, "✗"), t.paint(ansiRed, err.Error()))
+		t.hint(err, "    ")
+	}
+	for _, step := range res.steps {
+		if t.quiet && step.err == nil {
+			continue
+		}
+		symbol := t.paint(ansiGreen, "✓")
+		if step.err != nil {
+			symbol = t.paint(ansiRed, "✗")
+		}
+		fmt.Fprintf(t.out, "  %s %s %s\n", symbol, step.name, t.paint(ansiDim, "("+formatDuration(step.duration)+")"))
+		if !t.quiet {
+			for _, l := range step.logs {
+				fmt.Fprintf(t.out, "      %s\n", indentLines(l, "      "))
+			}
+		}
+		if step.err != nil {
+			fmt.Fprintf(t.out, "      %s\n", indentLines(t.failureText(step.failure()), "      "))
+			t.hint(step.err, "      ")
+		}
+	}
+}
+
+// failureText paints a step failure red, except for the lines of a value
+// diff, which are red for expected and green for actual values.
+func (t *textReporter) failureText(err error) string {
+	var expectErr *ExpectationError
+	if !t.color || !errors.As(err, &expectErr) || expectErr.Diff() == nil {
+		return t.paint(ansiRed, err.Error())
+	}
+	lines := strings.Split(err.Error(), "\n")
+	// The diff ends the message; anything wrapping it comes first.
+	start := len(lines) - len(expectErr.Diff())
+	for i, l := range lines {
+		switch {
+		case i < start || strings.HasPrefix(l, "-"):
+			lines[i] = t.paint(ansiRed, l)
+		case strings.HasPrefix(l, "+"):
+			lines[i] = t.paint(ansiGreen, l)
+		default:
+			lines[i] = t.paint(ansiDim, l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hint prints advice on fixing err beneath it, if any is known.
+func (t *textReporter) hint(err error, indent string) {
+	if hint := errorHint(err); hint != "" {
+		fmt.Fprintf(t.out, "%s%s\n", indent, t.paint(ansiYellow, "hint: "+hint))
+	}
+}
+
+func (t *textReporter) record(res fileResult) {
+	t.files++
+	if len(res.errs) > 0 {
+		t.failedFiles++
+	}
+	t.skippedSteps += res.skipped
+	for _, step := range res.steps {
+		if step.err == nil {
+			t.passed++
+		} else {
+			t.failed++
+		}
+		t.timings = append(t.timings, stepTiming{name: res.name + " > " + step.name, duration: step.duration})
+	}
+}
+
+func (t *textReporter) done() {
+	fmt.Fprintln(t.out)
+	fmt.Fprintln(t.out, t.paint(ansiBold, "Summary"))
+	fmt.Fprintf(t.out, "  Files:  %d run, %d failed\n", t.files, t.failedFiles)
+	fmt.Fprintf(t.out, "  Steps:  %d passed, %d failed, %d skipped\n", t.passed, t.failed, t.skippedSteps)
+	fmt.Fprintf(t.out, "  Time:   %s\n", formatDuration(time.Since(t.start)))
+
+	if len(t.timings) == 0 {
+		return
+	}
+	sort.SliceStable(t.timings, func(i, j int) bool {
+		return t.timings[i].duration > t.timings[j].duration
+	})
+	fmt.Fprintln(t.out, "  Slowest steps:")
+	for i, timing := range t.timings {
+		if i == slowestSteps {
+			break
+		}
+		fmt.Fprintf(t.out, "    %8s  %s\n", formatDuration(timing.duration), timing.name)
+	}
+}
+
+// formatDuration rounds to a precision that suits the magnitude, e.g. 812ms
+// or 1.24s.
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(time.Millisecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
+
+// indentLines indents every line after the first so multi-line messages stay
+// aligned under their step.
+func indentLines(s, indent string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+indent)
+}
+
+// tapReporter writes TAP version 13. Each step is a test point; log lines
+// become comments and failures carry a YAML diagnostic block.
+type tapReporter struct {
+	out   io.Writer
+	count int
+}
+
+func newTAPReporter(out io.Writer) *tapReporter {
+	fmt.Fprintln(out, "TAP version 13")
+	return &tapReporter{out: out}
+}
+
+func (t *tapReporter) file(res fileResult) {
+	fmt.Fprintf(t.out, "# %s (%s)\n", res.name, res.path)
+	t.comments(res.logs)
+	for _, err := range res.fileErrors() {
+		t.point(res.name, "", res.path, err)
+	}
+	for _, step := range res.steps {
+		t.comments(step.logs)
+		t.point(res.name, step.name, res.path, step.err)
+	}
+}
+
+func (t *tapReporter) comments(lines []string) {
+	for _, l := range lines {
+		fmt.Fprintf(t.out, "# %s\n", indentLines(l, "# "))
+	}
+}
+
+func (t *tapReporter) point(name, step, path string, err error) {
+	t.count++
+	desc := name
+	if step != "" {
+		desc += " > " + step
+	}
+	desc = strings.ReplaceAll(desc, "#", `\#`)
+	if err == nil {
+		fmt.Fprintf(t.out, "ok %d - %s\n", t.count, desc)
+		return
+	}
+	fmt.Fprintf(t.out, "not ok %d - %s\n", t.count, desc)
+	fmt.Fprintln(t.out, "  ---")
+	fmt.Fprintf(t.out, "  file: %q\n", path)
+	if step != "" {
+		fmt.Fprintf(t.out, "  step: %q\n", step)
+	}
+	se, _ := err.(*StepError)
+	if se != nil {
+		err = se.Err
+	}
+	fmt.Fprintf(t.out, "  message: %q\n", err.Error())
+	if code := errorCode(err); code != "" {
+		fmt.Fprintf(t.out, "  code: %s\n", code)
+	}
+	if hint := errorHint(err); hint != "" {
+		fmt.Fprintf(t.out, "  hint: %q\n", hint)
+	}
+	if se != nil {
+		if se.Request != "" {
+			fmt.Fprintf(t.out, "  request: %q\n", se.Request)
+		}
+		if se.Status != 0 {
+			fmt.Fprintf(t.out, "  status: %d\n", se.Status)
+		}
+		if se.Expected != "" || se.Actual != "" {
+			fmt.Fprintf(t.out, "  expected: %q\n", se.Expected)
+			fmt.Fprintf(t.out, "  actual: %q\n", se.Actual)
+		}
+	}
+	fmt.Fprintln(t.out, "  ...")
+}
+
+func (t *tapReporter) done() {
+	fmt.Fprintf(t.out, "1..%d\n", t.count)
+}
+
+// logEvent is a single line of --log-format json output.
+type logEvent struct {
+	Level      string   `json:"level"`
+	File       string   `json:"file"`
+	Workflow   string   `json:"workflow,omitempty"`
+	Step       string   `json:"step,omitempty"`
+	Message    string   `json:"message"`
+	Error      string   `json:"error,omitempty"`
+	Code       string   `json:"code,omitempty"`
+	Hint       string   `json:"hint,omitempty"`
+	DurationMS *float64 `json:"duration_ms,omitempty"`
+	// Failure details a failed step's request, response and mismatch.
+	Failure *StepError `json:"failure,omitempty"`
+}
+
+// jsonReporter writes each log line and step result as a JSON object so
+// runs can be shipped to a log aggregator and queried by file or step. In
+// quiet mode only error events are written.
+type jsonReporter struct {
+	enc   *json.Encoder
+	quiet bool
+}
+
+func (j *jsonReporter) emit(ev logEvent) {
+	if j.quiet && ev.Level != "error" {
+		return
+	}
+	j.enc.Encode(ev)
+}
+
+func (j *jsonReporter) file(res fileResult) {
+	base := logEvent{Level: "info", File: res.path, Workflow: res.name}
+	for _, l := range res.logs {
+		ev := base
+		ev.Message = l
+		j.emit(ev)
+	}
+	for _, err := range res.fileErrors() {
+		ev := base
+		ev.Level = "error"
+		ev.Message = "file failed"
+		ev.Error = err.Error()
+		ev.Code = errorCode(err)
+		ev.Hint = errorHint(err)
+		j.emit(ev)
+	}
+	for _, step := range res.steps {
+		for _, l := range step.logs {
+			ev := base
+			ev.Step = step.name
+			ev.Message = l
+			j.emit(ev)
+		}
+		ev := base
+		ev.Step = step.name
+		ev.Message = "step passed"
+		ms := float64(step.duration) / float64(time.Millisecond)
+		ev.DurationMS = &ms
+		if step.err != nil {
+			ev.Level = "error"
+			ev.Message = "step failed"
+			ev.Error = step.failure().Error()
+			ev.Code = errorCode(step.err)
+			ev.Hint = errorHint(step.err)
+			ev.Failure, _ = step.err.(*StepError)
+		}
+		j.emit(ev)
+	}
+}
+
+func (j *jsonReporter) done() {}
+
+// errorHint returns advice on fixing err, or "" if none is known.
+func errorHint(err error) string {
+	return e.Hint(err)
+}
+
+// errorCode returns the stable code of err's kind, or "" if it has none.
+func errorCode(err error) string {
+	if kind := e.KindOf(err); kind != nil {
+		return kind.Code()
+	}
+	return ""
+}
@@ -0,0 +1,247 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+func TestTAPReport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	workflow := fmt.Sprintf(`
+metadata:
+  name: "TAP"
+config:
+  base_url: "%s"
+workflow:
+- step: "found"
+  request:
+    url: "/ok"
+  expect:
+    status: 200
+- step: "missing"
+  request:
+    url: "/missing"
+  expect:
+    status: 200
+`, srv.URL)
+	if err := os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	r := New(5*time.Second, false, WithReport(ReportTAP))
+	r.out = &out
+	if err := r.RunPaths([]string{dir}); err == nil {
+		t.Fatal("expected failing step to fail the run")
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"TAP version 13\n",
+		"# TAP (",
+		"ok 1 - TAP > found\n",
+		"not ok 2 - TAP > missing\n  ---\n",
+		`  step: "missing"`,
+		"expected status 200, got 404",
+		"  ...\n1..2\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("TAP output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestTAPReportFileError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.yaml"), []byte("workflow: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	r := New(5*time.Second, false, WithReport(ReportTAP))
+	r.out = &out
+	if err := r.RunPaths([]string{dir}); err == nil {
+		t.Fatal("expected parse error")
+	}
+	if got := out.String(); !strings.Contains(got, "not ok 1 - bad.yaml\n") || !strings.HasSuffix(got, "1..1\n") {
+		t.Errorf("unexpected TAP output:\n%s", got)
+	}
+}
+
+func TestTextReport(t *testing.T) {
+	res := fileResult{
+		path: "flows/users.yaml",
+		name: "Users",
+		steps: []stepResult{
+			{name: "create", logs: []string{"Received status: 201 (HTTP/1.1)"}, duration: 41 * time.Millisecond},
+			{name: "fetch", err: &StepError{Step: "fetch", Err: fmt.Errorf("expected status 200, got 404")}, duration: 1240 * time.Millisecond},
+		},
+	}
+
+	var plain bytes.Buffer
+	rep := &textReporter{out: &plain}
+	rep.file(res)
+	want := "Users (flows/users.yaml)\n" +
+		"  ✓ create (41ms)\n" +
+		"      Received status: 201 (HTTP/1.1)\n" +
+		"  ✗ fetch (1.24s)\n" +
+		"      expected status 200, got 404\n"
+	if plain.String() != want {
+		t.Errorf("unexpected text output:\n%s\nwant:\n%s", plain.String(), want)
+	}
+
+	var colored bytes.Buffer
+	rep = &textReporter{out: &colored, color: true}
+	rep.file(res)
+	if !strings.Contains(colored.String(), ansiGreen+"✓"+ansiReset) || !strings.Contains(colored.String(), ansiRed+"✗"+ansiReset) {
+		t.Errorf("expected colored symbols, got %q", colored.String())
+	}
+}
+
+func TestTextReportHint(t *testing.T) {
+	res := fileResult{
+		path: "flows/login.yaml",
+		name: "Login",
+		steps: []stepResult{
+			{name: "profile", err: &StepError{Step: "profile", Err: e.Wrap(errors.New("invalid character '<' looking for beginning of value"), "parse response json")}},
+		},
+	}
+	var out bytes.Buffer
+	(&textReporter{out: &out}).file(res)
+	want := "Login (flows/login.yaml)\n" +
+		"  ✗ profile (0s)\n" +
+		"      parse response json: invalid character '<' looking for beginning of value\n" +
+		"      hint: The response is HTML, not JSON. Did the request hit a login page, an error page or the wrong URL?\n"
+	if out.String() != want {
+		t.Errorf("unexpected text output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestTextReportQuiet(t *testing.T) {
+	passing := fileResult{path: "ok.yaml", name: "OK", steps: []stepResult{{name: "fine", logs: []string{"printed"}}}}
+	fetchErr := &StepError{Step: "fetch", Err: fmt.Errorf("expected status 200, got 500")}
+	failing := fileResult{
+		path: "bad.yaml",
+		name: "Bad",
+		steps: []stepResult{
+			{name: "fine", logs: []string{"printed"}},
+			{name: "fetch", logs: []string{"Received status: 500 (HTTP/1.1)"}, err: fetchErr},
+		},
+		errs: []error{fetchErr},
+	}
+
+	var out bytes.Buffer
+	rep := &textReporter{out: &out, quiet: true}
+	rep.file(passing)
+	rep.file(failing)
+	want := "Bad (bad.yaml)\n" +
+		"  ✗ fetch (0s)\n" +
+		"      expected status 200, got 500\n"
+	if out.String() != want {
+		t.Errorf("unexpected quiet output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestJSONLogs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "logs.yaml")
+	workflow := fmt.Sprintf(`
+metadata:
+  name: "JSON Logs"
+config:
+  base_url: "%s"
+workflow:
+- step: "good"
+  request:
+    url: "/ok"
+  output:
+    print: "all good"
+- step: "bad"
+  request:
+    url: "/bad"
+  expect:
+    status: 200
+`, srv.URL)
+	if err := os.WriteFile(path, []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	r := New(5*time.Second, false, WithJSONLogs(true))
+	r.out = &out
+	if err := r.RunPaths([]string{path}); err == nil {
+		t.Fatal("expected failing step")
+	}
+
+	var events []logEvent
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var ev logEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("invalid JSON log line %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d: %s", len(events), out.String())
+	}
+	if ev := events[0]; ev.Step != "good" || ev.Message != "all good" || ev.File != path || ev.Workflow != "JSON Logs" {
+		t.Errorf("unexpected log event: %+v", ev)
+	}
+	if ev := events[1]; ev.Level != "info" || ev.Message != "step passed" || ev.DurationMS == nil {
+		t.Errorf("unexpected pass event: %+v", ev)
+	}
+	if ev := events[2]; ev.Level != "error" || ev.Step != "bad" || ev.Error != "expected status 200, got 500" {
+		t.Errorf("unexpected failure event: %+v", ev)
+	}
+}
+
+func TestTextReportSummary(t *testing.T) {
+	var out bytes.Buffer
+	rep := &textReporter{out: &out, start: time.Now()}
+	rep.file(fileResult{name: "A", steps: []stepResult{
+		{name: "fast", duration: 3 * time.Millisecond},
+		{name: "slow", duration: 900 * time.Millisecond, err: &StepError{Err: fmt.Errorf("boom")}},
+	}, errs: []error{&StepError{Err: fmt.Errorf("boom")}}})
+	rep.file(fileResult{name: "B", skipped: 4, errs: []error{fmt.Errorf("oauth2 token")}})
+	rep.file(fileResult{name: "C", steps: []stepResult{{name: "mid", duration: 50 * time.Millisecond}}})
+	out.Reset()
+	rep.done()
+
+	got := out.String()
+	for _, want := range []string{
+		"Summary\n",
+		"  Files:  3 run, 2 failed\n",
+		"  Steps:  2 passed, 1 failed, 4 skipped\n",
+		"  Slowest steps:\n       900ms  A > slow\n        50ms  C > mid\n         3ms  A > fast\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"errors"
+	"time"
+)
+
+// RunResult is what Run did: every workflow file in the order it was named,
+// with the outcome of each step. Passed, Failed and Skipped count steps
+// across all files.
+type RunResult struct {
+	Start    time.Time
+	Duration time.Duration
+	Files    []*FileResult
+	Passed   int
+	Failed   int
+	Skipped  int
+}
+
+// FileResult is the outcome of one workflow file. Err is why the file
+// failed outside of its steps, such as a parse error, or nil. Skipped
+// counts steps that never ran because of it or because the run was
+// cancelled. Vars holds the file's variables as they were when it ended,
+// including captured values.
+type FileResult struct {
+	Path     string
+	Name     string
+	Duration time.Duration
+	Steps    []*StepResult
+	Skipped  int
+	Err      error
+	Vars     map[string]string
+}
+
+// StepResult is the outcome of one step. Request is the method and URL
+// that were sent and Status the response status, if a response arrived.
+// Err is nil if the step passed, otherwise a *StepError.
+type StepResult struct {
+	Name        string
+	Description string
+	Duration    time.Duration
+	Request     string
+	Status      int
+	Logs        []string
+	Err         error
+}
+
+// Passed reports whether the step passed.
+func (s *StepResult) Passed() bool {
+	return s.Err == nil
+}
+
+// Failed reports whether the file failed or any of its steps did.
+func (f *FileResult) Failed() bool {
+	if f.Err != nil {
+		return true
+	}
+	for _, step := range f.Steps {
+		if step.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func newRunResult(results []fileResult, start time.Time) *RunResult {
+	run := &RunResult{Start: start, Duration: time.Since(start)}
+	for _, res := range results {
+		file := &FileResult{
+			Path:     res.path,
+			Name:     res.name,
+			Duration: res.duration,
+			Skipped:  res.skipped,
+			Err:      errors.Join(res.fileErrors()...),
+			Vars:     res.vars,
+		}
+		for _, sr := range res.steps {
+			file.Steps = append(file.Steps, &StepResult{
+				Name:        sr.name,
+				Description: sr.description,
+				Duration:    sr.duration,
+				Request:     sr.request,
+				Status:      sr.status,
+				Logs:        sr.logs,
+				Err:         sr.err,
+			})
+			if sr.err != nil {
+				run.Failed++
+			} else {
+				run.Passed++
+			}
+		}
+		run.Skipped += res.skipped
+		run.Files = append(run.Files, file)
+	}
+	return run
+}
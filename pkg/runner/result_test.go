@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunResult(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"id": 42}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "a.yaml")
+	workflow := fmt.Sprintf(`
+metadata:
+  name: "Users"
+config:
+  base_url: "%s"
+workflow:
+- step: "create"
+  request:
+    method: "POST"
+    url: "/users"
+  expect:
+    status: 200
+  capture:
+  - json_path: "id"
+    as: "user_id"
+- step: "fetch"
+  description: "Fetch the new user"
+  request:
+    method: "GET"
+    url: "/missing"
+  expect:
+    status: 200
+`, srv.URL)
+	bad := filepath.Join(dir, "b.yaml")
+	for path, content := range map[string]string{good: workflow, bad: "workflow: [\n"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := New(5*time.Second, false, WithOutput(io.Discard))
+	result, err := r.Run(context.Background(), []string{good, bad})
+	if err == nil {
+		t.Fatal("expected the run to fail")
+	}
+	if result == nil || len(result.Files) != 2 {
+		t.Fatalf("expected two files, got %+v", result)
+	}
+	if result.Passed != 1 || result.Failed != 1 {
+		t.Errorf("expected 1 passed and 1 failed step, got %d and %d", result.Passed, result.Failed)
+	}
+
+	users := result.Files[0]
+	if users.Name != "Users" || users.Path != good || !users.Failed() || users.Err != nil {
+		t.Errorf("unexpected file result %+v", users)
+	}
+	if users.Vars["user_id"] != "42" {
+		t.Errorf("expected captured user_id 42, got %q", users.Vars["user_id"])
+	}
+	if len(users.Steps) != 2 {
+		t.Fatalf("expected two steps, got %d", len(users.Steps))
+	}
+	create, fetch := users.Steps[0], users.Steps[1]
+	if !create.Passed() || create.Request != "POST "+srv.URL+"/users" || create.Status != 200 {
+		t.Errorf("unexpected create result %+v", create)
+	}
+	if fetch.Passed() || fetch.Description != "Fetch the new user" || fetch.Status != 404 {
+		t.Errorf("unexpected fetch result %+v", fetch)
+	}
+
+	if parse := result.Files[1]; parse.Err == nil || len(parse.Steps) != 0 {
+		t.Errorf("expected a parse failure for %s, got %+v", bad, parse)
+	}
+}
@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retry resends requests that fail with a network error, 429 Too Many
+// Requests or a 5xx status. The delay doubles after each attempt, with
+// jitter, unless the response has a Retry-After header.
+type Retry struct {
+	// Attempts is the total number of tries, including the first.
+	Attempts int `yaml:"attempts"`
+	// Backoff is the delay before the first retry. Defaults to 100ms.
+	Backoff time.Duration `yaml:"backoff,omitempty"`
+	// MaxBackoff caps the delay between retries. Defaults to 10s.
+	MaxBackoff time.Duration `yaml:"max_backoff,omitempty"`
+}
+
+// WithRetry retries failed requests in files that don't set config.retry.
+func WithRetry(retry Retry) Option {
+	return func(r *Runner) {
+		r.retry = &retry
+	}
+}
+
+// fileRetry returns the retry policy for a file, or nil if requests are only
+// sent once.
+func (r *Runner) fileRetry(cfg Config) *Retry {
+	retry := cfg.Retry
+	if retry == nil {
+		retry = r.retry
+	}
+	if retry == nil || retry.Attempts <= 1 {
+		return nil
+	}
+	return retry
+}
+
+// retryTransport resends failed requests. It sits outside the rate limiter
+// and HAR recorder, so every attempt is limited and recorded.
+type retryTransport struct {
+	base  http.RoundTripper
+	retry Retry
+}
+
+func retryClient(c *http.Client, retry *Retry) *http.Client {
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	retried := *c
+	retried.Transport = &retryTransport{base: base, retry: *retry}
+	return &retried
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.retry.Attempts || !retryable(resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+		// A body can only be sent again if the request knows how to
+		// rewind it.
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		delay := t.retry.delay(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable reports whether a request that got resp or err is worth
+// sending again.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// delay returns how long to wait after the given attempt. A Retry-After
+// header is honoured as is; otherwise the backoff doubles each attempt, up to
+// MaxBackoff, and a random half of it is dropped so concurrent clients don't
+// retry in step.
+func (r Retry) delay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return d
+		}
+	}
+	backoff, max := r.Backoff, r.MaxBackoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+	d := backoff
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		d := time.Until(at)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleep waits for d, returning early with ctx's error if it is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
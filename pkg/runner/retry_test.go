@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{in: "3", want: 3 * time.Second, ok: true},
+		{in: "0", want: 0, ok: true},
+		{in: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0, ok: true},
+		{in: "", ok: false},
+		{in: "soon", ok: false},
+		{in: "-1", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("retryAfter(%q) = %s, %v", tt.in, got, ok)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	retry := Retry{Attempts: 10, Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for attempt, want := range map[int]time.Duration{1: 100, 2: 200, 3: 300, 6: 300} {
+		want *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if d := retry.delay(attempt, nil); d < want/2 || d > want {
+				t.Errorf("delay after attempt %d = %s, want between %s and %s", attempt, d, want/2, want)
+			}
+		}
+	}
+	resp := &http.Response{Header: http.Header{"Retry-After": {"2"}}}
+	if d := retry.delay(1, resp); d != 2*time.Second {
+		t.Errorf("delay with Retry-After = %s", d)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	failures := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(body))
+		if len(bodies) <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer api.Close()
+
+	workflow := func(retry string) string {
+		path := filepath.Join(t.TempDir(), "create.yaml")
+		os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+%s
+workflow:
+- step: "create"
+  request:
+    method: POST
+    url: "/widgets"
+    body:
+      name: "gear"
+  expect:
+    status: 201
+`, api.URL, retry)), 0644)
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		retry    string
+		opts     []Option
+		failures int
+		requests int
+		wantErr  bool
+	}{
+		{"no retry", "", nil, 1, 1, true},
+		{"config", "  retry:\n    attempts: 3\n    backoff: 1ms", nil, 2, 3, false},
+		{"attempts exhausted", "  retry:\n    attempts: 2\n    backoff: 1ms", nil, 5, 2, true},
+		{"option", "", []Option{WithRetry(Retry{Attempts: 2, Backoff: time.Millisecond})}, 1, 2, false},
+		{"config overrides option", "  retry:\n    attempts: 1", []Option{WithRetry(Retry{Attempts: 3})}, 1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodies, failures = nil, tt.failures
+			r := New(5*time.Second, false, tt.opts...)
+			r.out = io.Discard
+			err := r.RunPaths([]string{workflow(tt.retry)})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunPaths() error = %v", err)
+			}
+			if len(bodies) != tt.requests {
+				t.Fatalf("got %d requests, want %d", len(bodies), tt.requests)
+			}
+			for _, body := range bodies {
+				if body != `{"name":"gear"}` {
+					t.Errorf("retried request body = %q", body)
+				}
+			}
+		})
+	}
+}
@@ -2,10 +2,14 @@ package runner
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -16,6 +20,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/michaelmccabe/ramjam/pkg/config"
 	e "github.com/michaelmccabe/ramjam/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
@@ -23,45 +29,101 @@ import (
 type (
 	InstructionsFile struct {
 		Metadata struct {
-			Name        string `yaml:"name"`
-			Author      string `yaml:"author"`
-			Description string `yaml:"description"`
+			Name        string   `yaml:"name"`
+			Author      string   `yaml:"author"`
+			Description string   `yaml:"description"`
+			Tags        []string `yaml:"tags,omitempty"`
 		} `yaml:"metadata"`
-		Config struct {
-			BaseURL string `yaml:"base_url"`
-		} `yaml:"config"`
-		Workflow []Step `yaml:"workflow"`
+		Config Config `yaml:"config"`
+		// Vars are set before the first step, above the directory's
+		// defaults. Values may refer to variables from the defaults.
+		Vars     map[string]string `yaml:"vars,omitempty"`
+		Workflow []Step            `yaml:"workflow"`
+		vars     map[string]string // variables from the directory's defaults
+	}
+
+	Config struct {
+		BaseURL     string            `yaml:"base_url"`
+		MaxBodySize ByteSize          `yaml:"max_body_size,omitempty"`
+		HTTPVersion string            `yaml:"http_version,omitempty"`
+		TLS         TLSConfig         `yaml:"tls,omitempty"`
+		Proxy       string            `yaml:"proxy,omitempty"`
+		UserAgent   string            `yaml:"user_agent,omitempty"`
+		Headers     map[string]string `yaml:"headers,omitempty"`
+		Auth        *Auth             `yaml:"auth,omitempty"`
+		OpenAPI     string            `yaml:"openapi,omitempty"`
+		RateLimit   RateLimit         `yaml:"rate_limit,omitempty"`
+		Retry       *Retry            `yaml:"retry,omitempty"`
+		Thresholds  []Threshold       `yaml:"thresholds,omitempty"`
+		// ImportsVars lists workflow files, relative to this one, that must
+		// finish before it starts so it can use their global captures.
+		ImportsVars []string `yaml:"imports_vars,omitempty"`
 	}
 
 	Step struct {
-		Step        string      `yaml:"step"`
-		Description string      `yaml:"description"`
-		Request     StepRequest `yaml:"request"`
-		Expect      StepExpect  `yaml:"expect"`
-		Capture     []Capture   `yaml:"capture"`
-		Output      Output      `yaml:"output"`
+		Step        string         `yaml:"step"`
+		Description string         `yaml:"description"`
+		Request     StepRequest    `yaml:"request"`
+		Expect      StepExpect     `yaml:"expect"`
+		Capture     []Capture      `yaml:"capture"`
+		Output      Output         `yaml:"output"`
+		Poll        *Poll          `yaml:"poll,omitempty"`
+		WebSocket   *WebSocketStep `yaml:"websocket,omitempty"`
+		// Needs lists earlier steps that also run when this one is picked
+		// with WithSteps, such as the login it depends on.
+		Needs []string `yaml:"needs,omitempty"`
+		// Vars are set for this step only, over every other variable.
+		Vars map[string]string `yaml:"vars,omitempty"`
+		// When is an expression such as page < total_pages. If it is
+		// false, the step passes without sending its request.
+		When string       `yaml:"when,omitempty"`
+		file *fileContext // settings shared by every step in the file
 	}
 
 	StepRequest struct {
-		Method     string                 `yaml:"method"`
-		URL        string                 `yaml:"url"`
-		Headers    map[string]string      `yaml:"headers"`
-		Body       map[string]interface{} `yaml:"body,omitempty"`
-		BodyFile   string                 `yaml:"body_file,omitempty"`
-		Params     map[string]string      `yaml:"params"`
-		bodyData   map[string]interface{} // resolved body data
-		bodySource string                 // tracks source for debugging
+		Method          string                 `yaml:"method"`
+		URL             string                 `yaml:"url"`
+		Headers         map[string]string      `yaml:"headers"`
+		Body            map[string]interface{} `yaml:"body,omitempty"`
+		BodyFile        string                 `yaml:"body_file,omitempty"`
+		BodyRaw         string                 `yaml:"body_raw,omitempty"`
+		ContentType     string                 `yaml:"content_type,omitempty"`
+		CompressBody    bool                   `yaml:"compress_body,omitempty"`
+		FollowRedirects *bool                  `yaml:"follow_redirects,omitempty"`
+		Auth            *Auth                  `yaml:"auth,omitempty"`
+		Params          map[string]string      `yaml:"params"`
+		Form            map[string]string      `yaml:"form,omitempty"`
+		GraphQL         *GraphQLRequest        `yaml:"graphql,omitempty"`
+		Multipart       []MultipartPart        `yaml:"multipart,omitempty"`
+		bodyData        map[string]interface{} // resolved body data
+		bodySource      string                 // tracks source for debugging
 	}
 
 	StepExpect struct {
-		Status        int                 `yaml:"status"`
-		JSONPathMatch []JSONPathVal       `yaml:"json_path_match"`
-		Headers       []HeaderExpectation `yaml:"headers"`
+		Status           int                 `yaml:"status"`
+		JSONPathMatch    []JSONPathVal       `yaml:"json_path_match"`
+		Headers          []HeaderExpectation `yaml:"headers"`
+		SSE              *SSEExpect          `yaml:"sse,omitempty"`
+		SHA256           string              `yaml:"sha256,omitempty"`
+		ContentLength    *int64              `yaml:"content_length,omitempty"`
+		BodyFormat       string              `yaml:"body_format,omitempty"`
+		BodyContains     string              `yaml:"body_contains,omitempty"`
+		BodyRegex        string              `yaml:"body_regex,omitempty"`
+		ContentEncoding  string              `yaml:"content_encoding,omitempty"`
+		Proto            string              `yaml:"proto,omitempty"`
+		RedirectLocation string              `yaml:"redirect_location,omitempty"`
+		MaxDuration      time.Duration       `yaml:"max_duration,omitempty"`
+		// Custom maps names given to Runner.RegisterAssertion to their
+		// arguments.
+		Custom map[string]interface{} `yaml:"custom,omitempty"`
 	}
 
 	JSONPathVal struct {
 		Path  string      `yaml:"path"`
 		Value interface{} `yaml:"value"`
+		// Aggregate compares a count, sum, minimum or maximum instead
+		// of the value at path.
+		Aggregate `yaml:",inline"`
 	}
 
 	HeaderExpectation struct {
@@ -75,10 +137,21 @@ type (
 		Header   string `yaml:"header,omitempty"`
 		Regex    string `yaml:"regex,omitempty"`
 		As       string `yaml:"as"`
+		// Scope is file (the default) or global, which also makes the
+		// variable available to files that run later in the same run.
+		Scope string `yaml:"scope,omitempty"`
+		// Mode is first (the default) or all, which stores every match
+		// as a JSON array.
+		Mode string `yaml:"mode,omitempty"`
+		// Aggregate captures a count, sum, minimum or maximum instead of
+		// the value at json_path.
+		Aggregate `yaml:",inline"`
 	}
 
 	Output struct {
-		Print string `yaml:"print"`
+		Print    string `yaml:"print"`
+		SaveBody string `yaml:"save_body,omitempty"`
+		Curl     bool   `yaml:"curl,omitempty"`
 	}
 
 	StepError struct {
@@ -86,6 +159,18 @@ type (
 		Step        string
 		Description string
 		Err         error
+		// Request summarises the request that failed, such as
+		// "GET https://api.example.com/users/1".
+		Request string
+		// Status is the response status code, or 0 if no response arrived.
+		Status int
+		// Body is the start of the response body.
+		Body string
+		// Expected and Actual are set when the step failed a comparison,
+		// such as an expected status or JSONPath value.
+		Expected string
+		Actual   string
+		Duration time.Duration
 	}
 )
 
@@ -98,75 +183,334 @@ func (e *StepError) Unwrap() error {
 }
 
 type Runner struct {
-	client  *http.Client
-	verbose bool
+	client     *http.Client
+	verbosity  Verbosity
+	tls        TLSConfig
+	proxy      string
+	tokens     *tokenCache
+	report     string
+	logJSON    bool
+	color      bool
+	out        io.Writer
+	warnings   io.Writer
+	progress   io.Writer
+	har        *harRecorder
+	printCurl  bool
+	dryRun     bool
+	vars       map[string]string
+	responses  *responseLog
+	notify     *Notification
+	metrics    *Metrics
+	tracing    *Tracing
+	tracer     *tracer
+	clients    *sync.Map // per-file clients, reused across load iterations
+	rateLimit  *rateLimiter
+	limiters   *sync.Map // config.rate_limit limiters by file path
+	retry      *Retry
+	middleware []Middleware
+	transport  TransportOptions
+	userAgent  string
+	headers    map[string]string
+	profile    *Profile
+	lenient    bool
+	strictVars bool
+	recursive  bool
+	sequential bool
+	globals    *globalVars // variables captured with scope: global in this run
+	steps      []string    // names of the only steps to run, from WithSteps
+	fromStep   string      // step to start each file at, from WithFromStep
+	stateFile  string      // checkpoint file, from WithCheckpoint
+	resume     bool        // continue from the checkpoint file
+	checkpoint *checkpoint // progress of the current run, if checkpointing
+	events     []Events
+	logger     *slog.Logger // from WithLogger
+	assertions map[string]AssertionFunc
+	funcs      map[string]Func // functions from RegisterFunc
 }
 
-func New(timeout time.Duration, verbose bool) *Runner {
-	return &Runner{
-		client:  &http.Client{Timeout: timeout},
-		verbose: verbose,
+// Option configures optional Runner behaviour.
+type Option func(*Runner)
+
+// WithTLS sets default TLS settings, used for any field a workflow file's
+// config.tls leaves empty.
+func WithTLS(cfg TLSConfig) Option {
+	return func(r *Runner) {
+		r.tls = cfg
 	}
 }
 
-func (r *Runner) RunPaths(paths []string) error {
-	if len(paths) == 0 {
-		return fmt.Errorf("no paths provided")
+// WithProxy routes requests through the given proxy URL unless a workflow
+// file sets its own config.proxy.
+func WithProxy(proxyURL string) Option {
+	return func(r *Runner) {
+		r.proxy = proxyURL
 	}
+}
 
-	var files []string
-	for _, p := range paths {
-		fs, err := r.collectFiles(p)
+// WithVars sets variables before each file runs. They override values from
+// the workflow file, including config.base_url.
+func WithVars(vars map[string]string) Option {
+	return func(r *Runner) {
+		r.vars = vars
+	}
+}
+
+// WithLenient ignores unknown fields in workflow files instead of failing
+// them.
+func WithLenient(lenient bool) Option {
+	return func(r *Runner) {
+		r.lenient = lenient
+	}
+}
+
+// WithRecursive makes directories expand to the workflow files in their
+// subdirectories too, so a suite laid out as tests/auth/ and tests/billing/
+// runs from tests/. Directories whose names start with a dot are skipped.
+func WithRecursive(recursive bool) Option {
+	return func(r *Runner) {
+		r.recursive = recursive
+	}
+}
+
+// WithSequential runs files one after another, in the order Files returns
+// them, instead of in parallel.
+func WithSequential(sequential bool) Option {
+	return func(r *Runner) {
+		r.sequential = sequential
+	}
+}
+
+// WithUserAgent sets the User-Agent header for files that don't set
+// config.user_agent. Defaults to ramjam-cli.
+func WithUserAgent(userAgent string) Option {
+	return func(r *Runner) {
+		r.userAgent = userAgent
+	}
+}
+
+// WithHeaders adds headers to every request, including WebSocket handshakes.
+// A file's config.headers and a step's headers override them.
+func WithHeaders(headers map[string]string) Option {
+	return func(r *Runner) {
+		r.headers = headers
+	}
+}
+
+const defaultUserAgent = "ramjam-cli"
+
+// fileUserAgent returns the User-Agent header for requests in a file.
+func (r *Runner) fileUserAgent(cfg Config, vars map[string]string) (string, error) {
+	switch {
+	case cfg.UserAgent != "":
+		return r.applyVars(cfg.UserAgent, vars)
+	case r.userAgent != "":
+		return r.userAgent, nil
+	}
+	return defaultUserAgent, nil
+}
+
+// setHeaders sets headers on h, with vars substituted into their values.
+func (r *Runner) setHeaders(h http.Header, headers map[string]string, vars map[string]string) error {
+	for k, v := range headers {
+		value, err := r.applyVars(v, vars)
 		if err != nil {
 			return err
 		}
-		files = append(files, fs...)
+		h.Set(k, value)
 	}
+	return nil
+}
 
-	if len(files) == 0 {
-		return fmt.Errorf("no files found")
+func New(timeout time.Duration, verbose bool, opts ...Option) *Runner {
+	r := &Runner{
+		client:   &http.Client{Timeout: timeout},
+		tokens:   newTokenCache(),
+		out:      os.Stdout,
+		warnings: os.Stderr,
+		limiters: &sync.Map{},
+	}
+	if verbose {
+		r.verbosity = VerbosityVerbose
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	if r.transport != (TransportOptions{}) && r.ownTransport() {
+		r.client.Transport = r.newTransport()
+	}
+	return r
+}
 
-	var wg sync.WaitGroup
-	type result struct {
-		logs []string
-		errs []error
+// RunPaths runs the workflow files named by paths, in parallel, and reports
+// the results.
+func (r *Runner) RunPaths(paths []string) error {
+	return r.RunPathsContext(context.Background(), paths)
+}
+
+// RunPathsContext is RunPaths with a context. Cancelling ctx aborts requests
+// in flight and skips the steps that haven't started; the files are still
+// reported, and ctx's error is returned with any failures.
+func (r *Runner) RunPathsContext(ctx context.Context, paths []string) error {
+	_, err := r.Run(ctx, paths)
+	return err
+}
+
+// Run is RunPathsContext that also returns what happened to each file and
+// step. The result is nil only if no files could be found; otherwise it
+// covers every file, whether or not the returned error is nil.
+func (r *Runner) Run(ctx context.Context, paths []string) (*RunResult, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no paths provided")
+	}
+
+	files, err := r.Files(paths)
+	if err != nil {
+		return nil, err
+	}
+	return r.run(ctx, files, (*Runner).runFile)
+}
+
+// RunWorkflow runs a workflow built in code, such as with NewWorkflow, and
+// reports it like a file named after its metadata name. Relative body_file
+// paths are resolved against the working directory.
+func (r *Runner) RunWorkflow(ctx context.Context, wf *InstructionsFile) (*RunResult, error) {
+	if wf == nil {
+		return nil, fmt.Errorf("no workflow provided")
+	}
+	name := wf.Metadata.Name
+	if name == "" {
+		name = "workflow"
 	}
-	results := make(chan result, len(files))
+	return r.run(ctx, []string{name}, func(run *Runner, ctx context.Context, path string) fileResult {
+		return run.runSpec(ctx, path, func(string) (InstructionsFile, error) {
+			spec := *wf
+			if run.profile != nil {
+				spec.Config = run.profile.apply(spec.Config)
+			}
+			return spec, nil
+		})
+	})
+}
 
-	for _, f := range files {
+// run runs each of files in parallel, or in order if the runner is
+// sequential, with runFile and reports the results.
+func (r *Runner) run(ctx context.Context, files []string, runFile func(*Runner, context.Context, string) fileResult) (*RunResult, error) {
+	start := time.Now()
+	if r.tracing != nil {
+		r.tracer = &tracer{}
+	}
+	globals := newGlobalVars(files, r.sequential)
+	var cp *checkpoint
+	if r.stateFile != "" {
+		var err error
+		if cp, err = loadCheckpoint(r.stateFile, r.resume, globals); err != nil {
+			return nil, err
+		}
+		files = cp.pending(files)
+	}
+	// The report is the first handler, so it has been written by the time
+	// the caller's handlers see a file.
+	report := &reportEvents{report: r.newReporter(), progress: newProgressLine(r.progress, len(files))}
+	run := *r
+	run.events = append([]Events{report}, r.events...)
+	run.globals = globals
+	if cp != nil {
+		run.checkpoint = cp
+		run.events = append(run.events, cp)
+	}
+	completed := make([]fileResult, len(files))
+	var wg sync.WaitGroup
+	for i, f := range files {
+		if r.sequential {
+			completed[i] = runFile(&run, ctx, f)
+			continue
+		}
 		wg.Add(1)
-		go func(f string) {
+		go func(i int, f string) {
 			defer wg.Done()
-			logs, errs := r.runFile(f)
-			results <- result{logs: logs, errs: errs}
-		}(f)
+			completed[i] = runFile(&run, ctx, f)
+		}(i, f)
 	}
-
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+	wg.Wait()
+	report.done()
 
 	var errs []error
-	for res := range results {
-		for _, l := range res.logs {
-			fmt.Println(l)
+	for _, res := range completed {
+		errs = append(errs, res.errs...)
+	}
+	if cp != nil {
+		if err := cp.finish(len(errs) == 0 && ctx.Err() == nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// A report that can't be delivered is a problem with the
+	// notification channel, not with the API under test.
+	if r.notify != nil {
+		if err := r.sendNotification(completed, time.Since(start)); err != nil {
+			r.warn(err)
 		}
-		if len(res.errs) > 0 {
-			errs = append(errs, res.errs...)
+	}
+	if r.metrics != nil {
+		if err := r.publishMetrics(completed, time.Since(start)); err != nil {
+			r.warn(err)
+		}
+	}
+	if r.tracing != nil {
+		if err := r.exportTraces(r.tracer); err != nil {
+			r.warn(err)
 		}
 	}
 
+	if r.har != nil {
+		if err := r.har.write(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	result := newRunResult(completed, start)
 	if len(errs) == 0 {
-		return nil
+		return result, nil
 	}
 
-	return errors.Join(errs...)
+	return result, errors.Join(errs...)
+}
+
+// Files returns the workflow files named by paths, expanding directories to
+// the YAML files they contain and glob patterns such as tests/**/smoke-*.yaml
+// to the workflow files they match.
+func (r *Runner) Files(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		fs, err := r.collectFiles(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, fs...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found")
+	}
+	return files, nil
 }
 
 func (r *Runner) collectFiles(path string) ([]string, error) {
+	if config.IsURL(path) {
+		return []string{path}, nil
+	}
 	info, err := os.Stat(path)
+	if err != nil && isGlob(path) {
+		files, err := globFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		base, _ := doublestar.SplitPattern(filepath.ToSlash(path))
+		r.orderFiles(filepath.FromSlash(base), files)
+		return files, nil
+	}
 	if err := e.Wrapf(err, "unable to access %s", path); err != nil {
 		return nil, err
 	}
@@ -174,77 +518,450 @@ func (r *Runner) collectFiles(path string) ([]string, error) {
 		return []string{path}, nil
 	}
 
+	var files []string
+	if r.recursive {
+		files, err = walkFiles(path)
+	} else {
+		files, err = listFiles(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.orderFiles(path, files)
+	return files, nil
+}
+
+// listFiles returns the workflow files directly inside the directory path
+// that its ignore file doesn't exclude, in lexical order.
+func listFiles(path string) ([]string, error) {
 	entries, err := os.ReadDir(path)
 	if err := e.Wrapf(err, "unable to read dir %s", path); err != nil {
 		return nil, err
 	}
+	ig := newIgnorer(path)
 	var files []string
-	for _, e := range entries {
-		if e.IsDir() {
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := filepath.Join(path, entry.Name())
+		if !isWorkflowFile(name) {
 			continue
 		}
-		if strings.HasSuffix(e.Name(), ".yaml") || strings.HasSuffix(e.Name(), ".yml") {
-			files = append(files, filepath.Join(path, e.Name()))
+		ignored, err := ig.ignored(name, false)
+		if err != nil {
+			return nil, err
+		}
+		if !ignored {
+			files = append(files, name)
 		}
 	}
 	sort.Strings(files)
 	return files, nil
 }
 
-func (r *Runner) runFile(path string) ([]string, []error) {
-	var logs []string
-	prefix := filepath.Base(path)
-	log := func(format string, args ...interface{}) {
-		msg := fmt.Sprintf(format, args...)
-		logs = append(logs, fmt.Sprintf("[%s] %s", prefix, msg))
+// walkFiles returns the workflow files in dir and its subdirectories,
+// skipping hidden directories such as .git and paths excluded by ignore
+// files. Entries are visited in lexical order, so tests/auth/ comes before
+// tests/billing/.
+func walkFiles(dir string) ([]string, error) {
+	ig := newIgnorer(dir)
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && !isWorkflowFile(path) {
+			return nil
+		}
+		ignored, err := ig.ignored(path, d.IsDir())
+		switch {
+		case err != nil:
+			return err
+		case ignored && d.IsDir():
+			return filepath.SkipDir
+		case !ignored && !d.IsDir():
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err := e.Wrapf(err, "unable to read dir %s", dir); err != nil {
+		return nil, err
 	}
+	return files, nil
+}
 
-	log("Running workflow file: %s", path)
+// isGlob reports whether path is a glob pattern rather than a file name.
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[{")
+}
 
-	data, err := os.ReadFile(path)
-	if err := e.Wrapf(err, "read %s", path); err != nil {
-		return logs, []error{err}
+// globFiles expands pattern, which may use ** to match any number of
+// directories, to the workflow files it matches that ignore files below the
+// pattern's base directory don't exclude. Patterns are expanded here
+// rather than by the shell so they behave the same everywhere, Windows
+// included.
+func globFiles(pattern string) ([]string, error) {
+	matches, err := doublestar.FilepathGlob(pattern, doublestar.WithFilesOnly())
+	if err := e.Wrapf(err, "invalid pattern %s", pattern); err != nil {
+		return nil, err
 	}
-	var spec InstructionsFile
-	if err := e.Wrapf(yaml.Unmarshal(data, &spec), "parse %s", path); err != nil {
-		return logs, []error{err}
+	base, _ := doublestar.SplitPattern(filepath.ToSlash(pattern))
+	ig := newIgnorer(filepath.FromSlash(base))
+	var files []string
+	for _, m := range matches {
+		if !isWorkflowFile(m) {
+			continue
+		}
+		ignored, err := ig.ignored(m, false)
+		if err != nil {
+			return nil, err
+		}
+		if !ignored {
+			files = append(files, m)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no workflow files match %s", pattern)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// isWorkflowFile reports whether a file found in a directory is a workflow:
+// any YAML file other than the directory's defaults, or a JSON file with a
+// top-level "workflow" key, so request bodies kept next to JSON workflows are
+// skipped.
+func isWorkflowFile(path string) bool {
+	if filepath.Base(path) == DefaultsFileName {
+		return false
+	}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return true
+	case ".json":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false
+		}
+		var doc map[string]json.RawMessage
+		if json.Unmarshal(data, &doc) != nil {
+			return false
+		}
+		_, ok := doc["workflow"]
+		return ok
+	}
+	return false
+}
+
+func (r *Runner) runFile(ctx context.Context, path string) fileResult {
+	return r.runSpec(ctx, path, r.parseFile)
+}
+
+// runSpec runs the workflow that load returns for path.
+func (r *Runner) runSpec(ctx context.Context, path string, load func(string) (InstructionsFile, error)) (res fileResult) {
+	res = fileResult{path: path, name: filepath.Base(path)}
+	fileStart := time.Now()
+	defer func() {
+		res.duration = time.Since(fileStart)
+		if r.globals != nil {
+			r.globals.finished(path, len(res.errs) > 0)
+		}
+		r.fileEnded(fileEnd(res))
+	}()
+	// Log lines are grouped under the step that produced them.
+	logs := &res.logs
+	var current string // the step being run, if any
+	log := func(format string, args ...interface{}) {
+		line := fmt.Sprintf(format, args...)
+		*logs = append(*logs, line)
+		r.logLine(path, current, line)
+	}
+
+	var fileSpan *span
+	if r.tracer != nil {
+		fileSpan = r.tracer.start(res.name, spanKindInternal, nil)
+		fileSpan.setAttr(stringAttr("ramjam.file", path))
+		defer func() {
+			fileSpan.Name = res.name
+			var err error
+			if len(res.errs) > 0 {
+				err = fmt.Errorf("%d of %d steps failed", len(res.errs), len(res.steps)+res.skipped)
+				if len(res.fileErrors()) > 0 {
+					err = res.fileErrors()[0]
+				}
+			}
+			r.tracer.end(fileSpan, err)
+		}()
+	}
+
+	spec, err := load(path)
+	if err != nil {
+		res.errs = append(res.errs, err)
+		return res
 	}
 
 	if spec.Metadata.Name != "" {
-		prefix = spec.Metadata.Name
+		res.name = spec.Metadata.Name
+	}
+
+	if r.fromStep != "" {
+		spec.Workflow, err = stepsFrom(spec.Workflow, r.fromStep)
+		if err := e.Wrapf(err, "start %s", path); err != nil {
+			res.errs = append(res.errs, err)
+			return res
+		}
+	}
+	if len(r.steps) > 0 {
+		spec.Workflow, err = selectSteps(spec.Workflow, r.steps)
+		if err := e.Wrapf(err, "select steps in %s", path); err != nil {
+			res.errs = append(res.errs, err)
+			return res
+		}
+	}
+
+	var resumed map[string]string
+	if r.checkpoint != nil {
+		var passed int
+		passed, resumed = r.checkpoint.resumeAt(path, spec.Workflow)
+		spec.Workflow = spec.Workflow[passed:]
+	}
+
+	for _, imported := range spec.Config.ImportsVars {
+		err := fmt.Errorf("imports_vars %s is not part of this run", imported)
+		if r.globals != nil {
+			err = r.globals.wait(ctx, path, imported)
+		}
+		if err := e.Wrapf(err, "import variables for %s", path); err != nil {
+			res.errs = append(res.errs, err)
+			res.skipped = len(spec.Workflow)
+			return res
+		}
 	}
 
 	vars := map[string]string{
 		"base_url": spec.Config.BaseURL,
 	}
+	sources := varSources{"base_url": "config.base_url"}
+	sources.merge(vars, spec.vars, "directory defaults")
+	fileVars := make(map[string]string, len(spec.Vars))
+	for k, v := range spec.Vars {
+		if fileVars[k], err = r.applyVars(v, vars); err != nil {
+			res.errs = append(res.errs, e.Wrapf(err, "vars of %s", path))
+			res.skipped = len(spec.Workflow)
+			return res
+		}
+	}
+	sources.merge(vars, fileVars, "file vars")
+	if r.profile != nil {
+		sources.merge(vars, r.profile.Vars, "profile")
+	}
+	if r.globals != nil {
+		sources.merge(vars, r.globals.snapshot(), "global captures")
+	}
+	sources.merge(vars, resumed, "checkpoint")
+	sources.merge(vars, r.vars, "command line")
+	res.vars = vars
+
+	client, err := r.fileClient(path, spec.Config)
+	if err := e.Wrapf(err, "configure client for %s", path); err != nil {
+		res.errs = append(res.errs, err)
+		res.skipped = len(spec.Workflow)
+		return res
+	}
+	if len(r.middleware) > 0 {
+		client = r.middlewareClient(client)
+	}
+	if r.har != nil {
+		client = r.har.client(client)
+	}
+	if limiters := r.rateLimiters(path, spec.Config); len(limiters) > 0 {
+		client = limitClient(client, limiters)
+	}
+	if retry := r.fileRetry(spec.Config); retry != nil {
+		client = retryClient(client, retry)
+	}
+
+	// Fetch OAuth2 tokens up front so credential problems fail the file once
+	// rather than every step.
+	if auth := spec.Config.Auth; auth != nil && auth.OAuth2 != nil && !r.dryRun {
+		if err := r.prefetchToken(ctx, client, spec.Config, vars); err != nil {
+			res.errs = append(res.errs, e.Wrapf(err, "oauth2 token for %s", path))
+			res.skipped = len(spec.Workflow)
+			return res
+		}
+	}
+
+	contract, err := loadContract(spec.Config, workflowDir(path))
+	if err := e.Wrapf(err, "load openapi spec for %s", path); err != nil {
+		res.errs = append(res.errs, err)
+		res.skipped = len(spec.Workflow)
+		return res
+	}
 
 	// Resolve body files relative to the YAML file's directory
-	baseDir := filepath.Dir(path)
+	fc := &fileContext{
+		path:     path,
+		baseDir:  workflowDir(path),
+		config:   spec.Config,
+		client:   client,
+		contract: contract,
+	}
 
-	var errs []error
-	for _, step := range spec.Workflow {
-		// Resolve body from file if specified
-		if err := r.resolveBodyFile(&step, baseDir); err != nil {
-			errs = append(errs, &StepError{
-				File:        path,
-				Step:        step.Step,
-				Description: step.Description,
-				Err:         fmt.Errorf("resolve body file: %w", err),
-			})
-			continue
+	for i, step := range spec.Workflow {
+		if ctx.Err() != nil {
+			res.skipped = len(spec.Workflow) - i
+			break
+		}
+		step.file = fc
+
+		r.stepStarted(StepStart{File: path, Step: step.Step, Index: i})
+		result := stepResult{name: step.Step, description: step.Description}
+		logs, current = &result.logs, step.Step
+		start := time.Now()
+		if fileSpan != nil {
+			fc.span = r.tracer.start(step.Step, spanKindClient, fileSpan)
+			fc.span.setAttr(stringAttr("ramjam.step", step.Step))
 		}
 
-		if err := r.executeStep(step, vars, log); err != nil {
-			errs = append(errs, &StepError{
+		fc.exchange = exchange{}
+		restore, err := sources.stepVars(step, vars, r.applyVars)
+		var before map[string]string
+		if r.verbose() {
+			sources.trace(step, vars, log)
+			before = copyVars(vars)
+		}
+		run := false
+		if err == nil {
+			run, err = stepWhen(step, vars)
+		}
+		switch {
+		case err != nil:
+		case !run:
+			log("Skipped: when %s is false", step.When)
+		default:
+			// Resolve body from file if specified
+			if err = r.resolveBodyFile(&step, fc.baseDir); err != nil {
+				err = fmt.Errorf("resolve body file: %w", err)
+			} else if err = r.checkVars(step, vars); err == nil {
+				err = r.executeStep(ctx, step, vars, log)
+			}
+		}
+		if r.verbose() {
+			sources.captured(step, before, vars, log)
+		} else {
+			sources.captured(step, nil, vars, nil)
+		}
+		restore()
+		if fc.span != nil {
+			r.tracer.end(fc.span, err)
+		}
+
+		result.duration = time.Since(start)
+		result.request, result.status = fc.exchange.request, fc.exchange.status
+		logs, current = &res.logs, ""
+		if err != nil {
+			se := &StepError{
 				File:        path,
 				Step:        step.Step,
 				Description: step.Description,
 				Err:         err,
-			})
+				Request:     fc.exchange.request,
+				Status:      fc.exchange.status,
+				Body:        fc.exchange.body,
+				Duration:    result.duration,
+			}
+			var mismatch *ExpectationError
+			if errors.As(err, &mismatch) {
+				se.Expected, se.Actual = mismatch.Expected, mismatch.Actual
+			}
+			result.err = se
+			res.errs = append(res.errs, result.err)
 		}
+		res.steps = append(res.steps, result)
+		r.stepEnded(StepEnd{File: path, Step: step.Step, Index: i, Duration: result.duration, Logs: result.logs, Err: result.err, vars: vars})
 	}
 
-	return logs, errs
+	return res
+}
+
+// prefetchToken fetches the token for cfg.auth.oauth2 into the cache.
+func (r *Runner) prefetchToken(ctx context.Context, client *http.Client, cfg Config, vars map[string]string) error {
+	oauth2, err := r.oauth2Config(cfg.Auth.OAuth2, vars)
+	if err != nil {
+		return err
+	}
+	userAgent, err := r.fileUserAgent(cfg, vars)
+	if err != nil {
+		return err
+	}
+	_, err = r.tokens.token(ctx, client, oauth2, userAgent)
+	return err
+}
+
+// parseFile reads the workflow file at path, with its directory's defaults
+// and the runner's profile merged into its config. Unknown fields are errors
+// unless the runner is lenient, so a typo such as expcet can't silently skip
+// assertions.
+func (r *Runner) parseFile(path string) (InstructionsFile, error) {
+	var spec InstructionsFile
+	data, err := readPath(path)
+	if err := e.Wrapf(err, "read %s", path); err != nil {
+		return spec, err
+	}
+	if err := e.Wrapf(r.decode(data, &spec), "parse %s", path); err != nil {
+		return spec, e.Mark(err, e.ParseError)
+	}
+	var defaults *Defaults
+	if !config.IsURL(path) {
+		if defaults, err = r.loadDefaults(filepath.Dir(path)); err != nil {
+			return spec, err
+		}
+	}
+	if defaults != nil {
+		spec.Config = defaults.apply(spec.Config)
+		spec.vars = defaults.Variables
+	}
+	if r.profile != nil {
+		spec.Config = r.profile.apply(spec.Config)
+	}
+	return spec, nil
+}
+
+// readPath reads a local file or fetches an http(s) URL.
+func readPath(path string) ([]byte, error) {
+	if config.IsURL(path) {
+		return config.ReadURL(path)
+	}
+	return os.ReadFile(path)
+}
+
+// workflowDir returns the directory that relative paths in a workflow file
+// are resolved against. For a remote workflow this is the working directory;
+// only body_file is fetched relative to the workflow's URL.
+func workflowDir(path string) string {
+	if config.IsURL(path) {
+		return "."
+	}
+	return filepath.Dir(path)
+}
+
+// decode unmarshals YAML or JSON data into v, rejecting unknown fields unless
+// the runner is lenient. Empty data leaves v unchanged.
+func (r *Runner) decode(data []byte, v interface{}) error {
+	if r.lenient {
+		return yaml.Unmarshal(data, v)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return unknownFieldError(err)
 }
 
 func (r *Runner) resolveBodyFile(step *Step, baseDir string) error {
@@ -259,12 +976,17 @@ func (r *Runner) resolveBodyFile(step *Step, baseDir string) error {
 
 	// Resolve the file path relative to the YAML file
 	bodyPath := step.Request.BodyFile
-	if !filepath.IsAbs(bodyPath) {
+	if step.file != nil && config.IsURL(step.file.path) && !filepath.IsAbs(bodyPath) {
+		var err error
+		if bodyPath, err = config.ResolveURL(step.file.path, bodyPath); err != nil {
+			return err
+		}
+	} else if !filepath.IsAbs(bodyPath) {
 		bodyPath = filepath.Join(baseDir, bodyPath)
 	}
 
 	// Read the JSON file
-	data, err := os.ReadFile(bodyPath)
+	data, err := readPath(bodyPath)
 	if err := e.Wrapf(err, "read body file %s", step.Request.BodyFile); err != nil {
 		return err
 	}
@@ -280,74 +1002,225 @@ func (r *Runner) resolveBodyFile(step *Step, baseDir string) error {
 	return nil
 }
 
-func (r *Runner) executeStep(step Step, vars map[string]string, log func(string, ...interface{})) error {
-	if r.verbose {
-		log("Executing step: %s", step.Step)
+func (r *Runner) executeStep(ctx context.Context, step Step, vars map[string]string, log func(string, ...interface{})) error {
+	if step.WebSocket != nil {
+		return r.websocketStep(ctx, step, vars, log)
 	}
+	if step.Poll != nil {
+		return r.pollStep(ctx, step, vars, log)
+	}
+	return r.attemptStep(ctx, step, vars, log)
+}
 
+// networkError marks err, a failure to send a request or read its
+// response, as a timeout or a connection error. Cancelled runs are left
+// unmarked.
+func networkError(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return err
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return e.Mark(err, e.TimeoutError)
+	}
+	return e.Mark(err, e.ConnectionError)
+}
+
+// responseError marks an error found once a response has arrived. Reading
+// the body can still fail on the network; anything else is an unmet
+// expectation.
+func responseError(err error) error {
+	if err == nil || e.KindOf(err) != nil {
+		return err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.Canceled) {
+		return networkError(err)
+	}
+	return e.Mark(err, e.AssertionError)
+}
+
+// attemptStep sends the step's request once and evaluates its expectations,
+// captures and output.
+func (r *Runner) attemptStep(ctx context.Context, step Step, vars map[string]string, log func(string, ...interface{})) (err error) {
 	method := strings.ToUpper(strings.TrimSpace(step.Request.Method))
 	if method == "" {
 		method = http.MethodGet
+		if step.Request.GraphQL != nil {
+			method = http.MethodPost
+		}
 	}
 
-	requestURL := applyVars(step.Request.URL, vars)
+	requestURL, err := r.applyVars(step.Request.URL, vars)
+	if err != nil {
+		return err
+	}
 	if len(step.Request.Params) > 0 {
 		if idx := strings.Index(requestURL, "?"); idx >= 0 {
 			requestURL = requestURL[:idx]
 		}
 	}
 
-	url := requestURL
-	if !strings.HasPrefix(url, "http") && vars["base_url"] != "" {
-		url = strings.TrimSuffix(vars["base_url"], "/") + "/" + strings.TrimPrefix(url, "/")
+	url := withBaseURL(requestURL, vars)
+
+	bodyReader, contentType, err := r.buildBody(step, vars, log)
+	if err != nil {
+		return err
 	}
 
-	bodyReader := io.Reader(nil)
-	if len(step.Request.bodyData) > 0 {
-		body := applyVarsToInterface(step.Request.bodyData, vars)
-		payload, err := json.Marshal(body)
-		if err := e.Wrap(err, "marshal body"); err != nil {
-			return err
-		}
-		bodyReader = bytes.NewReader(payload)
-		if r.verbose && step.Request.bodySource != "" {
-			log("Using body from: %s", step.Request.bodySource)
-		}
+	if bodyReader != nil && step.Request.CompressBody {
+		bodyReader = gzipBody(bodyReader)
+	}
+	// client.Do closes the body, but a step can fail before then. Closing
+	// it on every return stops the goroutine writing a multipart or gzip
+	// body, which would otherwise block forever.
+	if c, ok := bodyReader.(io.Closer); ok {
+		defer c.Close()
 	}
 
-	req, err := http.NewRequest(method, url, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err := e.Wrap(err, "build request"); err != nil {
 		return err
 	}
-	req.Header.Set("User-Agent", "ramjam-cli")
-	if bodyReader != nil {
-		req.Header.Set("Content-Type", "application/json")
+	step.file.exchange.request = method + " " + url
+	// Until the body is handed to the assertions, a failure reads an
+	// excerpt of it for the StepError.
+	bodyRead := false
+	userAgent, err := r.fileUserAgent(step.file.config, vars)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if bodyReader != nil && step.Request.CompressBody {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if step.Expect.SSE != nil {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	if err := r.setHeaders(req.Header, r.headers, vars); err != nil {
+		return err
+	}
+	if err := r.setHeaders(req.Header, step.file.config.Headers, vars); err != nil {
+		return err
+	}
+	if err := r.applyAuth(req, step.file.config.Auth, step.file.client, vars); err != nil {
+		return err
+	}
+	if err := r.applyAuth(req, step.Request.Auth, step.file.client, vars); err != nil {
+		return err
 	}
 
-	for k, v := range step.Request.Headers {
-		req.Header.Set(k, applyVars(v, vars))
+	if err := r.setHeaders(req.Header, step.Request.Headers, vars); err != nil {
+		return err
 	}
 
 	if len(step.Request.Params) > 0 {
 		query := req.URL.Query()
 		for key, value := range step.Request.Params {
-			query.Set(key, applyVars(value, vars))
+			value, err := r.applyVars(value, vars)
+			if err != nil {
+				return err
+			}
+			query.Set(key, value)
 		}
 		req.URL.RawQuery = query.Encode()
 	}
 
-	resp, err := r.client.Do(req)
+	if h := stepHMAC(step); h != nil {
+		if err := e.Wrap(r.signHMAC(req, h, vars), "hmac auth"); err != nil {
+			return err
+		}
+	}
+
+	if r.printCurl || step.Output.Curl {
+		curl, err := r.curlCommand(req, step)
+		if err != nil {
+			return err
+		}
+		log("%s", curl)
+	}
+
+	if r.dryRun {
+		return r.logDryRun(req, log)
+	}
+
+	client := step.file.client
+	if step.Request.FollowRedirects != nil && !*step.Request.FollowRedirects {
+		noRedirect := *client
+		noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		client = &noRedirect
+	}
+
+	traceRequest(step, req)
+	r.dumpRequest(req, log)
+	sent := time.Now()
+	resp, err := client.Do(req)
 	if err := e.Wrap(err, "request"); err != nil {
-		return err
+		return networkError(err)
 	}
 	defer resp.Body.Close()
+	defer func() { err = responseError(err) }()
+	step.file.exchange.status = resp.StatusCode
+	defer func() {
+		if err != nil && !step.file.exchange.hasBody && !bodyRead {
+			step.file.exchange.readBody(resp)
+		}
+	}()
+	traceResponse(step, resp)
+	r.dumpResponseHeader(resp, log)
+
+	var recorded *StepResponse
+	if r.responses != nil {
+		recorded = &StepResponse{File: step.file.path, Step: step.Step, Status: resp.StatusCode}
+		defer func() {
+			// Keep the body of responses that failed an expectation
+			// before it was read, since those are often the interesting
+			// ones to compare.
+			if r.responses.bodies && recorded.Body == nil {
+				recorded.Body = unreadBody(resp)
+			}
+			r.responses.add(recorded)
+		}()
+	}
 
-	if r.verbose {
-		log("Received status: %d", resp.StatusCode)
+	vars["last_proto"] = resp.Proto
+	if r.verbose() {
+		log("Received status: %d (%s)", resp.StatusCode, resp.Proto)
+	}
+
+	if step.Expect.Proto != "" {
+		expected, err := r.applyVars(step.Expect.Proto, vars)
+		if err != nil {
+			return err
+		}
+		if resp.Proto != expected {
+			return &ExpectationError{fmt.Sprintf("expected protocol %s, got %s", expected, resp.Proto), expected, resp.Proto}
+		}
 	}
 
 	if step.Expect.Status != 0 && resp.StatusCode != step.Expect.Status {
-		return fmt.Errorf("expected status %d, got %d", step.Expect.Status, resp.StatusCode)
+		return &ExpectationError{fmt.Sprintf("expected status %d, got %d", step.Expect.Status, resp.StatusCode), strconv.Itoa(step.Expect.Status), strconv.Itoa(resp.StatusCode)}
+	}
+
+	if step.Expect.RedirectLocation != "" {
+		expected, err := r.applyVars(step.Expect.RedirectLocation, vars)
+		if err != nil {
+			return err
+		}
+		actual := resp.Header.Get("Location")
+		if r.verbose() {
+			log("Asserting redirect location == %s", expected)
+		}
+		if actual != expected {
+			return &ExpectationError{fmt.Sprintf("expected redirect to %q, got %q", expected, actual), expected, actual}
+		}
 	}
 
 	for _, headerExpect := range step.Expect.Headers {
@@ -360,62 +1233,242 @@ func (r *Runner) executeStep(step Step, vars map[string]string, log func(string,
 		}
 		actual := resp.Header.Get(name)
 		if headerExpect.Value != "" {
-			expected := applyVars(headerExpect.Value, vars)
-			if r.verbose {
+			expected, err := r.applyVars(headerExpect.Value, vars)
+			if err != nil {
+				return err
+			}
+			if r.verbose() {
 				log("Asserting header %s == %s", name, expected)
 			}
 			if actual != expected {
-				return fmt.Errorf("expected header %s to equal %q, got %q", name, expected, actual)
+				return &ExpectationError{fmt.Sprintf("expected header %s to equal %q, got %q", name, expected, actual), expected, actual}
 			}
 		}
 		if headerExpect.Contains != "" {
-			expected := applyVars(headerExpect.Contains, vars)
-			if r.verbose {
+			expected, err := r.applyVars(headerExpect.Contains, vars)
+			if err != nil {
+				return err
+			}
+			if r.verbose() {
 				log("Asserting header %s contains %s", name, expected)
 			}
 			if !strings.Contains(actual, expected) {
-				return fmt.Errorf("expected header %s to contain %q, got %q", name, expected, actual)
+				return &ExpectationError{fmt.Sprintf("expected header %s to contain %q, got %q", name, expected, actual), expected, actual}
 			}
 		}
 	}
 
-	rawBody, err := io.ReadAll(resp.Body)
-	if err := e.Wrap(err, "read body"); err != nil {
+	if err := r.checkContentEncoding(resp, step.Expect, vars, log); err != nil {
+		return err
+	}
+
+	bodyRead = true
+	decoded, err := decodeBody(resp)
+	if err != nil {
+		return err
+	}
+
+	if step.Expect.SSE != nil {
+		if err := r.checkSSE(decoded, step.Expect.SSE, vars, log); err != nil {
+			return err
+		}
+		return r.printOutput(step, vars, log)
+	}
+
+	body, err := r.readBody(decoded, step, vars, log)
+	if err != nil {
+		return err
+	}
+	step.file.exchange.setBody(body.buf.Bytes())
+	if max := step.Expect.MaxDuration; max > 0 {
+		if took := time.Since(sent); took > max {
+			return &ExpectationError{fmt.Sprintf("expected response within %s, took %s", max, formatDuration(took)), max.String(), formatDuration(took)}
+		}
+	}
+	r.dumpResponseBody(body, log)
+	if recorded != nil && r.responses.bodies {
+		recorded.Body = responseValue(body)
+	}
+
+	if err := r.checkBodyStream(body, step.Expect, vars, log); err != nil {
 		return err
 	}
 
+	if err := r.checkContract(resp, body, step, log); err != nil {
+		return err
+	}
+
+	jsonObj, err := parseBody(body, step)
+	if err != nil {
+		return err
+	}
+
+	if err := r.matchJSONPaths(jsonObj, step.Expect.JSONPathMatch, vars, log); err != nil {
+		return err
+	}
+
+	if err := r.checkCustom(resp, body, jsonObj, time.Since(sent), step.Expect.Custom, vars, log); err != nil {
+		return err
+	}
+
+	if err := r.captureValues(step.Capture, jsonObj, resp.Header, vars, log); err != nil {
+		return err
+	}
+
+	return r.printOutput(step, vars, log)
+}
+
+// printOutput logs the step's output.print message.
+func (r *Runner) printOutput(step Step, vars map[string]string, log func(string, ...interface{})) error {
+	if step.Output.Print == "" {
+		return nil
+	}
+	msg, err := r.applyVars(step.Output.Print, vars)
+	if err != nil {
+		return err
+	}
+	log("%s", msg)
+	return nil
+}
+
+// parseBody decodes the response body according to expect.body_format. When
+// no format is declared the body is only parsed as JSON if an assertion or
+// capture needs it, so HTML, text and binary responses pass through untouched.
+func parseBody(body *bodyStream, step Step) (interface{}, error) {
+	needsJSON := needsJSON(step)
+	switch step.Expect.BodyFormat {
+	case "":
+		if !needsJSON {
+			return nil, nil
+		}
+	case "json":
+	case "text":
+		if needsJSON {
+			return nil, fmt.Errorf("json_path assertions and captures require body_format json, got text")
+		}
+		return nil, nil
+	case "none":
+		if body.size > 0 {
+			return nil, fmt.Errorf("expected empty body, got %d bytes", body.size)
+		}
+		if needsJSON {
+			return nil, fmt.Errorf("json_path assertions and captures require a body, but body_format is none")
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown body_format %q (expected text, json or none)", step.Expect.BodyFormat)
+	}
+
+	rawBody, err := body.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
 	var jsonObj interface{}
 	if len(rawBody) > 0 {
 		if err := e.Wrap(json.Unmarshal(rawBody, &jsonObj), "parse response json"); err != nil {
-			return err
+			return nil, err
 		}
 	}
+	return jsonObj, nil
+}
 
-	for _, matcher := range step.Expect.JSONPathMatch {
-		actual, err := evalJSONPath(jsonObj, matcher.Path)
-		if err := e.Wrapf(err, "jsonpath %s", matcher.Path); err != nil {
+// needsJSON reports whether step has JSONPath assertions or captures.
+func needsJSON(step Step) bool {
+	if len(step.Expect.JSONPathMatch) > 0 {
+		return true
+	}
+	for _, cap := range step.Capture {
+		if agg, _ := cap.aggregate(); cap.JSONPath != "" || agg != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// withBaseURL prefixes relative URLs with the base_url variable, if set.
+func withBaseURL(url string, vars map[string]string) string {
+	if !strings.HasPrefix(url, "http") && vars["base_url"] != "" {
+		url = strings.TrimSuffix(vars["base_url"], "/") + "/" + strings.TrimPrefix(url, "/")
+	}
+	return url
+}
+
+// matchJSONPaths asserts each JSONPath matcher against a decoded JSON document.
+func (r *Runner) matchJSONPaths(jsonObj interface{}, matchers []JSONPathVal, vars map[string]string, log func(string, ...interface{})) error {
+	for _, matcher := range matchers {
+		path := matcher.Path
+		if err := matcher.check(); err != nil {
+			return err
+		}
+		var actual interface{}
+		var err error
+		if agg, aggPath := matcher.aggregate(); agg != "" {
+			path = agg + " " + aggPath
+			actual, err = aggregate(agg, aggPath, jsonObj)
+		} else {
+			actual, err = evalJSONPath(jsonObj, matcher.Path)
+		}
+		if err := e.Wrapf(err, "jsonpath %s", path); err != nil {
 			return err
 		}
-		expected := applyVars(fmt.Sprint(matcher.Value), vars)
-		if r.verbose {
-			log("Asserting %s == %s", matcher.Path, expected)
+		expected, err := r.applyVars(fmt.Sprint(matcher.Value), vars)
+		if err != nil {
+			return err
+		}
+		if r.verbose() {
+			log("Asserting %s == %s", path, expected)
 		}
 		if fmt.Sprint(actual) != expected {
-			return fmt.Errorf("jsonpath %s expected %q, got %q", matcher.Path, expected, actual)
+			if diffable(matcher.Value) || diffable(actual) {
+				// Objects, arrays and multi-line strings are easier to
+				// compare as a diff of their pretty-printed forms.
+				pretty, err := r.applyVars(prettyValue(matcher.Value), vars)
+				if err != nil {
+					return err
+				}
+				return &ExpectationError{fmt.Sprintf("jsonpath %s does not match (-expected +actual)", path), pretty, prettyValue(actual)}
+			}
+			return &ExpectationError{fmt.Sprintf("jsonpath %s expected %q, got %q", path, expected, actual), expected, fmt.Sprint(actual)}
 		}
 	}
+	return nil
+}
 
-	for _, cap := range step.Capture {
+// captureValues stores values extracted from a JSON document or headers into vars.
+func (r *Runner) captureValues(captures []Capture, jsonObj interface{}, header http.Header, vars map[string]string, log func(string, ...interface{})) error {
+	for _, cap := range captures {
 		var val interface{}
 		var err error
 
-		if cap.JSONPath != "" {
+		if cap.Scope != "" && cap.Scope != ScopeFile && cap.Scope != ScopeGlobal {
+			return fmt.Errorf("capture %s has unknown scope %q (expected %s or %s)", cap.As, cap.Scope, ScopeFile, ScopeGlobal)
+		}
+
+		if cap.Mode != "" && cap.Mode != CaptureFirst && cap.Mode != CaptureAll {
+			return fmt.Errorf("capture %s has unknown mode %q (expected %s or %s)", cap.As, cap.Mode, CaptureFirst, CaptureAll)
+		}
+
+		if err := e.Wrapf(cap.check(), "capture %s", cap.As); err != nil {
+			return err
+		}
+
+		if agg, path := cap.aggregate(); agg != "" {
+			val, err = aggregate(agg, path, jsonObj)
+			if err := e.Wrapf(err, "capture %s %s", agg, path); err != nil {
+				return err
+			}
+		} else if cap.Mode == CaptureAll {
+			if val, err = captureAll(cap, jsonObj, header); err != nil {
+				return err
+			}
+		} else if cap.JSONPath != "" {
 			val, err = evalJSONPath(jsonObj, cap.JSONPath)
 			if err := e.Wrapf(err, "capture json_path %s", cap.JSONPath); err != nil {
 				return err
 			}
 		} else if cap.Header != "" {
-			headerVal := resp.Header.Get(cap.Header)
+			headerVal := header.Get(cap.Header)
 			if cap.Regex != "" {
 				re, err := regexp.Compile(cap.Regex)
 				if err := e.Wrapf(err, "invalid regex %s", cap.Regex); err != nil {
@@ -433,87 +1486,174 @@ func (r *Runner) executeStep(step Step, vars map[string]string, log func(string,
 				val = headerVal
 			}
 		} else {
-			return fmt.Errorf("capture must specify json_path or header")
+			return fmt.Errorf("capture must specify json_path, header or an aggregate such as length_of")
 		}
 
-		if r.verbose {
+		if r.verbose() {
 			log("Captured %s => %s", cap.As, fmt.Sprint(val))
 		}
 		vars[cap.As] = fmt.Sprint(val)
+		if cap.Scope == ScopeGlobal && r.globals != nil {
+			r.globals.set(cap.As, vars[cap.As])
+		}
 	}
-
-	if step.Output.Print != "" {
-		msg := applyVars(step.Output.Print, vars)
-		log("%s", msg)
-	}
-
 	return nil
 }
 
-var varPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+var (
+	varPattern   = regexp.MustCompile(`\$\{([^}]+)\}`)
+	fallbackExpr = regexp.MustCompile(`^([^:()]+):([-?])(.*)$`)
+)
 
-func applyVars(input string, vars map[string]string) string {
-	return varPattern.ReplaceAllStringFunc(input, func(m string) string {
+// applyVars substitutes vars into each ${...} in input. One that can't be
+// resolved, such as an unset variable, is left in place. It returns the
+// error of the first function that fails, which fails the step.
+func (r *Runner) applyVars(input string, vars map[string]string) (string, error) {
+	var err error
+	out := varPattern.ReplaceAllStringFunc(input, func(m string) string {
 		key := strings.TrimSuffix(strings.TrimPrefix(m, "${"), "}")
-		if v, ok := vars[key]; ok {
+		v, evalErr := r.evalExpr(key, vars)
+		switch {
+		case evalErr == nil:
 			return v
+		case err == nil && !errors.Is(evalErr, errUnresolved):
+			err = fmt.Errorf("${%s}: %w", key, evalErr)
 		}
 		return m
 	})
+	return out, err
+}
+
+// evalExpr returns the value of expr, the text inside ${...}: a variable,
+// a variable with a fallback, a pipeline, an element of an array variable,
+// a function call or an expression such as count + 1. It returns
+// errUnresolved if expr can't be resolved, in which case the ${...} is
+// left in place, or the error of a function it calls.
+func (r *Runner) evalExpr(expr string, vars map[string]string) (string, error) {
+	if v, ok := vars[expr]; ok {
+		return v, nil
+	}
+	if head, filters, ok := splitPipe(expr); ok {
+		return r.pipe(head, filters, vars)
+	}
+	if name, op, arg, ok := splitFallback(expr); ok {
+		if v := vars[name]; v != "" {
+			return v, nil
+		}
+		// ${name:?message} is left in place; checkVars fails the step.
+		if op != "-" {
+			return "", errUnresolved
+		}
+		return arg, nil
+	}
+	if v, ok := indexVar(expr, vars); ok {
+		return v, nil
+	}
+	if name, args, isCall := parseCall(expr); isCall {
+		if v, err := r.call(name, args, vars); !errors.Is(err, errUnresolved) {
+			return v, err
+		}
+	}
+	if v, ok := evalArith(expr, vars); ok {
+		return v, nil
+	}
+	return "", errUnresolved
 }
 
-func applyVarsToInterface(val interface{}, vars map[string]string) interface{} {
+// splitFallback splits expr, the text inside ${...}, of the form
+// name:-default or name:?message. Either part is used when the variable is
+// unset or empty: the default in its place, or the message in the error
+// that fails the step.
+func splitFallback(expr string) (name, op, arg string, ok bool) {
+	m := fallbackExpr.FindStringSubmatch(expr)
+	if m == nil {
+		return "", "", "", false
+	}
+	return strings.TrimSpace(m[1]), m[2], m[3], true
+}
+
+// applyVarsToInterface substitutes vars into every string in val. It
+// returns new slices and maps rather than changing val, which belongs to
+// the workflow and is substituted again on every attempt and every run.
+func (r *Runner) applyVarsToInterface(val interface{}, vars map[string]string) (interface{}, error) {
+	var err error
 	switch v := val.(type) {
 	case string:
-		return applyVars(v, vars)
+		return r.applyVars(v, vars)
 	case []interface{}:
+		out := make([]interface{}, len(v))
 		for i := range v {
-			v[i] = applyVarsToInterface(v[i], vars)
+			if out[i], err = r.applyVarsToInterface(v[i], vars); err != nil {
+				return nil, err
+			}
 		}
-		return v
+		return out, nil
 	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
 		for k := range v {
-			v[k] = applyVarsToInterface(v[k], vars)
+			if out[k], err = r.applyVarsToInterface(v[k], vars); err != nil {
+				return nil, err
+			}
 		}
-		return v
+		return out, nil
 	default:
-		return v
+		return v, nil
 	}
 }
 
+var (
+	jsonPathFilter = regexp.MustCompile(`^\$\[\?\(@\.([A-Za-z0-9_\-]+)==['"]?([^'"]+)['"]?\)\](?:\.(.*))?$`)
+	jsonPathIndex  = regexp.MustCompile(`^\$\[([0-9]+)\](?:\.(.*))?$`)
+)
+
+// filterJSON returns the objects in the array obj whose field equals val,
+// for the filter in path.
+func filterJSON(obj interface{}, path, field, val string) ([]interface{}, error) {
+	arr, ok := obj.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected array for filter %s", path)
+	}
+	var matches []interface{}
+	for _, el := range arr {
+		if mp, ok := el.(map[string]interface{}); ok {
+			if fmt.Sprint(mp[field]) == val {
+				matches = append(matches, el)
+			}
+		}
+	}
+	return matches, nil
+}
+
 func evalJSONPath(obj interface{}, path string) (interface{}, error) {
+	return lookupJSONPath(obj, path, true)
+}
+
+// lookupJSONPath evaluates path against obj. A key that isn't there gives
+// nil if missingOK is set, and an error if not.
+func lookupJSONPath(obj interface{}, path string, missingOK bool) (interface{}, error) {
 	p := strings.TrimSpace(path)
 	if p == "" {
 		return nil, fmt.Errorf("empty path")
 	}
 
 	// Handle filter of form $[?(@.field==value)].rest (value may be quoted or bare)
-	if m := regexp.MustCompile(`^\$\[\?\(@\.([A-Za-z0-9_\-]+)==['"]?([^'"]+)['"]?\)\](?:\.(.*))?$`).FindStringSubmatch(p); m != nil {
-		field, val, rest := m[1], m[2], m[3]
-		arr, ok := obj.([]interface{})
-		if !ok {
-			return nil, fmt.Errorf("expected array for filter %s", path)
-		}
-		var matches []interface{}
-		for _, el := range arr {
-			if mp, ok := el.(map[string]interface{}); ok {
-				if fmt.Sprint(mp[field]) == val {
-					matches = append(matches, el)
-				}
-			}
+	if m := jsonPathFilter.FindStringSubmatch(p); m != nil {
+		matches, err := filterJSON(obj, path, m[1], m[2])
+		if err != nil {
+			return nil, err
 		}
 		if len(matches) == 0 {
 			return nil, fmt.Errorf("no match for filter %s", path)
 		}
 		selected := matches[0]
-		if rest != "" {
-			return evalJSONPath(selected, rest)
+		if rest := m[3]; rest != "" {
+			return lookupJSONPath(selected, rest, missingOK)
 		}
 		return matches, nil
 	}
 
 	// Handle index of form $[0].rest
-	if m := regexp.MustCompile(`^\$\[([0-9]+)\](?:\.(.*))?$`).FindStringSubmatch(p); m != nil {
+	if m := jsonPathIndex.FindStringSubmatch(p); m != nil {
 		idx, _ := strconv.Atoi(m[1])
 		arr, ok := obj.([]interface{})
 		if !ok {
@@ -524,7 +1664,7 @@ func evalJSONPath(obj interface{}, path string) (interface{}, error) {
 		}
 		selected := arr[idx]
 		if rest := m[2]; rest != "" {
-			return evalJSONPath(selected, rest)
+			return lookupJSONPath(selected, rest, missingOK)
 		}
 		return selected, nil
 	}
@@ -533,17 +1673,20 @@ func evalJSONPath(obj interface{}, path string) (interface{}, error) {
 	p = strings.TrimPrefix(strings.TrimPrefix(p, "$."), "$")
 	segments := strings.Split(p, ".")
 	cur := obj
-	for _, seg := range segments {
+	for i, seg := range segments {
 		if seg == "" {
 			continue
 		}
 		name := seg
 		idx := -1
+		wildcard := false
 		if strings.Contains(seg, "[") && strings.HasSuffix(seg, "]") {
 			parts := strings.SplitN(seg, "[", 2)
 			name = parts[0]
 			idStr := strings.TrimSuffix(parts[1], "]")
-			if idStr != "" {
+			if idStr == "*" {
+				wildcard = true
+			} else if idStr != "" {
 				parsed, err := strconv.Atoi(idStr)
 				if err != nil {
 					return nil, fmt.Errorf("invalid index in segment %s", seg)
@@ -556,7 +1699,32 @@ func evalJSONPath(obj interface{}, path string) (interface{}, error) {
 			if !ok {
 				return nil, fmt.Errorf("expected object for segment %s", name)
 			}
-			cur = m[name]
+			v, found := m[name]
+			if !found && !missingOK {
+				return nil, fmt.Errorf("no field %s", name)
+			}
+			cur = v
+		}
+		if wildcard {
+			// Apply the remainder of the path to every element, e.g. errors[*].message
+			arr, ok := cur.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected array for segment %s", seg)
+			}
+			rest := strings.Join(segments[i+1:], ".")
+			all := make([]interface{}, 0, len(arr))
+			for _, el := range arr {
+				if rest == "" {
+					all = append(all, el)
+					continue
+				}
+				v, err := lookupJSONPath(el, rest, missingOK)
+				if err != nil {
+					return nil, err
+				}
+				all = append(all, v)
+			}
+			return all, nil
 		}
 		if idx >= 0 {
 			arr, ok := cur.([]interface{})
@@ -571,3 +1739,48 @@ func evalJSONPath(obj interface{}, path string) (interface{}, error) {
 	}
 	return cur, nil
 }
+
+// checkJSONPath reports syntax errors in a path without evaluating it, using
+// the same rules as evalJSONPath.
+func checkJSONPath(path string) error {
+	p := strings.TrimSpace(path)
+	if p == "" {
+		return fmt.Errorf("empty path")
+	}
+
+	if strings.HasPrefix(p, "$[") {
+		m := jsonPathFilter.FindStringSubmatch(p)
+		if m == nil {
+			m = jsonPathIndex.FindStringSubmatch(p)
+		}
+		if m == nil {
+			return fmt.Errorf("invalid filter or index in %s", path)
+		}
+		if rest := m[len(m)-1]; rest != "" {
+			return checkJSONPath(rest)
+		}
+		return nil
+	}
+
+	p = strings.TrimPrefix(strings.TrimPrefix(p, "$."), "$")
+	for _, seg := range strings.Split(p, ".") {
+		open := strings.Index(seg, "[")
+		if open < 0 {
+			if strings.Contains(seg, "]") {
+				return fmt.Errorf("unbalanced ] in segment %s", seg)
+			}
+			continue
+		}
+		if !strings.HasSuffix(seg, "]") || strings.Count(seg, "[") != 1 || strings.Count(seg, "]") != 1 {
+			return fmt.Errorf("invalid segment %s", seg)
+		}
+		idx := seg[open+1 : len(seg)-1]
+		if idx == "" || idx == "*" {
+			continue
+		}
+		if _, err := strconv.Atoi(idx); err != nil {
+			return fmt.Errorf("invalid index in segment %s", seg)
+		}
+	}
+	return nil
+}
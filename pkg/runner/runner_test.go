@@ -1,6 +1,8 @@
 package runner
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +12,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
 )
 
 func TestSimpleGet(t *testing.T) {
@@ -365,6 +369,40 @@ workflow:
 	}
 }
 
+func TestExpectMaxDuration(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "fast"
+  request:
+    url: "/fast"
+  expect:
+    max_duration: 1s
+- step: "slow"
+  request:
+    url: "/slow"
+  expect:
+    max_duration: 20ms
+`, srv.URL)
+
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), "expected response within 20ms, took") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(err.Error(), "fast") {
+		t.Errorf("fast step failed: %v", err)
+	}
+}
+
 func TestExpectJsonPathFailure(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`{"status": "error"}`))
@@ -444,6 +482,187 @@ workflow:
 	}
 }
 
+func TestJSONWorkflows(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		paths = append(paths, r.URL.Path+" "+string(body))
+		w.Write([]byte(`{"id": 7}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "orders.json"), []byte(fmt.Sprintf(`{
+  "metadata": {"name": "Orders"},
+  "config": {"base_url": %q},
+  "workflow": [
+    {
+      "step": "create",
+      "request": {"method": "POST", "url": "/orders", "body_file": "order.json"},
+      "expect": {"status": 200, "json_path_match": [{"path": "id", "value": 7}]},
+      "capture": [{"json_path": "id", "as": "order_id"}]
+    },
+    {"step": "fetch", "request": {"url": "/orders/${order_id}"}}
+  ]
+}`, srv.URL)), 0644)
+	// A request body next to the workflow is not run as one.
+	os.WriteFile(filepath.Join(dir, "order.json"), []byte(`{"sku": "A-100"}`), 0644)
+
+	r := New(10*time.Second, false)
+	files, err := r.Files([]string{dir})
+	if err != nil || len(files) != 1 || filepath.Base(files[0]) != "orders.json" {
+		t.Fatalf("Files() = %v, %v", files, err)
+	}
+	r.out = io.Discard
+	if err := r.RunPaths([]string{dir}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+	if strings.Join(paths, ",") != `/orders {"sku":"A-100"},/orders/7 ` {
+		t.Errorf("requests = %q", paths)
+	}
+}
+
+func TestGlobPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"smoke-login.yaml",
+		"orders/smoke-create.yaml",
+		"orders/deep/smoke-cancel.yml",
+		"orders/full-create.yaml",
+		"orders/smoke-body.json",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("workflow: []\n"), 0644)
+	}
+
+	r := New(10*time.Second, false)
+	files, err := r.Files([]string{filepath.Join(dir, "**", "smoke-*.{yaml,yml,json}")})
+	if err != nil {
+		t.Fatalf("Files failed: %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		rel, _ := filepath.Rel(dir, f)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := "orders/deep/smoke-cancel.yml,orders/smoke-create.yaml,smoke-login.yaml"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Files() = %v, want %s", got, want)
+	}
+
+	if _, err := r.Files([]string{filepath.Join(dir, "**", "nothing-*.yaml")}); err == nil || !strings.Contains(err.Error(), "no workflow files match") {
+		t.Errorf("expected an error for a pattern with no matches, got %v", err)
+	}
+}
+
+func TestRecursiveFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"top.yaml",
+		"auth/login.yaml",
+		"billing/invoices/list.yml",
+		"billing/notes.txt",
+		".git/config.yaml",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("workflow: []\n"), 0644)
+	}
+
+	files, err := New(10*time.Second, false).Files([]string{dir})
+	if err != nil || len(files) != 1 || filepath.Base(files[0]) != "top.yaml" {
+		t.Errorf("expected only top.yaml without recursion, got %v, %v", files, err)
+	}
+
+	files, err = New(10*time.Second, false, WithRecursive(true)).Files([]string{dir})
+	if err != nil {
+		t.Fatalf("Files failed: %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		rel, _ := filepath.Rel(dir, f)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	if want := "auth/login.yaml,billing/invoices/list.yml,top.yaml"; strings.Join(got, ",") != want {
+		t.Errorf("Files() = %v, want %s", got, want)
+	}
+}
+
+func TestRemoteWorkflow(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	var requests []string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+		switch r.URL.Path {
+		case "/suites/smoke.yaml":
+			fmt.Fprintf(w, `
+config:
+  base_url: %q
+workflow:
+- step: "create"
+  request:
+    method: POST
+    url: "/orders"
+    body_file: "bodies/order.json"
+`, srv.URL)
+		case "/suites/bodies/order.json":
+			w.Write([]byte(`{"sku": "A-100"}`))
+		}
+	}))
+	defer srv.Close()
+
+	r := New(10*time.Second, false)
+	r.out = io.Discard
+	if err := r.RunPaths([]string{srv.URL + "/suites/smoke.yaml"}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+	want := []string{"GET /suites/smoke.yaml ", "GET /suites/bodies/order.json ", `POST /orders {"sku":"A-100"}`}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = %q, want %q", requests, want)
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer api.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name     string
+		workflow string
+		want     *e.Kind
+	}{
+		{"assertion", fmt.Sprintf("config:\n  base_url: %q\nworkflow:\n- step: s\n  request:\n    url: /\n  expect:\n    status: 200\n", api.URL), e.AssertionError},
+		{"connection", fmt.Sprintf("config:\n  base_url: %q\nworkflow:\n- step: s\n  request:\n    url: /\n", closed.URL), e.ConnectionError},
+		{"timeout", fmt.Sprintf("config:\n  base_url: %q\nworkflow:\n- step: s\n  request:\n    url: /slow\n", api.URL), e.TimeoutError},
+		{"parse", "workflow: [", e.ParseError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "w.yaml")
+			os.WriteFile(path, []byte(tt.workflow), 0644)
+			r := New(50*time.Millisecond, false)
+			r.out = io.Discard
+			err := r.RunPaths([]string{path})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("RunPaths() error = %v, want kind %s", err, tt.want.Code())
+			}
+			if got := e.KindOf(err); got != tt.want {
+				t.Errorf("KindOf() = %v", got)
+			}
+		})
+	}
+}
+
 func TestContinueOnFailure(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/fail" {
@@ -681,7 +900,217 @@ workflow:
 	}
 }
 
+func TestNonJSONBodyWithoutAssertions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>OK</body></html>`))
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "HTML Response"
+config:
+  base_url: "%s"
+workflow:
+- step: "health-page"
+  request:
+    url: "/health"
+  expect:
+    status: 200
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
+
+func TestBodyFormat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			w.Write([]byte(`plain text`))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		format  string
+		wantErr string
+	}{
+		{name: "text", url: "/text", format: "text"},
+		{name: "none", url: "/empty", format: "none"},
+		{name: "json rejects text", url: "/text", format: "json", wantErr: "parse response json"},
+		{name: "none rejects body", url: "/text", format: "none", wantErr: "expected empty body, got 10 bytes"},
+		{name: "unknown", url: "/text", format: "xml", wantErr: `unknown body_format "xml"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Body Format"
+config:
+  base_url: "%s"
+workflow:
+- step: "check"
+  request:
+    url: "%s"
+  expect:
+    body_format: "%s"
+`, srv.URL, tt.url, tt.format)
+
+			err := runTestError(t, yamlContent)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("RunPaths failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Write([]byte(`{"page": "new"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Redirects"
+config:
+  base_url: "%s"
+workflow:
+- step: "no-follow"
+  request:
+    url: "/old"
+    follow_redirects: false
+  expect:
+    status: 301
+    redirect_location: "/new"
+- step: "follow"
+  request:
+    url: "/old"
+  expect:
+    status: 200
+    json_path_match:
+    - path: "page"
+      value: "new"
+`, srv.URL)
+
+	runTest(t, yamlContent)
+
+	yamlContent = fmt.Sprintf(`
+metadata:
+  name: "Redirect Mismatch"
+config:
+  base_url: "%s"
+workflow:
+- step: "wrong-location"
+  request:
+    url: "/old"
+    follow_redirects: false
+  expect:
+    redirect_location: "/elsewhere"
+`, srv.URL)
+
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), `expected redirect to "/elsewhere", got "/new"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 // Helper to run a test from YAML content string
+func TestRunPathsContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/slow" {
+			cancel()
+			<-r.Context().Done()
+		}
+	}))
+	defer srv.Close()
+
+	tmpFile := filepath.Join(t.TempDir(), "cancel.yaml")
+	os.WriteFile(tmpFile, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "fast"
+  request:
+    url: "/fast"
+- step: "slow"
+  request:
+    url: "/slow"
+- step: "never"
+  request:
+    url: "/never"
+`, srv.URL)), 0644)
+
+	r := New(10*time.Second, false)
+	r.out = io.Discard
+	start := time.Now()
+	err := r.RunPathsContext(ctx, []string{tmpFile})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunPathsContext() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled run took %s", elapsed)
+	}
+	if strings.Join(paths, ",") != "/fast,/slow" {
+		t.Errorf("requests = %v, want /fast then /slow", paths)
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	tmpFile := filepath.Join(t.TempDir(), "typo.yaml")
+	os.WriteFile(tmpFile, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "health"
+  request:
+    url: "/health"
+  expcet:
+    status: 200
+`, srv.URL)), 0644)
+
+	r := New(10*time.Second, false)
+	r.out = io.Discard
+	err := r.RunPaths([]string{tmpFile})
+	if err == nil || !strings.Contains(err.Error(), `parse `+tmpFile+`: line 8: unknown field "expcet"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r = New(10*time.Second, false, WithLenient(true))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{tmpFile}); err != nil {
+		t.Fatalf("lenient run failed: %v", err)
+	}
+}
+
 func runTest(t *testing.T, yamlContent string) {
 	if err := runTestError(t, yamlContent); err != nil {
 		t.Fatalf("RunPaths failed: %v", err)
@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// jsonSchema is the subset of JSON Schema (draft-07) used to describe
+// workflow files.
+type jsonSchema struct {
+	Schema      string                 `json:"$schema,omitempty"`
+	ID          string                 `json:"$id,omitempty"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Type        interface{}            `json:"type,omitempty"`
+	Properties  map[string]*jsonSchema `json:"properties,omitempty"`
+	// AdditionalProperties is false for structs and the value schema for
+	// maps.
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema `json:"items,omitempty"`
+	Enum                 []string    `json:"enum,omitempty"`
+}
+
+// schemaTypes describes types that unmarshal themselves from YAML.
+var schemaTypes = map[reflect.Type]*jsonSchema{
+	reflect.TypeOf(time.Duration(0)): {Type: "string", Description: "A duration, such as 500ms or 30s."},
+	reflect.TypeOf(ByteSize(0)):      {Type: []string{"string", "integer"}, Description: "A size in bytes, or with a unit such as 10MB."},
+	reflect.TypeOf(RateLimit{}):      {Type: []string{"string", "integer"}, Description: "Requests per unit of time, such as 10/s or 600/m."},
+	reflect.TypeOf(Threshold{}):      {Type: "string", Description: `A limit such as "p95 < 500ms" or "error_rate < 1%".`},
+}
+
+// schemaEnums lists the values allowed for fields that take one of a fixed
+// set, keyed by type and YAML field name.
+var schemaEnums = map[string][]string{
+	"Config.http_version":   {"1.1", "2", "2-prior-knowledge"},
+	"OAuth2Auth.auth_style": {"body", "header"},
+	"APIKeyAuth.in":         {"header", "query"},
+	"Capture.scope":         {ScopeFile, ScopeGlobal},
+	"Capture.mode":          {CaptureFirst, CaptureAll},
+}
+
+// Schema returns a JSON Schema for workflow files, for editors such as VS
+// Code's YAML extension to offer completion and inline validation.
+func Schema() ([]byte, error) {
+	s := schemaFor(reflect.TypeOf(InstructionsFile{}))
+	s.Schema = "http://json-schema.org/draft-07/schema#"
+	s.ID = "https://github.com/michaelmccabe/ramjam/ramjam.schema.json"
+	s.Title = "ramjam workflow"
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// workflowSchema is the schema for InstructionsFile, built once for Lint.
+var workflowSchema = schemaFor(reflect.TypeOf(InstructionsFile{}))
+
+func schemaFor(typ reflect.Type) *jsonSchema {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if s, ok := schemaTypes[typ]; ok {
+		copied := *s
+		return &copied
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: "array", Items: schemaFor(typ.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: schemaFor(typ.Elem())}
+	case reflect.Struct:
+		s := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}, AdditionalProperties: false}
+		addProperties(s, typ)
+		return s
+	}
+	// interface{} values, such as bodies and expected JSON values, can be
+	// anything.
+	return &jsonSchema{}
+}
+
+// addProperties adds a schema for each of a struct's YAML fields, including
+// those of inlined structs.
+func addProperties(s *jsonSchema, typ reflect.Type) {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") {
+			addProperties(s, f.Type)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		prop := schemaFor(f.Type)
+		if enum, ok := schemaEnums[typ.Name()+"."+name]; ok {
+			prop.Enum = enum
+		}
+		s.Properties[name] = prop
+	}
+}
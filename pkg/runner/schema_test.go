@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchema(t *testing.T) {
+	data, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+
+	step := workflowSchema.Properties["workflow"].Items
+	tests := []struct {
+		name string
+		got  *jsonSchema
+		want string
+	}{
+		{"step", step.Properties["step"], `{"type":"string"}`},
+		{"status", step.Properties["expect"].Properties["status"], `{"type":"integer"}`},
+		{"headers", step.Properties["request"].Properties["headers"], `{"type":"object","additionalProperties":{"type":"string"}}`},
+		{"body", step.Properties["request"].Properties["body"], `{"type":"object","additionalProperties":{}}`},
+		{"follow_redirects", step.Properties["request"].Properties["follow_redirects"], `{"type":"boolean"}`},
+		{"http_version", workflowSchema.Properties["config"].Properties["http_version"], `{"type":"string","enum":["1.1","2","2-prior-knowledge"]}`},
+		{"rate_limit", workflowSchema.Properties["config"].Properties["rate_limit"], `{"description":"Requests per unit of time, such as 10/s or 600/m.","type":["string","integer"]}`},
+	}
+	for _, tt := range tests {
+		got, _ := json.Marshal(tt.got)
+		if string(got) != tt.want {
+			t.Errorf("%s schema = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLintSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workflow.yaml")
+	os.WriteFile(path, []byte(`
+config:
+  http_version: "3"
+  auth:
+    oauth2:
+      token_url: "https://auth.example.com/token"
+      client_id: "ramjam"
+      auth_style: "${style}"
+workflow:
+- step: "health"
+  request:
+    url: "https://api.example.com/health"
+    headers:
+      X-Trace: "on"
+  expcet:
+    status: 200
+`), 0644)
+
+	r := New(10*time.Second, false, WithVars(map[string]string{"style": "body"}))
+	problems, err := r.Lint([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, strings.TrimPrefix(p.String(), path))
+	}
+	want := []string{
+		`:3: invalid http_version "3" (expected 1.1, 2, 2-prior-knowledge)`,
+		`:15: unknown field "expcet"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
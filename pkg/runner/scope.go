@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// varSources records where each of a file's variables was last set, for
+// the verbose trace of how a step's variables were resolved.
+//
+// From lowest to highest precedence, a file's variables come from its
+// directory defaults, its vars block, the profile, global captures from
+// other files, a resumed checkpoint and the runner's own variables (--env,
+// --var-file and --var). Captures replace them as steps run, and a step's
+// vars block overrides them all for that step only.
+type varSources map[string]string
+
+// merge copies from into vars, recording source for each variable.
+func (s varSources) merge(vars, from map[string]string, source string) {
+	for k, v := range from {
+		vars[k] = v
+		s[k] = source
+	}
+}
+
+// stepVars sets the variables in step's vars block, substituted with
+// applyVars, for the duration of the step. The returned function restores
+// the values they shadowed, except for variables the step went on to
+// capture. It is always safe to call, even when stepVars returns an error.
+func (s varSources) stepVars(step Step, vars map[string]string, applyVars func(string, map[string]string) (string, error)) (func(), error) {
+	if len(step.Vars) == 0 {
+		return func() {}, nil
+	}
+	type saved struct {
+		value, source string
+		ok            bool
+	}
+	shadowed := map[string]saved{}
+	resolved := map[string]string{}
+	for k, v := range step.Vars {
+		old, ok := vars[k]
+		shadowed[k] = saved{old, s[k], ok}
+		// Values refer to the variables outside the block.
+		value, err := applyVars(v, vars)
+		if err != nil {
+			return func() {}, fmt.Errorf("vars: %w", err)
+		}
+		resolved[k] = value
+	}
+	s.merge(vars, resolved, fmt.Sprintf("vars of step %q", step.Step))
+
+	return func() {
+		captured := map[string]bool{}
+		for _, c := range allCaptures(step) {
+			captured[c.As] = true
+		}
+		for k, old := range shadowed {
+			switch {
+			case captured[k]:
+			case old.ok:
+				vars[k], s[k] = old.value, old.source
+			default:
+				delete(vars, k)
+				delete(s, k)
+			}
+		}
+	}, nil
+}
+
+// captured records the variables step captured, logging any that replaced
+// a different value set elsewhere, so a capture that clobbers a variable
+// is visible in the verbose output.
+func (s varSources) captured(step Step, before, vars map[string]string, log func(string, ...interface{})) {
+	source := fmt.Sprintf("capture in step %q", step.Step)
+	for _, c := range allCaptures(step) {
+		v, ok := vars[c.As]
+		if !ok || c.As == "" {
+			continue
+		}
+		if old, existed := before[c.As]; existed && old != v && log != nil {
+			log("Capture %s replaced %q from %s", c.As, old, s[c.As])
+		}
+		s[c.As] = source
+	}
+}
+
+// trace logs the value and source of every variable step refers to.
+func (s varSources) trace(step Step, vars map[string]string, log func(string, ...interface{})) {
+	data, err := yaml.Marshal(step)
+	if err != nil {
+		return
+	}
+	var refs []string
+	for _, m := range varPattern.FindAllStringSubmatch(string(data), -1) {
+		refs = append(refs, exprVars(m[1])...)
+	}
+	if expr, wrapped := conditionExpr(step.When); !wrapped {
+		_, whenVars, _ := parseExpr(expr)
+		refs = append(refs, whenVars...)
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, name := range refs {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v, ok := vars[name]; ok {
+			log("Variable %s = %q (from %s)", name, v, s[name])
+		} else {
+			log("Variable %s is not set", name)
+		}
+	}
+}
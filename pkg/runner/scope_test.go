@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVariableScopes(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.String())
+		w.Write([]byte(`{"page": 2}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, DefaultsFileName), []byte("variables:\n  region: eu\n  tenant: acme\n"), 0644)
+	path := filepath.Join(dir, "scopes.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+vars:
+  region: "us"
+  page: "1"
+  home: "${tenant}-home"
+workflow:
+- step: "one"
+  request:
+    url: "/one?region=${region}&page=${page}&home=${home}&token=${token}"
+  capture:
+  - json_path: "page"
+    as: "page"
+- step: "two"
+  vars:
+    region: "ap"
+    limit: "${page}0"
+  request:
+    url: "/two?region=${region}&limit=${limit}&page=${page}"
+- step: "three"
+  request:
+    url: "/three?region=${region}&limit=${limit}"
+`, srv.URL)), 0644)
+
+	r := New(10*time.Second, true, WithOutput(io.Discard), WithVars(map[string]string{"token": "cli"}))
+	result, err := r.Run(context.Background(), []string{path})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := []string{
+		"/one?region=us&page=1&home=acme-home&token=cli",
+		"/two?region=ap&limit=20&page=2",
+		"/three?region=us&limit=${limit}",
+	}
+	if strings.Join(seen, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = %q, want %q", seen, want)
+	}
+
+	steps := result.Files[0].Steps
+	for _, tt := range []struct {
+		step int
+		line string
+	}{
+		{0, `Variable token = "cli" (from command line)`},
+		{0, `Capture page replaced "1" from file vars`},
+		{1, `Variable region = "ap" (from vars of step "two")`},
+		{1, `Variable page = "2" (from capture in step "one")`},
+		{2, `Variable limit is not set`},
+	} {
+		if logs := strings.Join(steps[tt.step].Logs, "\n"); !strings.Contains(logs, tt.line) {
+			t.Errorf("step %s logs missing %q:\n%s", steps[tt.step].Name, tt.line, logs)
+		}
+	}
+}
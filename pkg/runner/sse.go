@@ -0,0 +1,154 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+const defaultSSEMaxWait = 10 * time.Second
+
+// SSEExpect asserts on the events of a text/event-stream response. Each entry
+// in Events is matched against the next event received, in order.
+type SSEExpect struct {
+	MaxWait time.Duration    `yaml:"max_wait"`
+	Events  []SSEEventExpect `yaml:"events"`
+}
+
+// SSEEventExpect holds assertions and captures for a single event.
+type SSEEventExpect struct {
+	Event         string        `yaml:"event,omitempty"`
+	DataContains  string        `yaml:"data_contains,omitempty"`
+	JSONPathMatch []JSONPathVal `yaml:"json_path_match"`
+	Capture       []Capture     `yaml:"capture"`
+}
+
+type sseEvent struct {
+	Name string
+	Data string
+	ID   string
+}
+
+func (r *Runner) checkSSE(body io.ReadCloser, expect *SSEExpect, vars map[string]string, log func(string, ...interface{})) error {
+	maxWait := expect.MaxWait
+	if maxWait <= 0 {
+		maxWait = defaultSSEMaxWait
+	}
+
+	events := make(chan sseEvent)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		readErr <- readSSE(body, events, done)
+	}()
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	for i, want := range expect.Events {
+		var ev sseEvent
+		select {
+		case ev = <-events:
+		case err := <-readErr:
+			if err == nil {
+				err = io.EOF
+			}
+			return fmt.Errorf("event stream ended after %d of %d events: %w", i, len(expect.Events), err)
+		case <-timer.C:
+			body.Close()
+			return fmt.Errorf("received %d of %d events within %s", i, len(expect.Events), maxWait)
+		}
+
+		if r.verbose() {
+			log("Received event %q: %s", ev.Name, ev.Data)
+		}
+		if err := r.checkSSEEvent(ev, want, vars, log); err != nil {
+			return e.Wrapf(err, "sse event %d", i)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) checkSSEEvent(ev sseEvent, want SSEEventExpect, vars map[string]string, log func(string, ...interface{})) error {
+	if want.Event != "" {
+		expected, err := r.applyVars(want.Event, vars)
+		if err != nil {
+			return err
+		}
+		if ev.Name != expected {
+			return fmt.Errorf("expected event %q, got %q", expected, ev.Name)
+		}
+	}
+	if want.DataContains != "" {
+		expected, err := r.applyVars(want.DataContains, vars)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(ev.Data, expected) {
+			return fmt.Errorf("expected data to contain %q, got %q", expected, ev.Data)
+		}
+	}
+	if len(want.JSONPathMatch) == 0 && len(want.Capture) == 0 {
+		return nil
+	}
+
+	var jsonObj interface{}
+	if err := e.Wrap(json.Unmarshal([]byte(ev.Data), &jsonObj), "parse event data json"); err != nil {
+		return err
+	}
+	if err := r.matchJSONPaths(jsonObj, want.JSONPathMatch, vars, log); err != nil {
+		return err
+	}
+	return r.captureValues(want.Capture, jsonObj, nil, vars, log)
+}
+
+// readSSE parses a text/event-stream and delivers each dispatched event until
+// the stream ends or done is closed.
+func readSSE(body io.Reader, events chan<- sseEvent, done <-chan struct{}) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var ev sseEvent
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) == 0 {
+				ev = sseEvent{}
+				continue
+			}
+			ev.Data = strings.Join(data, "\n")
+			if ev.Name == "" {
+				ev.Name = "message"
+			}
+			select {
+			case events <- ev:
+			case <-done:
+				return nil
+			}
+			ev, data = sseEvent{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Name = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			ev.ID = value
+		}
+	}
+	return scanner.Err()
+}
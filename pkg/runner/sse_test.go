@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEExpectations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("expected Accept text/event-stream, got %s", r.Header.Get("Accept"))
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "event: job\ndata: {\"id\": \"j-9\", \"state\": \"queued\"}\n\n")
+		flusher.Flush()
+		fmt.Fprint(w, "event: job\nid: 2\ndata: {\"id\": \"j-9\",\ndata:  \"state\": \"done\"}\n\n")
+		flusher.Flush()
+		// Keep the stream open; the runner should stop after the expected events.
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "SSE"
+config:
+  base_url: "%s"
+workflow:
+- step: "job-events"
+  request:
+    url: "/events"
+  expect:
+    status: 200
+    sse:
+      max_wait: 2s
+      events:
+      - event: "job"
+        json_path_match:
+        - path: "state"
+          value: "queued"
+        capture:
+        - json_path: "id"
+          as: "job_id"
+      - event: "job"
+        data_contains: "done"
+        json_path_match:
+        - path: "id"
+          value: "${job_id}"
+  output:
+    print: "Job ${job_id} finished"
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
+
+func TestSSEMaxWait(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "SSE Timeout"
+config:
+  base_url: "%s"
+workflow:
+- step: "slow-events"
+  request:
+    url: "/events"
+  expect:
+    sse:
+      max_wait: 100ms
+      events:
+      - event: "message"
+        data_contains: "first"
+      - data_contains: "second"
+`, srv.URL)
+
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), "received 1 of 2 events within 100ms") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
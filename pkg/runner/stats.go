@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// LatencyStats summarizes a set of durations, in milliseconds.
+// Percentiles use the nearest-rank method.
+type LatencyStats struct {
+	MinMS    float64 `json:"min_ms"`
+	MeanMS   float64 `json:"mean_ms"`
+	P50MS    float64 `json:"p50_ms"`
+	P95MS    float64 `json:"p95_ms"`
+	P99MS    float64 `json:"p99_ms"`
+	MaxMS    float64 `json:"max_ms"`
+	StdDevMS float64 `json:"stddev_ms"`
+}
+
+// latencyStats computes the statistics of samples, which it sorts in place.
+func latencyStats(samples []time.Duration) LatencyStats {
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	var sum float64
+	for _, d := range samples {
+		sum += milliseconds(d)
+	}
+	mean := sum / float64(len(samples))
+	var squares float64
+	for _, d := range samples {
+		squares += (milliseconds(d) - mean) * (milliseconds(d) - mean)
+	}
+
+	return LatencyStats{
+		MinMS:    milliseconds(samples[0]),
+		MeanMS:   mean,
+		P50MS:    milliseconds(percentile(samples, 50)),
+		P95MS:    milliseconds(percentile(samples, 95)),
+		P99MS:    milliseconds(percentile(samples, 99)),
+		MaxMS:    milliseconds(samples[len(samples)-1]),
+		StdDevMS: math.Sqrt(squares / float64(len(samples))),
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted samples.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,31 @@
+package runner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyStats(t *testing.T) {
+	var samples []time.Duration
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	got := latencyStats(samples)
+	want := LatencyStats{MinMS: 1, MeanMS: 50.5, P50MS: 50, P95MS: 95, P99MS: 99, MaxMS: 100}
+	stddev := got.StdDevMS
+	got.StdDevMS = 0
+	if got != want {
+		t.Errorf("latencyStats = %+v, want %+v", got, want)
+	}
+	if stddev < 28.86 || stddev > 28.87 {
+		t.Errorf("stddev = %v, want 28.866", stddev)
+	}
+
+	one := latencyStats([]time.Duration{3 * time.Millisecond})
+	if one.P99MS != 3 || one.StdDevMS != 0 {
+		t.Errorf("single sample = %+v", one)
+	}
+	if (latencyStats(nil) != LatencyStats{}) {
+		t.Error("expected zero stats for no samples")
+	}
+}
@@ -0,0 +1,82 @@
+package runner
+
+import "fmt"
+
+// WithSteps runs only the named steps of each file, together with the steps
+// they list under needs, so one request in a long workflow can be tried on
+// its own. A file without one of the steps fails.
+func WithSteps(names []string) Option {
+	return func(r *Runner) {
+		r.steps = names
+	}
+}
+
+// WithFromStep starts each file at the named step, skipping the steps
+// before it, so a long workflow can be resumed after a failing step is
+// fixed without repeating earlier calls. Variables those steps would have
+// captured can be given with WithVars. A file without the step fails.
+func WithFromStep(name string) Option {
+	return func(r *Runner) {
+		r.fromStep = name
+	}
+}
+
+// stepsFrom returns the steps from the first one called name onwards.
+func stepsFrom(steps []Step, name string) ([]Step, error) {
+	for i, step := range steps {
+		if step.Step == name {
+			return steps[i:], nil
+		}
+	}
+	return nil, fmt.Errorf("no step named %q", name)
+}
+
+// selectSteps returns the steps called names and, transitively, the steps
+// they need, in workflow order.
+func selectSteps(steps []Step, names []string) ([]Step, error) {
+	index := map[string]int{}
+	for i, step := range steps {
+		if _, ok := index[step.Step]; !ok {
+			index[step.Step] = i
+		}
+	}
+
+	keep := make([]bool, len(steps))
+	var visit func(i int) error
+	visit = func(i int) error {
+		if keep[i] {
+			return nil
+		}
+		keep[i] = true
+		for _, need := range steps[i].Needs {
+			j, ok := index[need]
+			if !ok {
+				return fmt.Errorf("step %q needs unknown step %q", steps[i].Step, need)
+			}
+			if j >= i {
+				return fmt.Errorf("step %q needs %q, which comes after it", steps[i].Step, need)
+			}
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, name := range names {
+		i, ok := index[name]
+		if !ok {
+			return nil, fmt.Errorf("no step named %q", name)
+		}
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	var selected []Step
+	for i, step := range steps {
+		if keep[i] {
+			selected = append(selected, step)
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,126 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithSteps(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Path)
+		w.Write([]byte(`{"token": "abc", "id": 7}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "users.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "login"
+  request:
+    url: "/login"
+  capture:
+  - json_path: "token"
+    as: "token"
+- step: "list-users"
+  request:
+    url: "/users"
+- step: "create-user"
+  needs: ["login"]
+  request:
+    method: "POST"
+    url: "/users?token=${token}"
+- step: "delete-user"
+  needs: ["create-user"]
+  request:
+    method: "DELETE"
+    url: "/users/7"
+`, srv.URL)), 0644)
+
+	tests := []struct {
+		steps []string
+		want  string
+		err   string
+	}{
+		{[]string{"list-users"}, "/users", ""},
+		{[]string{"create-user"}, "/login,/users", ""},
+		{[]string{"delete-user", "list-users"}, "/login,/users,/users,/users/7", ""},
+		{[]string{"missing"}, "", `no step named "missing"`},
+	}
+	for _, tt := range tests {
+		seen = nil
+		r := New(10*time.Second, false, WithSteps(tt.steps))
+		r.out = io.Discard
+		err := r.RunPaths([]string{path})
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("steps %v: expected error %q, got %v", tt.steps, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("steps %v: RunPaths failed: %v", tt.steps, err)
+		}
+		if got := strings.Join(seen, ","); got != tt.want {
+			t.Errorf("steps %v: requests = %s, want %s", tt.steps, got, tt.want)
+		}
+	}
+
+	if _, err := selectSteps([]Step{{Step: "a", Needs: []string{"b"}}, {Step: "b"}}, []string{"a"}); err == nil || err.Error() != `step "a" needs "b", which comes after it` {
+		t.Errorf("expected a forward need to fail, got %v", err)
+	}
+}
+
+func TestWithFromStep(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Method+" "+r.URL.String())
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "orders.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "create"
+  request:
+    method: "POST"
+    url: "/orders"
+  capture:
+  - header: "Location"
+    as: "order"
+- step: "pay"
+  request:
+    method: "POST"
+    url: "/orders/${order}/pay"
+- step: "ship"
+  request:
+    method: "POST"
+    url: "/orders/${order}/ship"
+`, srv.URL)), 0644)
+
+	r := New(10*time.Second, false, WithFromStep("pay"), WithVars(map[string]string{"order": "42"}))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{path}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+	if got := strings.Join(seen, ","); got != "POST /orders/42/pay,POST /orders/42/ship" {
+		t.Errorf("requests = %s", got)
+	}
+
+	r = New(10*time.Second, false, WithFromStep("refund"))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{path}); err == nil || !strings.Contains(err.Error(), `no step named "refund"`) {
+		t.Errorf("expected a missing step error, got %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ByteSize is a size in bytes that may be written in YAML as a plain integer
+// or with a unit suffix such as "512KB", "10MB" or "1GB".
+type ByteSize int64
+
+func (b *ByteSize) UnmarshalYAML(node *yaml.Node) error {
+	size, err := parseByteSize(node.Value)
+	if err != nil {
+		return err
+	}
+	*b = ByteSize(size)
+	return nil
+}
+
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
+
+// bodyStream consumes a response body once, feeding every byte to the
+// streaming assertions (digest, contains, regex) and to output.save_body,
+// while buffering at most maxSize bytes for JSON parsing. Bodies nothing
+// needs in full are only buffered as far as the failure excerpt.
+type bodyStream struct {
+	buf       bytes.Buffer
+	maxSize   int64
+	truncated bool
+	size      int64
+
+	hash     hash.Hash
+	contains *containsWriter
+	regex    *regexWriter
+	file     *os.File
+}
+
+func (r *Runner) readBody(body io.Reader, step Step, vars map[string]string, log func(string, ...interface{})) (*bodyStream, error) {
+	bs := &bodyStream{maxSize: int64(step.file.config.MaxBodySize)}
+	if !r.needsBody(step) {
+		bs.maxSize = bodyExcerptSize + 1
+	}
+	writers := []io.Writer{bs}
+
+	if step.Expect.SHA256 != "" {
+		bs.hash = sha256.New()
+		writers = append(writers, bs.hash)
+	}
+	if step.Expect.BodyContains != "" {
+		needle, err := r.applyVars(step.Expect.BodyContains, vars)
+		if err != nil {
+			return nil, err
+		}
+		bs.contains = newContainsWriter(needle)
+		writers = append(writers, bs.contains)
+	}
+	if step.Expect.BodyRegex != "" {
+		pattern, err := r.applyVars(step.Expect.BodyRegex, vars)
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(pattern)
+		if err := e.Wrapf(err, "invalid body_regex %s", step.Expect.BodyRegex); err != nil {
+			return nil, err
+		}
+		bs.regex = newRegexWriter(re)
+		writers = append(writers, bs.regex)
+	}
+	if step.Output.SaveBody != "" {
+		f, err := r.createSaveBodyFile(step, vars)
+		if err != nil {
+			return nil, err
+		}
+		bs.file = f
+		writers = append(writers, f)
+	}
+
+	_, copyErr := io.Copy(io.MultiWriter(writers...), body)
+	if bs.regex != nil {
+		bs.regex.close()
+	}
+	if bs.file != nil {
+		if err := e.Wrapf(bs.file.Close(), "save body to %s", bs.file.Name()); err != nil && copyErr == nil {
+			copyErr = err
+		}
+		if r.verbose() {
+			log("Saved %d bytes to %s", bs.size, bs.file.Name())
+		}
+	}
+	if err := e.Wrap(copyErr, "read body"); err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+// needsBody reports whether anything reads step's response body in full,
+// rather than as it streams past: JSON parsing, an OpenAPI contract,
+// custom assertions, recorded response bodies or the debug dump.
+func (r *Runner) needsBody(step Step) bool {
+	return needsJSON(step) || step.Expect.BodyFormat == "json" || len(step.Expect.Custom) > 0 ||
+		step.file.contract != nil || r.debug() || (r.responses != nil && r.responses.bodies)
+}
+
+// Write counts and buffers body bytes up to maxSize; bytes beyond the limit
+// are still seen by the streaming assertions but are not retained.
+func (bs *bodyStream) Write(p []byte) (int, error) {
+	bs.size += int64(len(p))
+	if bs.maxSize <= 0 {
+		return bs.buf.Write(p)
+	}
+	if room := bs.maxSize - int64(bs.buf.Len()); room < int64(len(p)) {
+		bs.truncated = true
+		if room > 0 {
+			bs.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return bs.buf.Write(p)
+}
+
+// Bytes returns the buffered body, failing if it was cut off by max_body_size.
+func (bs *bodyStream) Bytes() ([]byte, error) {
+	if bs.truncated {
+		return nil, fmt.Errorf("response body of %d bytes exceeds max_body_size %d", bs.size, bs.maxSize)
+	}
+	return bs.buf.Bytes(), nil
+}
+
+// checkBodyStream evaluates the streaming assertions once the body has been consumed.
+func (r *Runner) checkBodyStream(bs *bodyStream, expect StepExpect, vars map[string]string, log func(string, ...interface{})) error {
+	if expect.ContentLength != nil {
+		if r.verbose() {
+			log("Asserting content length == %d", *expect.ContentLength)
+		}
+		if bs.size != *expect.ContentLength {
+			return &ExpectationError{fmt.Sprintf("expected content length %d, got %d", *expect.ContentLength, bs.size), strconv.FormatInt(*expect.ContentLength, 10), strconv.FormatInt(bs.size, 10)}
+		}
+	}
+
+	if bs.hash != nil {
+		expected, err := r.applyVars(expect.SHA256, vars)
+		if err != nil {
+			return err
+		}
+		expected = strings.ToLower(strings.TrimSpace(expected))
+		actual := hex.EncodeToString(bs.hash.Sum(nil))
+		if r.verbose() {
+			log("Asserting sha256 == %s", expected)
+		}
+		if actual != expected {
+			return &ExpectationError{fmt.Sprintf("expected sha256 %s, got %s", expected, actual), expected, actual}
+		}
+	}
+
+	if bs.contains != nil {
+		if r.verbose() {
+			log("Asserting body contains %s", bs.contains.needle)
+		}
+		if !bs.contains.found {
+			return &ExpectationError{fmt.Sprintf("expected body to contain %q", bs.contains.needle), bs.contains.needle, excerpt(bs.buf.Bytes())}
+		}
+	}
+
+	if bs.regex != nil {
+		if r.verbose() {
+			log("Asserting body matches %s", bs.regex.re)
+		}
+		if !bs.regex.matched {
+			return &ExpectationError{fmt.Sprintf("expected body to match regex %s", bs.regex.re), bs.regex.re.String(), excerpt(bs.buf.Bytes())}
+		}
+	}
+	return nil
+}
+
+// createSaveBodyFile opens output.save_body, resolved relative to the
+// workflow file, for writing.
+func (r *Runner) createSaveBodyFile(step Step, vars map[string]string) (*os.File, error) {
+	path, err := r.applyVars(step.Output.SaveBody, vars)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(step.file.baseDir, path)
+	}
+	if err := e.Wrapf(os.MkdirAll(filepath.Dir(path), 0755), "create directory for %s", path); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err := e.Wrapf(err, "save body to %s", path); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// containsWriter searches for a substring across write boundaries, keeping
+// only the last len(needle)-1 bytes between writes.
+type containsWriter struct {
+	needle string
+	tail   []byte
+	found  bool
+}
+
+func newContainsWriter(needle string) *containsWriter {
+	return &containsWriter{needle: needle}
+}
+
+func (c *containsWriter) Write(p []byte) (int, error) {
+	if c.found || c.needle == "" {
+		c.found = true
+		return len(p), nil
+	}
+	window := append(c.tail, p...)
+	if bytes.Contains(window, []byte(c.needle)) {
+		c.found = true
+		c.tail = nil
+		return len(p), nil
+	}
+	keep := len(c.needle) - 1
+	if len(window) > keep {
+		window = window[len(window)-keep:]
+	}
+	c.tail = append([]byte(nil), window...)
+	return len(p), nil
+}
+
+// regexWriter feeds the body through a pipe into regexp.MatchReader, which
+// scans its input without retaining it.
+type regexWriter struct {
+	re      *regexp.Regexp
+	pw      *io.PipeWriter
+	done    chan struct{}
+	matched bool
+}
+
+func newRegexWriter(re *regexp.Regexp) *regexWriter {
+	pr, pw := io.Pipe()
+	w := &regexWriter{re: re, pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		w.matched = re.MatchReader(bufio.NewReader(pr))
+		// Drain the remainder so writers are never blocked after a match.
+		io.Copy(io.Discard, pr)
+	}()
+	return w
+}
+
+func (w *regexWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *regexWriter) close() {
+	w.pw.Close()
+	<-w.done
+}
@@ -0,0 +1,224 @@
+package runner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveBodyWithChecksum(t *testing.T) {
+	payload := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0x10, 0x20}
+	sum := sha256.Sum256(payload)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(payload)
+	}))
+	defer srv.Close()
+
+	tmpDir := t.TempDir()
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Download"
+config:
+  base_url: "%s"
+workflow:
+- step: "download-logo"
+  request:
+    url: "/logo.png"
+  expect:
+    status: 200
+    content_length: %d
+    sha256: "%s"
+  output:
+    save_body: "downloads/logo.png"
+`, srv.URL, len(payload), strings.ToUpper(hex.EncodeToString(sum[:])))
+
+	yamlFilePath := filepath.Join(tmpDir, "download.yaml")
+	if err := os.WriteFile(yamlFilePath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write yaml file: %v", err)
+	}
+
+	r := New(10*time.Second, true)
+	if err := r.RunPaths([]string{yamlFilePath}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+
+	saved, err := os.ReadFile(filepath.Join(tmpDir, "downloads", "logo.png"))
+	if err != nil {
+		t.Fatalf("expected saved body: %v", err)
+	}
+	if !bytes.Equal(saved, payload) {
+		t.Errorf("saved body mismatch: %v", saved)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not the file you wanted"))
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Checksum Mismatch"
+config:
+  base_url: "%s"
+workflow:
+- step: "download"
+  request:
+    url: "/file.bin"
+  expect:
+    sha256: "0000000000000000000000000000000000000000000000000000000000000000"
+`, srv.URL)
+
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), "expected sha256 0000") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestStreamingAssertionsOnLargeBody(t *testing.T) {
+	chunk := strings.Repeat("abcdefghij", 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 2000; i++ {
+			w.Write([]byte(chunk))
+			if i == 1500 {
+				w.Write([]byte("MARKER-12345"))
+			}
+		}
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Large Body"
+config:
+  base_url: "%s"
+  max_body_size: 1KB
+workflow:
+- step: "export"
+  request:
+    url: "/export"
+  expect:
+    status: 200
+    body_contains: "MARKER-123"
+    body_regex: "MARKER-[0-9]{5}"
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
+
+func TestMaxBodySizeExceeded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items": [` + strings.Repeat(`"x",`, 500) + `"y"]}`))
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Body Too Large"
+config:
+  base_url: "%s"
+  max_body_size: 100
+workflow:
+- step: "items"
+  request:
+    url: "/items"
+  expect:
+    json_path_match:
+    - path: "items[0]"
+      value: "x"
+`, srv.URL)
+
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), "exceeds max_body_size 100") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestBodyContainsFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "Contains Failure"
+config:
+  base_url: "%s"
+workflow:
+- step: "greeting"
+  request:
+    url: "/"
+  expect:
+    body_contains: "goodbye"
+`, srv.URL)
+
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), `expected body to contain "goodbye"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":    512,
+		"10B":    10,
+		"2KB":    2048,
+		"10MB":   10 << 20,
+		" 1gb ":  1 << 30,
+		"100 KB": 100 << 10,
+	}
+	for in, want := range tests {
+		got, err := parseByteSize(in)
+		if err != nil {
+			t.Errorf("parseByteSize(%q) error = %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("parseByteSize(%q) = %d, want %d", in, got, want)
+		}
+	}
+	if _, err := parseByteSize("ten MB"); err == nil {
+		t.Error("expected error for invalid size")
+	}
+}
+
+func TestUnneededBodyIsNotBuffered(t *testing.T) {
+	body := strings.Repeat("0123456789", 100000)
+	sum := sha256.Sum256([]byte(body))
+
+	for _, tt := range []struct {
+		name   string
+		expect StepExpect
+		full   bool
+	}{
+		{"checksum only", StepExpect{SHA256: hex.EncodeToString(sum[:])}, false},
+		{"contains only", StepExpect{BodyContains: "789012"}, false},
+		{"json format", StepExpect{BodyFormat: "json"}, true},
+		{"custom assertion", StepExpect{Custom: map[string]interface{}{"x": nil}}, true},
+	} {
+		r := New(10*time.Second, false)
+		step := Step{Expect: tt.expect, file: &fileContext{}}
+		bs, err := r.readBody(strings.NewReader(body), step, map[string]string{}, nil)
+		if err != nil {
+			t.Fatalf("%s: readBody failed: %v", tt.name, err)
+		}
+		if err := r.checkBodyStream(bs, step.Expect, nil, nil); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if full := bs.buf.Len() == len(body); full != tt.full || bs.size != int64(len(body)) {
+			t.Errorf("%s: buffered %d of %d bytes", tt.name, bs.buf.Len(), bs.size)
+		}
+	}
+}
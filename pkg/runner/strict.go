@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WithStrictVars fails a step before it sends its request if anything it
+// would substitute refers to a variable that isn't set, instead of sending
+// the ${name} text as it is.
+func WithStrictVars(strict bool) Option {
+	return func(r *Runner) {
+		r.strictVars = strict
+	}
+}
+
+// checkVars returns an error for a ${name:?message} whose variable is unset
+// or empty, or, if the runner is strict about variables, naming the
+// variables step refers to that aren't set in vars. Config and runner
+// headers and auth count as part of the step, since they are sent with its
+// request. Variables the step captures itself are taken as set, as its
+// expectations and output may refer to them. The when expression has
+// already been evaluated by then.
+func (r *Runner) checkVars(step Step, vars map[string]string) error {
+	values := []interface{}{step, r.headers}
+	if step.file != nil {
+		cfg := step.file.config
+		values = append(values, cfg.Headers, cfg.Auth, cfg.UserAgent)
+	}
+	if step.Request.BodyFile != "" {
+		values = append(values, step.Request.bodyData)
+	}
+	captured := map[string]bool{}
+	for _, c := range allCaptures(step) {
+		captured[c.As] = true
+	}
+
+	var missing []string
+	for _, v := range values {
+		var node yaml.Node
+		if err := node.Encode(v); err != nil {
+			continue
+		}
+		for _, ref := range varRefs(&node, "", nil) {
+			value, ok := vars[ref.name]
+			switch {
+			case captured[ref.name], ref.op == "-", ref.key == "when":
+			case ref.op == "?" && value == "":
+				if ref.arg == "" {
+					return fmt.Errorf("variable ${%s} is not set", ref.name)
+				}
+				return fmt.Errorf("%s: %s", ref.name, ref.arg)
+			case ok, !r.strictVars:
+			case ref.key == "string_to_sign" && slices.Contains(hmacPlaceholders, ref.name):
+			case !slices.Contains(missing, ref.name):
+				missing = append(missing, ref.name)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("undefined variable ${%s}", strings.Join(missing, "}, ${"))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStrictVars(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Path)
+		w.Write([]byte(`{"id": "7"}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "strict.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "create"
+  request:
+    url: "/orders"
+  capture:
+  - json_path: "id"
+    as: "order"
+  output:
+    print: "created ${order}"
+- step: "fetch"
+  request:
+    url: "/orders/${order}"
+    headers:
+      X-Tenant: "${tenant}"
+- step: "lines"
+  request:
+    url: "/orders/${order_id}/lines"
+`, srv.URL)), 0644)
+
+	r := New(10*time.Second, false)
+	r.out = io.Discard
+	if err := r.RunPaths([]string{path}); err != nil {
+		t.Fatalf("expected unset variables to be sent as they are, got %v", err)
+	}
+	if got := strings.Join(seen, ","); got != "/orders,/orders/7,/orders/${order_id}/lines" {
+		t.Errorf("requests = %s", got)
+	}
+
+	seen = nil
+	r = New(10*time.Second, false, WithStrictVars(true))
+	r.out = io.Discard
+	err := r.RunPaths([]string{path})
+	if err == nil || !strings.Contains(err.Error(), `step "fetch" in `+path+` failed: undefined variable ${tenant}`) ||
+		!strings.Contains(err.Error(), `step "lines" in `+path+` failed: undefined variable ${order_id}`) {
+		t.Errorf("expected undefined variable errors, got %v", err)
+	}
+	if got := strings.Join(seen, ","); got != "/orders" {
+		t.Errorf("strict requests = %s", got)
+	}
+}
+
+func TestFallbackVars(t *testing.T) {
+	vars := map[string]string{"region": "eu", "empty": ""}
+	for _, tt := range []struct{ input, want string }{
+		{"${region:-us}", "eu"},
+		{"${tenant:-acme}", "acme"},
+		{"${empty:-none}", "none"},
+		{"${tenant:-}", ""},
+		{"/v1/${tenant:-a:b}/x", "/v1/a:b/x"},
+		{"${token:?log in first}", "${token:?log in first}"},
+	} {
+		if got, err := (&Runner{}).applyVars(tt.input, vars); err != nil || got != tt.want {
+			t.Errorf("applyVars(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.String())
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "fallback.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+workflow:
+- step: "list"
+  request:
+    url: "/orders?limit=${limit:-20}"
+- step: "pay"
+  request:
+    url: "/orders/pay"
+    headers:
+      Authorization: "Bearer ${token:?set token with --var token=...}"
+`, srv.URL)), 0644)
+
+	r := New(10*time.Second, false)
+	r.out = io.Discard
+	err := r.RunPaths([]string{path})
+	if err == nil || !strings.Contains(err.Error(), "failed: token: set token with --var token=...") {
+		t.Errorf("expected the required variable to fail the step, got %v", err)
+	}
+	if got := strings.Join(seen, ","); got != "/orders?limit=20" {
+		t.Errorf("requests = %s", got)
+	}
+
+	seen = nil
+	r = New(10*time.Second, false, WithVars(map[string]string{"limit": "5", "token": "t"}))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{path}); err != nil {
+		t.Fatalf("RunPaths failed: %v", err)
+	}
+	if got := strings.Join(seen, ","); got != "/orders?limit=5,/orders/pay" {
+		t.Errorf("requests = %s", got)
+	}
+
+	problems, err := New(10*time.Second, false).Lint([]string{path})
+	if err != nil || len(problems) != 1 || !strings.Contains(problems[0].Message, "undefined variable ${token}") {
+		t.Errorf("expected only the required variable to be reported, got %v, %v", problems, err)
+	}
+}
@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is how many unchanged lines are kept around each change.
+	diffContext = 3
+	// maxDiffCells bounds the work lineDiff does; larger inputs are shown
+	// as a removal of one and an addition of the other.
+	maxDiffCells = 1 << 20
+)
+
+// diffable reports whether v is better compared line by line.
+func diffable(v interface{}) bool {
+	switch v := v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	case string:
+		return strings.Contains(v, "\n")
+	}
+	return false
+}
+
+// prettyValue renders strings as they are and anything else as indented
+// JSON.
+func prettyValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// lineDiff compares two texts line by line. Each returned line starts with
+// "-" if it is only in a, "+" if it is only in b, or " " if it is in both;
+// runs of unchanged lines far from a change are collapsed to "...".
+func lineDiff(a, b string) []string {
+	as, bs := strings.Split(a, "\n"), strings.Split(b, "\n")
+	if len(as)*len(bs) > maxDiffCells {
+		var lines []string
+		for _, l := range as {
+			lines = append(lines, "-"+l)
+		}
+		for _, l := range bs {
+			lines = append(lines, "+"+l)
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of as[i:]
+	// and bs[j:].
+	lcs := make([][]int, len(as)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bs)+1)
+	}
+	for i := len(as) - 1; i >= 0; i-- {
+		for j := len(bs) - 1; j >= 0; j-- {
+			if as[i] == bs[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(as) || j < len(bs) {
+		switch {
+		case i < len(as) && j < len(bs) && as[i] == bs[j]:
+			lines = append(lines, " "+as[i])
+			i++
+			j++
+		case i < len(as) && (j == len(bs) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+as[i])
+			i++
+		default:
+			lines = append(lines, "+"+bs[j])
+			j++
+		}
+	}
+	return trimContext(lines)
+}
+
+// trimContext drops unchanged lines more than diffContext lines from a
+// change, marking each gap with "...".
+func trimContext(lines []string) []string {
+	keep := make([]bool, len(lines))
+	for i, l := range lines {
+		if l[0] == ' ' {
+			continue
+		}
+		for k := max(0, i-diffContext); k <= min(len(lines)-1, i+diffContext); k++ {
+			keep[k] = true
+		}
+	}
+	var out []string
+	skipped := false
+	for i, l := range lines {
+		if !keep[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			out = append(out, "...")
+			skipped = false
+		}
+		out = append(out, l)
+	}
+	if skipped {
+		out = append(out, "...")
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{"changed", "a\nb\nc", "a\nx\nc", []string{" a", "-b", "+x", " c"}},
+		{"added", "a\nc", "a\nb\nc", []string{" a", "+b", " c"}},
+		{"context", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10", "1\n2\n3\n4\n5\nfive\n7\n8\n9\n10",
+			[]string{"...", " 3", " 4", " 5", "-6", "+five", " 7", " 8", " 9", "..."}},
+	}
+	for _, tt := range tests {
+		if got := lineDiff(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: lineDiff() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExpectJsonPathObjectDiff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"user": {"id": 7, "name": "Ada", "roles": ["admin"]}}`))
+	}))
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "JSONPath Diff"
+config:
+  base_url: "%s"
+workflow:
+- step: "user"
+  request:
+    method: "GET"
+    url: "/"
+  expect:
+    json_path_match:
+    - path: "user"
+      value:
+        id: 7
+        name: "Ada"
+        roles: ["viewer"]
+`, srv.URL)
+
+	err := runTestError(t, yamlContent)
+	var expectErr *ExpectationError
+	if !errors.As(err, &expectErr) {
+		t.Fatalf("expected an ExpectationError, got %v", err)
+	}
+	for _, want := range []string{"jsonpath user does not match (-expected +actual)", `-    "viewer"`, `+    "admin"`, `   "name": "Ada",`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in error, got:\n%v", want, err)
+		}
+	}
+
+	var out bytes.Buffer
+	(&textReporter{out: &out, color: true}).file(fileResult{name: "Diff", steps: []stepResult{{name: "user", err: err}}})
+	for _, want := range []string{ansiRed + `-    "viewer"` + ansiReset, ansiGreen + `+    "admin"` + ansiReset} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in colored output, got %q", want, out.String())
+		}
+	}
+}
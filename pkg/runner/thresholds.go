@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Threshold is a limit on a file's results across a load test or soak run,
+// written as "<metric> < <limit>", such as "p95 < 500ms" or
+// "error_rate < 1%". Latency metrics are those in BenchMetrics.
+type Threshold struct {
+	Metric string
+	// Limit is in milliseconds for latency metrics and a ratio for
+	// error_rate.
+	Limit     float64
+	Inclusive bool // <= rather than <
+	text      string
+}
+
+func (t *Threshold) UnmarshalYAML(node *yaml.Node) error {
+	threshold, err := ParseThreshold(node.Value)
+	if err != nil {
+		return err
+	}
+	*t = threshold
+	return nil
+}
+
+func (t Threshold) MarshalYAML() (interface{}, error) {
+	return t.String(), nil
+}
+
+func (t Threshold) String() string {
+	return t.text
+}
+
+// ParseThreshold parses a threshold such as "p95 < 500ms".
+func ParseThreshold(s string) (Threshold, error) {
+	t := Threshold{text: strings.TrimSpace(s)}
+	metric, limit, found := strings.Cut(t.text, "<")
+	if !found {
+		return Threshold{}, fmt.Errorf("invalid threshold %q (expected <metric> < <limit>, such as p95 < 500ms)", s)
+	}
+	if strings.HasPrefix(limit, "=") {
+		t.Inclusive, limit = true, limit[1:]
+	}
+	t.Metric, limit = strings.TrimSpace(metric), strings.TrimSpace(limit)
+
+	switch {
+	case t.Metric == "error_rate":
+		pct, ok := strings.CutSuffix(limit, "%")
+		n, err := strconv.ParseFloat(strings.TrimSpace(pct), 64)
+		if err != nil || n < 0 {
+			return Threshold{}, fmt.Errorf("invalid threshold %q (expected a rate such as 1%% or 0.01)", s)
+		}
+		if ok {
+			n /= 100
+		}
+		t.Limit = n
+	case slices.Contains(BenchMetrics, t.Metric):
+		d, err := time.ParseDuration(limit)
+		if err != nil || d <= 0 {
+			return Threshold{}, fmt.Errorf("invalid threshold %q (expected a duration such as 500ms)", s)
+		}
+		t.Limit = milliseconds(d)
+	default:
+		return Threshold{}, fmt.Errorf("invalid threshold %q (unknown metric %q; expected error_rate, %s)", s, t.Metric, strings.Join(BenchMetrics, ", "))
+	}
+	return t, nil
+}
+
+// check returns an error if the results exceed the threshold.
+func (t Threshold) check(stats LatencyStats, errorRate float64) error {
+	value, actual := errorRate, fmt.Sprintf("%.2f%%", errorRate*100)
+	if t.Metric != "error_rate" {
+		value = stats.Metric(t.Metric)
+		actual = fmt.Sprintf("%.1fms", value)
+	}
+	if value < t.Limit || t.Inclusive && value == t.Limit {
+		return nil
+	}
+	return fmt.Errorf("threshold %s failed: %s was %s", t, t.Metric, actual)
+}
+
+// checkThresholds returns a message for each threshold the results exceed.
+func checkThresholds(file string, thresholds []Threshold, stats LatencyStats, errorRate float64) []string {
+	var failures []string
+	for _, t := range thresholds {
+		if err := t.check(stats, errorRate); err != nil {
+			failures = append(failures, file+": "+err.Error())
+		}
+	}
+	return failures
+}
+
+// fileThresholds reads config.thresholds from a workflow file, returning
+// nil if the file can't be read; runFile reports those errors.
+func (r *Runner) fileThresholds(path string) []Threshold {
+	spec, err := r.parseFile(path)
+	if err != nil {
+		return nil
+	}
+	return spec.Config.Thresholds
+}
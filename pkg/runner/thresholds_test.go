@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Threshold
+		err  string
+	}{
+		{in: "p95 < 500ms", want: Threshold{Metric: "p95", Limit: 500}},
+		{in: "max<=2s", want: Threshold{Metric: "max", Limit: 2000, Inclusive: true}},
+		{in: "error_rate < 1%", want: Threshold{Metric: "error_rate", Limit: 0.01}},
+		{in: "error_rate < 0.05", want: Threshold{Metric: "error_rate", Limit: 0.05}},
+		{in: "p95 500ms", err: "expected <metric> < <limit>"},
+		{in: "p90 < 500ms", err: `unknown metric "p90"`},
+		{in: "p95 < fast", err: "expected a duration"},
+		{in: "error_rate < lots", err: "expected a rate"},
+	}
+	for _, tt := range tests {
+		got, err := ParseThreshold(tt.in)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseThreshold(%q) error = %v, want %q", tt.in, err, tt.err)
+			}
+			continue
+		}
+		got.text = ""
+		if err != nil || got != tt.want {
+			t.Errorf("ParseThreshold(%q) = %+v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestThresholdCheck(t *testing.T) {
+	stats := LatencyStats{P95MS: 500, P99MS: 800}
+	for _, tt := range []struct {
+		threshold string
+		errorRate float64
+		want      string
+	}{
+		{"p95 < 600ms", 0, ""},
+		{"p95 < 500ms", 0, "threshold p95 < 500ms failed: p95 was 500.0ms"},
+		{"p95 <= 500ms", 0, ""},
+		{"p99 < 1s", 0, ""},
+		{"error_rate < 1%", 0.005, ""},
+		{"error_rate < 1%", 0.02, "threshold error_rate < 1% failed: error_rate was 2.00%"},
+	} {
+		threshold, _ := ParseThreshold(tt.threshold)
+		err := threshold.check(stats, tt.errorRate)
+		if got := fmt.Sprint(err); tt.want == "" && err != nil || tt.want != "" && got != tt.want {
+			t.Errorf("%s: check = %v, want %q", tt.threshold, err, tt.want)
+		}
+	}
+}
+
+func TestThresholdsYAML(t *testing.T) {
+	var cfg Config
+	if err := yaml.Unmarshal([]byte("thresholds:\n- p95 < 500ms\n- error_rate < 1%\n"), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Thresholds) != 2 || cfg.Thresholds[1].Limit != 0.01 {
+		t.Fatalf("thresholds = %+v", cfg.Thresholds)
+	}
+	out, _ := yaml.Marshal(cfg)
+	if !strings.Contains(string(out), "- p95 < 500ms\n") {
+		t.Errorf("marshalled config:\n%s", out)
+	}
+	if err := yaml.Unmarshal([]byte("thresholds: [p95 is fast]\n"), &cfg); err == nil {
+		t.Error("expected an error for an invalid threshold")
+	}
+}
+
+func TestLoadThresholds(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer api.Close()
+
+	path := filepath.Join(t.TempDir(), "ping.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+config:
+  base_url: "%s"
+  thresholds:
+  - p95 < 1ms
+  - error_rate < 1%%
+workflow:
+- step: "ping"
+  request:
+    url: "/ping"
+`, api.URL)), 0644)
+
+	res, err := New(5*time.Second, false).Load(path, LoadOptions{VUs: 2, Duration: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.ThresholdFailures) != 1 || !strings.HasPrefix(res.ThresholdFailures[0], path+": threshold p95 < 1ms failed: p95 was ") {
+		t.Errorf("threshold failures = %q", res.ThresholdFailures)
+	}
+}
@@ -0,0 +1,199 @@
+package runner
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+// Tracing exports each workflow file as an OpenTelemetry trace, with a span
+// per step, using OTLP over HTTP with JSON encoding.
+type Tracing struct {
+	// Endpoint is the collector's OTLP/HTTP address, such as
+	// http://localhost:4318. /v1/traces is added unless the URL has a path.
+	Endpoint string
+	// ServiceName is the service.name resource attribute. Defaults to
+	// ramjam.
+	ServiceName string
+}
+
+// WithTracing records a trace per workflow file and sends a W3C traceparent
+// header with each request, so backend spans join the test's trace. Traces
+// that can't be exported are reported as a warning.
+func WithTracing(t Tracing) Option {
+	return func(r *Runner) {
+		r.tracing = &t
+	}
+}
+
+// OTLP span kinds and status codes.
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+	statusOK         = 1
+	statusError      = 2
+)
+
+// span is a finished or in-progress span in OTLP JSON form.
+type span struct {
+	TraceID      string      `json:"traceId"`
+	SpanID       string      `json:"spanId"`
+	ParentSpanID string      `json:"parentSpanId,omitempty"`
+	Name         string      `json:"name"`
+	Kind         int         `json:"kind"`
+	Start        string      `json:"startTimeUnixNano"`
+	End          string      `json:"endTimeUnixNano"`
+	Attributes   []attribute `json:"attributes,omitempty"`
+	Status       spanStatus  `json:"status"`
+}
+
+type attribute struct {
+	Key   string         `json:"key"`
+	Value attributeValue `json:"value"`
+}
+
+type attributeValue struct {
+	String *string `json:"stringValue,omitempty"`
+	Int    *string `json:"intValue,omitempty"`
+}
+
+type spanStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func stringAttr(key, value string) attribute {
+	return attribute{Key: key, Value: attributeValue{String: &value}}
+}
+
+func intAttr(key string, value int) attribute {
+	s := strconv.Itoa(value)
+	return attribute{Key: key, Value: attributeValue{Int: &s}}
+}
+
+// tracer collects the spans of one run.
+type tracer struct {
+	mu    sync.Mutex
+	spans []*span
+}
+
+func newID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// start begins a span. An empty parent starts a new trace.
+func (t *tracer) start(name string, kind int, parent *span) *span {
+	s := &span{SpanID: newID(8), Name: name, Kind: kind, Start: unixNano(time.Now())}
+	if parent != nil {
+		s.TraceID, s.ParentSpanID = parent.TraceID, parent.SpanID
+	} else {
+		s.TraceID = newID(16)
+	}
+	return s
+}
+
+// end finishes a span, marking it failed if err is set.
+func (t *tracer) end(s *span, err error) {
+	s.End = unixNano(time.Now())
+	s.Status = spanStatus{Code: statusOK}
+	if err != nil {
+		s.Status = spanStatus{Code: statusError, Message: err.Error()}
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// traceparent returns the W3C trace context header for requests made
+// within s.
+func (s *span) traceparent() string {
+	return "00-" + s.TraceID + "-" + s.SpanID + "-01"
+}
+
+// exportTraces sends every collected span to the collector in one request.
+func (r *Runner) exportTraces(t *tracer) error {
+	if len(t.spans) == 0 {
+		return nil
+	}
+	service := r.tracing.ServiceName
+	if service == "" {
+		service = "ramjam"
+	}
+	payload := map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []attribute{stringAttr("service.name", service)},
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": "ramjam"},
+				"spans": t.spans,
+			}},
+		}},
+	}
+	data, err := json.Marshal(payload)
+	if err := e.Wrap(err, "encode traces"); err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(r.tracing.Endpoint, "/")
+	if i := strings.Index(url, "://"); i < 0 || !strings.Contains(url[i+3:], "/") {
+		url += "/v1/traces"
+	}
+	resp, err := r.client.Post(url, "application/json", bytes.NewReader(data))
+	if err := e.Wrap(err, "export traces"); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("export traces: collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// setAttr sets an attribute, replacing any earlier value so retried and
+// polled steps describe their last request.
+func (s *span) setAttr(a attribute) {
+	for i := range s.Attributes {
+		if s.Attributes[i].Key == a.Key {
+			s.Attributes[i] = a
+			return
+		}
+	}
+	s.Attributes = append(s.Attributes, a)
+}
+
+// traceRequest propagates the step's trace context and records the request
+// on its span.
+func traceRequest(step Step, req *http.Request) {
+	s := step.file.span
+	if s == nil {
+		return
+	}
+	req.Header.Set("traceparent", s.traceparent())
+	s.setAttr(stringAttr("http.request.method", req.Method))
+	s.setAttr(stringAttr("url.full", req.URL.String()))
+}
+
+// traceResponse records the response status on the step's span.
+func traceResponse(step Step, resp *http.Response) {
+	if s := step.file.span; s != nil {
+		s.setAttr(intAttr("http.response.status_code", resp.StatusCode))
+	}
+}
@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTracing(t *testing.T) {
+	var mu sync.Mutex
+	traceparents := map[string]string{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparents[r.URL.Path] = r.Header.Get("traceparent")
+		mu.Unlock()
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	var exported []byte
+	var exportPath, contentType string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exported, _ = io.ReadAll(r.Body)
+		exportPath, contentType = r.URL.Path, r.Header.Get("Content-Type")
+	}))
+	defer collector.Close()
+
+	path := filepath.Join(t.TempDir(), "orders.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+metadata:
+  name: "Orders"
+config:
+  base_url: "%s"
+workflow:
+- step: "list"
+  request:
+    url: "/orders"
+- step: "missing"
+  request:
+    url: "/missing"
+  expect:
+    status: 200
+`, api.URL)), 0644)
+
+	r := New(5*time.Second, false, WithTracing(Tracing{Endpoint: collector.URL, ServiceName: "checkout-tests"}))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{path}); err == nil {
+		t.Fatal("expected the missing step to fail")
+	}
+
+	if exportPath != "/v1/traces" || contentType != "application/json" {
+		t.Fatalf("exported to %s as %s", exportPath, contentType)
+	}
+	var payload struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []attribute `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []span `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(exported, &payload); err != nil {
+		t.Fatalf("decode export: %v\n%s", err, exported)
+	}
+	if attrs := payload.ResourceSpans[0].Resource.Attributes; *attrs[0].Value.String != "checkout-tests" {
+		t.Errorf("service.name = %s", *attrs[0].Value.String)
+	}
+
+	spans := map[string]span{}
+	for _, s := range payload.ResourceSpans[0].ScopeSpans[0].Spans {
+		spans[s.Name] = s
+	}
+	root, list, missing := spans["Orders"], spans["list"], spans["missing"]
+	if len(root.TraceID) != 32 || root.ParentSpanID != "" || root.Kind != spanKindInternal || root.Status.Code != statusError {
+		t.Errorf("file span = %+v", root)
+	}
+	for _, s := range []span{list, missing} {
+		if s.TraceID != root.TraceID || s.ParentSpanID != root.SpanID || s.Kind != spanKindClient {
+			t.Errorf("step span %s not a child of the file span: %+v", s.Name, s)
+		}
+		path := "/orders"
+		if s.Name == "missing" {
+			path = "/missing"
+		}
+		if want := "00-" + s.TraceID + "-" + s.SpanID + "-01"; traceparents[path] != want {
+			t.Errorf("traceparent for %s = %q, want %q", path, traceparents[path], want)
+		}
+	}
+	if list.Status.Code != statusOK {
+		t.Errorf("list status = %+v", list.Status)
+	}
+	if missing.Status.Code != statusError || !strings.Contains(missing.Status.Message, "404") {
+		t.Errorf("missing status = %+v", missing.Status)
+	}
+	attrs := map[string]string{}
+	for _, a := range missing.Attributes {
+		if a.Value.String != nil {
+			attrs[a.Key] = *a.Value.String
+		} else {
+			attrs[a.Key] = *a.Value.Int
+		}
+	}
+	if attrs["http.request.method"] != "GET" || attrs["url.full"] != api.URL+"/missing" || attrs["http.response.status_code"] != "404" {
+		t.Errorf("missing attributes = %v", attrs)
+	}
+}
+
+func TestTracingEndpointPath(t *testing.T) {
+	var got string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+	}))
+	defer collector.Close()
+
+	r := New(time.Second, false, WithTracing(Tracing{Endpoint: collector.URL + "/otlp/v1/traces"}))
+	tr := &tracer{}
+	tr.end(tr.start("file", spanKindInternal, nil), nil)
+	if err := r.exportTraces(tr); err != nil {
+		t.Fatal(err)
+	}
+	if got != "/otlp/v1/traces" {
+		t.Errorf("exported to %s", got)
+	}
+}
+
+func TestTracingExportError(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer collector.Close()
+
+	var warnings strings.Builder
+	r := New(5*time.Second, false, WithTracing(Tracing{Endpoint: collector.URL}), WithWarnings(&warnings))
+	r.out = io.Discard
+	if err := r.RunPaths([]string{notifyWorkflow(t, 200)}); err != nil {
+		t.Fatalf("expected an export failure not to fail the run, got %v", err)
+	}
+	if got := warnings.String(); got != "warning: export traces: collector returned 502 Bad Gateway\n" {
+		t.Errorf("warnings = %q", got)
+	}
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"strings"
+)
+
+// Verbosity controls how much the runner logs.
+type Verbosity int
+
+const (
+	// VerbosityQuiet reports only failing steps.
+	VerbosityQuiet Verbosity = iota - 1
+	// VerbosityNormal reports every step and its output.print lines.
+	VerbosityNormal
+	// VerbosityVerbose adds assertions, captures and response statuses.
+	VerbosityVerbose
+	// VerbosityDebug adds full request and response dumps.
+	VerbosityDebug
+)
+
+// WithVerbosity sets the log level, overriding the verbose argument to New.
+func WithVerbosity(v Verbosity) Option {
+	return func(r *Runner) {
+		r.verbosity = v
+	}
+}
+
+func (r *Runner) verbose() bool {
+	return r.verbosity >= VerbosityVerbose
+}
+
+func (r *Runner) debug() bool {
+	return r.verbosity >= VerbosityDebug
+}
+
+// dumpRequest logs the request line, headers and body at debug level. The
+// body is buffered by httputil, which restores it for sending.
+func (r *Runner) dumpRequest(req *http.Request, log func(string, ...interface{})) {
+	if !r.debug() {
+		return
+	}
+	dump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		log("Request dump failed: %v", err)
+		return
+	}
+	log("Request:\n%s", formatDump(dump))
+}
+
+// dumpResponseHeader logs the status line and headers at debug level. The
+// body is logged separately once it has been decoded.
+func (r *Runner) dumpResponseHeader(resp *http.Response, log func(string, ...interface{})) {
+	if !r.debug() {
+		return
+	}
+	dump, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		log("Response dump failed: %v", err)
+		return
+	}
+	log("Response:\n%s", formatDump(dump))
+}
+
+func (r *Runner) dumpResponseBody(body *bodyStream, log func(string, ...interface{})) {
+	if !r.debug() || body.buf.Len() == 0 {
+		return
+	}
+	suffix := ""
+	if body.truncated {
+		suffix = "\n... (truncated at max_body_size)"
+	}
+	log("Response body:\n%s%s", body.buf.String(), suffix)
+}
+
+func formatDump(dump []byte) string {
+	return strings.TrimRight(strings.ReplaceAll(string(dump), "\r\n", "\n"), "\n")
+}
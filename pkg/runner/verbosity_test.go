@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runCaptured runs a workflow with r and returns the report output.
+func runCaptured(t *testing.T, r *Runner, yamlContent string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "workflow.yaml")
+	if err := os.WriteFile(path, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	r.out = &out
+	if err := r.RunPaths([]string{path}); err != nil {
+		t.Fatalf("RunPaths failed: %v\n%s", err, out.String())
+	}
+	return out.String()
+}
+
+func newDumpServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Trace", "abc")
+		w.Write([]byte(`{"id": 7}`))
+	}))
+}
+
+const dumpWorkflow = `
+config:
+  base_url: "%s"
+workflow:
+- step: "create"
+  request:
+    method: "POST"
+    url: "/items"
+    body:
+      name: "widget"
+  expect:
+    status: 200
+`
+
+func TestDebugDumps(t *testing.T) {
+	srv := newDumpServer()
+	defer srv.Close()
+
+	out := runCaptured(t, New(5*time.Second, false, WithVerbosity(VerbosityDebug)), fmt.Sprintf(dumpWorkflow, srv.URL))
+	for _, want := range []string{
+		"Request:\n      POST /items HTTP/1.1",
+		`{"name":"widget"}`,
+		"Response:\n      HTTP/1.1 200 OK",
+		"X-Trace: abc",
+		"Response body:\n      {\"id\": 7}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("debug output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestVerboseHasNoDumps(t *testing.T) {
+	srv := newDumpServer()
+	defer srv.Close()
+
+	out := runCaptured(t, New(5*time.Second, true), fmt.Sprintf(dumpWorkflow, srv.URL))
+	if !strings.Contains(out, "Received status: 200") {
+		t.Errorf("expected verbose status line:\n%s", out)
+	}
+	if strings.Contains(out, "Request:") || strings.Contains(out, "X-Trace") {
+		t.Errorf("unexpected dump at verbose level:\n%s", out)
+	}
+}
@@ -0,0 +1,196 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/fsnotify/fsnotify"
+	"github.com/michaelmccabe/ramjam/pkg/config"
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// watchDebounce groups the bursts of events editors produce for one save.
+const watchDebounce = 150 * time.Millisecond
+
+// Watch runs paths, then runs them again whenever a workflow file, a file it
+// references (body files, multipart files, TLS files and OpenAPI specs) or a
+// YAML file in one of the given directories changes. after is called once
+// per run with the file that triggered it, empty for the first run, and the
+// run's error. Watch returns when ctx is cancelled.
+func (r *Runner) Watch(ctx context.Context, paths []string, after func(changed string, err error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err := e.Wrap(err, "start file watcher"); err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Parent directories are watched rather than files, so editors that save
+	// by replacing the file are still seen.
+	dirs := map[string]bool{}
+	var files map[string]bool
+	refresh := func() error {
+		var err error
+		files, err = r.watchedFiles(paths)
+		if err != nil {
+			return err
+		}
+		want := map[string]bool{}
+		for f := range files {
+			want[filepath.Dir(f)] = true
+		}
+		for _, p := range paths {
+			if info, err := os.Stat(p); err == nil && info.IsDir() {
+				want[filepath.Clean(p)] = true
+				if r.recursive {
+					for _, d := range subdirs(p) {
+						want[d] = true
+					}
+				}
+			} else if err != nil && isGlob(p) {
+				base, _ := doublestar.SplitPattern(filepath.ToSlash(p))
+				want[filepath.Clean(filepath.FromSlash(base))] = true
+			}
+		}
+		for d := range dirs {
+			if !want[d] {
+				watcher.Remove(d)
+				delete(dirs, d)
+			}
+		}
+		for d := range want {
+			if dirs[d] {
+				continue
+			}
+			if err := e.Wrapf(watcher.Add(d), "watch %s", d); err != nil {
+				return err
+			}
+			dirs[d] = true
+		}
+		return nil
+	}
+
+	run := func(changed string) {
+		err := r.RunPathsContext(ctx, paths)
+		if refreshErr := refresh(); err == nil {
+			err = refreshErr
+		}
+		after(changed, err)
+	}
+	run("")
+	if len(dirs) == 0 {
+		return fmt.Errorf("no files to watch")
+	}
+
+	var timer <-chan time.Time
+	var changed string
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.Errors:
+			return e.Wrap(err, "watch files")
+		case ev := <-watcher.Events:
+			if ev.Op == fsnotify.Chmod || !relevant(ev.Name, files, dirs) {
+				continue
+			}
+			changed = ev.Name
+			timer = time.After(watchDebounce)
+		case <-timer:
+			timer = nil
+			run(changed)
+		}
+	}
+}
+
+// relevant reports whether a changed path should trigger a run: a known
+// file, or a new workflow, defaults or ignore file in a watched directory.
+func relevant(name string, files, dirs map[string]bool) bool {
+	name = filepath.Clean(name)
+	if files[name] {
+		return true
+	}
+	base := filepath.Base(name)
+	return dirs[filepath.Dir(name)] && (isWorkflowFile(name) || base == DefaultsFileName || base == IgnoreFileName)
+}
+
+// subdirs returns the directories below dir that a recursive run reads.
+func subdirs(dir string) []string {
+	var dirs []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == dir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	return dirs
+}
+
+// watchedFiles returns the workflow files under paths and every local file
+// they reference.
+func (r *Runner) watchedFiles(paths []string) (map[string]bool, error) {
+	workflows, err := r.Files(paths)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]bool{}
+	for _, f := range workflows {
+		// Remote workflows are fetched on each run but can't be watched.
+		if config.IsURL(f) {
+			continue
+		}
+		files[filepath.Clean(f)] = true
+		defaults := filepath.Join(filepath.Dir(f), DefaultsFileName)
+		if _, err := os.Stat(defaults); err == nil {
+			files[defaults] = true
+		}
+		for _, ref := range referencedFiles(f) {
+			files[ref] = true
+		}
+	}
+	return files, nil
+}
+
+// referencedFiles lists the files a workflow reads, resolved against its
+// directory. Paths containing variables are skipped.
+func referencedFiles(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var spec InstructionsFile
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil
+	}
+
+	baseDir := filepath.Dir(path)
+	candidates := []string{spec.Config.OpenAPI, spec.Config.TLS.CertFile, spec.Config.TLS.KeyFile, spec.Config.TLS.CAFile}
+	for _, step := range spec.Workflow {
+		candidates = append(candidates, step.Request.BodyFile)
+		for _, part := range step.Request.Multipart {
+			candidates = append(candidates, part.File)
+		}
+	}
+
+	var refs []string
+	for _, c := range candidates {
+		if c == "" || varPattern.MatchString(c) {
+			continue
+		}
+		if !filepath.IsAbs(c) {
+			c = filepath.Join(baseDir, c)
+		}
+		refs = append(refs, filepath.Clean(c))
+	}
+	return refs
+}
@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	bodies := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies <- string(data)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	bodyFile := filepath.Join(dir, "body.json")
+	os.WriteFile(bodyFile, []byte(`{"n": 1}`), 0644)
+	os.WriteFile(filepath.Join(dir, "workflow.yaml"), []byte(fmt.Sprintf(`
+workflow:
+- step: "post"
+  request:
+    method: POST
+    url: "%s"
+    body_file: "body.json"
+`, srv.URL)), 0644)
+
+	type run struct {
+		changed string
+		err     error
+	}
+	runs := make(chan run, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	r := New(time.Second, false)
+	r.out = io.Discard
+	go func() {
+		done <- r.Watch(ctx, []string{dir}, func(changed string, err error) {
+			runs <- run{changed, err}
+		})
+	}()
+
+	next := func() run {
+		select {
+		case got := <-runs:
+			return got
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a run")
+		}
+		return run{}
+	}
+
+	if first := next(); first.changed != "" || first.err != nil {
+		t.Fatalf("unexpected first run: %+v", first)
+	}
+	if got := <-bodies; got != `{"n":1}` {
+		t.Errorf("unexpected first body %s", got)
+	}
+
+	os.WriteFile(bodyFile, []byte(`{"n": 2}`), 0644)
+	if second := next(); second.changed != bodyFile || second.err != nil {
+		t.Fatalf("unexpected second run: %+v", second)
+	}
+	if got := <-bodies; got != `{"n":2}` {
+		t.Errorf("expected edited body to be sent, got %s", got)
+	}
+
+	// Unrelated files do not trigger a run.
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644)
+	select {
+	case extra := <-runs:
+		t.Fatalf("unexpected run for unrelated file: %+v", extra)
+	case <-time.After(3 * watchDebounce):
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Watch returned %v", err)
+	}
+}
+
+func TestReferencedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "workflow.yaml")
+	os.WriteFile(path, []byte(`
+config:
+  openapi: "../api.yaml"
+  tls:
+    ca_file: "/etc/ca.pem"
+workflow:
+- step: "upload"
+  request:
+    body_file: "bodies/create.json"
+    multipart:
+    - name: "file"
+      file: "${upload}"
+    - name: "logo"
+      file: "logo.png"
+`), 0644)
+
+	got := referencedFiles(path)
+	sort.Strings(got)
+	want := []string{
+		"/etc/ca.pem",
+		filepath.Join(filepath.Dir(dir), "api.yaml"),
+		filepath.Join(dir, "bodies", "create.json"),
+		filepath.Join(dir, "logo.png"),
+	}
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("referencedFiles = %v, want %v", got, want)
+	}
+}
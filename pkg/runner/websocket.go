@@ -0,0 +1,209 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	e "github.com/michaelmccabe/ramjam/pkg/errors"
+)
+
+const defaultWebSocketReadTimeout = 10 * time.Second
+
+// WebSocketStep opens a WebSocket connection and runs an ordered script of
+// messages against it. Each message either sends a frame or waits for the
+// next received frame and asserts on it.
+type WebSocketStep struct {
+	URL         string             `yaml:"url"`
+	Headers     map[string]string  `yaml:"headers"`
+	ReadTimeout time.Duration      `yaml:"read_timeout"`
+	Messages    []WebSocketMessage `yaml:"messages"`
+}
+
+// WebSocketMessage is a single action in a WebSocket script. Messages with
+// an expect or capture block consume the next frame received from the server.
+type WebSocketMessage struct {
+	Send    string           `yaml:"send,omitempty"`
+	Expect  *WebSocketExpect `yaml:"expect,omitempty"`
+	Capture []Capture        `yaml:"capture,omitempty"`
+}
+
+// WebSocketExpect holds assertions on a received message payload.
+type WebSocketExpect struct {
+	JSONPathMatch []JSONPathVal `yaml:"json_path_match"`
+	Contains      string        `yaml:"contains,omitempty"`
+}
+
+func (r *Runner) websocketStep(ctx context.Context, step Step, vars map[string]string, log func(string, ...interface{})) error {
+	ws := step.WebSocket
+	url, err := r.applyVars(ws.URL, vars)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(url, "ws") {
+		url = websocketURL(withBaseURL(url, vars))
+	}
+
+	// The handshake gets the same headers and credentials as a request,
+	// so it is built as one.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err := e.Wrap(err, "build websocket request"); err != nil {
+		return err
+	}
+	userAgent, err := r.fileUserAgent(step.file.config, vars)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if err := r.setHeaders(req.Header, r.headers, vars); err != nil {
+		return err
+	}
+	if err := r.setHeaders(req.Header, step.file.config.Headers, vars); err != nil {
+		return err
+	}
+	if err := r.applyAuth(req, step.file.config.Auth, step.file.client, vars); err != nil {
+		return err
+	}
+	if err := r.setHeaders(req.Header, ws.Headers, vars); err != nil {
+		return err
+	}
+	if h := stepHMAC(step); h != nil {
+		if err := e.Wrap(r.signHMAC(req, h, vars), "hmac auth"); err != nil {
+			return err
+		}
+	}
+	// An api_key sent in the query changes the URL.
+	url = req.URL.String()
+	header := req.Header
+	if s := step.file.span; s != nil {
+		header.Set("traceparent", s.traceparent())
+		s.setAttr(stringAttr("url.full", url))
+	}
+
+	if r.dryRun {
+		log("WebSocket %s", url)
+		for _, msg := range ws.Messages {
+			if msg.Send != "" {
+				payload, err := r.applyVars(msg.Send, vars)
+				if err != nil {
+					return err
+				}
+				log("Send: %s", payload)
+			}
+		}
+		return nil
+	}
+
+	dialer := websocket.Dialer{
+		HandshakeTimeout: step.file.client.Timeout,
+		Proxy:            http.ProxyFromEnvironment,
+	}
+	if t, ok := httpTransport(step.file.client.Transport); ok {
+		dialer.TLSClientConfig = t.TLSClientConfig
+		dialer.Proxy = t.Proxy
+	}
+	for rt := step.file.client.Transport; rt != nil; rt = innerTransport(rt) {
+		if l, ok := rt.(*limitedTransport); ok {
+			if err := l.wait(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	step.file.exchange.request = "GET " + url
+	conn, resp, err := dialer.DialContext(ctx, url, header)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err := e.Wrapf(err, "websocket connect %s", url); err != nil {
+		return networkError(err)
+	}
+	defer conn.Close()
+
+	if r.verbose() {
+		log("Connected to %s", url)
+	}
+
+	readTimeout := ws.ReadTimeout
+	if readTimeout <= 0 {
+		readTimeout = defaultWebSocketReadTimeout
+	}
+
+	for i, msg := range ws.Messages {
+		if msg.Send != "" {
+			payload, err := r.applyVars(msg.Send, vars)
+			if err != nil {
+				return err
+			}
+			if r.verbose() {
+				log("Sending message: %s", payload)
+			}
+			if err := e.Wrapf(conn.WriteMessage(websocket.TextMessage, []byte(payload)), "websocket send message %d", i); err != nil {
+				return networkError(err)
+			}
+		}
+
+		if msg.Expect == nil && len(msg.Capture) == 0 {
+			continue
+		}
+
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		_, data, err := conn.ReadMessage()
+		if err := e.Wrapf(err, "websocket read message %d", i); err != nil {
+			return networkError(err)
+		}
+		if r.verbose() {
+			log("Received message: %s", string(data))
+		}
+
+		if err := r.checkWebSocketMessage(msg, data, vars, log); err != nil {
+			return e.Mark(e.Wrapf(err, "websocket message %d", i), e.AssertionError)
+		}
+	}
+
+	return r.printOutput(step, vars, log)
+}
+
+func (r *Runner) checkWebSocketMessage(msg WebSocketMessage, data []byte, vars map[string]string, log func(string, ...interface{})) error {
+	var matchers []JSONPathVal
+	if msg.Expect != nil {
+		if msg.Expect.Contains != "" {
+			expected, err := r.applyVars(msg.Expect.Contains, vars)
+			if err != nil {
+				return err
+			}
+			if !strings.Contains(string(data), expected) {
+				return fmt.Errorf("expected message to contain %q, got %q", expected, string(data))
+			}
+		}
+		matchers = msg.Expect.JSONPathMatch
+	}
+
+	if len(matchers) == 0 && len(msg.Capture) == 0 {
+		return nil
+	}
+
+	var jsonObj interface{}
+	if err := e.Wrap(json.Unmarshal(data, &jsonObj), "parse message json"); err != nil {
+		return err
+	}
+	if err := r.matchJSONPaths(jsonObj, matchers, vars, log); err != nil {
+		return err
+	}
+	return r.captureValues(msg.Capture, jsonObj, nil, vars, log)
+}
+
+// websocketURL maps http(s) URLs, such as those built from base_url, onto
+// their ws(s) equivalents.
+func websocketURL(url string) string {
+	switch {
+	case strings.HasPrefix(url, "https://"):
+		return "wss://" + strings.TrimPrefix(url, "https://")
+	case strings.HasPrefix(url, "http://"):
+		return "ws://" + strings.TrimPrefix(url, "http://")
+	}
+	return url
+}
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func newWebSocketServer(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Room") != "lobby" {
+			t.Errorf("expected X-Room header lobby, got %s", r.Header.Get("X-Room"))
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "welcome", "session": "s-1"}`))
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type": "echo", "payload": %s}`, data)))
+		}
+	}))
+}
+
+func TestWebSocketStep(t *testing.T) {
+	srv := newWebSocketServer(t)
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "WebSocket"
+config:
+  base_url: "%s"
+workflow:
+- step: "chat"
+  websocket:
+    url: "/ws"
+    headers:
+      X-Room: "lobby"
+    read_timeout: 2s
+    messages:
+    - expect:
+        json_path_match:
+        - path: "type"
+          value: "welcome"
+      capture:
+      - json_path: "session"
+        as: "session_id"
+    - send: '{"session": "${session_id}", "text": "hi"}'
+      expect:
+        contains: "echo"
+        json_path_match:
+        - path: "payload.session"
+          value: "s-1"
+        - path: "payload.text"
+          value: "hi"
+  output:
+    print: "Session ${session_id}"
+`, srv.URL)
+
+	runTest(t, yamlContent)
+}
+
+func TestWebSocketExpectationFailure(t *testing.T) {
+	srv := newWebSocketServer(t)
+	defer srv.Close()
+
+	yamlContent := fmt.Sprintf(`
+metadata:
+  name: "WebSocket Failure"
+workflow:
+- step: "chat"
+  websocket:
+    url: "%s/ws"
+    headers:
+      X-Room: "lobby"
+    messages:
+    - expect:
+        json_path_match:
+        - path: "type"
+          value: "goodbye"
+`, "ws"+strings.TrimPrefix(srv.URL, "http"))
+
+	err := runTestError(t, yamlContent)
+	if err == nil || !strings.Contains(err.Error(), `websocket message 0: jsonpath type expected "goodbye", got "welcome"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestWebSocketConfigHeadersAndAuth(t *testing.T) {
+	var seen http.Header
+	var query string
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, query = r.Header.Clone(), r.URL.RawQuery
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	runTest(t, fmt.Sprintf(`
+config:
+  base_url: "%s"
+  headers:
+    X-Tenant: "acme"
+    X-Room: "hall"
+  auth:
+    bearer: "t0ken"
+    api_key:
+      name: "key"
+      value: "k1"
+      in: "query"
+workflow:
+- step: "chat"
+  websocket:
+    url: "/ws"
+    headers:
+      X-Room: "lobby"
+`, srv.URL))
+
+	for name, want := range map[string]string{"X-Tenant": "acme", "X-Room": "lobby", "Authorization": "Bearer t0ken"} {
+		if got := seen.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if query != "key=k1" {
+		t.Errorf("query = %q, want key=k1", query)
+	}
+}
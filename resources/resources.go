@@ -0,0 +1,9 @@
+// Package resources holds files compiled into the ramjam binary.
+package resources
+
+import _ "embed"
+
+// Commands is the text for ramjam's commands, from commands.yaml.
+//
+//go:embed commands.yaml
+var Commands []byte